@@ -0,0 +1,446 @@
+// Package aa implements Andersson's AA tree, a variant of the redblack tree
+// whose red links lean right and are represented by levels instead of
+// colors: a node shares its level only with its right child and never with
+// its right grandchild. Two operations, skew and split, restore the rules
+// after any insertion or deletion, which makes it the simplest balanced class
+// of package bst.
+package aa
+
+import (
+	"math/bits"
+
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+func init() {
+	bst.Register("aa", bst.Factory{
+		New: New[any, any],
+		Typed: []interface{}{
+			New[int, int], New[int, string], New[int, any],
+			New[string, int], New[string, string], New[string, any],
+		},
+		Multimap: true,
+	})
+}
+
+type aaTree[K, V any] struct {
+	root    *node[K, V]
+	comp    bst.Comparator[K]
+	aug     bst.Augmenter[K, V]
+	enc     bst.Encoding[K, V]
+	stats   *bst.Stats
+	multi   bool               // MultiKeys 模式
+	updates []bst.Option[K, V] // 结构变化后自底向上更新节点
+}
+
+// New returns an empty AA tree. It is not safe for
+// concurrent use with writes, wrap it by bst.Synchronized to let readers run
+// in parallel.
+func New[K, V any](parms ...interface{}) bst.BST[K, V] {
+	t := new(aaTree[K, V])
+	t.comp = bst.DefaultCompare[K]()
+	for _, p := range parms {
+		switch v := p.(type) {
+		case bst.Comparator[K]:
+			t.comp = v
+		case func(a, b K) int:
+			t.comp = v
+		case bst.Augmenter[K, V]:
+			t.aug = v
+		case bst.Encoding[K, V]:
+			t.enc = v
+		case *bst.Stats:
+			t.stats = v
+		case bst.Mode:
+			t.multi = v == bst.MultiKeys
+		}
+	}
+	t.comp = bst.CountComparisons(t.comp, t.stats)
+	t.updates = []bst.Option[K, V]{updateSize[K, V]}
+	if t.aug != nil {
+		t.updates = append(t.updates, bst.AugmentOption(t.aug))
+	}
+	return t
+}
+
+func (t *aaTree[K, V]) Root() bst.Node[K, V] { return t.root }
+
+// Len returns the number of keys in the tree
+func (t *aaTree[K, V]) Len() int {
+	if t.root == nil {
+		return 0
+	}
+	return t.root.size
+}
+
+func (t *aaTree[K, V]) Print() {
+	bst.Print[K, V](t.root)
+}
+
+func (t *aaTree[K, V]) Search(key K) (bst.Node[K, V], bool) {
+	if n := t.find(key); n != nil {
+		return n, true
+	}
+	return nil, false
+}
+
+// find 查找 key，多重映射中返回最早插入的节点
+func (t *aaTree[K, V]) find(key K) *node[K, V] {
+	var hit *node[K, V]
+	for n := t.root; n != nil; {
+		c := t.comp(key, n.key)
+		switch {
+		case c < 0:
+			n = n.lchild
+		case c > 0:
+			n = n.rchild
+		case !t.multi:
+			return n
+		default:
+			hit, n = n, n.lchild
+		}
+	}
+	return hit
+}
+
+// dir 返回目标节点 target 相对于 h 的方向，相等的关键码中 target 最早插入，位于其余节点的左侧
+func (t *aaTree[K, V]) dir(h, target *node[K, V]) int {
+	if h == target {
+		return 0
+	}
+	if c := t.comp(target.key, h.key); c != 0 {
+		return c
+	}
+	return -1
+}
+
+// skew 右旋消除与 h 同层的左孩子，返回新的子树根
+func (t *aaTree[K, V]) skew(h *node[K, V]) *node[K, V] {
+	l := h.lchild
+	if l == nil || l.level != h.level {
+		return h
+	}
+	link(l.rchild, h, h.rchild, t.updates)
+	t.stats.Add(1)
+	return link(l.lchild, l, h, t.updates)
+}
+
+// split 左旋并提升中间节点，消除与 h 同层的右孙子，返回新的子树根
+func (t *aaTree[K, V]) split(h *node[K, V]) *node[K, V] {
+	r := h.rchild
+	if r == nil || r.rchild == nil || r.rchild.level != h.level {
+		return h
+	}
+	link(h.lchild, h, r.lchild, t.updates)
+	r.level++
+	t.stats.Add(1)
+	return link(h, r, r.rchild, t.updates)
+}
+
+// fixDel 删除后将 h 降至比孩子中较低的层数高一层，同层的右孩子随之下降，
+// 再以至多三次 skew 与两次 split 恢复层数的约束
+func (t *aaTree[K, V]) fixDel(h *node[K, V]) *node[K, V] {
+	if want := min(lv(h.lchild), lv(h.rchild)) + 1; want < h.level {
+		h.level = want
+		if want < lv(h.rchild) {
+			h.rchild.level = want
+		}
+	}
+	h = t.skew(h)
+	if r := h.rchild; r != nil {
+		r = t.skew(r)
+		if r.rchild != nil {
+			link(r.lchild, r, t.skew(r.rchild), t.updates)
+		}
+		link(h.lchild, h, r, t.updates)
+	}
+	h = t.split(h)
+	if h.rchild != nil {
+		link(h.lchild, h, t.split(h.rchild), t.updates)
+	}
+	return h
+}
+
+// insertion 一次插入的参数与结果
+type insertion[K, V any] struct {
+	key  K
+	data V
+	last bool        // 多重映射中插入到相等的关键码之后，否则查找最早插入的相等节点
+	hit  *node[K, V] // 已有的相等节点，此时不插入
+	new  *node[K, V] // 插入的节点
+}
+
+// put 插入 in 描述的关键码，已有相等的节点且非 in.last 时只记录该节点
+func (t *aaTree[K, V]) put(in *insertion[K, V]) {
+	root := t.insert(t.root, in)
+	if in.new != nil {
+		t.root = orphan(root)
+	}
+}
+
+// insert 在以 h 为根的子树中插入，返回新的子树根
+func (t *aaTree[K, V]) insert(h *node[K, V], in *insertion[K, V]) *node[K, V] {
+	if h == nil {
+		if in.hit != nil {
+			return nil
+		}
+		in.new = &node[K, V]{key: in.key, data: in.data, level: 1}
+		update(t.updates, in.new)
+		return in.new
+	}
+	l, r := h.lchild, h.rchild
+	c := t.comp(in.key, h.key)
+	switch {
+	case c == 0 && !t.multi:
+		in.hit = h
+		return h
+	case c < 0 || c == 0 && !in.last:
+		if c == 0 {
+			in.hit = h
+		}
+		l = t.insert(l, in)
+	default:
+		r = t.insert(r, in)
+	}
+	if in.new == nil {
+		return h
+	}
+	link(l, h, r, t.updates)
+	return t.split(t.skew(h))
+}
+
+func (t *aaTree[K, V]) Insert(key K, data V) (_ bst.Node[K, V], err error) {
+	if bst.Debug {
+		defer bst.MustValidate(t)
+	}
+	defer bst.CatchIncomparable(&err)
+	in := insertion[K, V]{key: key, data: data, last: true}
+	t.put(&in)
+	if in.hit != nil {
+		return nil, bst.ErrDuplicateKey
+	}
+	return in.new, nil
+}
+
+func (t *aaTree[K, V]) Remove(key K) (_ bst.Node[K, V], err error) {
+	if bst.Debug {
+		defer bst.MustValidate(t)
+	}
+	defer bst.CatchIncomparable(&err)
+	n := t.find(key)
+	if n == nil {
+		return nil, bst.ErrNotFound
+	}
+	t.detach(n)
+	return n, nil
+}
+
+// detach 删除节点 n
+func (t *aaTree[K, V]) detach(n *node[K, V]) {
+	t.root = orphan(t.delete(t.root, n))
+	n.lchild, n.rchild, n.parent, n.level = nil, nil, nil, 0
+}
+
+// delete 从以 h 为根的子树中删除 target，返回新的子树根。target 有左孩子时也有右孩子，
+// 以其后继节点替代它，否则以其右孩子替代
+func (t *aaTree[K, V]) delete(h, target *node[K, V]) *node[K, V] {
+	switch d := t.dir(h, target); {
+	case d < 0:
+		link(t.delete(h.lchild, target), h, h.rchild, t.updates)
+	case d > 0:
+		link(h.lchild, h, t.delete(h.rchild, target), t.updates)
+	case h.lchild == nil:
+		return h.rchild
+	default:
+		var succ *node[K, V]
+		r := t.deleteMin(h.rchild, &succ)
+		succ.level = h.level
+		h = link(h.lchild, succ, r, t.updates)
+	}
+	return t.fixDel(h)
+}
+
+// deleteMin 删除子树中的最小节点并记录于 min，返回新的子树根
+func (t *aaTree[K, V]) deleteMin(h *node[K, V], min **node[K, V]) *node[K, V] {
+	if h.lchild == nil {
+		*min = h
+		return h.rchild
+	}
+	link(t.deleteMin(h.lchild, min), h, h.rchild, t.updates)
+	return t.fixDel(h)
+}
+
+// Put inserts key with data or replaces its data after a single search
+func (t *aaTree[K, V]) Put(key K, data V) (old V, replaced bool, err error) {
+	if bst.Debug {
+		defer bst.MustValidate(t)
+	}
+	defer bst.CatchIncomparable(&err)
+	in := insertion[K, V]{key: key, data: data}
+	t.put(&in)
+	if in.hit == nil {
+		return old, false, nil
+	}
+	old = in.hit.data
+	t.replace(in.hit, data)
+	return old, true, nil
+}
+
+// replace 替换节点的数据，数据参与增强时更新摘要
+func (t *aaTree[K, V]) replace(n *node[K, V], data V) {
+	n.data = data
+	if t.aug != nil {
+		bst.UpdateAbove[K, V](n, t.updates...)
+	}
+}
+
+func (t *aaTree[K, V]) Update(key K, fn func(old V, ok bool) (V, bool)) (err error) {
+	if bst.Debug {
+		defer bst.MustValidate(t)
+	}
+	defer bst.CatchIncomparable(&err)
+	n := t.find(key)
+	var old V
+	if n != nil {
+		old = n.data
+	}
+	data, keep := fn(old, n != nil)
+	switch {
+	case n != nil && keep:
+		t.replace(n, data)
+	case n != nil:
+		t.detach(n)
+	case keep:
+		t.put(&insertion[K, V]{key: key, data: data, last: true})
+	}
+	return nil
+}
+
+func (t *aaTree[K, V]) GetOrInsert(key K, data V) (_ bst.Node[K, V], found bool, err error) {
+	if bst.Debug {
+		defer bst.MustValidate(t)
+	}
+	defer bst.CatchIncomparable(&err)
+	in := insertion[K, V]{key: key, data: data}
+	t.put(&in)
+	if in.hit != nil {
+		return in.hit, true, nil
+	}
+	return in.new, false, nil
+}
+
+// Count returns the number of entries of key in O(log n)
+func (t *aaTree[K, V]) Count(key K) int { return bst.CountRange(t.root, key, key, t.comp) }
+
+func (t *aaTree[K, V]) All(key K, fn func(n bst.Node[K, V]) bool) {
+	bst.Range(t.root, key, key, true, true, t.comp, fn)
+}
+
+// RemoveOne removes the oldest entry of key as Remove does
+func (t *aaTree[K, V]) RemoveOne(key K) (bst.Node[K, V], error) { return t.Remove(key) }
+
+func (t *aaTree[K, V]) RemoveAll(key K) (int, error) { return bst.RemoveEach(key, t.Remove) }
+
+// BuildSorted builds a tree of minimum height from the sorted keys in O(n)
+func (t *aaTree[K, V]) BuildSorted(keys []K, values []V, verify bool) error {
+	if verify {
+		check := bst.CheckSorted[K]
+		if t.multi {
+			check = bst.CheckSortedMulti[K]
+		}
+		if err := check(keys, t.comp); err != nil {
+			return err
+		}
+	}
+	t.root = orphan(t.build(keys, values))
+	return nil
+}
+
+// build 构建根的层数为 b = bits.Len(n+1)-1 的子树。两个孩子的层数均为 b-1 时各有
+// [2^(b-1)-1, 2^b-2] 个关键码，n 为 2^(b+1)-2 时超出该范围，根带一个同层的右孩子
+func (t *aaTree[K, V]) build(keys []K, values []V) *node[K, V] {
+	n := len(keys)
+	if n == 0 {
+		return nil
+	}
+	b := bits.Len(uint(n+1)) - 1
+	lo, hi, m := 1<<(b-1)-1, 1<<b-2, n-1
+	if m > 2*hi {
+		rk, rv := keys[hi+1:], values[hi+1:]
+		r := link(t.build(rk[:lo], rv[:lo]), &node[K, V]{key: rk[lo], data: rv[lo], level: b}, t.build(rk[lo+1:], rv[lo+1:]), t.updates)
+		return link(t.build(keys[:hi], values[:hi]), &node[K, V]{key: keys[hi], data: values[hi], level: b}, r, t.updates)
+	}
+	mid := m - min(m-lo, hi)
+	return link(t.build(keys[:mid], values[:mid]), &node[K, V]{key: keys[mid], data: values[mid], level: b}, t.build(keys[mid+1:], values[mid+1:]), t.updates)
+}
+
+func (t *aaTree[K, V]) Walk(o bst.Order, opts ...bst.Option[K, V]) {
+	switch o {
+	case bst.PreOrder:
+		bst.TravPre(t.root, opts...)
+	case bst.InOrder:
+		bst.TravIn(t.root, opts...)
+	case bst.PostOrder:
+		bst.TravPost(t.root, opts...)
+	case bst.LevelOrder:
+		bst.TravLevel(t.root, opts...)
+	case bst.ReverseInOrder:
+		bst.TravReverseIn(t.root, opts...)
+	default:
+		panic("unsupported walk order")
+	}
+}
+
+func (t *aaTree[K, V]) Traverse(o bst.Order, fn func(n bst.Node[K, V]) bool) bool {
+	return bst.Traverse[K, V](t.root, o, fn)
+}
+
+func (t *aaTree[K, V]) Iterator() bst.Iterator[K, V] {
+	return bst.NewIterator[K, V](t, t.comp)
+}
+
+func (t *aaTree[K, V]) Floor(key K) (bst.Node[K, V], bool) {
+	return bst.Floor(t.root, key, t.comp)
+}
+
+func (t *aaTree[K, V]) Ceiling(key K) (bst.Node[K, V], bool) {
+	return bst.Ceiling(t.root, key, t.comp)
+}
+
+func (t *aaTree[K, V]) Lower(key K) (bst.Node[K, V], bool) {
+	return bst.Lower(t.root, key, t.comp)
+}
+
+func (t *aaTree[K, V]) Higher(key K) (bst.Node[K, V], bool) {
+	return bst.Higher(t.root, key, t.comp)
+}
+
+func (t *aaTree[K, V]) Min() (bst.Node[K, V], bool) { return bst.Min(t.root) }
+
+func (t *aaTree[K, V]) Max() (bst.Node[K, V], bool) { return bst.Max(t.root) }
+
+func (t *aaTree[K, V]) Range(lo, hi K, loInclusive, hiInclusive bool, fn func(n bst.Node[K, V]) bool) {
+	bst.Range(t.root, lo, hi, loInclusive, hiInclusive, t.comp, fn)
+}
+
+func (t *aaTree[K, V]) Select(k int) (bst.Node[K, V], bool) { return bst.Select(t.root, k) }
+
+func (t *aaTree[K, V]) Rank(key K) int { return bst.Rank(t.root, key, t.comp) }
+
+func (t *aaTree[K, V]) CountRange(lo, hi K) int {
+	return bst.CountRange(t.root, lo, hi, t.comp)
+}
+
+func (t *aaTree[K, V]) Summary() any {
+	if t.aug == nil {
+		return nil
+	}
+	return bst.SummaryOf[K, V](t.root, t.aug)
+}
+
+func (t *aaTree[K, V]) SummaryRange(lo, hi K) any {
+	if t.aug == nil {
+		return nil
+	}
+	return bst.SummaryRange[K, V](t.root, lo, hi, t.comp, t.aug)
+}
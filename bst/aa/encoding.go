@@ -0,0 +1,48 @@
+package aa
+
+import (
+	"errors"
+
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+// MarshalBinary encodes the tree as configured by the bst.Encoding given to
+// New, the levels are not encoded since the shape determines them
+func (t *aaTree[K, V]) MarshalBinary() ([]byte, error) {
+	return bst.EncodeBinary[K, V](t, bst.AATree, t.enc)
+}
+
+// UnmarshalBinary replaces the content of the tree by data from MarshalBinary
+func (t *aaTree[K, V]) UnmarshalBinary(data []byte) error {
+	return bst.DecodeBinary[K, V](t, bst.AATree, t.enc, data, t.shapeBuilder())
+}
+
+func (t *aaTree[K, V]) MarshalJSON() ([]byte, error) {
+	return bst.EncodeJSON[K, V](t, bst.AATree, t.enc)
+}
+
+func (t *aaTree[K, V]) UnmarshalJSON(data []byte) error {
+	return bst.DecodeJSON[K, V](t, bst.AATree, t.enc, data, t.shapeBuilder())
+}
+
+var errLevel = errors.New("aa: decoding a shape violating the levels")
+
+// shapeBuilder 按原有形状重建节点，层数比左孩子高一层，拒绝不满足层数约束的形状
+func (t *aaTree[K, V]) shapeBuilder() bst.ShapeBuilder[K, V] {
+	return bst.ShapeBuilder[K, V]{
+		Comp:  t.comp,
+		Multi: t.multi,
+		Node: func(key K, data V, _ bool, l, r bst.Node[K, V]) (bst.Node[K, V], error) {
+			l0, r0 := nodeOf(l), nodeOf(r)
+			n := &node[K, V]{key: key, data: data, level: lv(l0) + 1}
+			if checkLevel(link(l0, n, r0, t.updates)) != nil {
+				return nil, errLevel
+			}
+			return n, nil
+		},
+		SetRoot: func(root bst.Node[K, V]) error {
+			t.root = orphan(nodeOf(root))
+			return nil
+		},
+	}
+}
@@ -0,0 +1,13 @@
+package aa_test
+
+import (
+	"testing"
+
+	"github.com/mooncaker816/gostructure/bst"
+	_ "github.com/mooncaker816/gostructure/bst/aa"
+	"github.com/mooncaker816/gostructure/bst/bsttest"
+)
+
+func FuzzAATree(f *testing.F) {
+	bsttest.Fuzz(f, bsttest.ClassTarget(bst.AATree))
+}
@@ -0,0 +1,38 @@
+package aa
+
+import (
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+// Validate checks the key order, the parent pointers, the sizes and the
+// levels of the AA tree
+func (t *aaTree[K, V]) Validate() error {
+	validate := bst.ValidateBinary[K, V]
+	if t.multi {
+		validate = bst.ValidateBinaryMulti[K, V]
+	}
+	_, err := validate(bst.AATree, t.root, t.comp, func(m bst.Node[K, V]) error {
+		n := m.(*node[K, V])
+		if err := checkLevel(n); err != nil {
+			return err
+		}
+		if size := bst.Size[K, V](n.lchild) + bst.Size[K, V](n.rchild) + 1; n.size != size {
+			return bst.NewInvariantError(bst.AATree, bst.RuleSize, n.key, "size %d, want %d", n.size, size)
+		}
+		return nil
+	})
+	return err
+}
+
+// checkLevel 检查层数的约束：左孩子低一层，右孩子同层或低一层，右孙子低于 n，叶节点为 1 层
+func checkLevel[K, V any](n *node[K, V]) error {
+	switch {
+	case lv(n.lchild) != n.level-1:
+		return bst.NewInvariantError(bst.AATree, bst.RuleLevel, n.key, "level %d, left child on level %d", n.level, lv(n.lchild))
+	case lv(n.rchild) != n.level && lv(n.rchild) != n.level-1:
+		return bst.NewInvariantError(bst.AATree, bst.RuleLevel, n.key, "level %d, right child on level %d", n.level, lv(n.rchild))
+	case n.rchild != nil && lv(n.rchild.rchild) == n.level:
+		return bst.NewInvariantError(bst.AATree, bst.RuleLevel, n.key, "right grandchild on the same level %d", n.level)
+	}
+	return nil
+}
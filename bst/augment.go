@@ -0,0 +1,114 @@
+package bst
+
+// Augmenter computes the summary of a node from the node itself and the
+// summaries of its two subtrees. Passing an Augmenter to New makes the tree
+// maintain the summary of every subtree through insertion, removal, rotation
+// and splaying, such as subtree sums, min/max or hashes.
+type Augmenter[K, V any] interface {
+	// Empty returns the summary of an empty subtree
+	Empty() any
+	// Aggregate returns the summary of the subtree rooted on n
+	Aggregate(left any, n Node[K, V], right any) any
+}
+
+// Augmented is implemented by the nodes able to keep a summary
+type Augmented interface {
+	Summary() any
+	SetSummary(s any)
+}
+
+// Summarizer is implemented by the trees maintaining an Augmenter
+type Summarizer[K any] interface {
+	// Summary returns the summary of the whole tree
+	Summary() any
+	// SummaryRange returns the summary of the keys in [lo, hi]
+	SummaryRange(lo, hi K) any
+}
+
+// Augment returns an Augmenter whose summaries are of type S
+func Augment[K, V, S any](empty S, aggregate func(left S, n Node[K, V], right S) S) Augmenter[K, V] {
+	return augmentation[K, V, S]{empty, aggregate}
+}
+
+type augmentation[K, V, S any] struct {
+	empty     S
+	aggregate func(left S, n Node[K, V], right S) S
+}
+
+func (a augmentation[K, V, S]) Empty() any { return a.empty }
+
+func (a augmentation[K, V, S]) Aggregate(left any, n Node[K, V], right any) any {
+	return a.aggregate(left.(S), n, right.(S))
+}
+
+// AugmentOption returns an Option recomputing the summary of a node by a,
+// whose children must have their summaries up to date
+func AugmentOption[K, V any](a Augmenter[K, V]) Option[K, V] {
+	return func(n Node[K, V]) {
+		n.(Augmented).SetSummary(a.Aggregate(SummaryOf(n.LChild(), a), n, SummaryOf(n.RChild(), a)))
+	}
+}
+
+// SummaryOf returns the summary of the subtree rooted on n
+func SummaryOf[K, V any](n Node[K, V], a Augmenter[K, V]) any {
+	if IsNil(n) {
+		return a.Empty()
+	}
+	return n.(Augmented).Summary()
+}
+
+// SummaryRange returns the summary of the nodes with keys in [lo, hi] in the
+// subtree rooted on n, visiting O(h) nodes
+func SummaryRange[K, V any](n Node[K, V], lo, hi K, comp Comparator[K], a Augmenter[K, V]) any {
+	if comp(lo, hi) > 0 {
+		return a.Empty()
+	}
+	return summaryRange(n, lo, hi, true, true, comp, a)
+}
+
+// summaryRange 仅在 loBounded/hiBounded 时检查对应一侧的边界
+func summaryRange[K, V any](n Node[K, V], lo, hi K, loBounded, hiBounded bool, comp Comparator[K], a Augmenter[K, V]) any {
+	if IsNil(n) {
+		return a.Empty()
+	}
+	if !loBounded && !hiBounded {
+		return SummaryOf(n, a)
+	}
+	if loBounded && comp(n.Key(), lo) < 0 {
+		return summaryRange(n.RChild(), lo, hi, loBounded, hiBounded, comp, a)
+	}
+	if hiBounded && comp(n.Key(), hi) > 0 {
+		return summaryRange(n.LChild(), lo, hi, loBounded, hiBounded, comp, a)
+	}
+	left := summaryRange(n.LChild(), lo, hi, loBounded, false, comp, a)
+	right := summaryRange(n.RChild(), lo, hi, false, hiBounded, comp, a)
+	return a.Aggregate(left, n, right)
+}
+
+// Summary returns the summary of keys in [lo, hi] of t as S, t should be
+// created with an Augmenter whose summaries are of type S
+func Summary[S, K, V any](t BST[K, V], lo, hi K) S {
+	if s, ok := t.(Summarizer[K]); ok {
+		return as[S](s.SummaryRange(lo, hi))
+	}
+	var zero S
+	return zero
+}
+
+// eraseAugmenter 将 Augmenter[K, V] 包装为 Augmenter[any, any]
+func eraseAugmenter[K, V any](a Augmenter[K, V]) Augmenter[any, any] {
+	if a0, ok := any(a).(Augmenter[any, any]); ok {
+		return a0
+	}
+	return erasedAugmenter[K, V]{a}
+}
+
+type erasedAugmenter[K, V any] struct {
+	a Augmenter[K, V]
+}
+
+func (e erasedAugmenter[K, V]) Empty() any { return e.a.Empty() }
+
+func (e erasedAugmenter[K, V]) Aggregate(left any, n Node[any, any], right any) any {
+	return e.a.Aggregate(left, wrapNode[K, V](n), right)
+}
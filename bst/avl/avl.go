@@ -1,27 +1,38 @@
 package avl
 
 import (
-	"errors"
-
 	"github.com/mooncaker816/gostructure/bst"
 )
 
 func init() {
-	bst.RegisterBST(bst.AVL, New)
+	bst.Register("avl", bst.Factory{
+		New: New[any, any],
+		Typed: []interface{}{
+			New[int, int], New[int, string], New[int, any],
+			New[string, int], New[string, string], New[string, any],
+		},
+		Multimap: true,
+	})
 }
 
 // AVL tree
-type avl struct {
-	root *node
-	comp bst.Comparator
+type avl[K, V any] struct {
+	root    *node[K, V]
+	comp    bst.Comparator[K]
+	aug     bst.Augmenter[K, V]
+	enc     bst.Encoding[K, V]
+	stats   *bst.Stats
+	multi   bool               // MultiKeys 模式
+	updates []bst.Option[K, V] // 结构变化后自底向上更新节点
+	rotates []bst.Option[K, V] // 旋转后更新节点
 }
 
-func avlOK(n *node) bool {
+func avlOK[K, V any](n *node[K, V]) bool {
 	lh, rh := -1, -1
-	if bst.HasLChild(n) {
+	if n.lchild != nil {
 		lh = n.lchild.height
 	}
-	if bst.HasRChild(n) {
+	if n.rchild != nil {
 		rh = n.rchild.height
 	}
 	diff := lh - rh
@@ -31,98 +42,164 @@ func avlOK(n *node) bool {
 	return true
 }
 
-// New returns a new empty avl tree with basic comparator
-func New(parms ...interface{}) bst.BST {
-	t := new(avl)
-	t.comp = bst.BasicCompare
+// New returns a new empty avl tree with the default comparator of K.
+// Concurrent reads are safe as long as no write runs, use bst.Synchronized
+// to share the tree with writers.
+func New[K, V any](parms ...interface{}) bst.BST[K, V] {
+	t := new(avl[K, V])
+	t.comp = bst.DefaultCompare[K]()
 	for _, p := range parms {
 		switch v := p.(type) {
-		case bst.Comparator:
+		case bst.Comparator[K]:
+			t.comp = v
+		case func(a, b K) int:
 			t.comp = v
+		case bst.Augmenter[K, V]:
+			t.aug = v
+		case bst.Encoding[K, V]:
+			t.enc = v
+		case *bst.Stats:
+			t.stats = v
+		case bst.Mode:
+			t.multi = v == bst.MultiKeys
 		}
 	}
+	t.comp = bst.CountComparisons(t.comp, t.stats)
+	t.updates = []bst.Option[K, V]{updateSize[K, V]}
+	if t.aug != nil {
+		t.updates = append(t.updates, bst.AugmentOption(t.aug))
+	}
+	t.rotates = append([]bst.Option[K, V]{updateHeight[K, V]}, t.updates...)
 	return t
 }
 
-func (avl *avl) Root() bst.Node {
+// Len returns the number of keys in the tree
+func (avl *avl[K, V]) Len() int {
+	if avl.root == nil {
+		return 0
+	}
+	return avl.root.size
+}
+
+func (avl *avl[K, V]) Root() bst.Node[K, V] {
 	return avl.root
 }
 
-func (avl *avl) Print() {
+func (avl *avl[K, V]) Print() {
 	bst.PrintWithUnitSize(avl.root, 2)
 }
 
-func (avl *avl) Search(key interface{}) (bst.Node, bool) {
-	n, result := avl.searchIn(avl.root, key)
+func (avl *avl[K, V]) Search(key K) (bst.Node[K, V], bool) {
+	if avl.root == nil {
+		return nil, false
+	}
+	n, result := avl.find(key)
 	if result == 0 {
 		return n, true
 	}
 	return n, false
 }
 
-func (avl *avl) searchIn(n *node, key interface{}) (*node, int) {
-	switch avl.comp(key, n.key) {
-	case 0:
+// find 在非空树中查找 key，多重映射中返回最早插入的节点
+func (avl *avl[K, V]) find(key K) (*node[K, V], int) {
+	if avl.multi {
+		return avl.searchMulti(avl.root, key, false)
+	}
+	return avl.searchIn(avl.root, key)
+}
+
+// searchMulti 在多重映射中查找 key 最早插入的节点，未找到或 last 时返回新节点的插入位置，
+// last 时新节点位于所有相等的关键码之后
+func (avl *avl[K, V]) searchMulti(n *node[K, V], key K, last bool) (*node[K, V], int) {
+	var hit *node[K, V]
+	for {
+		c := avl.comp(key, n.key)
+		next, result := n.rchild, 1
+		if c < 0 || c == 0 && !last {
+			next, result = n.lchild, -1
+		}
+		if c == 0 && !last {
+			hit = n
+		}
+		if next == nil {
+			if hit != nil {
+				return hit, 0
+			}
+			return n, result
+		}
+		n = next
+	}
+}
+
+func (avl *avl[K, V]) searchIn(n *node[K, V], key K) (*node[K, V], int) {
+	switch c := avl.comp(key, n.key); {
+	case c == 0:
 		return n, 0
-	case -1:
-		if bst.HasLChild(n) {
+	case c < 0:
+		if n.lchild != nil {
 			return avl.searchIn(n.lchild, key)
 		}
 		return n, -1
-	case 1:
-		if bst.HasRChild(n) {
+	default:
+		if n.rchild != nil {
 			return avl.searchIn(n.rchild, key)
 		}
 		return n, 1
-	default:
-		panic("compare result illegal")
 	}
 }
 
-func (avl *avl) Insert(key, data interface{}) (bst.Node, error) {
+func (avl *avl[K, V]) Insert(key K, data V) (_ bst.Node[K, V], err error) {
+	if bst.Debug {
+		defer bst.MustValidate(avl)
+	}
+	defer bst.CatchIncomparable(&err)
 	if avl.root == nil {
-		avl.root = newNode(key, data)
-		return avl.root, nil
+		return avl.attach(nil, 0, key, data), nil
+	}
+	if avl.multi {
+		n, result := avl.searchMulti(avl.root, key, true)
+		return avl.attach(n, result, key, data), nil
 	}
 	n, result := avl.searchIn(avl.root, key)
-	switch result {
-	case 0:
-		return nil, errors.New("insert node with duplicate key")
-	case -1:
-		new := newNode(key, data)
+	if result == 0 {
+		return nil, bst.ErrDuplicateKey
+	}
+	return avl.attach(n, result, key, data), nil
+}
+
+// attach 将新节点插入为 searchIn 返回的节点 n 的孩子，result 为查找的结果，n 为 nil 时作为根
+func (avl *avl[K, V]) attach(n *node[K, V], result int, key K, data V) *node[K, V] {
+	new := newNode(key, data)
+	switch {
+	case n == nil:
+		avl.root = new
+		bst.UpdateAbove[K, V](new, avl.updates...)
+		return new
+	case result < 0:
 		bst.AttachLChild(n, new)
-		if !bst.HasRChild(n) {
-			n.updateHeightAbove()
-		}
-		avl.reBalance(n, true)
-		return new, nil
-	case 1:
-		new := newNode(key, data)
+	default:
 		bst.AttachRChild(n, new)
-		if !bst.HasLChild(n) {
-			n.updateHeightAbove()
-		}
-		avl.reBalance(n, true)
-		return new, nil
 	}
-	return nil, nil
+	bst.UpdateAbove[K, V](new, avl.updates...)
+	avl.reBalance(n, true)
+	return new
 }
 
-func (avl *avl) reBalance(hot *node, insert bool) {
+func (avl *avl[K, V]) reBalance(hot *node[K, V], insert bool) {
 	for g := hot; g != nil; g = g.parent {
 		if !avlOK(g) {
 			x := g.parent
 			p := g.tallerChild()
 			v := p.tallerChild()
-			var tmp *node
+			var tmp *node[K, V]
 			if bst.IsLChild(g) {
-				x.lchild = rotateAndUpdateHeight(v)
+				x.lchild = avl.rotate(v)
 				tmp = x.lchild
 			} else if bst.IsRChild(g) {
-				x.rchild = rotateAndUpdateHeight(v)
+				x.rchild = avl.rotate(v)
 				tmp = x.rchild
 			} else {
-				avl.root = rotateAndUpdateHeight(v)
+				avl.root = avl.rotate(v)
 				tmp = avl.root
 			}
 			if insert {
@@ -136,26 +213,157 @@ func (avl *avl) reBalance(hot *node, insert bool) {
 	}
 }
 
-func rotateAndUpdateHeight(n *node) *node {
-	_, b, _ := bst.RotateAt(n, updateHeight)
-	return b.(*node)
+func (avl *avl[K, V]) rotate(n *node[K, V]) *node[K, V] {
+	avl.stats.Rotate(bst.Zigzag[K, V](n))
+	_, b, _ := bst.RotateAt[K, V](n, avl.rotates...)
+	return b.(*node[K, V])
 }
 
-func (avl *avl) Remove(key interface{}) (bst.Node, error) {
-	n, result := avl.searchIn(avl.root, key)
+func (avl *avl[K, V]) Remove(key K) (_ bst.Node[K, V], err error) {
+	if bst.Debug {
+		defer bst.MustValidate(avl)
+	}
+	defer bst.CatchIncomparable(&err)
+	if avl.root == nil {
+		return nil, bst.ErrNotFound
+	}
+	n, result := avl.find(key)
 	if result != 0 {
-		return nil, nil
+		return nil, bst.ErrNotFound
+	}
+	// n 有两个孩子时改为删除其后继节点，并将后继的关键码与数据移入 n，故先复制
+	removed := newNode(n.key, n.data)
+	avl.detach(n)
+	return removed, nil
+}
+
+// detach 删除节点 n
+func (avl *avl[K, V]) detach(n *node[K, V]) {
+	if n == avl.root && (n.lchild == nil || n.rchild == nil) {
+		avl.root = n.lchild
+		if avl.root == nil {
+			avl.root = n.rchild
+		}
 	}
-	hot, _ := bst.RemoveAt(n, avl.root)
-	hot0, ok := hot.(*node)
-	if ok {
-		hot0.updateHeightAbove()
+	hot, _ := bst.RemoveAt[K, V](n, avl.root, avl.updates...)
+	if hot0, ok := hot.(*node[K, V]); ok && hot0 != nil {
 		avl.reBalance(hot0, false)
 	}
-	return hot0, nil
 }
 
-func (avl *avl) Walk(o bst.Order, opts ...bst.Option) {
+// Put inserts key with data or replaces its data after a single search
+func (avl *avl[K, V]) Put(key K, data V) (old V, replaced bool, err error) {
+	if bst.Debug {
+		defer bst.MustValidate(avl)
+	}
+	defer bst.CatchIncomparable(&err)
+	if avl.root == nil {
+		avl.attach(nil, 0, key, data)
+		return old, false, nil
+	}
+	n, result := avl.find(key)
+	if result != 0 {
+		avl.attach(n, result, key, data)
+		return old, false, nil
+	}
+	old = n.data
+	avl.replace(n, data)
+	return old, true, nil
+}
+
+// replace 替换节点的数据，数据参与增强时更新摘要
+func (avl *avl[K, V]) replace(n *node[K, V], data V) {
+	n.data = data
+	if avl.aug != nil {
+		bst.UpdateAbove[K, V](n, avl.updates...)
+	}
+}
+
+func (avl *avl[K, V]) Update(key K, fn func(old V, ok bool) (V, bool)) (err error) {
+	if bst.Debug {
+		defer bst.MustValidate(avl)
+	}
+	defer bst.CatchIncomparable(&err)
+	var n *node[K, V]
+	result := 1
+	if avl.root != nil {
+		n, result = avl.find(key)
+	}
+	var old V
+	if result == 0 {
+		old = n.data
+	}
+	data, keep := fn(old, result == 0)
+	switch {
+	case result == 0 && keep:
+		avl.replace(n, data)
+	case result == 0:
+		avl.detach(n)
+	case keep:
+		avl.attach(n, result, key, data)
+	}
+	return nil
+}
+
+func (avl *avl[K, V]) GetOrInsert(key K, data V) (_ bst.Node[K, V], found bool, err error) {
+	if bst.Debug {
+		defer bst.MustValidate(avl)
+	}
+	defer bst.CatchIncomparable(&err)
+	if avl.root == nil {
+		return avl.attach(nil, 0, key, data), false, nil
+	}
+	n, result := avl.find(key)
+	if result == 0 {
+		return n, true, nil
+	}
+	return avl.attach(n, result, key, data), false, nil
+}
+
+// Count returns the number of entries of key in O(log n)
+func (avl *avl[K, V]) Count(key K) int { return bst.CountRange(avl.root, key, key, avl.comp) }
+
+func (avl *avl[K, V]) All(key K, fn func(n bst.Node[K, V]) bool) {
+	bst.Range(avl.root, key, key, true, true, avl.comp, fn)
+}
+
+// RemoveOne removes the oldest entry of key as Remove does
+func (avl *avl[K, V]) RemoveOne(key K) (bst.Node[K, V], error) { return avl.Remove(key) }
+
+func (avl *avl[K, V]) RemoveAll(key K) (int, error) { return bst.RemoveEach(key, avl.Remove) }
+
+// BuildSorted builds a perfectly balanced tree from the sorted keys in O(n)
+func (avl *avl[K, V]) BuildSorted(keys []K, values []V, verify bool) error {
+	if verify {
+		check := bst.CheckSorted[K]
+		if avl.multi {
+			check = bst.CheckSortedMulti[K]
+		}
+		if err := check(keys, avl.comp); err != nil {
+			return err
+		}
+	}
+	avl.root = avl.build(keys, values, nil)
+	return nil
+}
+
+// build 以中位数为根递归构建子树
+func (avl *avl[K, V]) build(keys []K, values []V, parent *node[K, V]) *node[K, V] {
+	if len(keys) == 0 {
+		return nil
+	}
+	mid := len(keys) / 2
+	n := newNode(keys[mid], values[mid])
+	n.parent = parent
+	n.lchild = avl.build(keys[:mid], values[:mid], n)
+	n.rchild = avl.build(keys[mid+1:], values[mid+1:], n)
+	for _, opt := range avl.rotates {
+		opt(n)
+	}
+	return n
+}
+
+func (avl *avl[K, V]) Walk(o bst.Order, opts ...bst.Option[K, V]) {
 	switch o {
 	case bst.PreOrder:
 		bst.TravPre(avl.root, opts...)
@@ -165,7 +373,63 @@ func (avl *avl) Walk(o bst.Order, opts ...bst.Option) {
 		bst.TravPost(avl.root, opts...)
 	case bst.LevelOrder:
 		bst.TravLevel(avl.root, opts...)
+	case bst.ReverseInOrder:
+		bst.TravReverseIn(avl.root, opts...)
 	default:
 		panic("unsupported walk order")
 	}
 }
+
+func (avl *avl[K, V]) Traverse(o bst.Order, fn func(n bst.Node[K, V]) bool) bool {
+	return bst.Traverse[K, V](avl.root, o, fn)
+}
+
+func (avl *avl[K, V]) Iterator() bst.Iterator[K, V] {
+	return bst.NewIterator[K, V](avl, avl.comp)
+}
+
+func (avl *avl[K, V]) Floor(key K) (bst.Node[K, V], bool) {
+	return bst.Floor(avl.root, key, avl.comp)
+}
+
+func (avl *avl[K, V]) Ceiling(key K) (bst.Node[K, V], bool) {
+	return bst.Ceiling(avl.root, key, avl.comp)
+}
+
+func (avl *avl[K, V]) Lower(key K) (bst.Node[K, V], bool) {
+	return bst.Lower(avl.root, key, avl.comp)
+}
+
+func (avl *avl[K, V]) Higher(key K) (bst.Node[K, V], bool) {
+	return bst.Higher(avl.root, key, avl.comp)
+}
+
+func (avl *avl[K, V]) Min() (bst.Node[K, V], bool) { return bst.Min(avl.root) }
+
+func (avl *avl[K, V]) Max() (bst.Node[K, V], bool) { return bst.Max(avl.root) }
+
+func (avl *avl[K, V]) Range(lo, hi K, loInclusive, hiInclusive bool, fn func(n bst.Node[K, V]) bool) {
+	bst.Range(avl.root, lo, hi, loInclusive, hiInclusive, avl.comp, fn)
+}
+
+func (avl *avl[K, V]) Select(k int) (bst.Node[K, V], bool) { return bst.Select(avl.root, k) }
+
+func (avl *avl[K, V]) Rank(key K) int { return bst.Rank(avl.root, key, avl.comp) }
+
+func (avl *avl[K, V]) CountRange(lo, hi K) int {
+	return bst.CountRange(avl.root, lo, hi, avl.comp)
+}
+
+func (avl *avl[K, V]) Summary() any {
+	if avl.aug == nil {
+		return nil
+	}
+	return bst.SummaryOf[K, V](avl.root, avl.aug)
+}
+
+func (avl *avl[K, V]) SummaryRange(lo, hi K) any {
+	if avl.aug == nil {
+		return nil
+	}
+	return bst.SummaryRange[K, V](avl.root, lo, hi, avl.comp, avl.aug)
+}
@@ -0,0 +1,51 @@
+package avl
+
+import (
+	"errors"
+
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+// MarshalBinary encodes the tree as configured by the bst.Encoding given to New
+func (avl *avl[K, V]) MarshalBinary() ([]byte, error) {
+	return bst.EncodeBinary[K, V](avl, bst.AVL, avl.enc)
+}
+
+// UnmarshalBinary replaces the content of the tree by data from MarshalBinary
+func (avl *avl[K, V]) UnmarshalBinary(data []byte) error {
+	return bst.DecodeBinary[K, V](avl, bst.AVL, avl.enc, data, avl.shapeBuilder())
+}
+
+func (avl *avl[K, V]) MarshalJSON() ([]byte, error) {
+	return bst.EncodeJSON[K, V](avl, bst.AVL, avl.enc)
+}
+
+func (avl *avl[K, V]) UnmarshalJSON(data []byte) error {
+	return bst.DecodeJSON[K, V](avl, bst.AVL, avl.enc, data, avl.shapeBuilder())
+}
+
+// shapeBuilder 按原有形状重建节点，拒绝失衡的节点
+func (avl *avl[K, V]) shapeBuilder() bst.ShapeBuilder[K, V] {
+	return bst.ShapeBuilder[K, V]{
+		Comp:  avl.comp,
+		Multi: avl.multi,
+		Node: func(key K, data V, _ bool, l, r bst.Node[K, V]) (bst.Node[K, V], error) {
+			n := avl.link(nodeOf(l), newNode(key, data), nodeOf(r))
+			if !avlOK(n) {
+				return nil, errors.New("avl: decoding an unbalanced node")
+			}
+			return n, nil
+		},
+		SetRoot: func(root bst.Node[K, V]) error {
+			avl.root = orphan(nodeOf(root))
+			return nil
+		},
+	}
+}
+
+func nodeOf[K, V any](n bst.Node[K, V]) *node[K, V] {
+	if bst.IsNil(n) {
+		return nil
+	}
+	return n.(*node[K, V])
+}
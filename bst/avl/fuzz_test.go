@@ -0,0 +1,13 @@
+package avl_test
+
+import (
+	"testing"
+
+	"github.com/mooncaker816/gostructure/bst"
+	_ "github.com/mooncaker816/gostructure/bst/avl"
+	"github.com/mooncaker816/gostructure/bst/bsttest"
+)
+
+func FuzzAVL(f *testing.F) {
+	bsttest.Fuzz(f, bsttest.ClassTarget(bst.AVL))
+}
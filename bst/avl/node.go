@@ -4,36 +4,38 @@ import (
 	"github.com/mooncaker816/gostructure/bst"
 )
 
-type node struct {
-	lchild *node
-	rchild *node
-	parent *node
-	key    interface{}
-	data   interface{}
+type node[K, V any] struct {
+	lchild *node[K, V]
+	rchild *node[K, V]
+	parent *node[K, V]
+	key    K
+	data   V
+	aug    any // 子树摘要
 	height int
+	size   int // 子树规模
 }
 
-func newNode(key, data interface{}) *node {
-	return &node{key: key, data: data}
+func newNode[K, V any](key K, data V) *node[K, V] {
+	return &node[K, V]{key: key, data: data, size: 1}
 }
 
-func (n *node) Key() interface{}         { return n.key }
-func (n *node) SetKey(key interface{})   { n.key = key }
-func (n *node) Data() interface{}        { return n.data }
-func (n *node) SetData(data interface{}) { n.data = data }
-func (n *node) Height() int              { return n.height }
-func (n *node) LChild() bst.Node         { return n.lchild }
-func (n *node) RChild() bst.Node         { return n.rchild }
-func (n *node) Parent() bst.Node         { return n.parent }
-func (n *node) Color() string            { return "" }
+func (n *node[K, V]) Key() K                 { return n.key }
+func (n *node[K, V]) SetKey(key K)           { n.key = key }
+func (n *node[K, V]) Data() V                { return n.data }
+func (n *node[K, V]) SetData(data V)         { n.data = data }
+func (n *node[K, V]) Summary() any           { return n.aug }
+func (n *node[K, V]) SetSummary(s any)       { n.aug = s }
+func (n *node[K, V]) Height() int            { return n.height }
+func (n *node[K, V]) Size() int              { return n.size }
+func (n *node[K, V]) LChild() bst.Node[K, V] { return n.lchild }
+func (n *node[K, V]) RChild() bst.Node[K, V] { return n.rchild }
+func (n *node[K, V]) Parent() bst.Node[K, V] { return n.parent }
+func (n *node[K, V]) Color() string          { return "" }
+func (n *node[K, V]) IsNil() bool            { return n == nil }
 
-func (n *node) SetLChild(lc bst.Node) {
-	if lc == nil {
-		n.lchild = nil
-		return
-	}
-	lc0, ok := lc.(*node)
-	if !ok {
+func (n *node[K, V]) SetLChild(lc bst.Node[K, V]) {
+	lc0, ok := lc.(*node[K, V])
+	if !ok && !bst.IsNil(lc) {
 		panic("inconsistent node type")
 	}
 	if n != nil {
@@ -41,13 +43,9 @@ func (n *node) SetLChild(lc bst.Node) {
 	}
 }
 
-func (n *node) SetRChild(rc bst.Node) {
-	if rc == nil {
-		n.rchild = nil
-		return
-	}
-	rc0, ok := rc.(*node)
-	if !ok {
+func (n *node[K, V]) SetRChild(rc bst.Node[K, V]) {
+	rc0, ok := rc.(*node[K, V])
+	if !ok && !bst.IsNil(rc) {
 		panic("inconsistent node type")
 	}
 	if n != nil {
@@ -55,13 +53,9 @@ func (n *node) SetRChild(rc bst.Node) {
 	}
 }
 
-func (n *node) SetParent(p bst.Node) {
-	if p == nil {
-		n.parent = nil
-		return
-	}
-	p0, ok := p.(*node)
-	if !ok {
+func (n *node[K, V]) SetParent(p bst.Node[K, V]) {
+	p0, ok := p.(*node[K, V])
+	if !ok && !bst.IsNil(p) {
 		panic("inconsistent node type")
 	}
 	if n != nil {
@@ -69,46 +63,43 @@ func (n *node) SetParent(p bst.Node) {
 	}
 }
 
-func updateHeight(n bst.Node) {
-	n0 := n.(*node)
+func updateHeight[K, V any](n bst.Node[K, V]) {
+	n0 := n.(*node[K, V])
 	n0.height = n0.maxHeightOfChildren() + 1
 }
 
-func (n *node) updateHeightAbove() {
-	for max := n.maxHeightOfChildren(); n != nil && n.height != max+1; {
-		n.height = max + 1
-		n = n.parent
+func updateSize[K, V any](n bst.Node[K, V]) {
+	n0 := n.(*node[K, V])
+	n0.size = 1
+	if n0.lchild != nil {
+		n0.size += n0.lchild.size
+	}
+	if n0.rchild != nil {
+		n0.size += n0.rchild.size
 	}
 }
 
-func (n *node) maxHeightOfChildren() int {
+func (n *node[K, V]) maxHeightOfChildren() int {
 	lH, rH := -1, -1
-	if bst.HasLChild(n) {
+	if n.lchild != nil {
 		lH = n.lchild.height
 	}
-	if bst.HasRChild(n) {
+	if n.rchild != nil {
 		rH = n.rchild.height
 	}
 	return max(lH, rH)
 }
 
-func max(a, b int) int {
-	if a >= b {
-		return a
-	}
-	return b
-}
-
 // Tallerchild 返回高度较高的那个孩子节点，若同高，返回和n同侧的节点
-func (n *node) tallerChild() *node {
-	if bst.HasLChild(n) && n.rchild == nil {
+func (n *node[K, V]) tallerChild() *node[K, V] {
+	if n.lchild != nil && n.rchild == nil {
 		return n.lchild
 	}
-	if bst.HasRChild(n) && n.lchild == nil {
+	if n.rchild != nil && n.lchild == nil {
 		return n.rchild
 	}
 
-	if bst.HasLChild(n) && bst.HasRChild(n) {
+	if n.lchild != nil && n.rchild != nil {
 		if n.lchild.height < n.rchild.height {
 			return n.rchild
 		}
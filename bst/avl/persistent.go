@@ -0,0 +1,184 @@
+package avl
+
+import (
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+// persistent AVL tree, 每个版本创建后不再改变
+type persistent[K, V any] struct {
+	root *pnode[K, V]
+	comp bst.Comparator[K]
+	size int
+}
+
+// pnode 无父指针，可被多个版本共享
+type pnode[K, V any] struct {
+	left   *pnode[K, V]
+	right  *pnode[K, V]
+	key    K
+	data   V
+	height int
+}
+
+func (n *pnode[K, V]) Left() bst.Link[K, V]  { return n.left }
+func (n *pnode[K, V]) Right() bst.Link[K, V] { return n.right }
+func (n *pnode[K, V]) Key() K                { return n.key }
+func (n *pnode[K, V]) Data() V               { return n.data }
+func (n *pnode[K, V]) IsNil() bool           { return n == nil }
+
+func (n *pnode[K, V]) h() int {
+	if n == nil {
+		return -1
+	}
+	return n.height
+}
+
+// NewPersistent returns an empty persistent avl tree with the default
+// comparator of K, a Comparator[K] or func(a, b K) int in parms replaces it.
+func NewPersistent[K, V any](parms ...interface{}) bst.Persistent[K, V] {
+	t := &persistent[K, V]{comp: bst.DefaultCompare[K]()}
+	for _, p := range parms {
+		switch v := p.(type) {
+		case bst.Comparator[K]:
+			t.comp = v
+		case func(a, b K) int:
+			t.comp = v
+		}
+	}
+	return t
+}
+
+func (t *persistent[K, V]) Len() int { return t.size }
+
+func (t *persistent[K, V]) Snapshot() bst.Snapshot[K, V] { return t }
+
+func (t *persistent[K, V]) Search(key K) (V, bool) {
+	if n, ok := bst.SearchLink[K, V](t.root, key, t.comp); ok {
+		return n.Data(), true
+	}
+	var zero V
+	return zero, false
+}
+
+func (t *persistent[K, V]) Iterator() bst.Iterator[K, V] {
+	return bst.NewLinkIterator[K, V](t.root, t.comp)
+}
+
+// Print 将当前版本复制为普通 avl 树后打印
+func (t *persistent[K, V]) Print() {
+	bst.PrintWithUnitSize(thaw(t.root, nil), 2)
+}
+
+func thaw[K, V any](p *pnode[K, V], parent *node[K, V]) bst.Node[K, V] {
+	if p == nil {
+		return nil
+	}
+	n := &node[K, V]{parent: parent, key: p.key, data: p.data, height: p.height}
+	n.SetLChild(thaw(p.left, n))
+	n.SetRChild(thaw(p.right, n))
+	updateSize[K, V](n)
+	return n
+}
+
+func (t *persistent[K, V]) Insert(key K, data V) (p bst.Persistent[K, V], err error) {
+	p = t
+	defer bst.CatchIncomparable(&err)
+	root, err := t.insert(t.root, key, data)
+	if err != nil {
+		return t, err
+	}
+	return &persistent[K, V]{root: root, comp: t.comp, size: t.size + 1}, nil
+}
+
+// Remove returns the version itself and ErrNotFound when key is absent
+func (t *persistent[K, V]) Remove(key K) (p bst.Persistent[K, V], err error) {
+	p = t
+	defer bst.CatchIncomparable(&err)
+	root, ok := t.remove(t.root, key)
+	if !ok {
+		return t, bst.ErrNotFound
+	}
+	return &persistent[K, V]{root: root, comp: t.comp, size: t.size - 1}, nil
+}
+
+func (t *persistent[K, V]) insert(n *pnode[K, V], key K, data V) (*pnode[K, V], error) {
+	if n == nil {
+		return &pnode[K, V]{key: key, data: data}, nil
+	}
+	switch c := t.comp(key, n.key); {
+	case c == 0:
+		return nil, bst.ErrDuplicateKey
+	case c < 0:
+		l, err := t.insert(n.left, key, data)
+		if err != nil {
+			return nil, err
+		}
+		return balance(l, n.key, n.data, n.right), nil
+	default:
+		r, err := t.insert(n.right, key, data)
+		if err != nil {
+			return nil, err
+		}
+		return balance(n.left, n.key, n.data, r), nil
+	}
+}
+
+func (t *persistent[K, V]) remove(n *pnode[K, V], key K) (*pnode[K, V], bool) {
+	if n == nil {
+		return nil, false
+	}
+	switch c := t.comp(key, n.key); {
+	case c < 0:
+		l, ok := t.remove(n.left, key)
+		if !ok {
+			return n, false
+		}
+		return balance(l, n.key, n.data, n.right), true
+	case c > 0:
+		r, ok := t.remove(n.right, key)
+		if !ok {
+			return n, false
+		}
+		return balance(n.left, n.key, n.data, r), true
+	}
+	if n.left == nil {
+		return n.right, true
+	}
+	if n.right == nil {
+		return n.left, true
+	}
+	r, succ := removeMin(n.right)
+	return balance(n.left, succ.key, succ.data, r), true
+}
+
+// removeMin 删除子树中的最小节点，返回新子树及被删除的节点
+func removeMin[K, V any](n *pnode[K, V]) (*pnode[K, V], *pnode[K, V]) {
+	if n.left == nil {
+		return n.right, n
+	}
+	l, min := removeMin(n.left)
+	return balance(l, n.key, n.data, n.right), min
+}
+
+func join[K, V any](l *pnode[K, V], key K, data V, r *pnode[K, V]) *pnode[K, V] {
+	return &pnode[K, V]{left: l, right: r, key: key, data: data, height: max(l.h(), r.h()) + 1}
+}
+
+// balance 以 l、r 为左右子树新建节点，两子树高度差不超过 2 时经至多两次旋转恢复平衡
+func balance[K, V any](l *pnode[K, V], key K, data V, r *pnode[K, V]) *pnode[K, V] {
+	switch {
+	case l.h() > r.h()+1:
+		if l.left.h() >= l.right.h() { // zig
+			return join(l.left, l.key, l.data, join(l.right, key, data, r))
+		}
+		lr := l.right // zag-zig
+		return join(join(l.left, l.key, l.data, lr.left), lr.key, lr.data, join(lr.right, key, data, r))
+	case r.h() > l.h()+1:
+		if r.right.h() >= r.left.h() { // zag
+			return join(join(l, key, data, r.left), r.key, r.data, r.right)
+		}
+		rl := r.left // zig-zag
+		return join(join(l, key, data, rl.left), rl.key, rl.data, join(rl.right, r.key, r.data, r.right))
+	}
+	return join(l, key, data, r)
+}
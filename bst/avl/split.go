@@ -0,0 +1,137 @@
+package avl
+
+import (
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+// Split moves the keys not less than key into a new avl tree in O(log n)
+func (avl *avl[K, V]) Split(key K) bst.BST[K, V] {
+	l, r := avl.split(avl.root, key)
+	other := *avl
+	avl.root, other.root = orphan(l), orphan(r)
+	return &other
+}
+
+// Join moves the keys of other, an avl tree with greater keys, into the tree
+// in O(log n)
+func (avl *avl[K, V]) Join(other bst.BST[K, V]) error {
+	o, ok := asAVL(other)
+	if !ok {
+		return bst.ErrJoinClass
+	}
+	if err := bst.CheckJoin[K, V](avl, o, avl.comp); err != nil {
+		return err
+	}
+	if o.root == nil {
+		return nil
+	}
+	if avl.root == nil {
+		avl.root, o.root = o.root, nil
+		return nil
+	}
+	// 以左树的最大节点连接两树
+	last, _ := avl.Max()
+	mid := newNode(last.Key(), last.Data())
+	avl.detach(last.(*node[K, V]))
+	avl.root, o.root = orphan(avl.join(avl.root, mid, o.root)), nil
+	return nil
+}
+
+func asAVL[K, V any](t bst.BST[K, V]) (*avl[K, V], bool) {
+	t0, ok := t.(*avl[K, V])
+	return t0, ok
+}
+
+func orphan[K, V any](n *node[K, V]) *node[K, V] {
+	if n != nil {
+		n.parent = nil
+	}
+	return n
+}
+
+func (n *node[K, V]) h() int {
+	if n == nil {
+		return -1
+	}
+	return n.height
+}
+
+// split 将子树切分为小于 key 和不小于 key 的两棵子树，沿途的节点作为连接点重新加入
+func (avl *avl[K, V]) split(n *node[K, V], key K) (*node[K, V], *node[K, V]) {
+	if n == nil {
+		return nil, nil
+	}
+	l, r := orphan(n.lchild), orphan(n.rchild)
+	if avl.comp(key, n.key) <= 0 {
+		ll, lr := avl.split(l, key)
+		return ll, avl.join(lr, n, r)
+	}
+	rl, rr := avl.split(r, key)
+	return avl.join(l, n, rl), rr
+}
+
+// join 以 k 连接 l 和 r，l 中的关键码均小于 k，r 中的均大于 k，
+// 沿较高子树的一侧下降到高度相近处连接，再逐层向上恢复平衡
+func (avl *avl[K, V]) join(l, k, r *node[K, V]) *node[K, V] {
+	switch {
+	case l.h() > r.h()+1:
+		return avl.joinRight(l, k, r)
+	case r.h() > l.h()+1:
+		return avl.joinLeft(l, k, r)
+	}
+	return avl.link(l, k, r)
+}
+
+func (avl *avl[K, V]) joinRight(l, k, r *node[K, V]) *node[K, V] {
+	ll, lr := l.lchild, l.rchild
+	if lr.h() <= r.h()+1 {
+		return avl.balance(ll, l, avl.link(lr, k, r))
+	}
+	return avl.balance(ll, l, avl.joinRight(lr, k, r))
+}
+
+func (avl *avl[K, V]) joinLeft(l, k, r *node[K, V]) *node[K, V] {
+	rl, rr := r.lchild, r.rchild
+	if rl.h() <= l.h()+1 {
+		return avl.balance(avl.link(l, k, rl), r, rr)
+	}
+	return avl.balance(avl.joinLeft(l, k, rl), r, rr)
+}
+
+// balance 以 l、r 为 n 的左右子树，两子树高度差不超过 2 时经至多两次旋转恢复平衡
+func (avl *avl[K, V]) balance(l, n, r *node[K, V]) *node[K, V] {
+	switch {
+	case l.h() > r.h()+1:
+		ll, lr := l.lchild, l.rchild
+		avl.stats.Rotate(ll.h() < lr.h())
+		if ll.h() >= lr.h() { // zig
+			return avl.link(ll, l, avl.link(lr, n, r))
+		}
+		lrl, lrr := lr.lchild, lr.rchild // zag-zig
+		return avl.link(avl.link(ll, l, lrl), lr, avl.link(lrr, n, r))
+	case r.h() > l.h()+1:
+		rl, rr := r.lchild, r.rchild
+		avl.stats.Rotate(rr.h() < rl.h())
+		if rr.h() >= rl.h() { // zag
+			return avl.link(avl.link(l, n, rl), r, rr)
+		}
+		rll, rlr := rl.lchild, rl.rchild // zig-zag
+		return avl.link(avl.link(l, n, rll), rl, avl.link(rlr, r, rr))
+	}
+	return avl.link(l, n, r)
+}
+
+// link 以 l、r 为 n 的左右子树，并更新 n 的高度、规模与摘要
+func (avl *avl[K, V]) link(l, n, r *node[K, V]) *node[K, V] {
+	n.lchild, n.rchild = l, r
+	if l != nil {
+		l.parent = n
+	}
+	if r != nil {
+		r.parent = n
+	}
+	for _, opt := range avl.rotates {
+		opt(n)
+	}
+	return n
+}
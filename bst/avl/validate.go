@@ -0,0 +1,28 @@
+package avl
+
+import (
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+// Validate checks the key order, the parent pointers, the heights, sizes and
+// balance factors of the avl tree
+func (avl *avl[K, V]) Validate() error {
+	validate := bst.ValidateBinary[K, V]
+	if avl.multi {
+		validate = bst.ValidateBinaryMulti[K, V]
+	}
+	_, err := validate(bst.AVL, avl.root, avl.comp, func(m bst.Node[K, V]) error {
+		n := m.(*node[K, V])
+		if h := max(n.lchild.h(), n.rchild.h()) + 1; n.height != h {
+			return bst.NewInvariantError(bst.AVL, bst.RuleHeight, n.key, "height %d, want %d", n.height, h)
+		}
+		if size := bst.Size[K, V](n.lchild) + bst.Size[K, V](n.rchild) + 1; n.size != size {
+			return bst.NewInvariantError(bst.AVL, bst.RuleSize, n.key, "size %d, want %d", n.size, size)
+		}
+		if !avlOK(n) {
+			return bst.NewInvariantError(bst.AVL, bst.RuleBalance, n.key, "balance factor %d", n.lchild.h()-n.rchild.h())
+		}
+		return nil
+	})
+	return err
+}
@@ -0,0 +1,556 @@
+// Package bplustree implements the B+ tree, a B-tree keeping all the data in
+// its leaves. The internal nodes hold only the separators of their branches,
+// copies of leaf keys, so more of them fit in a node, and the leaves are
+// linked both ways in key order, so a range scan seeks its first key once and
+// then walks the leaf chain without climbing back through the tree.
+package bplustree
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+func init() {
+	bst.Register("bplustree", bst.Factory{
+		New: New[any, any],
+		Typed: []interface{}{
+			New[int, int], New[int, string], New[int, any],
+			New[string, int], New[string, string], New[string, any],
+		},
+		Order:    true,
+		Multimap: true,
+	})
+}
+
+const defaultOrder = 4
+
+type bPlusTree[K, V any] struct {
+	m     int // 阶数，内部节点至多 m 个分支，叶节点至多 m-1 个关键码
+	root  *node[K, V]
+	comp  bst.Comparator[K]
+	size  int
+	enc   bst.Encoding[K, V]
+	stats *bst.Stats
+	multi bool // MultiKeys 模式
+}
+
+// New returns an empty B+ tree, an int in parms stands for its order m: an
+// internal node has at most m branches and a leaf at most m-1 keys. Reads
+// never modify the tree and may run in parallel, writes need exclusive
+// access, such as by bst.Synchronized.
+func New[K, V any](parms ...interface{}) bst.BST[K, V] {
+	t := new(bPlusTree[K, V])
+	t.comp = bst.DefaultCompare[K]()
+	for _, p := range parms {
+		switch v := p.(type) {
+		case bst.Comparator[K]:
+			t.comp = v
+		case func(a, b K) int:
+			t.comp = v
+		case int:
+			t.m = v
+		case bst.Encoding[K, V]:
+			t.enc = v
+		case *bst.Stats:
+			t.stats = v
+		case bst.Mode:
+			t.multi = v == bst.MultiKeys
+		}
+	}
+	t.comp = bst.CountComparisons(t.comp, t.stats)
+	if t.m < 3 {
+		t.m = defaultOrder
+	}
+	return t
+}
+
+// bottom 返回非根节点关键码数的下限 ⌈m/2⌉-1
+func (t *bPlusTree[K, V]) bottom() int { return (t.m+1)/2 - 1 }
+
+// Len returns the number of keys in the tree
+func (t *bPlusTree[K, V]) Len() int { return t.size }
+
+// Root returns the first key of the root node, a separator unless the root
+// is a leaf
+func (t *bPlusTree[K, V]) Root() bst.Node[K, V] {
+	if t.root == nil {
+		return nil
+	}
+	return item[K, V]{t.root, 0}
+}
+
+// Search returns the key found, or the nearest key of the leaf it would be in
+func (t *bPlusTree[K, V]) Search(key K) (bst.Node[K, V], bool) {
+	if t.root == nil {
+		return nil, false
+	}
+	n, i, ok := t.find(key)
+	if i == len(n.key) {
+		i--
+	}
+	return item[K, V]{n, i}, ok
+}
+
+// descend 自根下行至 key 所在的叶节点。分支 i 中的关键码不小于第 i-1 个分隔关键码且小于第 i 个，
+// 多重映射中也可等于第 i 个；after 为假时在与 key 相等的分隔关键码处进入左侧分支
+func (t *bPlusTree[K, V]) descend(key K, after bool) *node[K, V] {
+	n := t.root
+	for n.children != nil {
+		i := sort.Search(len(n.key), func(i int) bool {
+			c := t.comp(n.key[i], key)
+			return c > 0 || c == 0 && !after
+		})
+		n = n.children[i]
+	}
+	return n
+}
+
+// seek 在非空树中返回首个不小于 key 的关键码所在的叶节点及其位置，after 为真时为首个大于 key 的关键码；
+// 位置可能为叶节点的末尾，此时所求为后继叶节点的首个关键码，新关键码仍可插入该位置
+func (t *bPlusTree[K, V]) seek(key K, after bool) (*node[K, V], int) {
+	// 只有多重映射中与分隔关键码相等的关键码可能位于其左侧分支
+	n := t.descend(key, after || !t.multi)
+	i := sort.Search(len(n.key), func(i int) bool {
+		c := t.comp(n.key[i], key)
+		return c > 0 || c == 0 && !after
+	})
+	return n, i
+}
+
+// find 在非空树中查找 key，多重映射中返回最早插入的关键码，未找到时返回新关键码在叶节点中的插入位置
+func (t *bPlusTree[K, V]) find(key K) (*node[K, V], int, bool) {
+	n, i := t.seek(key, false)
+	if i < len(n.key) {
+		return n, i, t.comp(n.key[i], key) == 0
+	}
+	if t.multi && n.next != nil && t.comp(n.next.key[0], key) == 0 {
+		return n.next, 0, true
+	}
+	return n, i, false
+}
+
+// Insert returns the exact node which stores the newly inserted key
+func (t *bPlusTree[K, V]) Insert(key K, data V) (_ bst.Node[K, V], err error) {
+	if bst.Debug {
+		defer bst.MustValidate(t)
+	}
+	defer bst.CatchIncomparable(&err)
+	if t.root == nil {
+		return t.attach(nil, 0, key, data), nil
+	}
+	if t.multi {
+		n, i := t.seek(key, true)
+		return t.attach(n, i, key, data), nil
+	}
+	n, i, ok := t.find(key)
+	if ok {
+		return nil, bst.ErrDuplicateKey
+	}
+	return t.attach(n, i, key, data), nil
+}
+
+// attach 将关键码插入 seek 返回的叶节点 n 的第 i 个位置，n 为 nil 时作为根
+func (t *bPlusTree[K, V]) attach(n *node[K, V], i int, key K, data V) item[K, V] {
+	t.size++
+	if n == nil {
+		t.root = newLeaf(key, data, t.m)
+		return item[K, V]{t.root, 0}
+	}
+	n.key = insert(n.key, key, i)
+	n.data = insert(n.data, data, i)
+	at := item[K, V]{n, i}
+	t.solveOverflow(n, &at)
+	return at
+}
+
+// solveOverflow 分裂上溢的节点 n，at 为新关键码的位置，随叶节点的分裂更新。
+// 叶节点分裂时右侧叶节点的首个关键码复制到父节点，内部节点分裂时居中的分隔关键码移至父节点
+func (t *bPlusTree[K, V]) solveOverflow(n *node[K, V], at *item[K, V]) {
+	if len(n.key) < t.m {
+		return
+	}
+	mid := t.m / 2
+	var up K
+	var sp *node[K, V]
+	if n.children == nil {
+		sp = n.splitLeaf(mid)
+		up = sp.key[0]
+		if at.n == n && at.i >= mid {
+			*at = item[K, V]{sp, at.i - mid}
+		}
+	} else {
+		up = n.key[mid]
+		sp = n.split(mid)
+	}
+	t.stats.Split()
+	p := n.parent
+	if p == nil {
+		p = &node[K, V]{children: []*node[K, V]{n}}
+		t.root = p
+		n.parent = p
+	}
+	// 多重映射中父节点可能含有相等的分隔关键码，故按 n 的秩而不按关键码确定位置
+	i := childRank(n)
+	p.key = insert(p.key, up, i)
+	p.children = insert(p.children, sp, i+1)
+	sp.parent = p
+	t.solveOverflow(p, at)
+}
+
+// Remove removes key, the oldest entry of it in a multimap, and returns the
+// removed entry
+func (t *bPlusTree[K, V]) Remove(key K) (_ bst.Node[K, V], err error) {
+	if bst.Debug {
+		defer bst.MustValidate(t)
+	}
+	defer bst.CatchIncomparable(&err)
+	if t.root == nil {
+		return nil, bst.ErrNotFound
+	}
+	n, i, ok := t.find(key)
+	if !ok {
+		return nil, bst.ErrNotFound
+	}
+	// 返回只含被删除关键码的叶节点，n 中的条目在删除后会移动
+	removed := item[K, V]{newLeaf(n.key[i], n.data[i], 1), 0}
+	t.detach(n, i)
+	return removed, nil
+}
+
+// detach 删除叶节点 n 的第 i 个关键码，分隔关键码可能仍为已删除的关键码，这并不影响查找
+func (t *bPlusTree[K, V]) detach(n *node[K, V], i int) {
+	t.size--
+	n.key = remove(n.key, i)
+	n.data = remove(n.data, i)
+	t.solveUnderflow(n)
+}
+
+// solveUnderflow 处理下溢的节点 n：向左右兄弟借一个关键码，兄弟也处于下限时与其合并，父节点随之可能下溢
+func (t *bPlusTree[K, V]) solveUnderflow(n *node[K, V]) {
+	p := n.parent
+	if p == nil {
+		switch {
+		case len(n.key) > 0:
+		case n.children != nil:
+			t.root = n.children[0]
+			t.root.parent = nil
+			n.children = nil
+		default:
+			t.root = nil
+		}
+		return
+	}
+	if len(n.key) >= t.bottom() {
+		return
+	}
+	i := childRank(n)
+	if i > 0 && len(p.children[i-1].key) > t.bottom() {
+		t.borrowLeft(p, i)
+		return
+	}
+	if i < len(p.children)-1 && len(p.children[i+1].key) > t.bottom() {
+		t.borrowRight(p, i)
+		return
+	}
+	t.stats.Merge()
+	if i > 0 {
+		t.merge(p, i-1)
+	} else {
+		t.merge(p, i)
+	}
+	t.solveUnderflow(p)
+}
+
+// borrowLeft 将 p 的第 i-1 个分支中最大的关键码移至第 i 个分支
+func (t *bPlusTree[K, V]) borrowLeft(p *node[K, V], i int) {
+	t.stats.Add(1)
+	n, ls := p.children[i], p.children[i-1]
+	last := len(ls.key) - 1
+	if n.children == nil {
+		// 叶节点直接接收左兄弟的关键码，并以其作为新的分隔关键码
+		n.key = insert(n.key, ls.key[last], 0)
+		n.data = insert(n.data, ls.data[last], 0)
+		ls.key, ls.data = remove(ls.key, last), remove(ls.data, last)
+		p.key[i-1] = n.key[0]
+		return
+	}
+	// 内部节点接收父节点中的分隔关键码及左兄弟的最右分支，左兄弟的最大关键码上升为分隔关键码
+	c := ls.children[last+1]
+	n.key = insert(n.key, p.key[i-1], 0)
+	n.children = insert(n.children, c, 0)
+	c.parent = n
+	p.key[i-1] = ls.key[last]
+	ls.key, ls.children = remove(ls.key, last), remove(ls.children, last+1)
+}
+
+// borrowRight 将 p 的第 i+1 个分支中最小的关键码移至第 i 个分支
+func (t *bPlusTree[K, V]) borrowRight(p *node[K, V], i int) {
+	t.stats.Add(1)
+	n, rs := p.children[i], p.children[i+1]
+	if n.children == nil {
+		n.key = append(n.key, rs.key[0])
+		n.data = append(n.data, rs.data[0])
+		rs.key, rs.data = remove(rs.key, 0), remove(rs.data, 0)
+		p.key[i] = rs.key[0]
+		return
+	}
+	c := rs.children[0]
+	n.key = append(n.key, p.key[i])
+	n.children = append(n.children, c)
+	c.parent = n
+	p.key[i] = rs.key[0]
+	rs.key, rs.children = remove(rs.key, 0), remove(rs.children, 0)
+}
+
+// merge 将 p 的第 i+1 个分支并入第 i 个分支并删去其间的分隔关键码。
+// 内部节点合并时分隔关键码下移至合并后的节点，叶节点合并时将右侧叶节点自叶节点链中摘除
+func (t *bPlusTree[K, V]) merge(p *node[K, V], i int) {
+	l, r := p.children[i], p.children[i+1]
+	if l.children == nil {
+		l.key = append(l.key, r.key...)
+		l.data = append(l.data, r.data...)
+		l.next = r.next
+		if r.next != nil {
+			r.next.prev = l
+		}
+		r.prev, r.next = nil, nil
+	} else {
+		l.key = append(append(l.key, p.key[i]), r.key...)
+		for _, c := range r.children {
+			c.parent = l
+		}
+		l.children = append(l.children, r.children...)
+		r.children = nil
+	}
+	p.key = remove(p.key, i)
+	p.children = remove(p.children, i+1)
+	r.parent = nil
+}
+
+// Put inserts key with data or replaces its data after a single search
+func (t *bPlusTree[K, V]) Put(key K, data V) (old V, replaced bool, err error) {
+	if bst.Debug {
+		defer bst.MustValidate(t)
+	}
+	defer bst.CatchIncomparable(&err)
+	if t.root == nil {
+		t.attach(nil, 0, key, data)
+		return old, false, nil
+	}
+	n, i, ok := t.find(key)
+	if !ok {
+		t.attach(n, i, key, data)
+		return old, false, nil
+	}
+	old, n.data[i] = n.data[i], data
+	return old, true, nil
+}
+
+func (t *bPlusTree[K, V]) Update(key K, fn func(old V, ok bool) (V, bool)) (err error) {
+	if bst.Debug {
+		defer bst.MustValidate(t)
+	}
+	defer bst.CatchIncomparable(&err)
+	var n *node[K, V]
+	var i int
+	var ok bool
+	if t.root != nil {
+		n, i, ok = t.find(key)
+	}
+	var old V
+	if ok {
+		old = n.data[i]
+	}
+	data, keep := fn(old, ok)
+	switch {
+	case ok && keep:
+		n.data[i] = data
+	case ok:
+		t.detach(n, i)
+	case keep:
+		t.attach(n, i, key, data)
+	}
+	return nil
+}
+
+func (t *bPlusTree[K, V]) GetOrInsert(key K, data V) (_ bst.Node[K, V], found bool, err error) {
+	if bst.Debug {
+		defer bst.MustValidate(t)
+	}
+	defer bst.CatchIncomparable(&err)
+	if t.root == nil {
+		return t.attach(nil, 0, key, data), false, nil
+	}
+	n, i, ok := t.find(key)
+	if ok {
+		return item[K, V]{n, i}, true, nil
+	}
+	return t.attach(n, i, key, data), false, nil
+}
+
+// Count returns the number of entries of key in O(log n + count)
+func (t *bPlusTree[K, V]) Count(key K) int {
+	cnt := 0
+	t.All(key, func(bst.Node[K, V]) bool {
+		cnt++
+		return true
+	})
+	return cnt
+}
+
+func (t *bPlusTree[K, V]) All(key K, fn func(n bst.Node[K, V]) bool) {
+	t.Range(key, key, true, true, fn)
+}
+
+// RemoveOne removes the oldest entry of key as Remove does
+func (t *bPlusTree[K, V]) RemoveOne(key K) (bst.Node[K, V], error) { return t.Remove(key) }
+
+func (t *bPlusTree[K, V]) RemoveAll(key K) (int, error) { return bst.RemoveEach(key, t.Remove) }
+
+// BuildSorted bulk loads the sorted keys in O(n): the keys are spread evenly
+// over the fewest leaves that hold them, which are linked in order, then each
+// level above is built the same way over the nodes of the level below, with
+// the smallest key of every branch but the first as its separator
+func (t *bPlusTree[K, V]) BuildSorted(keys []K, values []V, verify bool) error {
+	if verify {
+		check := bst.CheckSorted[K]
+		if t.multi {
+			check = bst.CheckSortedMulti[K]
+		}
+		if err := check(keys, t.comp); err != nil {
+			return err
+		}
+	}
+	t.root, t.size = nil, len(keys)
+	if len(keys) == 0 {
+		return nil
+	}
+	cnt := (len(keys) + t.m - 2) / (t.m - 1)
+	level := make([]*node[K, V], cnt)
+	lows := make([]K, cnt) // 各节点子树中的最小关键码
+	lo := 0
+	for i := range level {
+		hi := lo + spread(len(keys), cnt, i)
+		n := &node[K, V]{key: make([]K, hi-lo, t.m), data: make([]V, hi-lo, t.m)}
+		copy(n.key, keys[lo:hi])
+		copy(n.data, values[lo:hi])
+		if i > 0 {
+			n.prev, level[i-1].next = level[i-1], n
+		}
+		level[i], lows[i] = n, keys[lo]
+		lo = hi
+	}
+	for len(level) > 1 {
+		cnt := (len(level) + t.m - 1) / t.m
+		upper, upperLows := make([]*node[K, V], cnt), make([]K, cnt)
+		lo := 0
+		for i := range upper {
+			hi := lo + spread(len(level), cnt, i)
+			n := &node[K, V]{children: append([]*node[K, V](nil), level[lo:hi]...)}
+			n.key = append(make([]K, 0, t.m), lows[lo+1:hi]...)
+			for _, c := range n.children {
+				c.parent = n
+			}
+			upper[i], upperLows[i] = n, lows[lo]
+			lo = hi
+		}
+		level, lows = upper, upperLows
+	}
+	t.root = level[0]
+	return nil
+}
+
+// spread 返回将 total 个元素平均分给 cnt 个节点时第 i 个节点所得的个数
+func spread(total, cnt, i int) int {
+	if i < total%cnt {
+		return total/cnt + 1
+	}
+	return total / cnt
+}
+
+// Walk walks the keys of the leaves by o, see Traverse
+func (t *bPlusTree[K, V]) Walk(o bst.Order, opts ...bst.Option[K, V]) {
+	t.Traverse(o, func(n bst.Node[K, V]) bool {
+		for _, opt := range opts {
+			opt(n)
+		}
+		return true
+	})
+}
+
+// Traverse walks the keys of the leaves by o until fn returns false, the
+// separators of the internal nodes are only copies and never visited. All
+// the leaves are on the same level, so PreOrder, PostOrder and LevelOrder
+// reach them from left to right as InOrder, which follows the leaf chain.
+func (t *bPlusTree[K, V]) Traverse(o bst.Order, fn func(n bst.Node[K, V]) bool) bool {
+	if o > bst.ReverseInOrder {
+		panic("unsupported walk order")
+	}
+	it := &iterator[K, V]{t: t}
+	next, ok := it.Next, it.First()
+	if o == bst.ReverseInOrder {
+		next, ok = it.Prev, it.Last()
+	}
+	for ; ok; ok = next() {
+		if !fn(item[K, V]{it.n, it.i}) {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *bPlusTree[K, V]) Print() {
+	t.Fprint(os.Stdout)
+}
+
+// Fprint writes the separators of the internal nodes level by level from the
+// root, then the leaf chain, such as
+//
+//	[5]
+//	[3] [7 9]
+//	[1 2] <-> [3 4] <-> [5 6] <-> [7 8] <-> [9 10]
+func (t *bPlusTree[K, V]) Fprint(w io.Writer) error {
+	if t.root == nil {
+		_, err := io.WriteString(w, "Empty tree!\n")
+		return err
+	}
+	var b strings.Builder
+	for level := []*node[K, V]{t.root}; level[0].children != nil; {
+		var lower []*node[K, V]
+		for i, n := range level {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			writeKeys(&b, n.key)
+			lower = append(lower, n.children...)
+		}
+		b.WriteByte('\n')
+		level = lower
+	}
+	for n := leftmost(t.root); n != nil; n = n.next {
+		if n.prev != nil {
+			b.WriteString(" <-> ")
+		}
+		writeKeys(&b, n.key)
+	}
+	b.WriteByte('\n')
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeKeys 写入以方括号括起、以空格分隔的关键码
+func writeKeys[K any](b *strings.Builder, keys []K) {
+	b.WriteByte('[')
+	for i, key := range keys {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		fmt.Fprint(b, key)
+	}
+	b.WriteByte(']')
+}
@@ -0,0 +1,301 @@
+package bplustree
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+var errLayout = errors.New("bplustree: decoding an invalid node layout")
+
+// MarshalBinary encodes the tree as configured by the bst.Encoding given to
+// New, a kept shape records the order and the fill of every node, the
+// separators are restored as the smallest keys of the branches they precede
+func (t *bPlusTree[K, V]) MarshalBinary() ([]byte, error) {
+	e := t.enc.WithDefaults()
+	if !e.Shape {
+		return bst.EncodeBinary[K, V](t, bst.BPlusTree, e)
+	}
+	buf := bst.AppendHeader(nil, bst.BPlusTree, true)
+	buf = bst.AppendUvarint(buf, t.size)
+	buf = bst.AppendUvarint(buf, t.m)
+	if t.size == 0 {
+		return buf, nil
+	}
+	return appendNode(buf, t.root, e)
+}
+
+// appendNode 先序写入节点的分支数，叶节点的分支数为 0，其后为关键码个数及各关键码
+func appendNode[K, V any](buf []byte, n *node[K, V], e bst.Encoding[K, V]) ([]byte, error) {
+	buf = bst.AppendUvarint(buf, len(n.children))
+	var err error
+	if n.children == nil {
+		buf = bst.AppendUvarint(buf, len(n.key))
+		for i := range n.key {
+			if buf, err = bst.AppendEntry(buf, n.key[i], n.data[i], e); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	}
+	for _, c := range n.children {
+		if buf, err = appendNode(buf, c, e); err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary replaces the content of the tree by data from
+// MarshalBinary, a kept shape also replaces the order of the tree
+func (t *bPlusTree[K, V]) UnmarshalBinary(data []byte) error {
+	e := t.enc.WithDefaults()
+	shape, rest, err := bst.ReadHeader(data, bst.BPlusTree)
+	if err != nil {
+		return err
+	}
+	if !shape {
+		return bst.DecodeBinary[K, V](t, bst.BPlusTree, e, data, bst.ShapeBuilder[K, V]{})
+	}
+	size, rest, err := bst.ReadUvarint(rest, len(rest))
+	if err != nil {
+		return err
+	}
+	m, rest, err := bst.ReadUvarint(rest, math.MaxInt32)
+	if err != nil || m < 3 {
+		return errLayout
+	}
+	var root *node[K, V]
+	if size > 0 {
+		if root, rest, err = readNode(rest, m, e); err != nil {
+			return err
+		}
+	}
+	if len(rest) != 0 {
+		return bst.ErrCorrupt
+	}
+	return t.setRoot(root, m, size)
+}
+
+// readNode 读取 appendNode 写入的子树，分支数与关键码个数亦受剩余数据的长度限制，以免为损坏的数据分配过多内存
+func readNode[K, V any](data []byte, m int, e bst.Encoding[K, V]) (*node[K, V], []byte, error) {
+	branches, data, err := bst.ReadUvarint(data, min(m, len(data)))
+	if err != nil || branches == 1 {
+		return nil, nil, errLayout
+	}
+	n := new(node[K, V])
+	if branches == 0 {
+		cnt, rest, err := bst.ReadUvarint(data, min(m-1, len(data)))
+		if err != nil {
+			return nil, nil, errLayout
+		}
+		data = rest
+		n.key, n.data = make([]K, cnt), make([]V, cnt)
+		for i := 0; i < cnt; i++ {
+			if n.key[i], n.data[i], data, err = bst.ReadEntry(data, e); err != nil {
+				return nil, nil, err
+			}
+		}
+		return n, data, nil
+	}
+	n.children = make([]*node[K, V], branches)
+	for i := range n.children {
+		if n.children[i], data, err = readNode(data, m, e); err != nil {
+			return nil, nil, err
+		}
+		n.children[i].parent = n
+	}
+	return n, data, nil
+}
+
+// treeJSON 保留形状时 B+ 树的 JSON 根
+type treeJSON struct {
+	Order int       `json:"order"`
+	Node  *nodeJSON `json:"node,omitempty"`
+}
+
+// nodeJSON 内部节点只有分支，叶节点只有关键码
+type nodeJSON struct {
+	Entries  []bst.EntryJSON `json:"entries,omitempty"`
+	Children []*nodeJSON     `json:"children,omitempty"`
+}
+
+func (t *bPlusTree[K, V]) MarshalJSON() ([]byte, error) {
+	e := t.enc.WithDefaults()
+	if !e.Shape {
+		return bst.EncodeJSON[K, V](t, bst.BPlusTree, e)
+	}
+	doc := bst.TreeJSON{Version: bst.EncodingVersion, Class: bst.BPlusTree, Shape: true, Len: t.size}
+	root := treeJSON{Order: t.m}
+	var err error
+	if t.size > 0 {
+		if root.Node, err = nodeToJSON(t.root, e); err != nil {
+			return nil, err
+		}
+	}
+	if doc.Root, err = json.Marshal(root); err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+func nodeToJSON[K, V any](n *node[K, V], e bst.Encoding[K, V]) (*nodeJSON, error) {
+	j := new(nodeJSON)
+	if n.children == nil {
+		j.Entries = make([]bst.EntryJSON, len(n.key))
+		var err error
+		for i := range n.key {
+			if j.Entries[i], err = bst.MarshalEntry(n.key[i], n.data[i], e); err != nil {
+				return nil, err
+			}
+		}
+		return j, nil
+	}
+	for _, c := range n.children {
+		cj, err := nodeToJSON(c, e)
+		if err != nil {
+			return nil, err
+		}
+		j.Children = append(j.Children, cj)
+	}
+	return j, nil
+}
+
+func (t *bPlusTree[K, V]) UnmarshalJSON(data []byte) error {
+	e := t.enc.WithDefaults()
+	var doc bst.TreeJSON
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if err := doc.Check(bst.BPlusTree); err != nil {
+		return err
+	}
+	if !doc.Shape {
+		return bst.DecodeJSON[K, V](t, bst.BPlusTree, e, data, bst.ShapeBuilder[K, V]{})
+	}
+	var root treeJSON
+	if err := json.Unmarshal(doc.Root, &root); err != nil {
+		return err
+	}
+	if root.Order < 3 {
+		return errLayout
+	}
+	var n *node[K, V]
+	if root.Node != nil {
+		var err error
+		if n, err = nodeFromJSON(root.Node, root.Order, e); err != nil {
+			return err
+		}
+	}
+	return t.setRoot(n, root.Order, doc.Len)
+}
+
+func nodeFromJSON[K, V any](j *nodeJSON, m int, e bst.Encoding[K, V]) (*node[K, V], error) {
+	if len(j.Entries) > m-1 || len(j.Children) > m || j.Entries != nil && j.Children != nil {
+		return nil, errLayout
+	}
+	n := new(node[K, V])
+	if j.Children == nil {
+		cnt := len(j.Entries)
+		n.key, n.data = make([]K, cnt), make([]V, cnt)
+		var err error
+		for i, entry := range j.Entries {
+			if n.key[i], n.data[i], err = bst.UnmarshalEntry(entry, e); err != nil {
+				return nil, err
+			}
+		}
+		return n, nil
+	}
+	for _, cj := range j.Children {
+		c, err := nodeFromJSON(cj, m, e)
+		if err != nil {
+			return nil, err
+		}
+		c.parent = n
+		n.children = append(n.children, c)
+	}
+	return n, nil
+}
+
+// setRoot 检查各节点的分支数、关键码个数与叶子深度，补齐分隔关键码与叶节点链，
+// 再检查关键码次序后替换整棵树
+func (t *bPlusTree[K, V]) setRoot(root *node[K, V], m, size int) error {
+	var leaves []*node[K, V]
+	if root != nil {
+		leaf := -1
+		if !checkLayout(root, m, 0, &leaf, &leaves) {
+			return errLayout
+		}
+		for i, n := range leaves {
+			if i > 0 {
+				n.prev, leaves[i-1].next = leaves[i-1], n
+			}
+		}
+		fillSeparators(root)
+	}
+	if countOrdered(&bPlusTree[K, V]{m: m, root: root, comp: t.comp, multi: t.multi}) != size {
+		return errLayout
+	}
+	t.m, t.root, t.size = m, root, size
+	return nil
+}
+
+// checkLayout 检查以 n 为根的子树，非根的内部节点至少有 ⌈m/2⌉ 个分支，非根的叶节点至少有 ⌈m/2⌉-1 个关键码，
+// 叶子深度相同，并按次序收集叶节点
+func checkLayout[K, V any](n *node[K, V], m, depth int, leaf *int, leaves *[]*node[K, V]) bool {
+	min := 1
+	if depth > 0 {
+		min = (m+1)/2 - 1
+	}
+	if n.children == nil {
+		if len(n.key) < min || len(n.key) > m-1 {
+			return false
+		}
+		if *leaf < 0 {
+			*leaf = depth
+		}
+		*leaves = append(*leaves, n)
+		return *leaf == depth
+	}
+	if len(n.children) < min+1 || len(n.children) > m {
+		return false
+	}
+	for _, c := range n.children {
+		if !checkLayout(c, m, depth+1, leaf, leaves) {
+			return false
+		}
+	}
+	return true
+}
+
+// fillSeparators 以各分支中的最小关键码作为其左侧的分隔关键码
+func fillSeparators[K, V any](n *node[K, V]) {
+	if n.children == nil {
+		return
+	}
+	n.key = make([]K, 0, len(n.children)-1)
+	for i, c := range n.children {
+		fillSeparators(c)
+		if i > 0 {
+			n.key = append(n.key, leftmost(c).key[0])
+		}
+	}
+}
+
+// countOrdered 返回叶节点链中的关键码个数，关键码不严格递增时返回 -1，多重映射中允许相等
+func countOrdered[K, V any](t *bPlusTree[K, V]) int {
+	it := &iterator[K, V]{t: t}
+	if !it.First() {
+		return 0
+	}
+	cnt := 1
+	for prev := it.Key(); it.Next(); prev = it.Key() {
+		if !t.before(prev, it.Key()) {
+			return -1
+		}
+		cnt++
+	}
+	return cnt
+}
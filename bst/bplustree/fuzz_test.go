@@ -0,0 +1,17 @@
+package bplustree_test
+
+import (
+	"testing"
+
+	"github.com/mooncaker816/gostructure/bst"
+	_ "github.com/mooncaker816/gostructure/bst/bplustree"
+	"github.com/mooncaker816/gostructure/bst/bsttest"
+)
+
+func FuzzBPlusTree(f *testing.F) {
+	bsttest.Fuzz(f, bsttest.ClassTarget(bst.BPlusTree, 3))
+}
+
+func FuzzBPlusTreeOrder5(f *testing.F) {
+	bsttest.Fuzz(f, bsttest.ClassTarget(bst.BPlusTree, 5))
+}
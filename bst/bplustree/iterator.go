@@ -0,0 +1,89 @@
+package bplustree
+
+import "github.com/mooncaker816/gostructure/bst"
+
+// iterator 以叶节点及其中关键码的秩沿叶节点链移动
+type iterator[K, V any] struct {
+	t *bPlusTree[K, V]
+	n *node[K, V]
+	i int
+}
+
+func (t *bPlusTree[K, V]) Iterator() bst.Iterator[K, V] {
+	return &iterator[K, V]{t: t}
+}
+
+func (it *iterator[K, V]) Seek(key K) bool {
+	return it.seek(key, false)
+}
+
+// seekAfter 移动到大于 key 的最小关键码
+func (it *iterator[K, V]) seekAfter(key K) bool {
+	return it.seek(key, true)
+}
+
+func (it *iterator[K, V]) seek(key K, after bool) bool {
+	it.n = nil
+	if it.t.root == nil {
+		return false
+	}
+	n, i := it.t.seek(key, after)
+	if i == len(n.key) {
+		n, i = n.next, 0
+	}
+	it.n, it.i = n, i
+	return it.Valid()
+}
+
+func (it *iterator[K, V]) First() bool {
+	it.n = nil
+	if it.t.root == nil {
+		return false
+	}
+	it.n, it.i = leftmost(it.t.root), 0
+	return true
+}
+
+func (it *iterator[K, V]) Last() bool {
+	it.n = nil
+	if it.t.root == nil {
+		return false
+	}
+	it.n = rightmost(it.t.root)
+	it.i = len(it.n.key) - 1
+	return true
+}
+
+func (it *iterator[K, V]) Next() bool {
+	if !it.Valid() {
+		return false
+	}
+	if it.i++; it.i == len(it.n.key) {
+		it.n, it.i = it.n.next, 0
+	}
+	return it.Valid()
+}
+
+func (it *iterator[K, V]) Prev() bool {
+	if !it.Valid() {
+		return false
+	}
+	if it.i--; it.i < 0 {
+		if it.n = it.n.prev; it.n != nil {
+			it.i = len(it.n.key) - 1
+		}
+	}
+	return it.Valid()
+}
+
+func (it *iterator[K, V]) Valid() bool { return it.n != nil }
+func (it *iterator[K, V]) Key() K      { return it.n.key[it.i] }
+func (it *iterator[K, V]) Data() V     { return it.n.data[it.i] }
+
+// item returns the key the iterator positioned at as a bst.Node
+func (it *iterator[K, V]) item() (bst.Node[K, V], bool) {
+	if !it.Valid() {
+		return nil, false
+	}
+	return item[K, V]{it.n, it.i}, true
+}
@@ -0,0 +1,150 @@
+package bplustree
+
+import "github.com/mooncaker816/gostructure/bst"
+
+type node[K, V any] struct {
+	parent   *node[K, V]
+	children []*node[K, V] // 分支，叶节点为 nil
+	key      []K           // 叶节点中为关键码，内部节点中为分隔各分支的关键码
+	data     []V           // 数据，仅叶节点有
+	prev     *node[K, V]   // 叶节点链中的前驱
+	next     *node[K, V]   // 叶节点链中的后继
+}
+
+func newLeaf[K, V any](key K, data V, m int) *node[K, V] {
+	n := new(node[K, V])
+	n.key = append(make([]K, 0, m), key)
+	n.data = append(make([]V, 0, m), data)
+	return n
+}
+
+// splitLeaf 将叶节点 n 自第 i 个关键码起分裂出右侧的叶节点，并将其接入叶节点链
+func (n *node[K, V]) splitLeaf(i int) *node[K, V] {
+	sp := new(node[K, V])
+	sp.key = append(make([]K, 0, cap(n.key)), n.key[i:]...)
+	sp.data = append(make([]V, 0, cap(n.data)), n.data[i:]...)
+	clear(n.key[i:])
+	clear(n.data[i:])
+	n.key, n.data = n.key[:i], n.data[:i]
+	sp.prev, sp.next = n, n.next
+	if n.next != nil {
+		n.next.prev = sp
+	}
+	n.next = sp
+	return sp
+}
+
+// split 将内部节点 n 的第 i 个分隔关键码右侧的关键码及分支分裂出去，第 i 个关键码由调用者移至父节点
+func (n *node[K, V]) split(i int) *node[K, V] {
+	sp := new(node[K, V])
+	sp.key = append(sp.key, n.key[i+1:]...)
+	sp.children = append(sp.children, n.children[i+1:]...)
+	for _, child := range sp.children {
+		child.parent = sp
+	}
+	clear(n.key[i:])
+	clear(n.children[i+1:])
+	n.key, n.children = n.key[:i], n.children[:i+1]
+	return sp
+}
+
+// item 指向 B+ 树节点中的某一个关键码，叶节点中的关键码带有数据，
+// 内部节点中的关键码仅用于分隔分支，其数据为零值
+type item[K, V any] struct {
+	n *node[K, V]
+	i int
+}
+
+func (it item[K, V]) Key() K { return it.n.key[it.i] }
+
+func (it item[K, V]) Data() V {
+	if it.n.children != nil {
+		var zero V
+		return zero
+	}
+	return it.n.data[it.i]
+}
+
+func (it item[K, V]) Height() int            { return 0 }
+func (it item[K, V]) LChild() bst.Node[K, V] { return nil }
+func (it item[K, V]) RChild() bst.Node[K, V] { return nil }
+func (it item[K, V]) Parent() bst.Node[K, V] { return nil }
+func (it item[K, V]) Color() string          { return "" }
+func (it item[K, V]) IsNil() bool            { return false }
+
+// Entries returns the keys and data of the whole node, the separators of an
+// internal node come with zero data
+func (it item[K, V]) Entries() ([]K, []V) {
+	if it.n.children != nil {
+		return it.n.key, make([]V, len(it.n.key))
+	}
+	return it.n.key, it.n.data
+}
+
+// Children returns the first key of each branch, nil for a leaf
+func (it item[K, V]) Children() []bst.Node[K, V] {
+	var children []bst.Node[K, V]
+	for _, c := range it.n.children {
+		if len(c.key) == 0 {
+			continue
+		}
+		children = append(children, item[K, V]{c, 0})
+	}
+	return children
+}
+
+func (it item[K, V]) SetKey(key K) { it.n.key[it.i] = key }
+
+// SetData replaces the data of a key in a leaf, separators have no data
+func (it item[K, V]) SetData(data V) {
+	if it.n.children == nil {
+		it.n.data[it.i] = data
+	}
+}
+
+func (it item[K, V]) SetLChild(bst.Node[K, V]) {}
+func (it item[K, V]) SetRChild(bst.Node[K, V]) {}
+func (it item[K, V]) SetParent(bst.Node[K, V]) {}
+
+// leftmost returns the first leaf of the subtree rooted on n
+func leftmost[K, V any](n *node[K, V]) *node[K, V] {
+	for n.children != nil {
+		n = n.children[0]
+	}
+	return n
+}
+
+// rightmost returns the last leaf of the subtree rooted on n
+func rightmost[K, V any](n *node[K, V]) *node[K, V] {
+	for n.children != nil {
+		n = n.children[len(n.children)-1]
+	}
+	return n
+}
+
+// childRank returns the index of n among its parent's children
+func childRank[K, V any](n *node[K, V]) int {
+	for i, c := range n.parent.children {
+		if c == n {
+			return i
+		}
+	}
+	panic("bplustree: node is not a child of its parent")
+}
+
+func insert[T any](a []T, v T, i int) []T {
+	a = append(a, v)
+	if i < len(a)-1 {
+		copy(a[i+1:], a[i:])
+		a[i] = v
+	}
+	return a
+}
+
+// remove 删去 a 的第 i 个元素，并清零空出的末尾
+func remove[T any](a []T, i int) []T {
+	copy(a[i:], a[i+1:])
+	var zero T
+	a[len(a)-1] = zero
+	return a[:len(a)-1]
+}
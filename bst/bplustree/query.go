@@ -0,0 +1,68 @@
+package bplustree
+
+import "github.com/mooncaker816/gostructure/bst"
+
+func (t *bPlusTree[K, V]) Floor(key K) (bst.Node[K, V], bool) {
+	it := &iterator[K, V]{t: t}
+	it.seekAfter(key)
+	return t.lowerFrom(it)
+}
+
+func (t *bPlusTree[K, V]) Ceiling(key K) (bst.Node[K, V], bool) {
+	it := &iterator[K, V]{t: t}
+	it.Seek(key)
+	return it.item()
+}
+
+func (t *bPlusTree[K, V]) Lower(key K) (bst.Node[K, V], bool) {
+	it := &iterator[K, V]{t: t}
+	it.Seek(key)
+	return t.lowerFrom(it)
+}
+
+// lowerFrom returns the key before the position it seeked to
+func (t *bPlusTree[K, V]) lowerFrom(it *iterator[K, V]) (bst.Node[K, V], bool) {
+	if it.Valid() {
+		it.Prev()
+	} else {
+		it.Last()
+	}
+	return it.item()
+}
+
+func (t *bPlusTree[K, V]) Higher(key K) (bst.Node[K, V], bool) {
+	it := &iterator[K, V]{t: t}
+	it.seekAfter(key)
+	return it.item()
+}
+
+func (t *bPlusTree[K, V]) Min() (bst.Node[K, V], bool) {
+	it := &iterator[K, V]{t: t}
+	it.First()
+	return it.item()
+}
+
+func (t *bPlusTree[K, V]) Max() (bst.Node[K, V], bool) {
+	it := &iterator[K, V]{t: t}
+	it.Last()
+	return it.item()
+}
+
+// Range seeks lo once and then walks the leaf chain up to hi
+func (t *bPlusTree[K, V]) Range(lo, hi K, loInclusive, hiInclusive bool, fn func(n bst.Node[K, V]) bool) {
+	it := &iterator[K, V]{t: t}
+	var ok bool
+	if loInclusive {
+		ok = it.Seek(lo)
+	} else {
+		ok = it.seekAfter(lo)
+	}
+	for ; ok; ok = it.Next() {
+		if c := t.comp(it.Key(), hi); c > 0 || c == 0 && !hiInclusive {
+			return
+		}
+		if !fn(item[K, V]{it.n, it.i}) {
+			return
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package bplustree
+
+import "github.com/mooncaker816/gostructure/bst"
+
+// Validate checks the key order against the separators, the parent pointers,
+// the fill bounds of the order m, the depth of the leaves and that the leaf
+// chain links the leaves from left to right both ways
+func (t *bPlusTree[K, V]) Validate() error {
+	if t.root == nil {
+		if t.size != 0 {
+			return bst.NewInvariantError(bst.BPlusTree, bst.RuleSize, nil, "empty root, Len %d", t.size)
+		}
+		return nil
+	}
+	if t.root.parent != nil {
+		return bst.NewInvariantError(bst.BPlusTree, bst.RuleParent, firstKey(t.root), "root has parent")
+	}
+	v := validator[K, V]{t: t, leaf: -1}
+	size, err := v.walk(t.root, nil, nil, 0)
+	if err != nil {
+		return err
+	}
+	if v.last.next != nil {
+		return bst.NewInvariantError(bst.BPlusTree, bst.RuleLevel, firstKey(v.last), "last leaf links a next leaf")
+	}
+	if size != t.size {
+		return bst.NewInvariantError(bst.BPlusTree, bst.RuleSize, firstKey(t.root), "%d keys, Len %d", size, t.size)
+	}
+	return nil
+}
+
+type validator[K, V any] struct {
+	t    *bPlusTree[K, V]
+	leaf int         // 叶节点的深度，-1 为尚未确定
+	last *node[K, V] // 上一个访问的叶节点
+}
+
+// walk 检查子树，其关键码应不小于 lo 且小于 hi，多重映射中可等于 hi，返回叶节点中的关键码总数
+func (v *validator[K, V]) walk(n *node[K, V], lo, hi *K, depth int) (int, error) {
+	t := v.t
+	first := firstKey(n)
+	min := t.bottom()
+	if n.parent == nil {
+		min = 1
+	}
+	if len(n.key) > t.m-1 || len(n.key) < min {
+		return 0, bst.NewInvariantError(bst.BPlusTree, bst.RuleFill, first, "%d keys in a node of order %d", len(n.key), t.m)
+	}
+	for i, key := range n.key {
+		switch {
+		case i == 0 && lo != nil && t.comp(key, *lo) < 0:
+			return 0, bst.NewInvariantError(bst.BPlusTree, bst.RuleOrder, key, "less than separator %v", *lo)
+		case i > 0 && !t.before(n.key[i-1], key):
+			return 0, bst.NewInvariantError(bst.BPlusTree, bst.RuleOrder, key, "not greater than %v", n.key[i-1])
+		case i == len(n.key)-1 && hi != nil && !t.before(key, *hi):
+			return 0, bst.NewInvariantError(bst.BPlusTree, bst.RuleOrder, key, "not less than separator %v", *hi)
+		}
+	}
+	if n.children == nil {
+		if len(n.data) != len(n.key) {
+			return 0, bst.NewInvariantError(bst.BPlusTree, bst.RuleSize, first, "%d keys with %d data", len(n.key), len(n.data))
+		}
+		if v.leaf < 0 {
+			v.leaf = depth
+		}
+		if depth != v.leaf {
+			return 0, bst.NewInvariantError(bst.BPlusTree, bst.RuleDepth, first, "leaf at depth %d, want %d", depth, v.leaf)
+		}
+		if n.prev != v.last || v.last != nil && v.last.next != n {
+			return 0, bst.NewInvariantError(bst.BPlusTree, bst.RuleLevel, first, "not linked next to the previous leaf")
+		}
+		v.last = n
+		return len(n.key), nil
+	}
+	if n.data != nil || n.prev != nil || n.next != nil {
+		return 0, bst.NewInvariantError(bst.BPlusTree, bst.RuleLevel, first, "internal node holds data or leaf links")
+	}
+	if len(n.children) != len(n.key)+1 {
+		return 0, bst.NewInvariantError(bst.BPlusTree, bst.RuleFill, first, "%d branches for %d keys", len(n.children), len(n.key))
+	}
+	size := 0
+	for i, c := range n.children {
+		if c == nil {
+			return 0, bst.NewInvariantError(bst.BPlusTree, bst.RuleFill, first, "branch %d is nil", i)
+		}
+		if c.parent != n {
+			return 0, bst.NewInvariantError(bst.BPlusTree, bst.RuleParent, firstKey(c), "parent is not the node of %v", first)
+		}
+		l, h := lo, hi
+		if i > 0 {
+			l = &n.key[i-1]
+		}
+		if i < len(n.key) {
+			h = &n.key[i]
+		}
+		s, err := v.walk(c, l, h, depth+1)
+		if err != nil {
+			return 0, err
+		}
+		size += s
+	}
+	return size, nil
+}
+
+// before 检查 x 是否应位于 y 之前，多重映射中允许相等
+func (t *bPlusTree[K, V]) before(x, y K) bool {
+	c := t.comp(x, y)
+	return c < 0 || c == 0 && t.multi
+}
+
+// firstKey 返回节点的首个关键码，空节点返回 nil
+func firstKey[K, V any](n *node[K, V]) interface{} {
+	if len(n.key) == 0 {
+		return nil
+	}
+	return n.key[0]
+}
@@ -1,17 +1,31 @@
 package bst
 
 import (
+	"math"
+	"reflect"
 	"strconv"
+	"strings"
 )
 
 // BST identifies a Binary Search Tree interface.
-type BST interface {
-	Search(key interface{}) (Node, bool)
-	Insert(key, data interface{}) (Node, error)
-	Remove(key interface{}) (Node, error)
-	Root() Node
+type BST[K, V any] interface {
+	Search(key K) (Node[K, V], bool)
+	Insert(key K, data V) (Node[K, V], error)
+	// Remove removes key and returns the removed entry, a node detached from
+	// the tree whose Key and Data are those of key, or ErrNotFound
+	Remove(key K) (Node[K, V], error)
+	Root() Node[K, V]
+	Len() int
 	Print()
-	Walk(order Order, opts ...Option)
+	Walk(order Order, opts ...Option[K, V])
+	Iterator() Iterator[K, V]
+	Floor(key K) (Node[K, V], bool)
+	Ceiling(key K) (Node[K, V], bool)
+	Lower(key K) (Node[K, V], bool)
+	Higher(key K) (Node[K, V], bool)
+	Min() (Node[K, V], bool)
+	Max() (Node[K, V], bool)
+	Range(lo, hi K, loInclusive, hiInclusive bool, fn func(n Node[K, V]) bool)
 }
 type Order uint8
 
@@ -20,9 +34,10 @@ const (
 	InOrder
 	PostOrder
 	LevelOrder
+	ReverseInOrder // 自大到小的中序
 )
 
-type Option func(n Node)
+type Option[K, V any] func(n Node[K, V])
 
 // Class stands for the specific type of binary search tree, such as AVL,Red-Black Tree etc.
 
@@ -33,34 +48,239 @@ const (
 	RBTree
 	Splay
 	BTree
+	IntervalTree
+	Treap
+	SkipList
+	LLRB
+	AATree
+	Scapegoat
+	WeightBalanced
+	BPlusTree
 	maxClass
 )
 
-var classes = make([]func(parms ...interface{}) BST, maxClass)
+// Factory creates the trees of a class registered by Register
+type Factory struct {
+	// New returns a new tree of the class, parms are those of bst.New
+	New func(parms ...interface{}) BST[any, any]
+	// Typed holds instantiations of the generic constructor of the class
+	// for some types of keys and data, such as New[int, string], each a
+	// func(parms ...interface{}) BST[K, V] which NewOf[K, V] calls instead
+	// of wrapping the tree returned by New, see RegisterOf
+	Typed []interface{}
+	// 类别支持的 TreeOption，Make 拒绝其余的选项
+	Order    bool // WithOrder，阶次以 int 传入 New
+	Multimap bool // WithMultimap，以 MultiKeys 传入 New
+	// WithAlpha，检查 α 是否在类别允许的范围内，α 以 float64 传入 New
+	Alpha func(alpha float64) error
+	// TypedOnly reports that the trees of New can not be wrapped for other
+	// types of keys, such as the interval tree keyed by Interval[any], so
+	// NewOf[K, V] panics and Make fails unless Typed or RegisterOf provides
+	// a constructor for K and V
+	TypedOnly bool
+}
 
-// New returns a new BST as per the provided class.
-func New(c Class, parms ...interface{}) BST {
-	// fmt.Println(parms...)
-	if c > 0 && c < maxClass {
-		f := classes[c]
-		if f != nil {
-			return f(parms...)
-		}
+// classNames 本包定义的类别注册时所用的名字
+var classNames = [maxClass]string{
+	AVL:            "avl",
+	RBTree:         "redblack",
+	Splay:          "splay",
+	BTree:          "btree",
+	IntervalTree:   "interval",
+	Treap:          "treap",
+	SkipList:       "skiplist",
+	LLRB:           "llrb",
+	AATree:         "aa",
+	Scapegoat:      "scapegoat",
+	WeightBalanced: "wbt",
+	BPlusTree:      "bplustree",
+}
+
+var (
+	classes = make([]Factory, maxClass) // 以类别为下标
+	names   = make(map[string]Class)
+	typed   = make(map[typedKey]interface{})
+)
+
+// typedKey 以类别及构造函数的类型索引 Factory.Typed 与 RegisterOf 注册的构造函数
+type typedKey struct {
+	c Class
+	t reflect.Type
+}
+
+// New returns a new BST as per the provided class. The parms are not
+// checked, the class ignores those it does not know, see Make for options
+// validated with errors.
+func New(c Class, parms ...interface{}) BST[any, any] {
+	if f, ok := c.factory(); ok {
+		return f.New(parms...)
 	}
 	panic("bst: requested BST function #" + strconv.Itoa(int(c)) + " is unavailable")
 }
 
-// // Available reports whether the given BST class is linked into the binary.
-// func (c Class) Available() bool {
-// 	return c < maxClass && classes[c] != nil
-// }
+// NewOf returns a new BST with typed keys and data as per the provided class.
+// The tree is built by the constructor of the class registered for K and V
+// by Factory.Typed or RegisterOf, otherwise the tree of New is wrapped, which
+// boxes the keys and data and is slower. A Comparator[K] or func(a, b K) int
+// in parms replaces the default comparator of K, an Augmenter[K, V] or
+// Encoding[K, V] is converted for the class so that keys and data are decoded
+// as K and V, the other parms are passed to the class as they are. Keys of
+// built-in ordered types are compared by cmp.Compare, others by BasicCompare
+// unless a comparator is provided. NewOf panics if the class is
+// Factory.TypedOnly without a constructor for K and V, where Make returns an
+// error.
+func NewOf[K, V any](c Class, parms ...interface{}) BST[K, V] {
+	if ctor, ok := typed[typedKey{c, reflect.TypeFor[func(...interface{}) BST[K, V]]()}]; ok {
+		return withFallbacks(ctor.(func(...interface{}) BST[K, V])(parms...), c, parms)
+	}
+	if !holds[K, V](c) {
+		panic("bst: " + c.String() + " has no constructor for " + reflect.TypeFor[BST[K, V]]().String())
+	}
+	comp, _ := orderedComparator[K]()
+	var enc Encoding[K, V]
+	rest := make([]interface{}, 0, len(parms)+2)
+	for _, p := range parms {
+		switch v := p.(type) {
+		case Comparator[K]:
+			comp = v
+		case func(a, b K) int:
+			comp = v
+		case Augmenter[K, V]:
+			rest = append(rest, eraseAugmenter(v))
+		case Encoding[K, V]:
+			enc = v
+		default:
+			rest = append(rest, p)
+		}
+	}
+	if comp != nil {
+		rest = append(rest, eraseComparator(comp))
+	}
+	rest = append(rest, eraseEncoding(enc))
+	t := New(c, rest...)
+	if t0, ok := t.(BST[K, V]); ok {
+		return t0
+	}
+	return &typedBST[K, V]{t: t, c: c, parms: rest}
+}
+
+// holds 判断类别 c 能否构造 BST[K, V]：TypedOnly 的类别只能构造注册过的 K、V 及 New 本身的类型
+func holds[K, V any](c Class) bool {
+	f, ok := c.factory()
+	if !ok || !f.TypedOnly || reflect.TypeFor[BST[K, V]]() == reflect.TypeFor[BST[any, any]]() {
+		return true
+	}
+	_, ok = typed[typedKey{c, reflect.TypeFor[func(...interface{}) BST[K, V]]()}]
+	return ok
+}
+
+// Available reports whether the given BST class is linked into the binary.
+func (c Class) Available() bool {
+	_, ok := c.factory()
+	return ok
+}
+
+func (c Class) factory() (Factory, bool) {
+	if c == 0 || int(c) >= len(classes) || classes[c].New == nil {
+		return Factory{}, false
+	}
+	return classes[c], true
+}
+
+// String returns the name c is registered by
+func (c Class) String() string {
+	if int(c) < len(classNames) && classNames[c] != "" {
+		return classNames[c]
+	}
+	for name, c0 := range names {
+		if c0 == c {
+			return name
+		}
+	}
+	return "class " + strconv.Itoa(int(c))
+}
+
+// Registered returns the classes linked into the binary in ascending order
+func Registered() []Class {
+	var cs []Class
+	for c := Class(1); int(c) < len(classes); c++ {
+		if c.Available() {
+			cs = append(cs, c)
+		}
+	}
+	return cs
+}
+
+// Register makes the trees of f available under name and returns their
+// class. The names of the classes of this package, such as "avl" and
+// "btree", register them under their constants, other names get a new
+// class. This is intended to be called from the init function in packages
+// that implement BST, it panics if name is registered twice.
+func Register(name string, f Factory) Class {
+	if f.New == nil {
+		panic("bst: Register of " + name + " without New")
+	}
+	if _, dup := names[name]; dup {
+		panic("bst: Register called twice for " + name)
+	}
+	for _, ctor := range f.Typed {
+		if !isConstructor(reflect.TypeOf(ctor)) {
+			panic("bst: Register of " + name + " with an invalid typed constructor")
+		}
+	}
+	c := Class(0)
+	for c0, n := range classNames {
+		if n == name {
+			c = Class(c0)
+		}
+	}
+	if c == 0 {
+		if len(classes) > math.MaxUint8 {
+			panic("bst: too many registered classes")
+		}
+		c = Class(len(classes))
+		classes = append(classes, Factory{})
+	}
+	classes[c] = f
+	names[name] = c
+	for _, ctor := range f.Typed {
+		typed[typedKey{c, reflect.TypeOf(ctor)}] = ctor
+	}
+	return c
+}
+
+// isConstructor 判断 t 是否为 func(parms ...interface{}) BST[K, V]
+func isConstructor(t reflect.Type) bool {
+	if t == nil || t.Kind() != reflect.Func || !t.IsVariadic() || t.NumIn() != 1 || t.NumOut() != 1 {
+		return false
+	}
+	out := t.Out(0)
+	return t.In(0) == reflect.TypeFor[[]interface{}]() &&
+		out.PkgPath() == reflect.TypeFor[BST[any, any]]().PkgPath() && strings.HasPrefix(out.Name(), "BST[")
+}
+
+// RegisterOf makes NewOf[K, V] and Make[K, V] build the trees of class c by
+// ctor, typically the New[K, V] of the class package, as Factory.Typed does
+// for other types of keys and data. It panics if c is unavailable.
+func RegisterOf[K, V any](c Class, ctor func(parms ...interface{}) BST[K, V]) {
+	if !c.Available() {
+		panic("bst: RegisterOf of unavailable " + c.String())
+	}
+	typed[typedKey{c, reflect.TypeOf(ctor)}] = ctor
+}
+
+// Lookup returns the class registered under name
+func Lookup(name string) (Class, bool) {
+	c, ok := names[name]
+	return c, ok
+}
 
 // RegisterBST registers a function that returns a new instance of the given
-// BST class. This is intended to be called from the init function in
-// packages that implement BST.
-func RegisterBST(c Class, f func(parms ...interface{}) BST) {
-	if c >= maxClass {
+// BST class, which supports no TreeOption other than WithComparator and
+// WithStats. Register is preferred.
+func RegisterBST(c Class, f func(parms ...interface{}) BST[any, any]) {
+	if c == 0 || c >= maxClass {
 		panic("bst: RegisterBST of unknown BST class")
 	}
-	classes[c] = f
+	Register(classNames[c], Factory{New: f})
 }
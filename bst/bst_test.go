@@ -1,13 +1,39 @@
 package bst_test
 
 import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 
-	_ "github.com/mooncaker816/gostructure/bst/avl"
-	_ "github.com/mooncaker816/gostructure/bst/btree"
-	_ "github.com/mooncaker816/gostructure/bst/redblack"
+	_ "github.com/mooncaker816/gostructure/bst/aa"
+	"github.com/mooncaker816/gostructure/bst/avl"
+	"github.com/mooncaker816/gostructure/bst/bplustree"
+	"github.com/mooncaker816/gostructure/bst/bsttest"
+	"github.com/mooncaker816/gostructure/bst/btree"
+	"github.com/mooncaker816/gostructure/bst/interval"
+	_ "github.com/mooncaker816/gostructure/bst/llrb"
+	"github.com/mooncaker816/gostructure/bst/pager"
+	"github.com/mooncaker816/gostructure/bst/redblack"
+	"github.com/mooncaker816/gostructure/bst/scapegoat"
+	"github.com/mooncaker816/gostructure/bst/skiplist"
 	_ "github.com/mooncaker816/gostructure/bst/splay"
+	"github.com/mooncaker816/gostructure/bst/treap"
+	"github.com/mooncaker816/gostructure/bst/wbt"
+	"github.com/mooncaker816/gostructure/bst/workload"
 
 	"github.com/mooncaker816/gostructure/bst"
 )
@@ -127,8 +153,2470 @@ func TestRBTree(t *testing.T) {
 	fmt.Println("RBTREE END")
 }
 
-func withprintheight() func(n bst.Node) {
-	return func(n bst.Node) {
+func withprintheight() func(n bst.Node[any, any]) {
+	return func(n bst.Node[any, any]) {
 		fmt.Printf("%d ", n.Height())
 	}
 }
+
+func TestNewOf(t *testing.T) {
+	for _, c := range classes {
+		tr := bst.NewOf[int, string](c)
+		for i := 0; i < 20; i++ {
+			if _, err := tr.Insert(i, strconv.Itoa(i)); err != nil {
+				t.Fatalf("class %d: insert %d: %v", c, i, err)
+			}
+		}
+		for i := 0; i < 20; i++ {
+			n, ok := tr.Search(i)
+			if !ok || n.Key() != i || n.Data() != strconv.Itoa(i) {
+				t.Errorf("class %d: search %d got %v", c, i, n)
+			}
+		}
+		if _, ok := tr.Search(20); ok {
+			t.Errorf("class %d: search 20 should fail", c)
+		}
+		tr.Remove(5)
+		if _, ok := tr.Search(5); ok {
+			t.Errorf("class %d: 5 should be removed", c)
+		}
+	}
+}
+
+func TestNewOfComparator(t *testing.T) {
+	type point struct{ x, y int }
+	byX := func(a, b point) int { return a.x - b.x }
+	tr := bst.NewOf[point, int](bst.RBTree, byX)
+	for i := 10; i > 0; i-- {
+		tr.Insert(point{i, -i}, i)
+	}
+	var keys []int
+	tr.Walk(bst.InOrder, func(n bst.Node[point, int]) {
+		keys = append(keys, n.Key().x)
+	})
+	for i, k := range keys {
+		if k != i+1 {
+			t.Fatalf("in-order keys %v are not sorted", keys)
+		}
+	}
+}
+
+var classes = []bst.Class{bst.AVL, bst.RBTree, bst.Splay, bst.BTree, bst.Treap, bst.LLRB, bst.AATree, bst.WeightBalanced}
+
+func TestIterator(t *testing.T) {
+	for _, c := range classes {
+		tr := bst.NewOf[int, int](c)
+		for i := 0; i < 50; i++ {
+			tr.Insert(i*2, i)
+		}
+		it := tr.Iterator()
+		i := 0
+		for ok := it.First(); ok; ok = it.Next() {
+			if it.Key() != i*2 || it.Data() != i {
+				t.Fatalf("class %d: forward got %d:%d at %d", c, it.Key(), it.Data(), i)
+			}
+			i++
+		}
+		if i != 50 {
+			t.Fatalf("class %d: forward visited %d keys", c, i)
+		}
+		for ok := it.Last(); ok; ok = it.Prev() {
+			i--
+			if it.Key() != i*2 {
+				t.Fatalf("class %d: backward got %d at %d", c, it.Key(), i)
+			}
+		}
+		if i != 0 {
+			t.Fatalf("class %d: backward stopped at %d", c, i)
+		}
+		if !it.Seek(31) || it.Key() != 32 {
+			t.Errorf("class %d: seek 31 should stop at 32", c)
+		}
+		if !it.Prev() || it.Key() != 30 {
+			t.Errorf("class %d: prev of 32 should be 30", c)
+		}
+		if !it.Seek(40) || it.Key() != 40 {
+			t.Errorf("class %d: seek 40 should stop at 40", c)
+		}
+		if it.Seek(99) {
+			t.Errorf("class %d: seek 99 should be invalid", c)
+		}
+	}
+}
+
+func TestNearest(t *testing.T) {
+	for _, c := range classes {
+		tr := bst.NewOf[int, int](c)
+		if _, ok := tr.Min(); ok {
+			t.Errorf("class %d: min of empty tree", c)
+		}
+		for i := 1; i <= 30; i++ {
+			tr.Insert(i*10, i)
+		}
+		check := func(name string, n bst.Node[int, int], ok bool, want int) {
+			t.Helper()
+			if want < 0 {
+				if ok {
+					t.Errorf("class %d: %s got %d, want none", c, name, n.Key())
+				}
+				return
+			}
+			if !ok || n.Key() != want {
+				t.Errorf("class %d: %s got %v, want %d", c, name, n, want)
+			}
+		}
+		n, ok := tr.Floor(55)
+		check("floor 55", n, ok, 50)
+		n, ok = tr.Floor(50)
+		check("floor 50", n, ok, 50)
+		n, ok = tr.Floor(5)
+		check("floor 5", n, ok, -1)
+		n, ok = tr.Floor(1000)
+		check("floor 1000", n, ok, 300)
+		n, ok = tr.Ceiling(55)
+		check("ceiling 55", n, ok, 60)
+		n, ok = tr.Ceiling(60)
+		check("ceiling 60", n, ok, 60)
+		n, ok = tr.Ceiling(301)
+		check("ceiling 301", n, ok, -1)
+		n, ok = tr.Lower(60)
+		check("lower 60", n, ok, 50)
+		n, ok = tr.Lower(10)
+		check("lower 10", n, ok, -1)
+		n, ok = tr.Lower(1000)
+		check("lower 1000", n, ok, 300)
+		n, ok = tr.Higher(60)
+		check("higher 60", n, ok, 70)
+		n, ok = tr.Higher(300)
+		check("higher 300", n, ok, -1)
+		n, ok = tr.Min()
+		check("min", n, ok, 10)
+		n, ok = tr.Max()
+		check("max", n, ok, 300)
+
+		var got []int
+		collect := func(n bst.Node[int, int]) bool {
+			got = append(got, n.Key())
+			return true
+		}
+		tr.Range(100, 150, true, false, collect)
+		if fmt.Sprint(got) != "[100 110 120 130 140]" {
+			t.Errorf("class %d: range [100, 150) got %v", c, got)
+		}
+		got = nil
+		tr.Range(100, 150, false, true, collect)
+		if fmt.Sprint(got) != "[110 120 130 140 150]" {
+			t.Errorf("class %d: range (100, 150] got %v", c, got)
+		}
+		got = nil
+		tr.Range(0, 1000, true, true, func(n bst.Node[int, int]) bool {
+			got = append(got, n.Key())
+			return len(got) < 3
+		})
+		if fmt.Sprint(got) != "[10 20 30]" {
+			t.Errorf("class %d: stopped range got %v", c, got)
+		}
+	}
+}
+
+func TestOrderStatistic(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, c := range classes {
+		tr := bst.NewOf[int, int](c)
+		os, ok := tr.(bst.OrderStatistic[int, int])
+		if !ok {
+			t.Fatalf("class %d: typed tree should implement OrderStatistic", c)
+		}
+		keys := make(map[int]bool)
+		for i := 0; i < 2000; i++ {
+			k := r.Intn(500)
+			if r.Intn(3) == 0 {
+				tr.Remove(k)
+				delete(keys, k)
+			} else {
+				tr.Insert(k, k)
+				keys[k] = true
+			}
+		}
+		sorted := make([]int, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Ints(sorted)
+		if tr.Len() != len(sorted) {
+			t.Fatalf("class %d: len %d, want %d", c, tr.Len(), len(sorted))
+		}
+		for i, k := range sorted {
+			if n, ok := os.Select(i); !ok || n.Key() != k {
+				t.Fatalf("class %d: select %d got %v, want %d", c, i, n, k)
+			}
+			if rk := os.Rank(k); rk != i {
+				t.Fatalf("class %d: rank %d got %d, want %d", c, k, rk, i)
+			}
+		}
+		if _, ok := os.Select(len(sorted)); ok {
+			t.Errorf("class %d: select out of range", c)
+		}
+		want := sort.SearchInts(sorted, 301) - sort.SearchInts(sorted, 100)
+		if got := os.CountRange(100, 300); got != want {
+			t.Errorf("class %d: count [100, 300] got %d, want %d", c, got, want)
+		}
+	}
+	for _, c := range []bst.Class{bst.AVL, bst.RBTree} {
+		if _, ok := bst.New(c).(bst.OrderStatistic[any, any]); !ok {
+			t.Errorf("class %d should implement OrderStatistic", c)
+		}
+	}
+}
+
+func TestAugment(t *testing.T) {
+	sum := bst.Augment(0, func(left int, n bst.Node[int, int], right int) int {
+		return left + n.Data() + right
+	})
+	r := rand.New(rand.NewSource(2))
+	for _, c := range []bst.Class{bst.AVL, bst.RBTree, bst.Splay} {
+		tr := bst.NewOf[int, int](c, sum)
+		data := make(map[int]int)
+		for i := 0; i < 3000; i++ {
+			k := r.Intn(300)
+			switch r.Intn(4) {
+			case 0:
+				tr.Remove(k)
+				delete(data, k)
+			case 1:
+				tr.Search(k)
+			default:
+				if _, err := tr.Insert(k, i); err == nil {
+					data[k] = i
+				}
+			}
+			lo, hi := r.Intn(300), r.Intn(300)
+			want := 0
+			for k, v := range data {
+				if k >= lo && k <= hi {
+					want += v
+				}
+			}
+			if got := bst.Summary[int](tr, lo, hi); got != want {
+				t.Fatalf("class %d: sum [%d, %d] got %d, want %d", c, lo, hi, got, want)
+			}
+		}
+		total := 0
+		for _, v := range data {
+			total += v
+		}
+		if got := tr.(bst.Summarizer[int]).Summary(); got != total {
+			t.Errorf("class %d: total got %v, want %d", c, got, total)
+		}
+	}
+}
+
+func TestIntervalTree(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	it := interval.New[int, int]()
+	var all []interval.Interval[int]
+	for i := 0; i < 300; i++ {
+		lo := r.Intn(1000)
+		hi := lo + r.Intn(100)
+		if _, err := it.InsertInterval(lo, hi, i); err == nil {
+			all = append(all, interval.Interval[int]{Lo: lo, Hi: hi})
+		}
+	}
+	if _, err := it.InsertInterval(5, 1, 0); err == nil {
+		t.Error("inserting [5, 1] should fail")
+	}
+	data := make(map[interval.Interval[int]]int)
+	it.Walk(bst.InOrder, func(n bst.Node[interval.Interval[int], int]) { data[n.Key()] = n.Data() })
+	for i := 0; i < len(all); i += 3 {
+		n, err := it.RemoveInterval(all[i].Lo, all[i].Hi)
+		if err != nil || n.Key() != all[i] || n.Data() != data[all[i]] {
+			t.Fatalf("removing %v got %v %v", all[i], n, err)
+		}
+		all[i].Lo = -1
+	}
+	if _, err := it.RemoveInterval(-5, -1); !errors.Is(err, bst.ErrNotFound) {
+		t.Errorf("removing an absent interval got %v", err)
+	}
+	overlapping := func(lo, hi int) string {
+		var want []interval.Interval[int]
+		for _, iv := range all {
+			if iv.Lo >= 0 && iv.Lo <= hi && iv.Hi >= lo {
+				want = append(want, iv)
+			}
+		}
+		sort.Slice(want, func(i, j int) bool {
+			return want[i].Lo < want[j].Lo || want[i].Lo == want[j].Lo && want[i].Hi < want[j].Hi
+		})
+		return fmt.Sprint(want)
+	}
+	keys := func(ns []bst.Node[interval.Interval[int], int]) string {
+		ivs := make([]interval.Interval[int], 0, len(ns))
+		for _, n := range ns {
+			ivs = append(ivs, n.Key())
+		}
+		return fmt.Sprint(ivs)
+	}
+	for i := 0; i < 200; i++ {
+		x := r.Intn(1100)
+		if got, want := keys(it.Stab(x)), overlapping(x, x); got != want {
+			t.Fatalf("stab %d got %s, want %s", x, got, want)
+		}
+		lo := r.Intn(1100)
+		hi := lo + r.Intn(50)
+		if got, want := keys(it.Overlapping(lo, hi)), overlapping(lo, hi); got != want {
+			t.Fatalf("overlapping [%d, %d] got %s, want %s", lo, hi, got, want)
+		}
+	}
+
+	at := bst.New(bst.IntervalTree)
+	q := at.(interval.Querier[any, any])
+	q.InsertInterval(1, 5, "a")
+	q.InsertInterval(3, 9, "b")
+	at.Insert(interval.Interval[any]{Lo: 6, Hi: 7}, "c")
+	if got := len(q.Stab(4)); got != 2 {
+		t.Errorf("registered interval tree: stab 4 got %d intervals", got)
+	}
+	if n, ok := at.Min(); !ok || n.Data() != "a" {
+		t.Errorf("registered interval tree: min got %v", n)
+	}
+	if n, err := q.RemoveInterval(3, 9); err != nil || n.Key() != (interval.Interval[any]{Lo: 3, Hi: 9}) || n.Data() != "b" {
+		t.Errorf("registered interval tree: removing [3, 9] got %v %v", n, err)
+	}
+
+	// NewOf 与 Make 以注册的构造函数构造类型化的区间树
+	tt, err := bst.Make[interval.Interval[int], string](bst.IntervalTree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tq, ok := tt.(interval.Querier[int, string])
+	if !ok {
+		t.Fatalf("made interval tree %T is not a Querier", tt)
+	}
+	for i := 0; i < 10; i++ {
+		if _, err := tq.InsertInterval(i, i+2, strconv.Itoa(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := len(tq.Stab(5)); got != 3 {
+		t.Errorf("made interval tree: stab 5 got %d intervals", got)
+	}
+	ost := tt.(bst.OrderStatistic[interval.Interval[int], string])
+	if n, ok := ost.Select(4); !ok || n.Data() != "4" || ost.Rank(interval.Interval[int]{Lo: 4}) != 4 {
+		t.Errorf("made interval tree: select 4 got %v", n)
+	}
+	if hi := tt.(bst.Summarizer[interval.Interval[int]]).Summary(); hi != 11 {
+		t.Errorf("made interval tree: greatest hi %v", hi)
+	}
+	right := tt.(bst.Splitter[interval.Interval[int], string]).Split(interval.Interval[int]{Lo: 5})
+	if got := len(right.(interval.Querier[int, string]).Stab(5)); got != 1 || tt.Len() != 5 {
+		t.Errorf("split interval tree: stab 5 got %d intervals, %d left", got, tt.Len())
+	}
+	if err := tt.(bst.Splitter[interval.Interval[int], string]).Join(right); err != nil || tt.Len() != 10 {
+		t.Errorf("joining interval trees got %v with %d intervals", err, tt.Len())
+	}
+	if err := bst.Validate(tt); err != nil {
+		t.Error(err)
+	}
+	if err := tt.(bst.SortedBuilder[interval.Interval[int], string]).BuildSorted(
+		[]interval.Interval[int]{{Lo: 1, Hi: 2}, {Lo: 4, Hi: 3}}, []string{"a", "b"}, true); !errors.Is(err, interval.ErrInverted) {
+		t.Errorf("building with an inverted interval got %v", err)
+	}
+
+	// 没有构造函数的端点类型不能包装 Interval[any] 为键的树
+	if _, err := bst.Make[interval.Interval[uint8], int](bst.IntervalTree); !errors.Is(err, bst.ErrInvalidOption) {
+		t.Errorf("making an interval tree of uint8 got %v", err)
+	}
+	if _, err := bst.Make[interval.Interval[int], int](bst.IntervalTree, bst.WithMultimap()); !errors.Is(err, bst.ErrInvalidOption) {
+		t.Errorf("making an interval multimap got %v", err)
+	}
+	if _, err := bst.Make[int, int](bst.IntervalTree); !errors.Is(err, bst.ErrInvalidOption) {
+		t.Errorf("making an interval tree of int keys got %v", err)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("NewOf of an interval tree of uint8 should panic")
+			}
+		}()
+		bst.NewOf[interval.Interval[uint8], int](bst.IntervalTree)
+	}()
+	bst.RegisterOf(bst.IntervalTree, func(parms ...interface{}) bst.BST[interval.Interval[float32], int] {
+		return interval.New[float32, int](parms...)
+	})
+	ft, err := bst.Make[interval.Interval[float32], int](bst.IntervalTree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ft.(interval.Querier[float32, int]).InsertInterval(0.5, 1.5, 1); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPersistent(t *testing.T) {
+	news := map[string]func(parms ...interface{}) bst.Persistent[int, int]{
+		"avl":      avl.NewPersistent[int, int],
+		"redblack": redblack.NewPersistent[int, int],
+	}
+	for name, newTree := range news {
+		r := rand.New(rand.NewSource(7))
+		var versions []bst.Snapshot[int, int]
+		var wants [][]int
+		p := newTree()
+		set := map[int]bool{}
+		for i := 0; i < 2000; i++ {
+			k := r.Intn(200)
+			var err error
+			if r.Intn(3) > 0 {
+				if p, err = p.Insert(k, -k); (err == nil) == set[k] {
+					t.Fatalf("%s: insert %d got error %v", name, k, err)
+				}
+				set[k] = true
+			} else {
+				p, _ = p.Remove(k)
+				delete(set, k)
+			}
+			if i%50 == 0 {
+				want := make([]int, 0, len(set))
+				for k := range set {
+					want = append(want, k)
+				}
+				sort.Ints(want)
+				versions = append(versions, p.Snapshot())
+				wants = append(wants, want)
+			}
+		}
+		for i, v := range versions {
+			want := wants[i]
+			if v.Len() != len(want) {
+				t.Fatalf("%s: version %d has %d keys, want %d", name, i, v.Len(), len(want))
+			}
+			var got []int
+			it := v.Iterator()
+			for ok := it.First(); ok; ok = it.Next() {
+				if it.Data() != -it.Key() {
+					t.Fatalf("%s: version %d key %d has data %d", name, i, it.Key(), it.Data())
+				}
+				got = append(got, it.Key())
+			}
+			var rev []int
+			for ok := it.Last(); ok; ok = it.Prev() {
+				rev = append([]int{it.Key()}, rev...)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(want) || fmt.Sprint(rev) != fmt.Sprint(want) {
+				t.Fatalf("%s: version %d got %v and %v reversed, want %v", name, i, got, rev, want)
+			}
+			for k := -1; k <= 200; k += 13 {
+				j := sort.SearchInts(want, k)
+				if ok := it.Seek(k); ok != (j < len(want)) || ok && it.Key() != want[j] {
+					t.Fatalf("%s: version %d seek %d failed", name, i, k)
+				}
+				if _, ok := v.Search(k); ok != (j < len(want) && want[j] == k) {
+					t.Fatalf("%s: version %d search %d got %v", name, i, k, ok)
+				}
+			}
+		}
+	}
+}
+
+func TestSynchronized(t *testing.T) {
+	for _, c := range classes {
+		st := bst.Synchronized(bst.NewOf[int, int](c))
+		if st.SelfAdjusting() != (c == bst.Splay) {
+			t.Errorf("class %d: SelfAdjusting got %v", c, st.SelfAdjusting())
+		}
+		var wg sync.WaitGroup
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				r := rand.New(rand.NewSource(int64(g)))
+				for i := 0; i < 500; i++ {
+					k := r.Intn(100)
+					switch r.Intn(6) {
+					case 0:
+						st.Insert(k, k)
+					case 1:
+						st.Remove(k)
+					case 2:
+						if n, ok := st.Search(k); ok && n.Key() != k {
+							t.Errorf("class %d: search %d got %d", c, k, n.Key())
+						}
+					case 3:
+						if n, ok := st.Floor(k); ok && n.Key() > k {
+							t.Errorf("class %d: floor %d got %d", c, k, n.Key())
+						}
+					case 4:
+						st.Range(k, k+10, true, false, func(n bst.Node[int, int]) bool {
+							return n.Data() == n.Key()
+						})
+					default:
+						it := st.Iterator()
+						for ok := it.Seek(k); ok && it.Key() < k+5; ok = it.Next() {
+						}
+						st.Len()
+					}
+				}
+			}(g)
+		}
+		wg.Wait()
+		n := 0
+		st.View(func(t0 bst.BST[int, int]) {
+			prev := -1
+			it := t0.Iterator()
+			for ok := it.First(); ok; ok = it.Next() {
+				if it.Key() <= prev {
+					t.Errorf("class %d: keys out of order %d, %d", c, prev, it.Key())
+				}
+				prev = it.Key()
+				n++
+			}
+		})
+		if n != st.Len() {
+			t.Errorf("class %d: iterated %d keys, Len %d", c, n, st.Len())
+		}
+	}
+}
+
+func TestVersioned(t *testing.T) {
+	v := bst.NewVersioned(avl.NewPersistent[int, int]())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 500; i++ {
+			v.Insert(i, i)
+		}
+	}()
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				s := v.Snapshot()
+				n := 0
+				it := s.Iterator()
+				for ok := it.First(); ok; ok = it.Next() {
+					if it.Key() != n {
+						t.Errorf("snapshot of %d keys has %d at %d", s.Len(), it.Key(), n)
+						return
+					}
+					n++
+				}
+				if n != s.Len() {
+					t.Errorf("snapshot iterated %d keys, Len %d", n, s.Len())
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if err := v.Insert(3, 3); err == nil {
+		t.Error("inserting a duplicate key should fail")
+	}
+	if v.Snapshot().Len() != 500 {
+		t.Errorf("Len got %d, want 500", v.Snapshot().Len())
+	}
+}
+
+func depth(n bst.Node[int, int]) int {
+	if bst.IsNil(n) {
+		return 0
+	}
+	return 1 + max(depth(n.LChild()), depth(n.RChild()))
+}
+
+func TestBuildSorted(t *testing.T) {
+	sum := bst.Augment(0, func(left int, n bst.Node[int, int], right int) int {
+		return left + n.Data() + right
+	})
+	for _, c := range classes {
+		for _, n := range []int{0, 1, 2, 7, 8, 100, 1000} {
+			keys := make([]int, n)
+			values := make([]int, n)
+			for i := range keys {
+				keys[i], values[i] = 2*i, i
+			}
+			parms := []interface{}{bst.VerifySorted}
+			if c != bst.BTree {
+				parms = append(parms, sum)
+			}
+			tr, err := bst.BuildSorted(c, keys, values, parms...)
+			if err != nil {
+				t.Fatalf("class %d: build %d keys: %v", c, n, err)
+			}
+			if tr.Len() != n {
+				t.Fatalf("class %d: Len got %d, want %d", c, tr.Len(), n)
+			}
+			if c != bst.BTree {
+				if d := depth(tr.Root()); d != bits.Len(uint(n)) {
+					t.Errorf("class %d: %d keys built with depth %d", c, n, d)
+				}
+				if got := bst.Summary[int](tr, 0, 2*n); got != n*(n-1)/2 {
+					t.Errorf("class %d: sum of %d keys got %d", c, n, got)
+				}
+			}
+			i := 0
+			it := tr.Iterator()
+			for ok := it.First(); ok; ok = it.Next() {
+				if it.Key() != 2*i || it.Data() != i {
+					t.Fatalf("class %d: got %d:%d at %d", c, it.Key(), it.Data(), i)
+				}
+				i++
+			}
+			for i := 0; i < n; i += 3 {
+				tr.Remove(2 * i)
+				tr.Insert(2*i+1, 0)
+			}
+			if tr.Len() != n {
+				t.Errorf("class %d: Len got %d after updates, want %d", c, tr.Len(), n)
+			}
+		}
+		if _, err := bst.BuildSorted[int, int](c, []int{1, 3, 3}, nil, bst.VerifySorted); err == nil {
+			t.Errorf("class %d: duplicate keys should be rejected", c)
+		}
+		if _, err := bst.BuildSorted[int, int](c, []int{1, 5, 3}, nil, bst.VerifySorted); err == nil {
+			t.Errorf("class %d: unsorted keys should be rejected", c)
+		}
+		if _, err := bst.BuildSorted(c, []int{1, 2}, []int{1}); err == nil {
+			t.Errorf("class %d: values of another length should be rejected", c)
+		}
+	}
+}
+
+func keysOf(tr bst.BST[int, int]) []int {
+	var keys []int
+	it := tr.Iterator()
+	for ok := it.First(); ok; ok = it.Next() {
+		keys = append(keys, it.Key())
+	}
+	return keys
+}
+
+func TestSplitJoin(t *testing.T) {
+	sum := bst.Augment(0, func(left int, n bst.Node[int, int], right int) int {
+		return left + n.Key() + right
+	})
+	r := rand.New(rand.NewSource(5))
+	for _, c := range classes {
+		parms := []interface{}{}
+		if c != bst.BTree {
+			parms = append(parms, sum)
+		}
+		for round := 0; round < 50; round++ {
+			tr := bst.NewOf[int, int](c, parms...)
+			set := map[int]bool{}
+			for i := r.Intn(300); i > 0; i-- {
+				k := r.Intn(1000)
+				tr.Insert(k, k)
+				set[k] = true
+			}
+			var all []int
+			for k := range set {
+				all = append(all, k)
+			}
+			sort.Ints(all)
+			key := r.Intn(1100) - 50
+			j := sort.SearchInts(all, key)
+
+			right := tr.(bst.Splitter[int, int]).Split(key)
+			if got := keysOf(tr); fmt.Sprint(got) != fmt.Sprint(all[:j]) || tr.Len() != j {
+				t.Fatalf("class %d: split at %d left %v (Len %d), want %v", c, key, got, tr.Len(), all[:j])
+			}
+			if got := keysOf(right); fmt.Sprint(got) != fmt.Sprint(all[j:]) || right.Len() != len(all)-j {
+				t.Fatalf("class %d: split at %d right %v (Len %d), want %v", c, key, got, right.Len(), all[j:])
+			}
+			if c != bst.BTree {
+				want := 0
+				for _, k := range all[j:] {
+					want += k
+				}
+				if got := bst.Summary[int](right, -100, 2000); got != want {
+					t.Fatalf("class %d: sum of the right part got %d, want %d", c, got, want)
+				}
+			}
+			if j > 0 && j < len(all) {
+				if err := right.(bst.Splitter[int, int]).Join(tr); err == nil {
+					t.Fatalf("class %d: joining overlapped trees should fail", c)
+				}
+			}
+			if err := tr.(bst.Splitter[int, int]).Join(right); err != nil {
+				t.Fatalf("class %d: join: %v", c, err)
+			}
+			if got := keysOf(tr); fmt.Sprint(got) != fmt.Sprint(all) || tr.Len() != len(all) || right.Len() != 0 {
+				t.Fatalf("class %d: join got %v, want %v", c, got, all)
+			}
+			for _, k := range all {
+				if r.Intn(2) == 0 {
+					tr.Remove(k)
+				}
+				tr.Insert(r.Intn(1000), 0)
+			}
+		}
+		other := bst.NewOf[int, int](bst.AVL)
+		if c == bst.AVL {
+			other = bst.NewOf[int, int](bst.RBTree)
+		}
+		if err := bst.NewOf[int, int](c).(bst.Splitter[int, int]).Join(other); err != bst.ErrJoinClass {
+			t.Errorf("class %d: join with another class got %v", c, err)
+		}
+	}
+}
+
+// upperCodec 以大写形式存储字符串数据
+type upperCodec struct{}
+
+func (upperCodec) Marshal(v string) ([]byte, error) {
+	return json.Marshal(strings.ToUpper(v))
+}
+
+func (upperCodec) Unmarshal(data []byte) (string, error) {
+	var v string
+	err := json.Unmarshal(data, &v)
+	return strings.ToLower(v), err
+}
+
+func TestEncoding(t *testing.T) {
+	r := rand.New(rand.NewSource(11))
+	for _, c := range classes {
+		for _, shape := range []bool{false, true} {
+			enc := bst.Encoding[int, string]{Value: upperCodec{}, Shape: shape}
+			tr := bst.NewOf[int, string](c, enc, 3)
+			for i := 0; i < 200; i++ {
+				k := r.Intn(500)
+				tr.Insert(k, strconv.Itoa(k)+"x")
+				if r.Intn(3) == 0 {
+					tr.Remove(r.Intn(500))
+				}
+			}
+			bin, err := tr.(encoding.BinaryMarshaler).MarshalBinary()
+			if err != nil {
+				t.Fatalf("class %d: MarshalBinary: %v", c, err)
+			}
+			js, err := tr.(json.Marshaler).MarshalJSON()
+			if err != nil {
+				t.Fatalf("class %d: MarshalJSON: %v", c, err)
+			}
+			if !strings.Contains(string(js), "X") {
+				t.Fatalf("class %d: custom codec not used: %s", c, js)
+			}
+			for _, decode := range []func(bst.BST[int, string]) error{
+				func(d bst.BST[int, string]) error { return d.(encoding.BinaryUnmarshaler).UnmarshalBinary(bin) },
+				func(d bst.BST[int, string]) error { return json.Unmarshal(js, d) },
+			} {
+				d := bst.NewOf[int, string](c, enc, 3)
+				d.Insert(-1, "stale")
+				if err := decode(d); err != nil {
+					t.Fatalf("class %d shape %v: decode: %v", c, shape, err)
+				}
+				if d.Len() != tr.Len() {
+					t.Fatalf("class %d shape %v: Len got %d, want %d", c, shape, d.Len(), tr.Len())
+				}
+				it, want := d.Iterator(), tr.Iterator()
+				for ok := want.First(); ok; ok = want.Next() {
+					if !it.Valid() && !it.First() || it.Key() != want.Key() || it.Data() != want.Data() {
+						t.Fatalf("class %d shape %v: decoded %v, want %v:%q", c, shape, it.Key(), want.Key(), want.Data())
+					}
+					it.Next()
+				}
+				if shape {
+					// 形状一致时重新编码的结果相同
+					again, _ := d.(encoding.BinaryMarshaler).MarshalBinary()
+					if string(again) != string(bin) {
+						t.Fatalf("class %d: shape not kept", c)
+					}
+				}
+			}
+
+			d := bst.NewOf[int, string](c, enc, 3)
+			unmarshal := d.(encoding.BinaryUnmarshaler).UnmarshalBinary
+			future := append([]byte(nil), bin...)
+			future[3] = bst.EncodingVersion + 1
+			if err := unmarshal(future); err == nil {
+				t.Fatalf("class %d: a later version should be rejected", c)
+			}
+			if err := unmarshal(bin[:len(bin)-2]); err == nil {
+				t.Fatalf("class %d: truncated data should be rejected", c)
+			}
+			other := bst.AVL
+			if c == bst.AVL {
+				other = bst.RBTree
+			}
+			foreign, _ := bst.NewOf[int, string](other, enc).(encoding.BinaryMarshaler).MarshalBinary()
+			if err := unmarshal(foreign); err == nil {
+				t.Fatalf("class %d: data of class %d should be rejected", c, other)
+			}
+			if err := json.Unmarshal([]byte(fmt.Sprintf(`{"class":%d}`, c)), d); err == nil {
+				t.Fatalf("class %d: JSON without version should be rejected", c)
+			}
+		}
+	}
+
+	// 不满足平衡条件的形状被拒绝
+	bad := []byte(fmt.Sprintf(`{"version":1,"class":%d,"shape":true,"len":3,"root":`+
+		`{"key":1,"value":"a","right":{"key":2,"value":"b","right":{"key":3,"value":"c"}}}}`, bst.AVL))
+	if err := json.Unmarshal(bad, bst.NewOf[int, string](bst.AVL)); err == nil {
+		t.Fatal("unbalanced AVL shape should be rejected")
+	}
+	if err := json.Unmarshal(bytes.Replace(bad, []byte(`"right":{"key":3`), []byte(`"left":{"key":3`), 1), bst.NewOf[int, string](bst.AVL)); err == nil {
+		t.Fatal("unordered shape should be rejected")
+	}
+	sp := bst.NewOf[int, string](bst.Splay)
+	if err := json.Unmarshal(bytes.Replace(bad, []byte(`"class":1`), []byte(fmt.Sprintf(`"class":%d`, bst.Splay)), 1), sp); err != nil || sp.Len() != 3 {
+		t.Fatalf("splay accepts any shape, got %v", err)
+	}
+}
+
+// svgCount 检查 SVG 是否为合法的 XML，并统计各元素的个数
+func svgCount(t *testing.T, data []byte) map[string]int {
+	count := map[string]int{}
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return count
+		}
+		if err != nil {
+			t.Fatalf("invalid svg: %v\n%s", err, data)
+		}
+		if se, ok := tok.(xml.StartElement); ok {
+			count[se.Name.Local]++
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	for _, c := range classes {
+		tr := bst.NewOf[int, string](c, 3)
+		for i := 0; i < 40; i++ {
+			k := (i * 37) % 101
+			tr.Insert(k, fmt.Sprintf("<%d>", k))
+		}
+		opts := &bst.RenderOptions{Name: "t", Data: true, Heights: true}
+		var dot bytes.Buffer
+		if err := bst.FprintDOT(&dot, tr.Root(), opts); err != nil {
+			t.Fatal(err)
+		}
+		out := dot.String()
+		if !strings.HasPrefix(out, `digraph "t" {`) || !strings.HasSuffix(out, "}\n") {
+			t.Fatalf("class %d: bad dot output:\n%s", c, out)
+		}
+		var svg bytes.Buffer
+		if err := bst.FprintSVG(&svg, tr.Root(), opts); err != nil {
+			t.Fatal(err)
+		}
+		count := svgCount(t, svg.Bytes())
+		switch c {
+		case bst.BTree:
+			if !strings.Contains(out, "shape=record") || !strings.Contains(out, `\ \<`) {
+				t.Fatalf("btree dot should draw escaped records:\n%s", out)
+			}
+			if count["rect"] != tr.Len() || count["circle"] != 0 {
+				t.Fatalf("btree svg got %v, want %d cells", count, tr.Len())
+			}
+		default:
+			if got := strings.Count(out, "shape=circle"); got != tr.Len() {
+				t.Fatalf("class %d: %d nodes in dot, want %d", c, got, tr.Len())
+			}
+			if count["circle"] != tr.Len() || count["line"] != tr.Len()-1 {
+				t.Fatalf("class %d: svg got %v, want %d nodes", c, count, tr.Len())
+			}
+		}
+		switch c {
+		case bst.AVL:
+			if !strings.Contains(out, `b=0`) {
+				t.Fatalf("avl dot should show balance factors:\n%s", out)
+			}
+		case bst.RBTree:
+			if !strings.Contains(out, "fillcolor=red") || !strings.Contains(out, "fillcolor=black") {
+				t.Fatalf("rb dot should show colors:\n%s", out)
+			}
+		}
+	}
+
+	var empty bytes.Buffer
+	bst.FprintDOT[int, int](&empty, nil, nil)
+	if empty.String() != "digraph \"bst\" {\n\tnode [fontname=\"monospace\"];\n\tedge [arrowhead=none];\n}\n" {
+		t.Fatalf("empty tree got %q", empty.String())
+	}
+	empty.Reset()
+	bst.FprintSVG[int, int](&empty, nil, nil)
+	svgCount(t, empty.Bytes())
+}
+
+// builtinClasses 返回本模块注册的类别，不含测试中注册的类别
+func builtinClasses() []bst.Class {
+	var cs []bst.Class
+	for _, c := range bst.Registered() {
+		if !strings.HasPrefix(c.String(), "test-") {
+			cs = append(cs, c)
+		}
+	}
+	return cs
+}
+
+// intervalKey 将 int 关键码 k 映射为区间 [k/4, k/4+k%4]，按 (Lo, Hi) 保持顺序
+func intervalKey(k int) interval.Interval[int] {
+	return interval.Interval[int]{Lo: k / 4, Hi: k/4 + k%4}
+}
+
+func TestDecodeCorrupt(t *testing.T) {
+	r := rand.New(rand.NewSource(12))
+	for _, c := range builtinClasses() {
+		if c == bst.IntervalTree {
+			decodeCorrupt(t, r, c, intervalKey)
+		} else {
+			decodeCorrupt(t, r, c, func(k int) int { return k })
+		}
+	}
+}
+
+// decodeCorrupt 检查 NewOf 构造的类别 c 的树解码损坏的数据时只返回错误，不 panic
+func decodeCorrupt[K any](t *testing.T, r *rand.Rand, c bst.Class, key func(k int) K) {
+	for _, shape := range []bool{false, true} {
+		enc := bst.Encoding[K, string]{Shape: shape}
+		tr := bst.NewOf[K, string](c, enc)
+		for i := 0; i < 40; i++ {
+			tr.Insert(key(r.Intn(100)), strings.Repeat("v", r.Intn(5)))
+		}
+		bin, err := tr.(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			t.Fatalf("%v: MarshalBinary: %v", c, err)
+		}
+		decode := func(data []byte) (err error) {
+			defer func() {
+				if v := recover(); v != nil {
+					t.Fatalf("%v shape %v: decoding %x panics: %v", c, shape, data, v)
+				}
+			}()
+			return bst.NewOf[K, string](c, enc).(encoding.BinaryUnmarshaler).UnmarshalBinary(data)
+		}
+		for i := range bin {
+			if err := decode(bin[:i]); err == nil {
+				t.Fatalf("%v shape %v: prefix of %d bytes decoded", c, shape, i)
+			}
+		}
+		for i := 0; i < 200; i++ {
+			flipped := append([]byte(nil), bin...)
+			flipped[r.Intn(len(flipped))] ^= 1 << r.Intn(8)
+			decode(flipped)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	r := rand.New(rand.NewSource(13))
+	for _, c := range classes {
+		tr := bst.NewOf[int, int](c, 3)
+		for i := 0; i < 2000; i++ {
+			k := r.Intn(300)
+			if r.Intn(3) == 0 {
+				tr.Remove(k)
+			} else {
+				tr.Insert(k, k)
+			}
+			if i%50 == 0 {
+				if err := bst.Validate(tr); err != nil {
+					t.Fatalf("class %d: %v", c, err)
+				}
+			}
+		}
+		if err := bst.Validate[int, int](bst.Synchronized(tr)); err != nil {
+			t.Fatalf("class %d: %v", c, err)
+		}
+
+		var ie *bst.InvariantError
+		root := tr.Root()
+		key := root.Key()
+		root.SetKey(1000)
+		if err := bst.Validate(tr); !errors.As(err, &ie) || ie.Rule != bst.RuleOrder || ie.Class != c {
+			t.Fatalf("class %d: corrupted order got %v", c, err)
+		}
+		root.SetKey(key)
+		if c == bst.BTree {
+			continue
+		}
+		child := root.LChild()
+		child.SetParent(nil)
+		if err := bst.Validate(tr); !errors.As(err, &ie) || ie.Rule != bst.RuleParent || ie.Key != child.Key() {
+			t.Fatalf("class %d: corrupted parent got %v", c, err)
+		}
+	}
+
+	it := interval.New[int, string]()
+	for i := 0; i < 200; i++ {
+		lo := r.Intn(1000)
+		it.InsertInterval(lo, lo+r.Intn(50), "")
+		if r.Intn(4) == 0 {
+			it.RemoveInterval(lo, lo)
+		}
+	}
+	if err := bst.Validate[interval.Interval[int], string](it); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDifferential(t *testing.T) {
+	r := rand.New(rand.NewSource(17))
+	for _, c := range builtinClasses() {
+		if c == bst.IntervalTree {
+			// 区间树以 NewOf 构造的类型化的树参与比较
+			differential(t, r, bsttest.Target[interval.Interval[int]]{
+				Name: c.String(),
+				New:  func() bst.BST[interval.Interval[int], int] { return bst.NewOf[interval.Interval[int], int](c) },
+				Key:  intervalKey,
+			})
+		} else {
+			differential(t, r, bsttest.ClassTarget(c))
+		}
+	}
+	differential(t, r, bsttest.ClassTarget(bst.BTree, 3))
+	differential(t, r, bsttest.ClassTarget(bst.BTree, 6))
+}
+
+// differential 以固定的模式及随机的操作序列与参照比较 tg
+func differential[K comparable](t *testing.T, r *rand.Rand, tg bsttest.Target[K]) {
+	for name, ops := range bsttest.Patterns(150) {
+		tg := tg
+		tg.Name += " " + name
+		bsttest.Check(t, tg, ops)
+	}
+	for round := 0; round < 10; round++ {
+		bsttest.Check(t, tg, bsttest.Random(r, 400, 60+round*20))
+	}
+}
+
+func TestShrink(t *testing.T) {
+	ops := bsttest.Random(rand.New(rand.NewSource(3)), 200, 50)
+	// 同时包含插入 7 与删除 9 时视为失败
+	fails := func(ops []bsttest.Op) bool {
+		var ins, rem bool
+		for _, op := range ops {
+			ins = ins || op == bsttest.Op{Kind: bsttest.Insert, Key: 7}
+			rem = rem || op == bsttest.Op{Kind: bsttest.Remove, Key: 9}
+		}
+		return ins && rem
+	}
+	if !fails(ops) {
+		t.Skip("seed does not produce a failing sequence")
+	}
+	min := bsttest.Shrink(ops, fails)
+	if len(min) != 2 || !fails(min) {
+		t.Fatalf("shrunk to %s", bsttest.Format(min))
+	}
+	if got := bsttest.Decode(bsttest.Encode(min)); fmt.Sprint(got) != fmt.Sprint(min) {
+		t.Fatalf("decode got %v, want %v", got, min)
+	}
+}
+
+func BenchmarkWorkload(b *testing.B) {
+	for _, w := range workload.Standard(10000) {
+		for _, c := range classes {
+			b.Run(fmt.Sprintf("%s/class%d", w.Name, c), func(b *testing.B) {
+				preload, ops := w.Generate(rand.New(rand.NewSource(1)), 1<<14)
+				stats := new(bst.Stats)
+				tr := bst.NewOf[int, int](c, stats)
+				for _, k := range preload {
+					tr.Insert(k, k)
+				}
+				stats.Reset()
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i += len(ops) {
+					w.Apply(tr, ops[:min(len(ops), b.N-i)])
+				}
+				b.StopTimer()
+				b.ReportMetric(float64(stats.Comparisons)/float64(b.N), "cmp/op")
+				b.ReportMetric(float64(stats.Rotations+stats.Splits+stats.Merges)/float64(b.N), "rotations/op")
+				b.ReportMetric(float64(workload.Height(tr.Root())), "height")
+			})
+		}
+	}
+}
+
+func TestStats(t *testing.T) {
+	for _, c := range classes {
+		stats := new(bst.Stats)
+		tr := bst.NewOf[int, int](c, stats, 3)
+		for i := 0; i < 100; i++ {
+			tr.Insert(i, i)
+		}
+		tr.Search(0)
+		// 顺序插入必然引起旋转或分裂，伸展树在查找最深的节点时旋转
+		if stats.Comparisons == 0 || stats.Rotations+stats.Splits == 0 {
+			t.Fatalf("class %d: stats %+v", c, *stats)
+		}
+		r := workload.Run("t", c, workload.Standard(500)[1], 1000, 1)
+		if r.Ops != 1000 || r.Stats.Comparisons == 0 || r.Height < 0 || r.Len == 0 {
+			t.Fatalf("class %d: result %+v", c, r)
+		}
+	}
+	if h := workload.Height(bst.NewOf[int, int](bst.AVL).Root()); h != -1 {
+		t.Fatalf("height of an empty tree got %d", h)
+	}
+}
+
+// TestStatsConcurrent 在 -race 下检查并行读取对计数器的更新
+func TestStatsConcurrent(t *testing.T) {
+	for _, c := range []bst.Class{bst.AVL, bst.RBTree, bst.Splay, bst.BTree} {
+		var stats bst.Stats
+		tr, err := bst.Make[int, int](c, bst.WithStats(&stats))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 1000; i++ {
+			tr.Insert(i*7%1000, i)
+		}
+		st := bst.Synchronized(tr)
+		search := func() {
+			for i := 0; i < 1000; i++ {
+				st.Search(i)
+			}
+		}
+		before := stats.Load().Comparisons
+		search()
+		alone := stats.Load().Comparisons - before
+		const workers = 8
+		var wg sync.WaitGroup
+		before = stats.Load().Comparisons
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				search()
+			}()
+		}
+		wg.Wait()
+		// 伸展树的比较次数随访问顺序而变，其余的类不应丢失计数
+		if got := stats.Load().Comparisons - before; got == 0 || c != bst.Splay && got != workers*alone {
+			t.Errorf("%v: %d comparisons by %d readers, want %d", c, got, workers, workers*alone)
+		}
+		stats.Reset()
+		if stats.Load() != (bst.Stats{}) {
+			t.Errorf("%v: Reset left %+v", c, stats.Load())
+		}
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	sum := bst.Augment(0, func(left int, n bst.Node[int, int], right int) int {
+		return left + n.Data() + right
+	})
+	for _, c := range classes {
+		parms := []interface{}{}
+		if c != bst.BTree {
+			parms = append(parms, sum)
+		}
+		tr := bst.NewOf[int, int](c, parms...)
+		if _, err := tr.Remove(1); !errors.Is(err, bst.ErrNotFound) {
+			t.Fatalf("class %d: removing from an empty tree got %v", c, err)
+		}
+		for i := 0; i < 100; i++ {
+			if _, replaced, err := bst.Put(tr, i, i); replaced || err != nil {
+				t.Fatalf("class %d: Put new %d got %v %v", c, i, replaced, err)
+			}
+		}
+		if _, err := tr.Insert(5, 0); !errors.Is(err, bst.ErrDuplicateKey) {
+			t.Fatalf("class %d: duplicate Insert got %v", c, err)
+		}
+		if _, err := tr.Remove(100); !errors.Is(err, bst.ErrNotFound) {
+			t.Fatalf("class %d: Remove of an absent key got %v", c, err)
+		}
+		if old, replaced, err := bst.Put(tr, 7, 70); old != 7 || !replaced || err != nil {
+			t.Fatalf("class %d: Put got %v %v %v", c, old, replaced, err)
+		}
+		inc := func(old int, ok bool) (int, bool) { return old + 1, true }
+		if err := bst.Update(tr, 8, inc); err != nil {
+			t.Fatal(err)
+		}
+		if err := bst.Update(tr, 200, inc); err != nil {
+			t.Fatal(err)
+		}
+		drop := func(old int, ok bool) (int, bool) { return 0, false }
+		if err := bst.Update(tr, 9, drop); err != nil {
+			t.Fatal(err)
+		}
+		if err := bst.Update(tr, 300, drop); err != nil {
+			t.Fatal(err)
+		}
+		n, found, err := bst.GetOrInsert(tr, 10, -1)
+		if !found || err != nil || n.Data() != 10 {
+			t.Fatalf("class %d: GetOrInsert of a present key got %v %v %v", c, n, found, err)
+		}
+		n, found, err = bst.GetOrInsert(tr, 150, 150)
+		if found || err != nil || n.Key() != 150 {
+			t.Fatalf("class %d: GetOrInsert of an absent key got %v %v %v", c, n, found, err)
+		}
+		want := map[int]int{7: 70, 8: 9, 200: 1, 150: 150}
+		if tr.Len() != 101 {
+			t.Errorf("class %d: Len got %d, want 101", c, tr.Len())
+		}
+		if _, ok := tr.Search(9); ok {
+			t.Errorf("class %d: 9 should be removed by Update", c)
+		}
+		for k, v := range want {
+			if n, ok := tr.Search(k); !ok || n.Data() != v {
+				t.Errorf("class %d: %d got %v, want %d", c, k, n, v)
+			}
+		}
+		if c != bst.BTree {
+			total := 99*100/2 + 63 + 1 + 1 + 150 - 9
+			if got := bst.Summary[int](tr, 0, 1000); got != total {
+				t.Errorf("class %d: sum got %d, want %d", c, got, total)
+			}
+		}
+		if err := bst.Validate(tr); err != nil {
+			t.Error(err)
+		}
+
+		st := bst.Synchronized(bst.NewOf[int, int](c))
+		if _, _, err := bst.Put[int, int](st, 1, 1); err != nil {
+			t.Fatal(err)
+		}
+		if old, replaced, _ := bst.Put[int, int](st, 1, 2); old != 1 || !replaced {
+			t.Errorf("class %d: synchronized Put got %v %v", c, old, replaced)
+		}
+		if _, err := st.Remove(2); !errors.Is(err, bst.ErrNotFound) {
+			t.Errorf("class %d: synchronized Remove got %v", c, err)
+		}
+
+		loose := bst.New(c)
+		loose.Insert(1, nil)
+		if _, err := loose.Insert("a", nil); !errors.Is(err, bst.ErrIncomparable) {
+			t.Errorf("class %d: inserting a string among ints got %v", c, err)
+		}
+		if _, _, err := bst.Put(loose, 2.5, nil); !errors.Is(err, bst.ErrIncomparable) {
+			t.Errorf("class %d: putting a float among ints got %v", c, err)
+		}
+		if loose.Len() != 1 {
+			t.Errorf("class %d: Len got %d after failed writes", c, loose.Len())
+		}
+	}
+	p := avl.NewPersistent[int, int]()
+	p, _ = p.Insert(1, 1)
+	if q, err := p.Remove(2); q != p || !errors.Is(err, bst.ErrNotFound) {
+		t.Errorf("persistent Remove of an absent key got %v", err)
+	}
+	if q, err := p.Insert(1, 1); q != p || !errors.Is(err, bst.ErrDuplicateKey) {
+		t.Errorf("persistent duplicate Insert got %v", err)
+	}
+
+	it := interval.New[int, string]()
+	it.Put(interval.Interval[int]{Lo: 1, Hi: 2}, "a")
+	it.Put(interval.Interval[int]{Lo: 0, Hi: 9}, "b")
+	if old, replaced, _ := it.Put(interval.Interval[int]{Lo: 1, Hi: 2}, "c"); old != "a" || !replaced {
+		t.Errorf("interval Put got %v %v", old, replaced)
+	}
+	if err := it.Update(interval.Interval[int]{Lo: 0, Hi: 9}, func(string, bool) (string, bool) { return "", false }); err != nil {
+		t.Fatal(err)
+	}
+	if got := it.Stab(5); len(got) != 0 {
+		t.Errorf("interval Stab after Update got %v", got)
+	}
+	// 左端点大于右端点的区间无论经由哪个方法都被拒绝
+	inverted := interval.Interval[int]{Lo: 8, Hi: 3}
+	if _, err := it.Insert(inverted, "x"); !errors.Is(err, interval.ErrInverted) {
+		t.Errorf("interval Insert of an inverted key got %v", err)
+	}
+	if _, err := it.InsertInterval(8, 3, "x"); !errors.Is(err, interval.ErrInverted) {
+		t.Errorf("interval InsertInterval of an inverted key got %v", err)
+	}
+	if _, _, err := it.Put(inverted, "x"); !errors.Is(err, interval.ErrInverted) {
+		t.Errorf("interval Put of an inverted key got %v", err)
+	}
+	if _, _, err := it.GetOrInsert(inverted, "x"); !errors.Is(err, interval.ErrInverted) {
+		t.Errorf("interval GetOrInsert of an inverted key got %v", err)
+	}
+	if err := it.Update(inverted, func(string, bool) (string, bool) { return "x", true }); !errors.Is(err, interval.ErrInverted) {
+		t.Errorf("interval Update of an inverted key got %v", err)
+	}
+	at := bst.New(bst.IntervalTree)
+	if _, err := at.Insert(interval.Interval[any]{Lo: 8, Hi: 3}, "x"); !errors.Is(err, interval.ErrInverted) {
+		t.Errorf("registered interval Insert of an inverted key got %v", err)
+	}
+	if _, _, err := bst.Put[any, any](at, interval.Interval[any]{Lo: 8, Hi: 3}, "x"); !errors.Is(err, interval.ErrInverted) {
+		t.Errorf("registered interval Put of an inverted key got %v", err)
+	}
+	if it.Len() != 1 || at.Len() != 0 {
+		t.Errorf("inverted intervals were inserted, Len %d and %d", it.Len(), at.Len())
+	}
+	if got := it.Stab(8); len(got) != 0 {
+		t.Errorf("interval Stab 8 got %v", got)
+	}
+	if err := it.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	// Remove 返回脱离树的被删除条目，之后的修改不会改变它
+	for _, c := range builtinClasses() {
+		if c == bst.IntervalTree {
+			continue
+		}
+		r := rand.New(rand.NewSource(26))
+		tr := bst.NewOf[int, int](c)
+		for _, k := range r.Perm(300) {
+			tr.Insert(k, 10*k)
+		}
+		var removed []bst.Node[int, int]
+		for i, k := range r.Perm(300) {
+			n, err := tr.Remove(k)
+			if err != nil || n.Key() != k || n.Data() != 10*k {
+				t.Fatalf("%v: Remove %d got %v %v", c, k, n, err)
+			}
+			removed = append(removed, n)
+			if i%50 == 0 {
+				if err := bst.Validate(tr); err != nil {
+					t.Fatalf("%v: %v", c, err)
+				}
+			}
+		}
+		for _, n := range removed {
+			if n.Data() != 10*n.Key() {
+				t.Errorf("%v: removed entry %d changed to %d", c, n.Key(), n.Data())
+			}
+		}
+		if tr.Len() != 0 {
+			t.Errorf("%v: %d keys left", c, tr.Len())
+		}
+	}
+}
+
+// multiRef 以按关键码稳定排序的切片作为多重映射的参照
+type multiRef struct {
+	keys, data []int
+}
+
+func (r *multiRef) bounds(k int) (int, int) {
+	return sort.SearchInts(r.keys, k), sort.SearchInts(r.keys, k+1)
+}
+
+func (r *multiRef) insert(i, k, v int) {
+	r.keys = append(r.keys[:i], append([]int{k}, r.keys[i:]...)...)
+	r.data = append(r.data[:i], append([]int{v}, r.data[i:]...)...)
+}
+
+func (r *multiRef) remove(i, j int) {
+	r.keys = append(r.keys[:i], r.keys[j:]...)
+	r.data = append(r.data[:i], r.data[j:]...)
+}
+
+func TestMultimap(t *testing.T) {
+	targets := []struct {
+		c     bst.Class
+		parms []interface{}
+	}{
+		{bst.AVL, nil}, {bst.RBTree, nil}, {bst.Splay, nil}, {bst.Treap, nil}, {bst.SkipList, nil},
+		{bst.LLRB, nil}, {bst.AATree, nil},
+		{bst.BTree, []interface{}{3}}, {bst.BTree, []interface{}{4}}, {bst.BTree, []interface{}{5}},
+		{bst.BPlusTree, []interface{}{3}}, {bst.BPlusTree, []interface{}{4}}, {bst.BPlusTree, []interface{}{5}},
+	}
+	for _, tg := range targets {
+		r := rand.New(rand.NewSource(1))
+		tr := bst.NewOf[int, int](tg.c, append(tg.parms, bst.MultiKeys)...)
+		ref := new(multiRef)
+		for step := 0; step < 3000; step++ {
+			k := r.Intn(20)
+			i, j := ref.bounds(k)
+			switch op := r.Intn(10); {
+			case op < 5:
+				if _, err := tr.Insert(k, step); err != nil {
+					t.Fatalf("class %d step %d: Insert %d: %v", tg.c, step, k, err)
+				}
+				ref.insert(j, k, step)
+			case op < 7:
+				// 两者都删除并返回最早的条目
+				remove := tr.Remove
+				if op == 6 {
+					remove = tr.(bst.Multimap[int, int]).RemoveOne
+				}
+				n, err := remove(k)
+				if (i == j) != errors.Is(err, bst.ErrNotFound) {
+					t.Fatalf("class %d step %d: Remove %d got %v with %d entries", tg.c, step, k, err, j-i)
+				}
+				if i < j {
+					if n.Key() != k || n.Data() != ref.data[i] {
+						t.Fatalf("class %d step %d: Remove %d returned %d %d, want %d", tg.c, step, k, n.Key(), n.Data(), ref.data[i])
+					}
+					ref.remove(i, i+1)
+				}
+			case op < 8:
+				cnt, err := bst.RemoveAll(tr, k)
+				if cnt != j-i || (i == j) != errors.Is(err, bst.ErrNotFound) {
+					t.Fatalf("class %d step %d: RemoveAll %d got %d %v, want %d", tg.c, step, k, cnt, err, j-i)
+				}
+				ref.remove(i, j)
+			case op < 9:
+				old, replaced, _ := bst.Put(tr, k, step)
+				if replaced != (i < j) || replaced && old != ref.data[i] {
+					t.Fatalf("class %d step %d: Put %d got %d %v", tg.c, step, k, old, replaced)
+				}
+				if i < j {
+					ref.data[i] = step
+				} else {
+					ref.insert(i, k, step)
+				}
+			default:
+				n, ok := tr.Search(k)
+				if ok != (i < j) || ok && n.Data() != ref.data[i] {
+					t.Fatalf("class %d step %d: Search %d got %v %v", tg.c, step, k, n, ok)
+				}
+			}
+			if err := bst.Validate(tr); err != nil {
+				t.Fatalf("class %d step %d: %v", tg.c, step, err)
+			}
+			i, j = ref.bounds(k)
+			if got := bst.Count(tr, k); got != j-i {
+				t.Fatalf("class %d step %d: Count %d got %d, want %d", tg.c, step, k, got, j-i)
+			}
+			var all []int
+			bst.All(tr, k, func(n bst.Node[int, int]) bool {
+				all = append(all, n.Data())
+				return true
+			})
+			if fmt.Sprint(all) != fmt.Sprint(append([]int{}, ref.data[i:j]...)) {
+				t.Fatalf("class %d step %d: All %d got %v, want %v", tg.c, step, k, all, ref.data[i:j])
+			}
+			if i < j {
+				if n, _ := tr.Ceiling(k); n.Data() != ref.data[i] {
+					t.Fatalf("class %d step %d: Ceiling %d got %v", tg.c, step, k, n.Data())
+				}
+				if n, _ := tr.Floor(k); n.Data() != ref.data[j-1] {
+					t.Fatalf("class %d step %d: Floor %d got %v", tg.c, step, k, n.Data())
+				}
+			}
+			var keys, data []int
+			it := tr.Iterator()
+			for ok := it.First(); ok; ok = it.Next() {
+				keys, data = append(keys, it.Key()), append(data, it.Data())
+			}
+			if fmt.Sprint(keys, data) != fmt.Sprint(ref.keys, ref.data) || tr.Len() != len(ref.keys) {
+				t.Fatalf("class %d step %d: contents differ", tg.c, step)
+			}
+		}
+
+		for _, shape := range []bool{false, true} {
+			enc := bst.Encoding[int, int]{Shape: shape}
+			src := bst.NewOf[int, int](tg.c, append(tg.parms, bst.MultiKeys, enc)...)
+			for i, k := range ref.keys {
+				src.Insert(k, ref.data[i])
+			}
+			b, err := src.(encoding.BinaryMarshaler).MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			dup := bst.NewOf[int, int](tg.c, append(tg.parms, bst.MultiKeys, enc)...)
+			if err := dup.(encoding.BinaryUnmarshaler).UnmarshalBinary(b); err != nil {
+				t.Fatalf("class %d: decoding a multimap: %v", tg.c, err)
+			}
+			var data []int
+			it := dup.Iterator()
+			for ok := it.First(); ok; ok = it.Next() {
+				data = append(data, it.Data())
+			}
+			if fmt.Sprint(data) != fmt.Sprint(ref.data) {
+				t.Errorf("class %d: decoded multimap got %v, want %v", tg.c, data, ref.data)
+			}
+		}
+		if _, err := bst.BuildSorted[int, int](tg.c, []int{1, 1, 2}, nil, bst.VerifySorted, bst.MultiKeys); err != nil {
+			t.Errorf("class %d: building a multimap: %v", tg.c, err)
+		}
+	}
+
+	// 上表须包括所有支持 WithMultimap 的类
+	tested := make(map[bst.Class]bool)
+	for _, tg := range targets {
+		tested[tg.c] = true
+	}
+	for _, c := range builtinClasses() {
+		if _, err := bst.Make[int, int](c, bst.WithMultimap()); err == nil && !tested[c] {
+			t.Errorf("%v supports WithMultimap but is not tested", c)
+		}
+	}
+}
+
+func TestRemoveEach(t *testing.T) {
+	left := 3
+	removeOne := func(key int) (bst.Node[int, int], error) {
+		if left == 0 {
+			return nil, fmt.Errorf("removing %d: %w", key, bst.ErrNotFound)
+		}
+		left--
+		return nil, nil
+	}
+	if cnt, err := bst.RemoveEach(1, removeOne); cnt != 3 || err != nil {
+		t.Errorf("RemoveEach with a wrapped ErrNotFound got %d %v", cnt, err)
+	}
+	if cnt, err := bst.RemoveEach(1, removeOne); cnt != 0 || !errors.Is(err, bst.ErrNotFound) {
+		t.Errorf("RemoveEach of an absent key got %d %v", cnt, err)
+	}
+}
+
+// order 递归地按先序或后序收集二叉树中的关键码
+func order(n bst.Node[int, int], pre bool, keys []int) []int {
+	if bst.IsNil(n) {
+		return keys
+	}
+	if pre {
+		keys = append(keys, n.Key())
+	}
+	keys = order(n.LChild(), pre, keys)
+	keys = order(n.RChild(), pre, keys)
+	if !pre {
+		keys = append(keys, n.Key())
+	}
+	return keys
+}
+
+func TestTraverse(t *testing.T) {
+	orders := []bst.Order{bst.PreOrder, bst.InOrder, bst.PostOrder, bst.LevelOrder, bst.ReverseInOrder}
+	r := rand.New(rand.NewSource(18))
+	for _, c := range classes {
+		tr := bst.NewOf[int, int](c)
+		for _, k := range r.Perm(200) {
+			tr.Insert(k, k)
+		}
+		walked := make(map[bst.Order][]int)
+		for _, o := range orders {
+			var keys, visited []int
+			tr.Walk(o, func(n bst.Node[int, int]) { keys = append(keys, n.Key()) })
+			if !bst.Visit(tr, o, func(n bst.Node[int, int]) bool {
+				visited = append(visited, n.Key())
+				return true
+			}) {
+				t.Fatalf("class %d order %d: the walk should go to the end", c, o)
+			}
+			if fmt.Sprint(keys) != fmt.Sprint(visited) || len(keys) != 200 {
+				t.Fatalf("class %d order %d: Walk and Visit differ", c, o)
+			}
+			walked[o] = keys
+		}
+		for i := 0; i < 200; i++ {
+			if walked[bst.InOrder][i] != i || walked[bst.ReverseInOrder][i] != 199-i {
+				t.Fatalf("class %d: in-order %v, reverse %v", c, walked[bst.InOrder], walked[bst.ReverseInOrder])
+			}
+		}
+		if c != bst.BTree {
+			if fmt.Sprint(walked[bst.PreOrder]) != fmt.Sprint(order(tr.Root(), true, nil)) {
+				t.Errorf("class %d: wrong pre-order", c)
+			}
+			if fmt.Sprint(walked[bst.PostOrder]) != fmt.Sprint(order(tr.Root(), false, nil)) {
+				t.Errorf("class %d: wrong post-order", c)
+			}
+		}
+
+		for _, o := range orders {
+			cnt := 0
+			if bst.Visit(tr, o, func(n bst.Node[int, int]) bool {
+				cnt++
+				return cnt < 10
+			}) || cnt != 10 {
+				t.Errorf("class %d order %d: the walk should stop after 10 nodes, got %d", c, o, cnt)
+			}
+		}
+		stop := errors.New("found")
+		var found int
+		err := bst.VisitErr(bst.Synchronized(tr), bst.ReverseInOrder, func(n bst.Node[int, int]) error {
+			if n.Key()%7 == 0 {
+				found = n.Key()
+				return stop
+			}
+			return nil
+		})
+		if err != stop || found != 196 {
+			t.Errorf("class %d: VisitErr got %v at %d", c, err, found)
+		}
+		var first any
+		bst.Visit(bst.New(c), bst.InOrder, func(n bst.Node[any, any]) bool {
+			first = n.Key()
+			return false
+		})
+		if first != nil {
+			t.Errorf("class %d: an empty tree visited %v", c, first)
+		}
+	}
+
+	// 顺序插入后的伸展树退化为单链，遍历不应递归
+	tr := bst.NewOf[int, int](bst.Splay)
+	n := 200000
+	if bst.Debug {
+		n = 2000 // 每次插入后的校验需 O(n)
+	}
+	for i := 0; i < n; i++ {
+		tr.Insert(i, i)
+	}
+	if h := workload.Height(tr.Root()); h != n-1 {
+		t.Fatalf("splay tree should be a chain, height %d", h)
+	}
+	for _, o := range orders {
+		cnt := 0
+		if bst.Visit(tr, o, func(bst.Node[int, int]) bool {
+			cnt++
+			return cnt < 3
+		}) || cnt != 3 {
+			t.Errorf("order %d: the walk over a chain should stop early", o)
+		}
+	}
+}
+
+func TestMake(t *testing.T) {
+	for _, c := range append(classes, bst.IntervalTree) {
+		if got, ok := bst.Lookup(c.String()); !ok || got != c {
+			t.Errorf("class %d: Lookup(%q) got %d", c, c.String(), got)
+		}
+	}
+	if c, ok := bst.Lookup("avl"); !ok || c != bst.AVL {
+		t.Errorf("Lookup(avl) got %d", c)
+	}
+	if _, ok := bst.Lookup("avl "); ok {
+		t.Error("a misspelled name should not be found")
+	}
+
+	stats := new(bst.Stats)
+	tr, err := bst.Make[int, string](bst.BTree, bst.WithOrder(3), bst.WithMultimap(), bst.WithStats(stats),
+		bst.WithComparator(func(a, b int) int { return b - a }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		tr.Insert(i%10, strconv.Itoa(i))
+	}
+	var keys []int
+	tr.Walk(bst.InOrder, func(n bst.Node[int, string]) { keys = append(keys, n.Key()) })
+	if len(keys) != 20 || keys[0] != 9 || keys[19] != 0 || bst.Count(tr, 3) != 2 {
+		t.Errorf("reversed multimap got %v", keys)
+	}
+	if stats.Comparisons == 0 {
+		t.Error("WithStats should count the comparisons")
+	}
+	if err := bst.Validate(tr); err != nil {
+		t.Error(err)
+	}
+	if k, _ := tr.Root().(bst.Multiway[int, string]).Entries(); len(k) > 2 {
+		t.Errorf("a B-tree of order 3 has %d keys in a node", len(k))
+	}
+
+	for _, tc := range []struct {
+		c    bst.Class
+		opts []bst.TreeOption
+	}{
+		{bst.AVL, []bst.TreeOption{bst.WithOrder(4)}},
+		{bst.BTree, []bst.TreeOption{bst.WithOrder(2)}},
+		{bst.IntervalTree, []bst.TreeOption{bst.WithMultimap()}},
+		{bst.RBTree, []bst.TreeOption{bst.WithComparator(strings.Compare)}},
+		{bst.Splay, []bst.TreeOption{bst.WithStats(nil)}},
+		{bst.Splay, []bst.TreeOption{bst.WithComparator[int](nil)}},
+		{bst.Class(200), nil},
+	} {
+		if _, err := bst.Make[int, int](tc.c, tc.opts...); !errors.Is(err, bst.ErrInvalidOption) {
+			t.Errorf("class %d: Make should fail, got %v", tc.c, err)
+		}
+	}
+
+	c, ok := bst.Lookup("test-avl") // 以 -count 重复运行时已注册
+	if !ok {
+		c = bst.Register("test-avl", bst.Factory{New: avl.New[any, any], Multimap: true})
+	}
+	if got, ok := bst.Lookup("test-avl"); !ok || got != c || !c.Available() || c.String() != "test-avl" {
+		t.Fatalf("registered class %d, looked up %d", c, got)
+	}
+	mt, err := bst.Make[int, int](c, bst.WithMultimap())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mt.Insert(1, 1)
+	mt.Insert(1, 2)
+	if mt.Len() != 2 {
+		t.Errorf("registered multimap has %d keys", mt.Len())
+	}
+	if _, err := bst.Make[int, int](c, bst.WithOrder(5)); !errors.Is(err, bst.ErrInvalidOption) {
+		t.Errorf("registered class should reject WithOrder, got %v", err)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a name twice should panic")
+		}
+	}()
+	bst.Register("test-avl", bst.Factory{New: avl.New[any, any]})
+}
+
+func TestTypedConstructor(t *testing.T) {
+	// 注册了 K、V 的构造函数时 NewOf 不装箱关键码，多路树的 Search 本身要为返回的条目分配
+	for _, c := range builtinClasses() {
+		if c == bst.IntervalTree || c == bst.BTree || c == bst.BPlusTree {
+			continue
+		}
+		tr := bst.NewOf[int, int](c)
+		for i := 0; i < 100; i++ {
+			tr.Insert(i, i)
+		}
+		if allocs := testing.AllocsPerRun(100, func() { tr.Search(50) }); allocs != 0 {
+			t.Errorf("%v: Search allocates %v times", c, allocs)
+		}
+	}
+
+	// 各类及包装的节点自行判断是否为 nil 指针，IsNil 不必使用反射
+	for _, c := range builtinClasses() {
+		if c == bst.IntervalTree {
+			continue // 区间树的节点即红黑树的节点
+		}
+		tr := bst.NewOf[int, int](c)
+		tr.Insert(1, 1)
+		roots := []interface{}{tr.Root(), bst.Erase(tr).Root(), bst.Synchronized(tr).Root()}
+		for _, root := range roots {
+			if nc, ok := root.(bst.NilChecker); !ok || nc.IsNil() {
+				t.Errorf("%v: node %T does not tell it is not nil", c, root)
+			}
+		}
+		if lc := tr.Root().LChild(); lc != nil {
+			if nc, ok := lc.(bst.NilChecker); !ok || !nc.IsNil() || !bst.IsNil(lc) {
+				t.Errorf("%v: nil child %T does not tell it is nil", c, lc)
+			}
+		}
+	}
+
+	type point struct{ x, y int }
+	comp := func(a, b point) int {
+		if a.x != b.x {
+			return a.x - b.x
+		}
+		return a.y - b.y
+	}
+	bst.RegisterOf(bst.AVL, avl.New[point, string])
+	tr := bst.NewOf[point, string](bst.AVL, comp)
+	for i := 0; i < 10; i++ {
+		tr.Insert(point{i % 3, i}, strconv.Itoa(i))
+	}
+	if n, ok := tr.Search(point{1, 4}); !ok || n.Data() != "4" {
+		t.Errorf("registered point tree found %v", n)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { tr.Search(point{2, 5}) }); allocs != 0 {
+		t.Errorf("registered point tree allocates %v times in Search", allocs)
+	}
+	if os, ok := tr.(bst.OrderStatistic[point, string]); !ok || os.Rank(point{1, 0}) != 4 {
+		t.Error("registered point tree should rank point{1, 0} after 4 keys")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering an invalid typed constructor should panic")
+		}
+		if _, ok := bst.Lookup("test-typed"); ok {
+			t.Error("a failed Register should not register the name")
+		}
+	}()
+	bst.Register("test-typed", bst.Factory{New: avl.New[any, any], Typed: []interface{}{avl.New[int, int], 42}})
+}
+
+// plainBST 只实现 bst.BST 的方法
+type plainBST struct {
+	bst.BST[any, any]
+}
+
+func TestUnsupported(t *testing.T) {
+	c, ok := bst.Lookup("test-plain") // 以 -count 重复运行时已注册
+	if !ok {
+		c = bst.Register("test-plain", bst.Factory{New: func(parms ...interface{}) bst.BST[any, any] {
+			return plainBST{avl.New[any, any](parms...)}
+		}})
+	}
+	tr := bst.NewOf[int, int](c)
+	if _, err := tr.(encoding.BinaryMarshaler).MarshalBinary(); err == nil || !strings.Contains(err.Error(), "test-plain does not support") {
+		t.Errorf("MarshalBinary of a class without encoding got %v", err)
+	}
+}
+
+func TestTreap(t *testing.T) {
+	shape := func(seed int64) string {
+		tr := bst.NewOf[int, int](bst.Treap, rand.NewSource(seed))
+		for _, k := range rand.New(rand.NewSource(1)).Perm(100) {
+			tr.Insert(k, k)
+		}
+		var keys []int
+		tr.Walk(bst.PreOrder, func(n bst.Node[int, int]) { keys = append(keys, n.Key()) })
+		return fmt.Sprint(keys)
+	}
+	if shape(20) != shape(20) {
+		t.Error("treaps of the same seed should have the same shape")
+	}
+	if shape(20) == shape(21) {
+		t.Error("treaps of different seeds should differ")
+	}
+
+	// 顺序插入不会使树堆退化
+	const n = 1 << 14
+	for _, sorted := range []bool{false, true} {
+		tr := bst.NewOf[int, int](bst.Treap, rand.NewSource(3))
+		if sorted {
+			keys := make([]int, n)
+			for i := range keys {
+				keys[i] = i
+			}
+			tr, _ = bst.BuildSorted[int, int](bst.Treap, keys, keys, rand.NewSource(3))
+		} else {
+			for i := 0; i < n; i++ {
+				tr.Insert(i, i)
+			}
+		}
+		if h := workload.Height(tr.Root()); h > 4*bits.Len(n) {
+			t.Errorf("sorted %v: treap of %d keys has height %d", sorted, n, h)
+		}
+		if err := bst.Validate(tr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := rand.New(rand.NewSource(20))
+	seq := treap.NewImplicit[int](rand.NewSource(4))
+	var ref []int
+	for step := 0; step < 2000; step++ {
+		switch op := r.Intn(6); {
+		case op < 2 || len(ref) == 0:
+			i := r.Intn(len(ref) + 1)
+			seq.Insert(i, step)
+			ref = append(ref[:i], append([]int{step}, ref[i:]...)...)
+		case op == 2:
+			i := r.Intn(len(ref))
+			if v := seq.Remove(i); v != ref[i] {
+				t.Fatalf("step %d: removed %d at %d, want %d", step, v, i, ref[i])
+			}
+			ref = append(ref[:i], ref[i+1:]...)
+		case op == 3:
+			i := r.Intn(len(ref))
+			seq.Set(i, -step)
+			ref[i] = -step
+		case op == 4:
+			i := r.Intn(len(ref) + 1)
+			rest := seq.Split(i)
+			if rest.Len() != len(ref)-i || seq.Len() != i {
+				t.Fatalf("step %d: split at %d into %d and %d", step, i, seq.Len(), rest.Len())
+			}
+			seq.Concat(rest)
+		default:
+			seq.Append(step, step+1)
+			ref = append(ref, step, step+1)
+		}
+		if err := seq.Validate(); err != nil {
+			t.Fatalf("step %d: %v", step, err)
+		}
+		if fmt.Sprint(seq.Values()) != fmt.Sprint(ref) {
+			t.Fatalf("step %d: got %v, want %v", step, seq.Values(), ref)
+		}
+	}
+	for i := range ref {
+		if seq.At(i) != ref[i] {
+			t.Fatalf("At(%d) got %d, want %d", i, seq.At(i), ref[i])
+		}
+	}
+	i := 0
+	seq.Traverse(bst.InOrder, func(n bst.Node[int, int]) bool {
+		if n.Key() != i {
+			t.Fatalf("node %d has key %d", i, n.Key())
+		}
+		i++
+		return true
+	})
+}
+
+func TestSkipList(t *testing.T) {
+	// BuildSorted 中第 i 个节点的层数为 i 的二进制末尾 0 的个数加 1
+	sl := skiplist.New[int, int]()
+	keys := []int{1, 2, 3, 4, 5, 6, 7}
+	if err := sl.(bst.SortedBuilder[int, int]).BuildSorted(keys, keys, true); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	sl.(interface{ Fprint(io.Writer) error }).Fprint(&out)
+	want := "L2 head ----------------> 4 ----------------> nil\n" +
+		"L1 head ------> 2 ------> 4 ------> 6 ------> nil\n" +
+		"L0 head -> 1 -> 2 -> 3 -> 4 -> 5 -> 6 -> 7 -> nil\n"
+	if out.String() != want {
+		t.Errorf("printed\n%s\nwant\n%s", out.String(), want)
+	}
+	// 跳表没有树形，以层数代替树高
+	if l, ok := bst.Levels(sl); !ok || l != 3 {
+		t.Errorf("levels got %d %v, want 3", l, ok)
+	}
+	if l, ok := bst.Levels(bst.NewOf[float64, int](bst.SkipList)); !ok || l != 0 {
+		t.Errorf("levels of an empty wrapped list got %d %v", l, ok)
+	}
+	if _, ok := bst.Levels(bst.NewOf[int, int](bst.AVL)); ok {
+		t.Error("an AVL tree should have no levels")
+	}
+	if r := workload.Run("skiplist", bst.SkipList, workload.Standard(500)[1], 1000, 1); r.Height < 2 {
+		t.Errorf("skip list of %d keys reports height %d", r.Len, r.Height)
+	}
+	walk := func(tr bst.BST[int, int], o bst.Order) string {
+		var keys []int
+		tr.Walk(o, func(n bst.Node[int, int]) { keys = append(keys, n.Key()) })
+		return fmt.Sprint(keys)
+	}
+	if got := walk(sl, bst.LevelOrder); got != "[4 2 6 1 3 5 7]" {
+		t.Errorf("level order got %s", got)
+	}
+	if got := walk(sl, bst.PostOrder); got != "[1 3 2 5 7 6 4]" {
+		t.Errorf("post-order got %s", got)
+	}
+
+	// 比较器的传递与 bst.New 一致
+	type point struct{ x, y int }
+	pl := bst.NewOf[point, int](bst.SkipList, func(a, b point) int { return b.x - a.x })
+	for i := 0; i < 10; i++ {
+		pl.Insert(point{i, -i}, i)
+	}
+	if n, ok := pl.Min(); !ok || n.Key().x != 9 {
+		t.Errorf("reversed comparator got min %v", n)
+	}
+	if n, ok := bst.New(bst.SkipList).Insert(point{1, 2}, nil); ok != nil || n.Key() != (point{1, 2}) {
+		t.Errorf("struct keys compared by BasicCompare got %v", ok)
+	}
+
+	levels := func(seed int64) string {
+		tr := bst.NewOf[int, int](bst.SkipList, rand.NewSource(seed))
+		for _, k := range rand.New(rand.NewSource(1)).Perm(100) {
+			tr.Insert(k, k)
+		}
+		return walk(tr, bst.LevelOrder)
+	}
+	if levels(21) != levels(21) {
+		t.Error("skip lists of the same seed should have the same levels")
+	}
+	if levels(21) == levels(22) {
+		t.Error("skip lists of different seeds should differ")
+	}
+
+	// 顺序插入后层数仍为对数级别，区间查询与秩与参照一致
+	const n = 1 << 14
+	tr := bst.NewOf[int, int](bst.SkipList, rand.NewSource(5))
+	for i := 0; i < n; i++ {
+		tr.Insert(2*i, i)
+	}
+	top := -1
+	bst.Visit(tr, bst.LevelOrder, func(n bst.Node[int, int]) bool {
+		top = n.Height()
+		return false
+	})
+	if top < bits.Len(n)/2 || top > 2*bits.Len(n) {
+		t.Errorf("%d keys linked on %d levels", n, top+1)
+	}
+	os := tr.(bst.OrderStatistic[int, int])
+	below := func(k int) int { return min(max((k+1)/2, 0), n) } // 小于 k 的关键码数
+	r := rand.New(rand.NewSource(21))
+	for i := 0; i < 200; i++ {
+		lo, hi := r.Intn(2*n+10)-5, r.Intn(2*n+10)-5
+		var got []int
+		tr.Range(lo, hi, true, false, func(n bst.Node[int, int]) bool {
+			got = append(got, n.Key())
+			return len(got) < 50
+		})
+		for j, k := range got {
+			if k != 2*(below(lo)+j) || k >= hi {
+				t.Fatalf("range [%d, %d) got %v", lo, hi, got)
+			}
+		}
+		if want := max(below(hi+1)-below(lo), 0); os.CountRange(lo, hi) != want {
+			t.Fatalf("count [%d, %d] got %d, want %d", lo, hi, os.CountRange(lo, hi), want)
+		}
+		k := r.Intn(n)
+		if m, ok := os.Select(k); !ok || m.Key() != 2*k || os.Rank(2*k+1) != k+1 {
+			t.Fatalf("select %d got %v, rank %d", k, m, os.Rank(2*k+1))
+		}
+	}
+
+	// 分裂与合并后链接的跨度仍然正确
+	for round := 0; round < 50; round++ {
+		key := r.Intn(2*n+10) - 5
+		right := tr.(bst.Splitter[int, int]).Split(key)
+		j := below(key)
+		if tr.Len() != j || right.Len() != n-j {
+			t.Fatalf("split at %d into %d and %d", key, tr.Len(), right.Len())
+		}
+		for _, part := range []bst.BST[int, int]{tr, right} {
+			if err := bst.Validate(part); err != nil {
+				t.Fatalf("split at %d: %v", key, err)
+			}
+		}
+		if err := tr.(bst.Splitter[int, int]).Join(right); err != nil || tr.Len() != n {
+			t.Fatalf("join at %d: %v", key, err)
+		}
+		if err := bst.Validate(tr); err != nil {
+			t.Fatalf("join at %d: %v", key, err)
+		}
+	}
+
+	// 保留形状时各节点的层数不变
+	enc := bst.Encoding[int, int]{Shape: true}
+	src := skiplist.New[int, int](enc, rand.NewSource(6))
+	for _, k := range r.Perm(60) {
+		src.Insert(k, k)
+	}
+	js, err := json.Marshal(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := skiplist.New[int, int](enc)
+	if err := json.Unmarshal(js, dst); err != nil {
+		t.Fatal(err)
+	}
+	if walk(dst, bst.LevelOrder) != walk(src, bst.LevelOrder) {
+		t.Error("decoded skip list has other levels")
+	}
+	if err := json.Unmarshal(bytes.Replace(js, []byte(`"levels":1`), []byte(`"levels":40`), 1), dst); err == nil {
+		t.Error("a node above the top level should be rejected")
+	}
+}
+
+func TestBalancedVariants(t *testing.T) {
+	// 升序插入后左倾红黑树标注颜色，AA 树标注层数，经 NewOf 与 Synchronized 包装的节点亦然
+	wants := map[bst.Class]string{
+		bst.LLRB: "\n ┌─4B┐ \n┌2R┐┌6B\n1B3B5R \n",
+		bst.AATree: "\n  ┌2:2─────┐       \n1:1     ┌4:2──┐    \n" +
+			"      3:1   5:1──┐ \n               6:1 \n",
+	}
+	for c, want := range wants {
+		tr := bst.NewOf[int, int](c)
+		for i := 1; i <= 6; i++ {
+			tr.Insert(i, i)
+		}
+		var out, synced bytes.Buffer
+		bst.Fprint(tr.Root(), &out)
+		if out.String() != want {
+			t.Errorf("%v printed\n%s\nwant\n%s", c, out.String(), want)
+		}
+		bst.Fprint(bst.Synchronized(tr).Root(), &synced)
+		if synced.String() != want {
+			t.Errorf("%v synchronized printed\n%s\nwant\n%s", c, synced.String(), want)
+		}
+	}
+
+	// 逐个删除的过程中保持不变式，旋转次数为对数级别
+	for _, c := range []bst.Class{bst.LLRB, bst.AATree} {
+		var stats bst.Stats
+		tr := bst.NewOf[int, int](c, &stats)
+		const n = 1 << 10
+		for i := 0; i < n; i++ {
+			tr.Insert(i, i)
+		}
+		if h := tr.Root().Height(); h < bits.Len(n)/2 || h > bits.Len(n) {
+			t.Errorf("%v: root height %d after %d ascending inserts", c, h, n)
+		}
+		r := rand.New(rand.NewSource(7))
+		for _, k := range r.Perm(n) {
+			before := stats.Rotations
+			if _, err := tr.Remove(k); err != nil {
+				t.Fatalf("%v: remove %d: %v", c, k, err)
+			}
+			if rot := stats.Rotations - before; rot > int64(8*bits.Len(n)) {
+				t.Errorf("%v: %d rotations removing %d", c, rot, k)
+			}
+			if k%64 == 0 {
+				if err := bst.Validate(tr); err != nil {
+					t.Fatalf("%v: after removing %d: %v", c, k, err)
+				}
+			}
+		}
+	}
+
+	// 保留形状时按颜色与层数的约束检查解码的形状，左倾红黑树中 2 个关键码的形状不是 AA 树
+	enc := bst.Encoding[int, int]{Shape: true}
+	src, err := bst.BuildSorted(bst.LLRB, []int{1, 2}, []int{1, 2}, enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	js, err := json.Marshal(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(js, bst.NewOf[int, int](bst.LLRB, enc)); err != nil {
+		t.Fatal(err)
+	}
+	var doc bst.TreeJSON
+	if err := json.Unmarshal(js, &doc); err != nil {
+		t.Fatal(err)
+	}
+	doc.Class = bst.AATree
+	if js, err = json.Marshal(doc); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(js, bst.NewOf[int, int](bst.AATree, enc)); err == nil {
+		t.Error("a left child on the same level should be rejected")
+	}
+	js = bytes.Replace(js, []byte(`"color":"R"`), []byte(`"color":"B"`), 1)
+	if err := json.Unmarshal(js, &doc); err != nil {
+		t.Fatal(err)
+	}
+	doc.Class = bst.LLRB
+	if js, err = json.Marshal(doc); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(js, bst.NewOf[int, int](bst.LLRB, enc)); err == nil {
+		t.Error("unequal black heights should be rejected")
+	}
+}
+
+func TestAlphaBalanced(t *testing.T) {
+	// 替罪羊树的节点不记录规模，升序插入时依靠重建保持高度不超过 log_{1/α} n
+	const n = 1000
+	heights := make(map[float64]int)
+	for _, alpha := range []float64{0.55, 0.7, 0.9} {
+		var stats bst.Stats
+		tr := scapegoat.New[int, int](alpha, &stats)
+		for i := 0; i < n; i++ {
+			tr.Insert(i, i)
+		}
+		if _, ok := tr.Root().(bst.Sizer); ok {
+			t.Fatal("scapegoat nodes should not keep sizes")
+		}
+		heights[alpha] = workload.Height[int, int](tr.Root())
+		if bound := math.Log(n) / math.Log(1/alpha); float64(heights[alpha]) > bound {
+			t.Errorf("alpha %v: height %d above %.1f", alpha, heights[alpha], bound)
+		}
+		if stats.Rebuilds == 0 {
+			t.Errorf("alpha %v: no rebuild during ascending inserts", alpha)
+		}
+		// 删除至 α 倍以下时重建整棵树
+		for i := 0; float64(tr.Len()) >= alpha*n; i++ {
+			tr.Remove(i)
+		}
+		if h := workload.Height[int, int](tr.Root()); h != bits.Len(uint(tr.Len()))-1 {
+			t.Errorf("alpha %v: height %d after rebuilding %d keys", alpha, h, tr.Len())
+		}
+		if err := bst.Validate(tr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if heights[0.55] >= heights[0.9] {
+		t.Errorf("a lower alpha should keep the tree lower, heights %v", heights)
+	}
+
+	// 保留的形状须在解码方的 α 所允许的高度内
+	enc := bst.Encoding[int, int]{Shape: true}
+	src := scapegoat.New[int, int](0.9, enc)
+	for i := 0; i < 100; i++ {
+		src.Insert(i, i)
+	}
+	js, err := json.Marshal(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(js, scapegoat.New[int, int](0.9, enc)); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(js, scapegoat.New[int, int](0.55, enc)); err == nil {
+		t.Error("a shape too high for alpha 0.55 should be rejected")
+	}
+
+	// 重量平衡树的规模兼作顺序统计，各节点两侧的权重不低于 α
+	for _, alpha := range []float64{0.2, 0.25, 0.29} {
+		tr := wbt.New[int, int](alpha)
+		for i := 0; i < n; i++ {
+			tr.Insert(2*i, i)
+		}
+		if h, bound := workload.Height[int, int](tr.Root()), math.Log(n+1)/math.Log(1/(1-alpha)); float64(h) > bound {
+			t.Errorf("alpha %v: height %d above %.1f", alpha, h, bound)
+		}
+		ost := tr.(bst.OrderStatistic[int, int])
+		for _, k := range []int{0, 1, 499, 999} {
+			if m, ok := ost.Select(k); !ok || m.Key() != 2*k {
+				t.Errorf("alpha %v: select %d got %v", alpha, k, m)
+			}
+			if r := ost.Rank(2*k + 1); r != k+1 {
+				t.Errorf("alpha %v: rank of %d got %d", alpha, 2*k+1, r)
+			}
+		}
+		if err := bst.Validate(tr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 不支持多重映射，α 须在各自的范围内
+	for _, c := range []bst.Class{bst.Scapegoat, bst.WeightBalanced} {
+		if _, err := bst.Make[int, int](c, bst.WithMultimap()); !errors.Is(err, bst.ErrInvalidOption) {
+			t.Errorf("%v: WithMultimap got %v", c, err)
+		}
+	}
+	for _, tc := range []struct {
+		c     bst.Class
+		alpha float64
+	}{
+		{bst.Scapegoat, 0.4}, {bst.Scapegoat, 0.5}, {bst.WeightBalanced, 0.15},
+		{bst.WeightBalanced, 0.3}, {bst.AVL, 0.7}, {bst.BTree, 0.25},
+		{bst.Scapegoat, 0}, {bst.Scapegoat, 1}, {bst.WeightBalanced, math.NaN()},
+	} {
+		if _, err := bst.Make[int, int](tc.c, bst.WithAlpha(tc.alpha)); !errors.Is(err, bst.ErrInvalidOption) {
+			t.Errorf("%v: WithAlpha(%v) got %v", tc.c, tc.alpha, err)
+		}
+	}
+
+	// Make 给出的 α 与直接传给 New 的相同
+	made := make(map[float64]int)
+	for _, alpha := range []float64{0.55, 0.9} {
+		tr, err := bst.Make[int, int](bst.Scapegoat, bst.WithAlpha(alpha))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < n; i++ {
+			tr.Insert(i, i)
+		}
+		made[alpha] = workload.Height[int, int](tr.Root())
+		if made[alpha] != heights[alpha] {
+			t.Errorf("scapegoat alpha %v: height %d, New gives %d", alpha, made[alpha], heights[alpha])
+		}
+	}
+	if made[0.55] >= made[0.9] {
+		t.Errorf("a lower alpha should keep the made tree lower, heights %v", made)
+	}
+	for _, alpha := range []float64{0.2, 0.29} {
+		tr, err := bst.Make[int, int](bst.WeightBalanced, bst.WithAlpha(alpha))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < n; i++ {
+			tr.Insert(i, i)
+		}
+		if h, bound := workload.Height[int, int](tr.Root()), math.Log(n+1)/math.Log(1/(1-alpha)); float64(h) > bound {
+			t.Errorf("wbt alpha %v: height %d above %.1f", alpha, h, bound)
+		}
+		if err := bst.Validate(tr); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBPlusTree(t *testing.T) {
+	r := rand.New(rand.NewSource(24))
+	for _, m := range []int{3, 5, 8} {
+		for round := 0; round < 10; round++ {
+			bsttest.Check(t, bsttest.ClassTarget(bst.BPlusTree, m), bsttest.Random(r, 400, 40+round*20))
+		}
+	}
+
+	// 批量构建的叶节点尽量装满，内部节点只含分隔关键码，Print 列出叶节点链
+	keys := make([]int, 10)
+	for i := range keys {
+		keys[i] = i + 1
+	}
+	tr := bplustree.New[int, int](4)
+	if err := tr.(bst.SortedBuilder[int, int]).BuildSorted(keys, keys, true); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := tr.(interface{ Fprint(io.Writer) error }).Fprint(&out); err != nil {
+		t.Fatal(err)
+	}
+	if want := "[4 7 9]\n[1 2 3] <-> [4 5 6] <-> [7 8] <-> [9 10]\n"; out.String() != want {
+		t.Errorf("printed\n%s\nwant\n%s", out.String(), want)
+	}
+	root := tr.Root().(bst.Multiway[int, int])
+	if seps, data := root.Entries(); fmt.Sprint(seps, data) != "[4 7 9] [0 0 0]" || len(root.Children()) != 4 {
+		t.Errorf("root holds %v %v with %d branches", seps, data, len(root.Children()))
+	}
+	for _, n := range []int{1, 100, 1000} {
+		keys := make([]int, n)
+		for i := range keys {
+			keys[i] = i
+		}
+		for _, m := range []int{3, 4, 7} {
+			tr, err := bst.BuildSorted[int, int](bst.BPlusTree, keys, nil, m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := bst.Validate(tr); err != nil {
+				t.Fatalf("order %d, %d keys: %v", m, n, err)
+			}
+			// 叶节点装有 m-1 个关键码，内部节点有 m 个分支
+			leaves := (n + m - 2) / (m - 1)
+			h := 0
+			for c := leaves; c > 1; c = (c + m - 1) / m {
+				h++
+			}
+			if got := workload.Height[int, int](tr.Root()); got != h {
+				t.Errorf("order %d, %d keys: height %d, want %d", m, n, got, h)
+			}
+		}
+	}
+
+	// 区间查询定位一次后沿叶节点链前进，反向遍历沿后向链接
+	tr = bplustree.New[int, int](3)
+	for _, k := range r.Perm(200) {
+		tr.Insert(k, -k)
+	}
+	for k := 0; k < 200; k += 2 {
+		tr.Remove(k)
+	}
+	var got []int
+	tr.Range(10, 30, false, true, func(n bst.Node[int, int]) bool {
+		if n.Data() != -n.Key() {
+			t.Errorf("data %d of key %d", n.Data(), n.Key())
+		}
+		got = append(got, n.Key())
+		return true
+	})
+	if fmt.Sprint(got) != "[11 13 15 17 19 21 23 25 27 29]" {
+		t.Errorf("range got %v", got)
+	}
+	var desc []int
+	bst.Visit[int, int](tr, bst.ReverseInOrder, func(n bst.Node[int, int]) bool {
+		desc = append(desc, n.Key())
+		return len(desc) < 3
+	})
+	if fmt.Sprint(desc) != "[199 197 195]" {
+		t.Errorf("reverse walk got %v", desc)
+	}
+	var pre int
+	tr.Walk(bst.PreOrder, func(n bst.Node[int, int]) {
+		if n.Key() != 2*pre+1 {
+			t.Errorf("pre-order visits %d at %d", n.Key(), pre)
+		}
+		pre++
+	})
+	if pre != 100 {
+		t.Errorf("pre-order visits %d keys, separators should be skipped", pre)
+	}
+
+	// 保留形状时叶节点的装填不变，分隔关键码取各分支的最小关键码
+	enc := bst.Encoding[int, int]{Shape: true}
+	src := bplustree.New[int, int](enc, 4)
+	for i := 0; i < 60; i++ {
+		src.Insert(i, i)
+	}
+	printed := func(tr bst.BST[int, int]) string {
+		var b bytes.Buffer
+		tr.(interface{ Fprint(io.Writer) error }).Fprint(&b)
+		return b.String()
+	}
+	data, err := src.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := bplustree.New[int, int](enc)
+	if err := dst.(encoding.BinaryUnmarshaler).UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if printed(dst) != printed(src) {
+		t.Errorf("decoded\n%s\nwant\n%s", printed(dst), printed(src))
+	}
+	js, err := json.Marshal(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(bytes.Replace(js, []byte(`"order":4`), []byte(`"order":3`), 1), dst); err == nil {
+		t.Error("nodes overflowing order 3 should be rejected")
+	}
+
+	tm, err := bst.Make[int, string](bst.BPlusTree, bst.WithOrder(3), bst.WithMultimap())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		tm.Insert(i%2, strconv.Itoa(i))
+	}
+	if n := tm.(bst.Multimap[int, string]).Count(1); n != 5 {
+		t.Errorf("count %d entries of 1, want 5", n)
+	}
+}
+
+func TestDiskBTree(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index")
+	opts := pager.Options{PageSize: 256, CachePages: 4}
+	var stats bst.Stats
+	dt, err := btree.OpenDisk[int, string](path, 4, opts, &stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := rand.New(rand.NewSource(25))
+	ref := make(map[int]string)
+	for step := 0; step < 3000; step++ {
+		k := r.Intn(400)
+		switch r.Intn(4) {
+		case 0, 1:
+			v := strconv.Itoa(step)
+			old, replaced, err := dt.Put(k, v)
+			if want, ok := ref[k]; err != nil || replaced != ok || old != want {
+				t.Fatalf("step %d: Put %d got %q %v %v", step, k, old, replaced, err)
+			}
+			ref[k] = v
+		case 2:
+			data, err := dt.Remove(k)
+			if want, ok := ref[k]; ok != (err == nil) || data != want {
+				t.Fatalf("step %d: Remove %d got %q %v", step, k, data, err)
+			}
+			delete(ref, k)
+		case 3:
+			data, ok, err := dt.Get(k)
+			if want, found := ref[k]; err != nil || ok != found || data != want {
+				t.Fatalf("step %d: Get %d got %q %v %v", step, k, data, ok, err)
+			}
+		}
+		if dt.Len() != len(ref) {
+			t.Fatalf("step %d: Len %d, want %d", step, dt.Len(), len(ref))
+		}
+		// 不时关闭并重新打开文件，已有文件保留其阶数
+		if step%250 == 249 {
+			if err := dt.Validate(); err != nil {
+				t.Fatalf("step %d: %v", step, err)
+			}
+			if err := dt.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if dt, err = btree.OpenDisk[int, string](path, opts, &stats); err != nil {
+				t.Fatal(err)
+			}
+			if dt.Len() != len(ref) || dt.Order() != 4 {
+				t.Fatalf("reopened with %d keys of order %d", dt.Len(), dt.Order())
+			}
+		}
+	}
+	if stats.Splits == 0 || stats.Merges == 0 {
+		t.Errorf("no split or merge recorded: %+v", stats)
+	}
+	var want, got []int
+	for k := range ref {
+		if k > 100 && k <= 300 {
+			want = append(want, k)
+		}
+	}
+	sort.Ints(want)
+	err = dt.Range(100, 300, false, true, func(k int, data string) bool {
+		if data != ref[k] {
+			t.Errorf("range visits %d:%q, want %q", k, data, ref[k])
+		}
+		got = append(got, k)
+		return true
+	})
+	if err != nil || fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("range got %v %v, want %v", got, err, want)
+	}
+	if err := dt.Insert(want[0], ""); !errors.Is(err, bst.ErrDuplicateKey) {
+		t.Errorf("inserting a present key got %v", err)
+	}
+	// 放不进一页的节点使写操作失败，树保持不变
+	if err := dt.Insert(-1, strings.Repeat("x", 300)); !errors.Is(err, btree.ErrNodeTooLarge) {
+		t.Errorf("inserting a large value got %v", err)
+	}
+	if _, ok, _ := dt.Get(-1); ok || dt.Len() != len(ref) {
+		t.Error("a failed insert changed the tree")
+	}
+
+	// 删空后再次写入时复用释放的页，文件不再增长
+	for k := range ref {
+		if _, err := dt.Remove(k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := dt.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := os.Stat(path)
+	for k := 0; k < 200; k++ {
+		dt.Insert(k, "")
+	}
+	if err := dt.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if after, _ := os.Stat(path); after.Size() != before.Size() {
+		t.Errorf("file grew from %d to %d bytes", before.Size(), after.Size())
+	}
+	if _, _, err := dt.Get(0); !errors.Is(err, pager.ErrClosed) {
+		t.Errorf("Get after Close got %v", err)
+	}
+}
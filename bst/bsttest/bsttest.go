@@ -0,0 +1,416 @@
+// Package bsttest replays sequences of operations against the trees of
+// package bst and a sorted slice as reference, checking every result and the
+// invariants of the tree after each step. Failing sequences are shrunk to a
+// minimal reproduction.
+package bsttest
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+// Kind is the kind of an operation
+type Kind uint8
+
+const (
+	Insert Kind = iota
+	Remove
+	Search
+	Put    // 插入或替换数据
+	Update // 删除存在的关键码，插入不存在的关键码
+	numKinds
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Insert:
+		return "Insert"
+	case Remove:
+		return "Remove"
+	case Search:
+		return "Search"
+	case Put:
+		return "Put"
+	case Update:
+		return "Update"
+	}
+	return fmt.Sprintf("Kind(%d)", k)
+}
+
+// Op is an operation on the key Key
+type Op struct {
+	Kind Kind
+	Key  int
+}
+
+func (op Op) String() string { return fmt.Sprintf("{%v, %d}", op.Kind, op.Key) }
+
+// Format returns ops as a Go literal to paste into a test
+func Format(ops []Op) string {
+	var b strings.Builder
+	b.WriteString("[]bsttest.Op{")
+	for i, op := range ops {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "{bsttest.%v, %d}", op.Kind, op.Key)
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// Target is a tree under test, the int keys of the ops are mapped to its
+// keys by Key, which must be strictly increasing
+type Target[K comparable] struct {
+	Name string
+	New  func() bst.BST[K, int]
+	Key  func(k int) K
+}
+
+// ClassTarget returns the Target of class c with int keys, parms are passed
+// to bst.NewOf
+func ClassTarget(c bst.Class, parms ...interface{}) Target[int] {
+	return Target[int]{
+		Name: fmt.Sprintf("class %d", c),
+		New:  func() bst.BST[int, int] { return bst.NewOf[int, int](c, parms...) },
+		Key:  func(k int) int { return k },
+	}
+}
+
+// reference 以有序切片实现的参照
+type reference struct {
+	keys []int
+	data []int
+}
+
+func (r *reference) find(k int) (int, bool) {
+	i := sort.SearchInts(r.keys, k)
+	return i, i < len(r.keys) && r.keys[i] == k
+}
+
+func (r *reference) insert(i, k, data int) {
+	r.keys = insert(r.keys, k, i)
+	r.data = insert(r.data, data, i)
+}
+
+func (r *reference) remove(i int) {
+	r.keys = append(r.keys[:i], r.keys[i+1:]...)
+	r.data = append(r.data[:i], r.data[i+1:]...)
+}
+
+// Run replays ops against a new tree of tg, returning an error for the first
+// step whose result differs from the reference or after which the tree
+// breaks an invariant. Panics of the tree are returned as errors.
+func Run[K comparable](tg Target[K], ops []Op) (err error) {
+	step := -1
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("step %d %v: panic: %v", step, ops[step], p)
+		}
+	}()
+	t := tg.New()
+	ref := new(reference)
+	for step = range ops {
+		if err := apply(tg, t, ref, ops[step], step); err != nil {
+			return fmt.Errorf("step %d %v: %v", step, ops[step], err)
+		}
+	}
+	return nil
+}
+
+func apply[K comparable](tg Target[K], t bst.BST[K, int], ref *reference, op Op, step int) error {
+	key := tg.Key(op.Key)
+	i, ok := ref.find(op.Key)
+	switch op.Kind {
+	case Insert:
+		n, err := t.Insert(key, step)
+		if ok != errors.Is(err, bst.ErrDuplicateKey) || !ok && err != nil {
+			return fmt.Errorf("got error %v, key present %v", err, ok)
+		}
+		if !ok {
+			if bst.IsNil(n) || n.Key() != key {
+				return fmt.Errorf("returned node %v", n)
+			}
+			ref.insert(i, op.Key, step)
+		}
+	case Remove:
+		if _, err := t.Remove(key); ok && err != nil || !ok && !errors.Is(err, bst.ErrNotFound) {
+			return fmt.Errorf("got error %v, key present %v", err, ok)
+		}
+		if ok {
+			ref.remove(i)
+		}
+	case Search:
+		n, found := t.Search(key)
+		if found != ok {
+			return fmt.Errorf("found %v, want %v", found, ok)
+		}
+		if ok && (n.Key() != key || n.Data() != ref.data[i]) {
+			return fmt.Errorf("found %v:%v, want %v:%v", n.Key(), n.Data(), key, ref.data[i])
+		}
+	case Put:
+		old, replaced, err := bst.Put(t, key, step)
+		if err != nil || replaced != ok || ok && old != ref.data[i] {
+			return fmt.Errorf("got %v %v %v, key present %v", old, replaced, err, ok)
+		}
+		if ok {
+			ref.data[i] = step
+		} else {
+			ref.insert(i, op.Key, step)
+		}
+	case Update:
+		err := bst.Update(t, key, func(old int, found bool) (int, bool) {
+			if found != ok || ok && old != ref.data[i] {
+				panic(fmt.Sprintf("fn called with %v %v, key present %v", old, found, ok))
+			}
+			return step, !found
+		})
+		if err != nil {
+			return fmt.Errorf("got error %v", err)
+		}
+		if ok {
+			ref.remove(i)
+		} else {
+			ref.insert(i, op.Key, step)
+		}
+	default:
+		return fmt.Errorf("unknown operation")
+	}
+	if t.Len() != len(ref.keys) {
+		return fmt.Errorf("Len %d, want %d", t.Len(), len(ref.keys))
+	}
+	if err := bst.Validate(t); err != nil {
+		return err
+	}
+	if err := checkNearest(tg, t, ref, op.Key); err != nil {
+		return err
+	}
+	return checkContents(tg, t, ref)
+}
+
+// checkNearest 检查 k 的前驱、后继及最值
+func checkNearest[K comparable](tg Target[K], t bst.BST[K, int], ref *reference, k int) error {
+	i, ok := ref.find(k)
+	n := len(ref.keys)
+	at := func(j int) (int, bool) {
+		if j < 0 || j >= n {
+			return 0, false
+		}
+		return j, true
+	}
+	floor, ceiling, higher := i-1, i, i
+	if ok {
+		floor, higher = i, i+1
+	}
+	queries := []struct {
+		name string
+		f    func(K) (bst.Node[K, int], bool)
+		j    int
+	}{
+		{"Floor", t.Floor, floor},
+		{"Ceiling", t.Ceiling, ceiling},
+		{"Lower", t.Lower, i - 1},
+		{"Higher", t.Higher, higher},
+		{"Min", func(K) (bst.Node[K, int], bool) { return t.Min() }, 0},
+		{"Max", func(K) (bst.Node[K, int], bool) { return t.Max() }, n - 1},
+	}
+	for _, q := range queries {
+		node, found := q.f(tg.Key(k))
+		j, want := at(q.j)
+		if found != want || found && node.Key() != tg.Key(ref.keys[j]) {
+			return fmt.Errorf("%s(%d) got %v %v, want %v", q.name, k, node, found, want)
+		}
+	}
+	return nil
+}
+
+// checkContents 以迭代器比较全部关键码与数据
+func checkContents[K comparable](tg Target[K], t bst.BST[K, int], ref *reference) error {
+	it := t.Iterator()
+	i := 0
+	for ok := it.First(); ok; ok = it.Next() {
+		if i >= len(ref.keys) {
+			return fmt.Errorf("iterator yields more than %d keys", len(ref.keys))
+		}
+		if it.Key() != tg.Key(ref.keys[i]) || it.Data() != ref.data[i] {
+			return fmt.Errorf("iterator yields %v:%v at %d, want %d:%d", it.Key(), it.Data(), i, ref.keys[i], ref.data[i])
+		}
+		i++
+	}
+	if i != len(ref.keys) {
+		return fmt.Errorf("iterator yields %d keys, want %d", i, len(ref.keys))
+	}
+	return nil
+}
+
+func insert(a []int, v, i int) []int {
+	a = append(a, 0)
+	copy(a[i+1:], a[i:])
+	a[i] = v
+	return a
+}
+
+// Shrink returns a minimal subsequence of ops for which fails still holds,
+// removing chunks of halving length as long as fails holds
+func Shrink(ops []Op, fails func(ops []Op) bool) []Op {
+	for n := len(ops) / 2; n >= 1; {
+		removed := false
+		for i := 0; i+n <= len(ops); {
+			cand := append(append([]Op(nil), ops[:i]...), ops[i+n:]...)
+			if fails(cand) {
+				ops, removed = cand, true
+				continue
+			}
+			i += n
+		}
+		if !removed {
+			n /= 2
+		}
+	}
+	return ops
+}
+
+// Check runs ops against tg and fails t with the shrunk sequence if any
+// step goes wrong
+func Check[K comparable](t testing.TB, tg Target[K], ops []Op) {
+	t.Helper()
+	if err := Run(tg, ops); err != nil {
+		min := Shrink(ops, func(ops []Op) bool { return Run(tg, ops) != nil })
+		t.Fatalf("%s: %v\nminimal reproduction of %d ops: %s\nfails with: %v", tg.Name, err, len(min), Format(min), Run(tg, min))
+	}
+}
+
+// Random returns n random ops on keys in [0, keys)
+func Random(r *rand.Rand, n, keys int) []Op {
+	ops := make([]Op, n)
+	for i := range ops {
+		// 插入占三分之一，使树保持一定规模
+		kind := Insert
+		switch r.Intn(6) {
+		case 2:
+			kind = Remove
+		case 3:
+			kind = Search
+		case 4:
+			kind = Put
+		case 5:
+			kind = Update
+		}
+		ops[i] = Op{kind, r.Intn(keys)}
+	}
+	return ops
+}
+
+// Sorted, Reverse, ZigZag and Sawtooth return n keys in adversarial orders:
+// ascending, descending, alternating between both ends, and interleaved
+// ascending runs of length tooth
+func Sorted(n int) []int {
+	keys := make([]int, n)
+	for i := range keys {
+		keys[i] = i
+	}
+	return keys
+}
+
+func Reverse(n int) []int {
+	keys := make([]int, n)
+	for i := range keys {
+		keys[i] = n - 1 - i
+	}
+	return keys
+}
+
+func ZigZag(n int) []int {
+	keys := make([]int, 0, n)
+	for lo, hi := 0, n-1; lo <= hi; lo, hi = lo+1, hi-1 {
+		keys = append(keys, lo)
+		if lo != hi {
+			keys = append(keys, hi)
+		}
+	}
+	return keys
+}
+
+func Sawtooth(n, tooth int) []int {
+	if tooth <= 0 {
+		tooth = 1
+	}
+	runs := (n + tooth - 1) / tooth
+	keys := make([]int, 0, n)
+	for i := 0; i < tooth; i++ {
+		for j := 0; j < runs; j++ {
+			if k := j*tooth + i; k < n {
+				keys = append(keys, k)
+			}
+		}
+	}
+	return keys
+}
+
+// Pattern returns the ops inserting keys in order, searching them, removing
+// every other one, inserting them back, putting and updating some and then
+// removing all of them
+func Pattern(keys []int) []Op {
+	var ops []Op
+	each := func(kind Kind, step int) {
+		for i := 0; i < len(keys); i += step {
+			ops = append(ops, Op{kind, keys[i]})
+		}
+	}
+	each(Insert, 1)
+	each(Search, 1)
+	each(Remove, 2)
+	each(Search, 1)
+	each(Insert, 2)
+	each(Put, 3)
+	each(Update, 4)
+	each(Remove, 1)
+	return ops
+}
+
+// Patterns returns the adversarial sequences of n keys by name
+func Patterns(n int) map[string][]Op {
+	return map[string][]Op{
+		"sorted":   Pattern(Sorted(n)),
+		"reverse":  Pattern(Reverse(n)),
+		"zigzag":   Pattern(ZigZag(n)),
+		"sawtooth": Pattern(Sawtooth(n, 7)),
+	}
+}
+
+// Decode turns fuzz input into ops, two bytes each: the kind and the key.
+// Keys fall in [0, 256) so that the ops often hit the same keys.
+func Decode(data []byte) []Op {
+	ops := make([]Op, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		ops = append(ops, Op{Kind(data[i] % byte(numKinds)), int(data[i+1])})
+	}
+	return ops
+}
+
+// Encode is the inverse of Decode for keys in [0, 256)
+func Encode(ops []Op) []byte {
+	data := make([]byte, 0, 2*len(ops))
+	for _, op := range ops {
+		data = append(data, byte(op.Kind), byte(op.Key))
+	}
+	return data
+}
+
+// Fuzz registers a fuzz target replaying the decoded input against tg,
+// seeded by the adversarial patterns
+func Fuzz[K comparable](f *testing.F, tg Target[K]) {
+	for _, ops := range Patterns(40) {
+		f.Add(Encode(ops))
+	}
+	f.Add(Encode(Random(rand.New(rand.NewSource(1)), 200, 64)))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		Check(t, tg, Decode(data))
+	})
+}
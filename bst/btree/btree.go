@@ -1,7 +1,6 @@
 package btree
 
 import (
-	"errors"
 	"fmt"
 	"math"
 	"sort"
@@ -10,43 +9,116 @@ import (
 )
 
 func init() {
-	bst.RegisterBST(bst.BTree, New)
+	bst.Register("btree", bst.Factory{
+		New: New[any, any],
+		Typed: []interface{}{
+			New[int, int], New[int, string], New[int, any],
+			New[string, int], New[string, string], New[string, any],
+		},
+		Order:    true,
+		Multimap: true,
+	})
 }
 
-type bTree struct {
-	m    int // 阶数
-	root *node
-	comp bst.Comparator
-	hot  *node
+const defaultOrder = 4 // 未指定阶数时为 2-3-4 树
+
+type bTree[K, V any] struct {
+	m     int // 阶数
+	root  *node[K, V]
+	comp  bst.Comparator[K]
+	hot   *node[K, V]
+	size  int
+	enc   bst.Encoding[K, V]
+	stats *bst.Stats
+	multi bool // MultiKeys 模式
 }
 
-func New(parms ...interface{}) bst.BST {
-	bt := new(bTree)
-	bt.comp = bst.BasicCompare
+// New returns an empty B-tree, an int in parms stands for its order.
+// Reads never modify the B-tree and may run in parallel, writes need
+// exclusive access, such as by bst.Synchronized.
+func New[K, V any](parms ...interface{}) bst.BST[K, V] {
+	bt := new(bTree[K, V])
+	bt.comp = bst.DefaultCompare[K]()
 	for _, p := range parms {
 		switch v := p.(type) {
-		case bst.Comparator:
+		case bst.Comparator[K]:
+			bt.comp = v
+		case func(a, b K) int:
 			bt.comp = v
 		case int:
 			bt.m = v
+		case bst.Encoding[K, V]:
+			bt.enc = v
+		case *bst.Stats:
+			bt.stats = v
+		case bst.Mode:
+			bt.multi = v == bst.MultiKeys
 		}
 	}
+	bt.comp = bst.CountComparisons(bt.comp, bt.stats)
+	if bt.m < 3 {
+		bt.m = defaultOrder
+	}
 	return bt
 }
 
-func (b *bTree) Root() bst.Node {
-	return b.root
+// Len returns the number of keys in the tree
+func (b *bTree[K, V]) Len() int { return b.size }
+
+// Root returns the first key of the root node
+func (b *bTree[K, V]) Root() bst.Node[K, V] {
+	if b.root == nil || len(b.root.key) == 0 {
+		return nil
+	}
+	return item[K, V]{b.root, 0}
+}
+
+// Search returns the key found, or the nearest key of the last visited node
+func (b *bTree[K, V]) Search(key K) (bst.Node[K, V], bool) {
+	if b.root == nil || len(b.root.key) == 0 {
+		return nil, false
+	}
+	n, i, ok := b.find(key)
+	if i == len(n.key) {
+		i--
+	}
+	return item[K, V]{n, i}, ok
 }
 
-func (b *bTree) Search(key interface{}) (bst.Node, bool) {
-	n, _, ok := b.searchIn(b.root, key)
-	return n, ok
+// find 在非空树中查找 key，多重映射中返回最早插入的关键码
+func (b *bTree[K, V]) find(key K) (*node[K, V], int, bool) {
+	if b.multi {
+		return b.searchMulti(b.root, key, false)
+	}
+	return b.searchIn(b.root, key)
+}
+
+// searchMulti 在多重映射中查找 key 最早插入的关键码，未找到或 last 时返回新关键码在叶节点中的插入位置，
+// last 时新关键码位于所有相等的关键码之后。相等的关键码可能分布于多层，故总是下降至叶节点
+func (b *bTree[K, V]) searchMulti(n *node[K, V], key K, last bool) (*node[K, V], int, bool) {
+	var hit *node[K, V]
+	var at int
+	for {
+		i := sort.Search(len(n.key), func(i int) bool {
+			c := b.comp(n.key[i], key)
+			return c > 0 || c == 0 && !last
+		})
+		if !last && i < len(n.key) && b.comp(n.key[i], key) == 0 {
+			hit, at = n, i
+		}
+		if n.children == nil {
+			if hit != nil {
+				return hit, at, true
+			}
+			return n, i, false
+		}
+		n = n.children[i]
+	}
 }
 
-func (b *bTree) searchIn(n *node, key interface{}) (hot *node, i int, ok bool) {
+func (b *bTree[K, V]) searchIn(n *node[K, V], key K) (hot *node[K, V], i int, ok bool) {
 	i = sort.Search(len(n.key), func(i int) bool {
-		result := b.comp(n.key[i], key)
-		return result == 1 || result == 0
+		return b.comp(n.key[i], key) >= 0
 	})
 
 	// 在当前节点找到了key
@@ -62,25 +134,40 @@ func (b *bTree) searchIn(n *node, key interface{}) (hot *node, i int, ok bool) {
 }
 
 // Insert returns the exact node which stores the newly inserted key
-func (b *bTree) Insert(key, data interface{}) (bst.Node, error) {
+func (b *bTree[K, V]) Insert(key K, data V) (_ bst.Node[K, V], err error) {
+	if bst.Debug {
+		defer bst.MustValidate(b)
+	}
+	defer bst.CatchIncomparable(&err)
 	if b.root == nil {
-		b.root = newNode(key, data, b.m)
-		return b.root, nil
+		return b.attach(nil, 0, key, data), nil
+	}
+	if b.multi {
+		n, i, _ := b.searchMulti(b.root, key, true)
+		return b.attach(n, i, key, data), nil
 	}
 	n, i, ok := b.searchIn(b.root, key)
 	if ok {
-		return nil, errors.New("insert with duplicate key")
+		return nil, bst.ErrDuplicateKey
+	}
+	return b.attach(n, i, key, data), nil
+}
+
+// attach 将关键码插入 searchIn 返回的叶节点 n 的第 i 个位置，n 为 nil 时作为根
+func (b *bTree[K, V]) attach(n *node[K, V], i int, key K, data V) item[K, V] {
+	b.size++
+	if n == nil {
+		b.root = newNode(key, data, b.m)
+		return item[K, V]{b.root, 0}
 	}
 	n.key = insert(n.key, key, i)
 	n.data = insert(n.data, data, i)
-	// n.children = append(n.children, nil)
-	// copy(n.children[i+1:], n.children[i:])
-	// n.children[i] = nil
-	b.solveOverflow(n, key)
-	return b.hot, nil
+	at := item[K, V]{n, i}
+	b.solveOverflow(n, &at)
+	return at
 }
 
-func insert(a []interface{}, v interface{}, i int) []interface{} {
+func insert[T any](a []T, v T, i int) []T {
 	if i < 0 {
 		panic("insert index can not be negetive")
 	}
@@ -92,33 +179,32 @@ func insert(a []interface{}, v interface{}, i int) []interface{} {
 	return a
 }
 
-func (b *bTree) solveOverflow(n *node, origKey interface{}) {
-	b.hot = n
+// solveOverflow 分裂上溢的节点 n，at 为新关键码的位置，随分裂更新
+func (b *bTree[K, V]) solveOverflow(n *node[K, V], at *item[K, V]) {
 	if b.m >= len(n.key)+1 {
 		return
 	}
 	mid := b.m / 2
 	upKey, upData := n.key[mid], n.data[mid]
 	sp := n.split(mid)
+	b.stats.Split()
 	p := n.parent
 	if p == nil {
-		p = new(node)
+		p = new(node[K, V])
 		b.root = p
 		n.parent = p
 		p.children = append(p.children, n)
 	}
+	// 上升的关键码位于 n 在父节点中的秩处，多重映射中父节点可能含有相等的关键码，故不按关键码查找
+	i := childRank(n)
 	switch {
-	case b.comp(origKey, upKey) == 0:
-		b.hot = p
-	case b.comp(origKey, upKey) == 1:
-		b.hot = sp
+	case at.n != n:
+	case at.i == mid:
+		*at = item[K, V]{p, i}
+	case at.i > mid:
+		*at = item[K, V]{sp, at.i - mid - 1}
 	}
 
-	i := sort.Search(len(p.key), func(i int) bool {
-		result := b.comp(p.key[i], upKey)
-		return result == 1 || result == 0
-	})
-
 	p.key = insert(p.key, upKey, i)
 	p.data = insert(p.data, upData, i)
 
@@ -128,14 +214,30 @@ func (b *bTree) solveOverflow(n *node, origKey interface{}) {
 		p.children[i+1] = sp
 	}
 	sp.parent = p
-	b.solveOverflow(p, origKey)
+	b.solveOverflow(p, at)
 }
 
-func (b *bTree) Remove(key interface{}) (bst.Node, error) {
-	n, i, ok := b.searchIn(b.root, key)
+func (b *bTree[K, V]) Remove(key K) (_ bst.Node[K, V], err error) {
+	if bst.Debug {
+		defer bst.MustValidate(b)
+	}
+	defer bst.CatchIncomparable(&err)
+	if b.root == nil {
+		return nil, bst.ErrNotFound
+	}
+	n, i, ok := b.find(key)
 	if !ok {
-		return nil, nil
+		return nil, bst.ErrNotFound
 	}
+	// 返回只含被删除关键码的节点，n 中的条目在删除后会移动
+	removed := item[K, V]{newNode(n.key[i], n.data[i], 2), 0}
+	b.detach(n, i)
+	return removed, nil
+}
+
+// detach 删除节点 n 的第 i 个关键码
+func (b *bTree[K, V]) detach(n *node[K, V], i int) {
+	b.size--
 	if n.children != nil {
 		succ := n.children[i+1]
 		for len(succ.children) > 0 {
@@ -146,15 +248,77 @@ func (b *bTree) Remove(key interface{}) (bst.Node, error) {
 		succ.key = succ.key[1:]
 		succ.data = succ.data[1:]
 		b.solveUnderflow(succ)
-		return b.hot, nil
+		return
 	}
 	n.key = append(n.key[:i], n.key[i+1:]...)
 	n.data = append(n.data[:i], n.data[i+1:]...)
 	b.solveUnderflow(n)
-	return b.hot, nil
 }
 
-func (b *bTree) solveUnderflow(n *node) {
+// Put inserts key with data or replaces its data after a single search
+func (b *bTree[K, V]) Put(key K, data V) (old V, replaced bool, err error) {
+	if bst.Debug {
+		defer bst.MustValidate(b)
+	}
+	defer bst.CatchIncomparable(&err)
+	if b.root == nil {
+		b.attach(nil, 0, key, data)
+		return old, false, nil
+	}
+	n, i, ok := b.find(key)
+	if !ok {
+		b.attach(n, i, key, data)
+		return old, false, nil
+	}
+	old, n.data[i] = n.data[i], data
+	return old, true, nil
+}
+
+func (b *bTree[K, V]) Update(key K, fn func(old V, ok bool) (V, bool)) (err error) {
+	if bst.Debug {
+		defer bst.MustValidate(b)
+	}
+	defer bst.CatchIncomparable(&err)
+	var n *node[K, V]
+	var i int
+	var ok bool
+	if b.root != nil {
+		n, i, ok = b.find(key)
+	}
+	var old V
+	if ok {
+		old = n.data[i]
+	}
+	data, keep := fn(old, ok)
+	switch {
+	case ok && keep:
+		n.data[i] = data
+	case ok:
+		b.detach(n, i)
+	case keep:
+		b.attach(n, i, key, data)
+	}
+	return nil
+}
+
+func (b *bTree[K, V]) GetOrInsert(key K, data V) (_ bst.Node[K, V], found bool, err error) {
+	if bst.Debug {
+		defer bst.MustValidate(b)
+	}
+	defer bst.CatchIncomparable(&err)
+	if b.root == nil {
+		return b.attach(nil, 0, key, data), false, nil
+	}
+	n, i, ok := b.find(key)
+	if ok {
+		return item[K, V]{n, i}, true, nil
+	}
+	return b.attach(n, i, key, data), false, nil
+}
+
+func (b *bTree[K, V]) solveUnderflow(n *node[K, V]) {
+	var zk K
+	var zv V
 	b.hot = n
 	bottom := int(math.Ceil(float64(b.m)/2)) - 1
 	// fmt.Println(bottom)
@@ -181,6 +345,7 @@ func (b *bTree) solveUnderflow(n *node) {
 		ls := p.children[i-1]
 		if len(ls.key) > bottom {
 			// 向父节点借关键码
+			b.stats.Add(1)
 			n.key = insert(n.key, p.key[i-1], 0)
 			n.data = insert(n.data, p.data[i-1], 0)
 			// 用左兄弟中最大的关键码填充父节点中被借出的关键码
@@ -191,7 +356,7 @@ func (b *bTree) solveUnderflow(n *node) {
 			ls.data = ls.data[:len(ls.data)-1]
 			// 过继原来左兄弟最大关键码的右孩子给 n，作为最左面的孩子
 			if len(ls.children) > 0 {
-				n.children = append([]*node{ls.children[len(ls.children)-1]}, n.children...)
+				n.children = append([]*node[K, V]{ls.children[len(ls.children)-1]}, n.children...)
 				if n.children[0] != nil {
 					n.children[0].parent = n
 				}
@@ -206,6 +371,7 @@ func (b *bTree) solveUnderflow(n *node) {
 		rs := p.children[i+1]
 		if len(rs.key) > bottom {
 			// 向父节点借关键码
+			b.stats.Add(1)
 			n.key = insert(n.key, p.key[i], len(n.key))
 			n.data = insert(n.data, p.data[i], len(n.data))
 			// 用右兄弟中最小的关键码填充父节点中被借出的关键码
@@ -227,6 +393,7 @@ func (b *bTree) solveUnderflow(n *node) {
 		}
 	}
 	// 左右兄弟要么不存在，要么都处于自身难保的情况
+	b.stats.Merge()
 	// 与左兄弟合并
 	if i > 0 {
 		ls := p.children[i-1]
@@ -236,9 +403,9 @@ func (b *bTree) solveUnderflow(n *node) {
 		// 删除父节点关键码
 		if i < len(p.key) {
 			copy(p.key[i-1:], p.key[i:])
-			p.key[len(p.key)-1] = nil
+			p.key[len(p.key)-1] = zk
 			copy(p.data[i-1:], p.data[i:])
-			p.data[len(p.data)-1] = nil
+			p.data[len(p.data)-1] = zv
 		}
 		p.key = p.key[:len(p.key)-1]
 		p.data = p.data[:len(p.data)-1]
@@ -260,14 +427,14 @@ func (b *bTree) solveUnderflow(n *node) {
 	} else {
 		// 与右兄弟合并
 		rs := p.children[i+1]
-		rs.key = append([]interface{}{p.key[i]}, rs.key...)
-		rs.data = append([]interface{}{p.data[i]}, rs.data...)
+		rs.key = append([]K{p.key[i]}, rs.key...)
+		rs.data = append([]V{p.data[i]}, rs.data...)
 		// 删除父节点关键码
 		if len(p.key) > i+1 {
 			copy(p.key[i:], p.key[i+1:])
-			p.key[len(p.key)-1] = nil
+			p.key[len(p.key)-1] = zk
 			copy(p.data[i:], p.data[i+1:])
-			p.data[len(p.data)-1] = nil
+			p.data[len(p.data)-1] = zv
 		}
 		p.key = p.key[:len(p.key)-1]
 		p.data = p.data[:len(p.data)-1]
@@ -288,8 +455,12 @@ func (b *bTree) solveUnderflow(n *node) {
 	b.solveUnderflow(p)
 }
 
-func (b *bTree) Print() {
-	q := make([]*node, 1)
+func (b *bTree[K, V]) Print() {
+	if b.root == nil || len(b.root.key) == 0 {
+		fmt.Println("Empty tree!")
+		return
+	}
+	q := make([]*node[K, V], 1)
 	q[0] = b.root
 	levelFirst := b.root
 	for len(q) > 0 {
@@ -319,23 +490,177 @@ func (b *bTree) Print() {
 	fmt.Println()
 }
 
-// LevelOrder only
-func (b *bTree) Walk(o bst.Order, opts ...bst.Option) {
-	if o != bst.LevelOrder {
-		panic("unsupported walk order for B-Tree")
+// Count returns the number of entries of key in O(log n + count)
+func (b *bTree[K, V]) Count(key K) int {
+	cnt := 0
+	b.All(key, func(bst.Node[K, V]) bool {
+		cnt++
+		return true
+	})
+	return cnt
+}
+
+func (b *bTree[K, V]) All(key K, fn func(n bst.Node[K, V]) bool) {
+	b.Range(key, key, true, true, fn)
+}
+
+// RemoveOne removes the oldest entry of key as Remove does
+func (b *bTree[K, V]) RemoveOne(key K) (bst.Node[K, V], error) { return b.Remove(key) }
+
+func (b *bTree[K, V]) RemoveAll(key K) (int, error) { return bst.RemoveEach(key, b.Remove) }
+
+// BuildSorted builds a packed B-tree of the least height from the sorted keys
+// in O(n), every node but the last few on each level is full
+func (b *bTree[K, V]) BuildSorted(keys []K, values []V, verify bool) error {
+	if verify {
+		check := bst.CheckSorted[K]
+		if b.multi {
+			check = bst.CheckSortedMulti[K]
+		}
+		if err := check(keys, b.comp); err != nil {
+			return err
+		}
 	}
-	q := make([]*node, 1)
-	q[0] = b.root
-	for len(q) > 0 {
-		n := q[0]
-		q = q[1:]
-		for _, c := range n.children {
-			if c != nil {
-				q = append(q, c)
-			}
+	b.root, b.hot, b.size = nil, nil, len(keys)
+	if len(keys) == 0 {
+		return nil
+	}
+	h := 0
+	for b.capacity(h, len(keys)) < len(keys) {
+		h++
+	}
+	b.root = b.build(keys, values, nil, h)
+	return nil
+}
+
+// capacity 返回高度为 h 的子树最多容纳的关键码数 m^(h+1)-1，超过 limit 时不再精确
+func (b *bTree[K, V]) capacity(h, limit int) int {
+	c := b.m
+	for ; h > 0 && c <= limit; h-- {
+		c *= b.m
+	}
+	return c - 1
+}
+
+// build 构建高度为 h 的子树，各孩子尽量装满，孩子数至少为 2，关键码平均分给各孩子
+func (b *bTree[K, V]) build(keys []K, values []V, parent *node[K, V], h int) *node[K, V] {
+	n := &node[K, V]{parent: parent}
+	if h == 0 {
+		n.key = append(make([]K, 0, b.m-1), keys...)
+		n.data = append(make([]V, 0, b.m-1), values...)
+		return n
+	}
+	sub := b.capacity(h-1, len(keys)) + 1
+	c := max(2, (len(keys)+sub)/sub) // ceil((len+1)/sub)
+	total := len(keys) - (c - 1)     // 分给孩子的关键码数
+	lo := 0
+	for i := 0; i < c; i++ {
+		cnt := total / c
+		if i < total%c {
+			cnt++
+		}
+		n.children = append(n.children, b.build(keys[lo:lo+cnt], values[lo:lo+cnt], n, h-1))
+		lo += cnt
+		if i < c-1 {
+			n.key = append(n.key, keys[lo])
+			n.data = append(n.data, values[lo])
+			lo++
 		}
+	}
+	return n
+}
+
+// Walk walks the keys by o, PreOrder and PostOrder visit the keys of a node
+// together before or after its branches
+func (b *bTree[K, V]) Walk(o bst.Order, opts ...bst.Option[K, V]) {
+	b.Traverse(o, func(n bst.Node[K, V]) bool {
 		for _, opt := range opts {
 			opt(n)
 		}
+		return true
+	})
+}
+
+// Traverse walks the keys by o as Walk does until fn returns false
+func (b *bTree[K, V]) Traverse(o bst.Order, fn func(n bst.Node[K, V]) bool) bool {
+	if o > bst.ReverseInOrder {
+		panic("unsupported walk order")
+	}
+	if b.root == nil || len(b.root.key) == 0 {
+		return true
+	}
+	// 中序借助迭代器沿父节点移动，其余次序以显式的栈或队列记录待访问的节点
+	switch o {
+	case bst.InOrder, bst.ReverseInOrder:
+		it := &iterator[K, V]{b: b}
+		next, ok := it.Next, it.First()
+		if o == bst.ReverseInOrder {
+			next, ok = it.Prev, it.Last()
+		}
+		for ; ok; ok = next() {
+			if !fn(item[K, V]{it.n, it.i}) {
+				return false
+			}
+		}
+	case bst.PreOrder:
+		stack := []*node[K, V]{b.root}
+		for len(stack) > 0 {
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if !visitKeys(n, fn) {
+				return false
+			}
+			for i := len(n.children) - 1; i >= 0; i-- {
+				if n.children[i] != nil {
+					stack = append(stack, n.children[i])
+				}
+			}
+		}
+	case bst.PostOrder:
+		// 栈中记录节点及其下一个待访问的分支
+		type frame struct {
+			n *node[K, V]
+			c int
+		}
+		stack := []frame{{b.root, 0}}
+		for len(stack) > 0 {
+			f := &stack[len(stack)-1]
+			if f.c < len(f.n.children) {
+				f.c++
+				if c := f.n.children[f.c-1]; c != nil {
+					stack = append(stack, frame{c, 0})
+				}
+				continue
+			}
+			if !visitKeys(f.n, fn) {
+				return false
+			}
+			stack = stack[:len(stack)-1]
+		}
+	case bst.LevelOrder:
+		q := []*node[K, V]{b.root}
+		for len(q) > 0 {
+			n := q[0]
+			q = q[1:]
+			for _, c := range n.children {
+				if c != nil {
+					q = append(q, c)
+				}
+			}
+			if !visitKeys(n, fn) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// visitKeys 依次访问节点 n 中的关键码
+func visitKeys[K, V any](n *node[K, V], fn func(n bst.Node[K, V]) bool) bool {
+	for i := range n.key {
+		if !fn(item[K, V]{n, i}) {
+			return false
+		}
 	}
+	return true
 }
@@ -0,0 +1,493 @@
+package btree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"sort"
+
+	"github.com/mooncaker816/gostructure/bst"
+	"github.com/mooncaker816/gostructure/bst/pager"
+)
+
+const defaultDiskOrder = 32 // 未指定阶数时磁盘 B-树的阶数
+
+var (
+	diskMagic = []byte("BTD1")
+	// ErrNodeTooLarge is returned by the writes of a DiskTree when a node
+	// does not fit in a page, the tree is left unchanged
+	ErrNodeTooLarge = errors.New("btree: node larger than a page")
+	errDiskLayout   = errors.New("btree: corrupt node page")
+)
+
+// DiskTree is a B-tree whose nodes are stored one per page of a
+// pager.Pager, so a large index survives a restart without being rebuilt.
+// Every operation decodes only the nodes on its search path, plus their
+// siblings for a removal, into the nodes of the in-memory B-tree, lets
+// solveOverflow and solveUnderflow split and merge them as they do for New,
+// and writes the changed nodes back. The keys and data are stored by the
+// codecs of a bst.Encoding. A DiskTree is not safe for concurrent use.
+type DiskTree[K, V any] struct {
+	p      *pager.Pager
+	b      *bTree[K, V] // 工作树，操作期间只含装入的节点
+	enc    bst.Encoding[K, V]
+	root   pager.PageID
+	loaded map[*node[K, V]]bool // 本次操作自页面装入的节点
+	closed bool
+}
+
+// OpenDisk opens the disk B-tree stored in the file of path, creating an
+// empty one if the file does not exist. parms are those of New: an int is
+// the order of a new file, 32 by default, an existing file keeps its order;
+// a bst.Encoding gives the codecs of the keys and data, a pager.Options
+// configures the pages. The comparator must order the keys as it did when
+// they were stored.
+func OpenDisk[K, V any](path string, parms ...interface{}) (*DiskTree[K, V], error) {
+	var opts pager.Options
+	m := 0
+	for _, p := range parms {
+		switch v := p.(type) {
+		case pager.Options:
+			opts = v
+		case int:
+			m = v
+		}
+	}
+	p, err := pager.Open(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	t := &DiskTree[K, V]{p: p, b: New[K, V](parms...).(*bTree[K, V])}
+	t.b.multi = false
+	t.enc = t.b.enc.WithDefaults()
+	if len(p.Meta()) == 0 {
+		if m < 3 {
+			m = defaultDiskOrder
+		}
+		t.b.m = m
+		err = p.SetMeta(t.meta())
+	} else {
+		err = t.readMeta(p.Meta())
+	}
+	if err != nil {
+		p.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+// meta 返回记录于头页的元数据：标记、阶数、根节点所在的页及关键码总数
+func (t *DiskTree[K, V]) meta() []byte {
+	buf := append([]byte(nil), diskMagic...)
+	buf = bst.AppendUvarint(buf, t.b.m)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(t.root))
+	return bst.AppendUvarint(buf, t.b.size)
+}
+
+func (t *DiskTree[K, V]) readMeta(data []byte) error {
+	if !bytes.HasPrefix(data, diskMagic) {
+		return pager.ErrCorrupt
+	}
+	m, rest, err := bst.ReadUvarint(data[len(diskMagic):], t.p.PageSize())
+	if err != nil || m < 3 || len(rest) < 4 {
+		return pager.ErrCorrupt
+	}
+	t.root = pager.PageID(binary.LittleEndian.Uint32(rest))
+	size, rest, err := bst.ReadUvarint(rest[4:], int(^uint(0)>>1))
+	if err != nil || len(rest) != 0 || t.root == 0 && size != 0 {
+		return pager.ErrCorrupt
+	}
+	t.b.m, t.b.size = m, size
+	return nil
+}
+
+// Len returns the number of keys in the tree
+func (t *DiskTree[K, V]) Len() int { return t.b.size }
+
+// Order returns the order of the B-tree
+func (t *DiskTree[K, V]) Order() int { return t.b.m }
+
+// Sync writes the changed pages back to the file and commits it to stable
+// storage
+func (t *DiskTree[K, V]) Sync() error { return t.p.Sync() }
+
+// Close syncs and closes the file, the tree can not be used any more
+func (t *DiskTree[K, V]) Close() error {
+	t.closed = true
+	return t.p.Close()
+}
+
+// read 读取并解码第 id 页上的节点，其分支为只记录页号的占位节点
+func (t *DiskTree[K, V]) read(id pager.PageID) (*node[K, V], error) {
+	data, err := t.p.Read(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, errDiskLayout
+	}
+	internal := data[0] != 0
+	cnt, data, err := bst.ReadUvarint(data[1:], t.b.m-1)
+	if err != nil {
+		return nil, errDiskLayout
+	}
+	n := &node[K, V]{page: id, key: make([]K, cnt, t.b.m-1), data: make([]V, cnt, t.b.m-1)}
+	if internal {
+		if len(data) < 4*(cnt+1) {
+			return nil, errDiskLayout
+		}
+		n.children = make([]*node[K, V], cnt+1)
+		for i := range n.children {
+			c := pager.PageID(binary.LittleEndian.Uint32(data[4*i:]))
+			if c == 0 {
+				return nil, errDiskLayout
+			}
+			n.children[i] = &node[K, V]{parent: n, page: c}
+		}
+		data = data[4*(cnt+1):]
+	}
+	for i := 0; i < cnt; i++ {
+		if n.key[i], n.data[i], data, err = bst.ReadEntry(data, t.enc); err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+// begin 开始一次写操作，装入根节点
+func (t *DiskTree[K, V]) begin() error {
+	if t.closed {
+		return pager.ErrClosed
+	}
+	t.loaded = make(map[*node[K, V]]bool)
+	t.b.root, t.b.hot = nil, nil
+	if t.root == 0 {
+		return nil
+	}
+	root, err := t.read(t.root)
+	if err != nil {
+		return err
+	}
+	t.b.root, t.loaded[root] = root, true
+	return nil
+}
+
+// end 结束操作，丢弃工作树，其余的节点仍在页面缓存中
+func (t *DiskTree[K, V]) end(size int) {
+	t.b.root, t.b.hot, t.b.size, t.loaded = nil, nil, size, nil
+}
+
+// child 装入 n 的第 i 个分支，siblings 时一并装入其左右兄弟，供下溢时借用或合并
+func (t *DiskTree[K, V]) child(n *node[K, V], i int, siblings bool) (*node[K, V], error) {
+	lo, hi := i, i
+	if siblings {
+		lo, hi = max(i-1, 0), min(i+1, len(n.children)-1)
+	}
+	for j := lo; j <= hi; j++ {
+		if c := n.children[j]; !t.loaded[c] {
+			c, err := t.read(c.page)
+			if err != nil {
+				return nil, err
+			}
+			c.parent, n.children[j], t.loaded[c] = n, c, true
+		}
+	}
+	return n.children[i], nil
+}
+
+// path 自根下行查找 key 并装入路径上的节点，返回值同 searchIn
+func (t *DiskTree[K, V]) path(key K, siblings bool) (*node[K, V], int, bool, error) {
+	n := t.b.root
+	for {
+		i := sort.Search(len(n.key), func(i int) bool {
+			return t.b.comp(n.key[i], key) >= 0
+		})
+		if i < len(n.key) && t.b.comp(n.key[i], key) == 0 {
+			return n, i, true, nil
+		}
+		if n.children == nil {
+			return n, i, false, nil
+		}
+		var err error
+		if n, err = t.child(n, i, siblings); err != nil {
+			return nil, 0, false, err
+		}
+	}
+}
+
+// stub 检查 n 是否为尚未装入的占位节点，新分裂出的节点尚无页号
+func (t *DiskTree[K, V]) stub(n *node[K, V]) bool {
+	return n.page != 0 && !t.loaded[n]
+}
+
+// commit 将工作树写回页面：先编码全部装入或新建的节点以确认其能放入一页，
+// 再释放被合并掉的节点所在的页，为新节点分配页，最后写入各节点及元数据
+func (t *DiskTree[K, V]) commit() error {
+	var nodes []*node[K, V]
+	var collect func(n *node[K, V])
+	collect = func(n *node[K, V]) {
+		if t.stub(n) {
+			return
+		}
+		nodes = append(nodes, n)
+		for _, c := range n.children {
+			collect(c)
+		}
+	}
+	if t.b.size > 0 {
+		collect(t.b.root)
+	}
+	entries := make([][]byte, len(nodes))
+	reachable := make(map[*node[K, V]]bool, len(nodes))
+	for i, n := range nodes {
+		var err error
+		for j := range n.key {
+			if entries[i], err = bst.AppendEntry(entries[i], n.key[j], n.data[j], t.enc); err != nil {
+				return err
+			}
+		}
+		if pageHeader(n)+len(entries[i]) > t.p.PageSize() {
+			return ErrNodeTooLarge
+		}
+		reachable[n] = true
+	}
+	// 按页号释放，使同样的操作序列得到同样的文件
+	var freed []pager.PageID
+	for n := range t.loaded {
+		if !reachable[n] {
+			freed = append(freed, n.page)
+		}
+	}
+	sort.Slice(freed, func(i, j int) bool { return freed[i] < freed[j] })
+	for _, id := range freed {
+		if err := t.p.Free(id); err != nil {
+			return err
+		}
+	}
+	for _, n := range nodes {
+		if n.page == 0 {
+			var err error
+			if n.page, err = t.p.Alloc(); err != nil {
+				return err
+			}
+		}
+	}
+	// 各节点的页依次为：是否为内部节点、关键码个数、各分支所在的页及各关键码
+	for i, n := range nodes {
+		buf := make([]byte, 0, pageHeader(n)+len(entries[i]))
+		if n.children == nil {
+			buf = append(buf, 0)
+		} else {
+			buf = append(buf, 1)
+		}
+		buf = bst.AppendUvarint(buf, len(n.key))
+		for _, c := range n.children {
+			buf = binary.LittleEndian.AppendUint32(buf, uint32(c.page))
+		}
+		if err := t.p.Write(n.page, append(buf, entries[i]...)); err != nil {
+			return err
+		}
+	}
+	t.root = 0
+	if len(nodes) > 0 {
+		t.root = nodes[0].page
+	}
+	return t.p.SetMeta(t.meta())
+}
+
+// pageHeader 返回节点的页中关键码之前的字节数
+func pageHeader[K, V any](n *node[K, V]) int {
+	return 1 + len(bst.AppendUvarint(nil, len(n.key))) + 4*len(n.children)
+}
+
+// Get returns the data of key
+func (t *DiskTree[K, V]) Get(key K) (data V, ok bool, err error) {
+	defer bst.CatchIncomparable(&err)
+	if t.closed {
+		return data, false, pager.ErrClosed
+	}
+	for id := t.root; id != 0; {
+		n, err := t.read(id)
+		if err != nil {
+			return data, false, err
+		}
+		i := sort.Search(len(n.key), func(i int) bool {
+			return t.b.comp(n.key[i], key) >= 0
+		})
+		if i < len(n.key) && t.b.comp(n.key[i], key) == 0 {
+			return n.data[i], true, nil
+		}
+		if n.children == nil {
+			break
+		}
+		id = n.children[i].page
+	}
+	return data, false, nil
+}
+
+// Insert inserts key with data, or returns bst.ErrDuplicateKey
+func (t *DiskTree[K, V]) Insert(key K, data V) (err error) {
+	defer bst.CatchIncomparable(&err)
+	size := t.b.size
+	if err := t.begin(); err != nil {
+		return err
+	}
+	defer func() { t.end(size) }()
+	if t.b.root == nil {
+		t.b.attach(nil, 0, key, data)
+	} else {
+		n, i, ok, err := t.path(key, false)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return bst.ErrDuplicateKey
+		}
+		t.b.attach(n, i, key, data)
+	}
+	if err := t.commit(); err != nil {
+		return err
+	}
+	size = t.b.size
+	return nil
+}
+
+// Put inserts key with data or replaces its data after a single search
+func (t *DiskTree[K, V]) Put(key K, data V) (old V, replaced bool, err error) {
+	defer bst.CatchIncomparable(&err)
+	size := t.b.size
+	if err := t.begin(); err != nil {
+		return old, false, err
+	}
+	defer func() { t.end(size) }()
+	if t.b.root == nil {
+		t.b.attach(nil, 0, key, data)
+	} else {
+		n, i, ok, err := t.path(key, false)
+		if err != nil {
+			return old, false, err
+		}
+		if ok {
+			old, n.data[i], replaced = n.data[i], data, true
+		} else {
+			t.b.attach(n, i, key, data)
+		}
+	}
+	if err := t.commit(); err != nil {
+		var zero V
+		return zero, false, err
+	}
+	size = t.b.size
+	return old, replaced, nil
+}
+
+// Remove removes key and returns its data, or returns bst.ErrNotFound
+func (t *DiskTree[K, V]) Remove(key K) (data V, err error) {
+	defer bst.CatchIncomparable(&err)
+	size := t.b.size
+	if err := t.begin(); err != nil {
+		return data, err
+	}
+	defer func() { t.end(size) }()
+	if t.b.root == nil {
+		return data, bst.ErrNotFound
+	}
+	n, i, ok, err := t.path(key, true)
+	if err != nil {
+		return data, err
+	}
+	if !ok {
+		return data, bst.ErrNotFound
+	}
+	// 内部节点中的关键码由其后继替代，后继位于右侧分支的最左叶节点
+	if n.children != nil {
+		c, err := t.child(n, i+1, true)
+		for err == nil && c.children != nil {
+			c, err = t.child(c, 0, true)
+		}
+		if err != nil {
+			return data, err
+		}
+	}
+	data = n.data[i]
+	t.b.detach(n, i)
+	if err := t.commit(); err != nil {
+		var zero V
+		return zero, err
+	}
+	size = t.b.size
+	return data, nil
+}
+
+// Range calls fn on the keys in the range from lo to hi in ascending order
+// until fn returns false, loading only the pages overlapping the range
+func (t *DiskTree[K, V]) Range(lo, hi K, loInclusive, hiInclusive bool, fn func(key K, data V) bool) (err error) {
+	defer bst.CatchIncomparable(&err)
+	if t.closed {
+		return pager.ErrClosed
+	}
+	if t.root == 0 {
+		return nil
+	}
+	_, err = t.rangeIn(t.root, lo, hi, loInclusive, hiInclusive, fn)
+	return err
+}
+
+// rangeIn 中序访问第 id 页上的子树中位于区间内的关键码，fn 返回 false 或越过 hi 时返回 false
+func (t *DiskTree[K, V]) rangeIn(id pager.PageID, lo, hi K, loInclusive, hiInclusive bool, fn func(key K, data V) bool) (bool, error) {
+	n, err := t.read(id)
+	if err != nil {
+		return false, err
+	}
+	// 跳过小于 lo 的关键码及其左侧的分支
+	i := sort.Search(len(n.key), func(i int) bool {
+		c := t.b.comp(n.key[i], lo)
+		return c > 0 || c == 0 && loInclusive
+	})
+	for ; ; i++ {
+		if n.children != nil {
+			if more, err := t.rangeIn(n.children[i].page, lo, hi, loInclusive, hiInclusive, fn); !more || err != nil {
+				return false, err
+			}
+		}
+		if i == len(n.key) {
+			return true, nil
+		}
+		if c := t.b.comp(n.key[i], hi); c > 0 || c == 0 && !hiInclusive {
+			return false, nil
+		}
+		if !fn(n.key[i], n.data[i]) {
+			return false, nil
+		}
+	}
+}
+
+// Validate loads the whole tree and checks it as the in-memory B-tree, see
+// Validate of New
+func (t *DiskTree[K, V]) Validate() error {
+	size := t.b.size
+	if err := t.begin(); err != nil {
+		return err
+	}
+	defer t.end(size)
+	if t.b.root == nil {
+		return nil
+	}
+	var load func(n *node[K, V]) error
+	load = func(n *node[K, V]) error {
+		for i := range n.children {
+			c, err := t.child(n, i, false)
+			if err != nil {
+				return err
+			}
+			if err := load(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := load(t.b.root); err != nil {
+		return err
+	}
+	return t.b.Validate()
+}
@@ -0,0 +1,258 @@
+package btree
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+var errLayout = errors.New("btree: decoding an invalid node layout")
+
+// MarshalBinary encodes the tree as configured by the bst.Encoding given to
+// New, a kept shape records the order and the keys of every node
+func (b *bTree[K, V]) MarshalBinary() ([]byte, error) {
+	e := b.enc.WithDefaults()
+	if !e.Shape {
+		return bst.EncodeBinary[K, V](b, bst.BTree, e)
+	}
+	buf := bst.AppendHeader(nil, bst.BTree, true)
+	buf = bst.AppendUvarint(buf, b.size)
+	buf = bst.AppendUvarint(buf, b.m)
+	if b.size == 0 {
+		return buf, nil
+	}
+	return b.appendNode(buf, b.root, e)
+}
+
+// appendNode 先序写入节点的关键码个数、是否为叶子及各关键码
+func (b *bTree[K, V]) appendNode(buf []byte, n *node[K, V], e bst.Encoding[K, V]) ([]byte, error) {
+	buf = bst.AppendUvarint(buf, len(n.key))
+	if n.children == nil {
+		buf = append(buf, 0)
+	} else {
+		buf = append(buf, 1)
+	}
+	var err error
+	for i := range n.key {
+		if buf, err = bst.AppendEntry(buf, n.key[i], n.data[i], e); err != nil {
+			return nil, err
+		}
+	}
+	for _, c := range n.children {
+		if buf, err = b.appendNode(buf, c, e); err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary replaces the content of the tree by data from
+// MarshalBinary, a kept shape also replaces the order of the tree
+func (b *bTree[K, V]) UnmarshalBinary(data []byte) error {
+	e := b.enc.WithDefaults()
+	shape, rest, err := bst.ReadHeader(data, bst.BTree)
+	if err != nil {
+		return err
+	}
+	if !shape {
+		return bst.DecodeBinary[K, V](b, bst.BTree, e, data, bst.ShapeBuilder[K, V]{})
+	}
+	size, rest, err := bst.ReadUvarint(rest, len(rest))
+	if err != nil {
+		return err
+	}
+	m, rest, err := bst.ReadUvarint(rest, len(rest)+3)
+	if err != nil || m < 3 {
+		return errLayout
+	}
+	var root *node[K, V]
+	if size > 0 {
+		if root, rest, err = readNode(rest, m, e); err != nil {
+			return err
+		}
+	}
+	if len(rest) != 0 {
+		return bst.ErrCorrupt
+	}
+	return b.setRoot(root, m, size)
+}
+
+func readNode[K, V any](data []byte, m int, e bst.Encoding[K, V]) (*node[K, V], []byte, error) {
+	cnt, data, err := bst.ReadUvarint(data, m-1)
+	if err != nil || len(data) == 0 {
+		return nil, nil, errLayout
+	}
+	internal := data[0] != 0
+	data = data[1:]
+	n := &node[K, V]{key: make([]K, cnt, m-1), data: make([]V, cnt, m-1)}
+	for i := 0; i < cnt; i++ {
+		if n.key[i], n.data[i], data, err = bst.ReadEntry(data, e); err != nil {
+			return nil, nil, err
+		}
+	}
+	if internal {
+		n.children = make([]*node[K, V], cnt+1)
+		for i := range n.children {
+			if n.children[i], data, err = readNode(data, m, e); err != nil {
+				return nil, nil, err
+			}
+			n.children[i].parent = n
+		}
+	}
+	return n, data, nil
+}
+
+// treeJSON 保留形状时 B-树的 JSON 根
+type treeJSON struct {
+	Order int       `json:"order"`
+	Node  *nodeJSON `json:"node,omitempty"`
+}
+
+type nodeJSON struct {
+	Entries  []bst.EntryJSON `json:"entries"`
+	Children []*nodeJSON     `json:"children,omitempty"`
+}
+
+func (b *bTree[K, V]) MarshalJSON() ([]byte, error) {
+	e := b.enc.WithDefaults()
+	if !e.Shape {
+		return bst.EncodeJSON[K, V](b, bst.BTree, e)
+	}
+	doc := bst.TreeJSON{Version: bst.EncodingVersion, Class: bst.BTree, Shape: true, Len: b.size}
+	root := treeJSON{Order: b.m}
+	var err error
+	if b.size > 0 {
+		if root.Node, err = nodeToJSON(b.root, e); err != nil {
+			return nil, err
+		}
+	}
+	if doc.Root, err = json.Marshal(root); err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+func nodeToJSON[K, V any](n *node[K, V], e bst.Encoding[K, V]) (*nodeJSON, error) {
+	j := &nodeJSON{Entries: make([]bst.EntryJSON, len(n.key))}
+	var err error
+	for i := range n.key {
+		if j.Entries[i], err = bst.MarshalEntry(n.key[i], n.data[i], e); err != nil {
+			return nil, err
+		}
+	}
+	for _, c := range n.children {
+		cj, err := nodeToJSON(c, e)
+		if err != nil {
+			return nil, err
+		}
+		j.Children = append(j.Children, cj)
+	}
+	return j, nil
+}
+
+func (b *bTree[K, V]) UnmarshalJSON(data []byte) error {
+	e := b.enc.WithDefaults()
+	var doc bst.TreeJSON
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if err := doc.Check(bst.BTree); err != nil {
+		return err
+	}
+	if !doc.Shape {
+		return bst.DecodeJSON[K, V](b, bst.BTree, e, data, bst.ShapeBuilder[K, V]{})
+	}
+	var root treeJSON
+	if err := json.Unmarshal(doc.Root, &root); err != nil {
+		return err
+	}
+	if root.Order < 3 {
+		return errLayout
+	}
+	var n *node[K, V]
+	if root.Node != nil {
+		var err error
+		if n, err = nodeFromJSON(root.Node, root.Order, e); err != nil {
+			return err
+		}
+	}
+	return b.setRoot(n, root.Order, doc.Len)
+}
+
+func nodeFromJSON[K, V any](j *nodeJSON, m int, e bst.Encoding[K, V]) (*node[K, V], error) {
+	cnt := len(j.Entries)
+	if cnt > m-1 || j.Children != nil && len(j.Children) != cnt+1 {
+		return nil, errLayout
+	}
+	n := &node[K, V]{key: make([]K, cnt, m-1), data: make([]V, cnt, m-1)}
+	var err error
+	for i, entry := range j.Entries {
+		if n.key[i], n.data[i], err = bst.UnmarshalEntry(entry, e); err != nil {
+			return nil, err
+		}
+	}
+	for _, cj := range j.Children {
+		c, err := nodeFromJSON(cj, m, e)
+		if err != nil {
+			return nil, err
+		}
+		c.parent = n
+		n.children = append(n.children, c)
+	}
+	return n, nil
+}
+
+// setRoot 检查各节点的关键码个数、叶子深度与关键码次序后替换整棵树
+func (b *bTree[K, V]) setRoot(root *node[K, V], m, size int) error {
+	if root != nil {
+		leaf := -1
+		if !checkLayout(root, m, 0, &leaf) {
+			return errLayout
+		}
+	}
+	if countOrdered(&bTree[K, V]{m: m, root: root, comp: b.comp, multi: b.multi}) != size {
+		return errLayout
+	}
+	b.m, b.root, b.hot, b.size = m, root, nil, size
+	return nil
+}
+
+// checkLayout 检查以 n 为根的子树，非根节点至少有 ⌈m/2⌉-1 个关键码，叶子深度相同
+func checkLayout[K, V any](n *node[K, V], m, depth int, leaf *int) bool {
+	min := 1
+	if depth > 0 {
+		min = (m+1)/2 - 1
+	}
+	if len(n.key) < min || len(n.key) > m-1 {
+		return false
+	}
+	if n.children == nil {
+		if *leaf < 0 {
+			*leaf = depth
+		}
+		return *leaf == depth
+	}
+	for _, c := range n.children {
+		if !checkLayout(c, m, depth+1, leaf) {
+			return false
+		}
+	}
+	return true
+}
+
+// countOrdered 返回中序遍历的关键码个数，关键码不严格递增时返回 -1，多重映射中允许相等
+func countOrdered[K, V any](t *bTree[K, V]) int {
+	it := &iterator[K, V]{b: t}
+	if !it.First() {
+		return 0
+	}
+	cnt := 1
+	for prev := it.Key(); it.Next(); prev = it.Key() {
+		if !t.before(prev, it.Key()) {
+			return -1
+		}
+		cnt++
+	}
+	return cnt
+}
@@ -0,0 +1,17 @@
+package btree_test
+
+import (
+	"testing"
+
+	"github.com/mooncaker816/gostructure/bst"
+	"github.com/mooncaker816/gostructure/bst/bsttest"
+	_ "github.com/mooncaker816/gostructure/bst/btree"
+)
+
+func FuzzBTree(f *testing.F) {
+	bsttest.Fuzz(f, bsttest.ClassTarget(bst.BTree, 3))
+}
+
+func FuzzBTreeOrder5(f *testing.F) {
+	bsttest.Fuzz(f, bsttest.ClassTarget(bst.BTree, 5))
+}
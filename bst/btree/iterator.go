@@ -0,0 +1,166 @@
+package btree
+
+import (
+	"sort"
+
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+// iterator 以节点内关键码的秩在 B-树中移动
+type iterator[K, V any] struct {
+	b *bTree[K, V]
+	n *node[K, V]
+	i int
+}
+
+func (b *bTree[K, V]) Iterator() bst.Iterator[K, V] {
+	return &iterator[K, V]{b: b}
+}
+
+func (it *iterator[K, V]) Seek(key K) bool {
+	it.n = nil
+	if it.b.root == nil {
+		return false
+	}
+	// 沿查找路径记录最后一个不小于 key 的关键码，多重映射中更早插入的相等关键码可能位于下层
+	for n := it.b.root; n != nil; {
+		i := sort.Search(len(n.key), func(i int) bool {
+			return it.b.comp(n.key[i], key) >= 0
+		})
+		if i < len(n.key) {
+			it.n, it.i = n, i
+			if !it.b.multi && it.b.comp(n.key[i], key) == 0 {
+				break
+			}
+		}
+		if n.children == nil {
+			break
+		}
+		n = n.children[i]
+	}
+	return it.Valid()
+}
+
+// seekAfter 移动到大于 key 的最小关键码
+func (it *iterator[K, V]) seekAfter(key K) bool {
+	it.n = nil
+	for n := it.b.root; n != nil; {
+		i := sort.Search(len(n.key), func(i int) bool {
+			return it.b.comp(n.key[i], key) > 0
+		})
+		if i < len(n.key) {
+			it.n, it.i = n, i
+		}
+		if n.children == nil {
+			break
+		}
+		n = n.children[i]
+	}
+	return it.Valid()
+}
+
+func (it *iterator[K, V]) First() bool {
+	it.n = nil
+	if it.b.root == nil || len(it.b.root.key) == 0 {
+		return false
+	}
+	it.n, it.i = leftmost(it.b.root), 0
+	return true
+}
+
+func (it *iterator[K, V]) Last() bool {
+	it.n = nil
+	if it.b.root == nil || len(it.b.root.key) == 0 {
+		return false
+	}
+	it.n = rightmost(it.b.root)
+	it.i = len(it.n.key) - 1
+	return true
+}
+
+func (it *iterator[K, V]) Next() bool {
+	if !it.Valid() {
+		return false
+	}
+	// 有右侧分支，则后继为该分支中的最小关键码
+	if it.n.children != nil {
+		it.n, it.i = leftmost(it.n.children[it.i+1]), 0
+		return true
+	}
+	if it.i+1 < len(it.n.key) {
+		it.i++
+		return true
+	}
+	// 叶节点中已是最大关键码，向上找到第一个作为左侧分支的祖先
+	for n := it.n; n.parent != nil; n = n.parent {
+		if r := childRank(n); r < len(n.parent.key) {
+			it.n, it.i = n.parent, r
+			return true
+		}
+	}
+	it.n = nil
+	return false
+}
+
+func (it *iterator[K, V]) Prev() bool {
+	if !it.Valid() {
+		return false
+	}
+	// 有左侧分支，则前驱为该分支中的最大关键码
+	if it.n.children != nil {
+		it.n = rightmost(it.n.children[it.i])
+		it.i = len(it.n.key) - 1
+		return true
+	}
+	if it.i > 0 {
+		it.i--
+		return true
+	}
+	// 叶节点中已是最小关键码，向上找到第一个作为右侧分支的祖先
+	for n := it.n; n.parent != nil; n = n.parent {
+		if r := childRank(n); r > 0 {
+			it.n, it.i = n.parent, r-1
+			return true
+		}
+	}
+	it.n = nil
+	return false
+}
+
+func (it *iterator[K, V]) Valid() bool { return it.n != nil }
+func (it *iterator[K, V]) Key() K      { return it.n.key[it.i] }
+func (it *iterator[K, V]) Data() V     { return it.n.data[it.i] }
+
+// item returns the key the iterator positioned at as a bst.Node
+func (it *iterator[K, V]) item() (bst.Node[K, V], bool) {
+	if !it.Valid() {
+		return nil, false
+	}
+	return item[K, V]{it.n, it.i}, true
+}
+
+// leftmost returns the leaf holding the smallest key of the subtree rooted on n
+func leftmost[K, V any](n *node[K, V]) *node[K, V] {
+	for n.children != nil {
+		n = n.children[0]
+	}
+	return n
+}
+
+// rightmost returns the leaf holding the largest key of the subtree rooted on n
+func rightmost[K, V any](n *node[K, V]) *node[K, V] {
+	for n.children != nil {
+		n = n.children[len(n.children)-1]
+	}
+	return n
+}
+
+// childRank returns the index of n among its parent's children
+func childRank[K, V any](n *node[K, V]) int {
+	for i, c := range n.parent.children {
+		if c == n {
+			return i
+		}
+	}
+	panic("btree: node is not a child of its parent")
+}
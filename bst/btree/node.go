@@ -1,37 +1,29 @@
 package btree
 
-import "github.com/mooncaker816/gostructure/bst"
+import (
+	"github.com/mooncaker816/gostructure/bst"
+	"github.com/mooncaker816/gostructure/bst/pager"
+)
 
-type node struct {
-	parent   *node
-	children []*node       // 分支
-	key      []interface{} // 关键码
-	data     []interface{} // 数据
+type node[K, V any] struct {
+	parent   *node[K, V]
+	children []*node[K, V] // 分支
+	key      []K           // 关键码
+	data     []V           // 数据
+	page     pager.PageID  // 磁盘 B-树中节点所在的页，尚未写入或在内存中的 B-树为 0
 }
 
-func newNode(key, data interface{}, m int) *node {
-	n := new(node)
-	n.key = make([]interface{}, 0, m-1)
-	n.data = make([]interface{}, 0, m-1)
+func newNode[K, V any](key K, data V, m int) *node[K, V] {
+	n := new(node[K, V])
+	n.key = make([]K, 0, m-1)
+	n.data = make([]V, 0, m-1)
+	n.key = append(n.key, key)
+	n.data = append(n.data, data)
 	return n
 }
 
-func (n *node) Key() interface{}  { return n.key }
-func (n *node) Data() interface{} { return n.data }
-func (n *node) Height() int       { return 0 }
-func (n *node) LChild() bst.Node  { return nil }
-func (n *node) RChild() bst.Node  { return nil }
-func (n *node) Parent() bst.Node  { return nil }
-func (n *node) Color() string     { return "" }
-
-func (n *node) SetKey(key interface{})   {}
-func (n *node) SetData(data interface{}) {}
-func (n *node) SetLChild(lc bst.Node)    {}
-func (n *node) SetRChild(rc bst.Node)    {}
-func (n *node) SetParent(p bst.Node)     {}
-
-func (n *node) split(i int) *node {
-	sp := new(node)
+func (n *node[K, V]) split(i int) *node[K, V] {
+	sp := new(node[K, V])
 	sp.key = append(sp.key, n.key[i+1:]...)
 	sp.data = append(sp.data, n.data[i+1:]...)
 	n.key = n.key[:i]
@@ -47,3 +39,40 @@ func (n *node) split(i int) *node {
 	}
 	return sp
 }
+
+// item 指向 B-树节点中的某一个关键码，B-树节点含有多个关键码，
+// 故以 item 作为 bst.Node 返回给调用者
+type item[K, V any] struct {
+	n *node[K, V]
+	i int
+}
+
+func (it item[K, V]) Key() K                 { return it.n.key[it.i] }
+func (it item[K, V]) Data() V                { return it.n.data[it.i] }
+func (it item[K, V]) Height() int            { return 0 }
+func (it item[K, V]) LChild() bst.Node[K, V] { return nil }
+func (it item[K, V]) RChild() bst.Node[K, V] { return nil }
+func (it item[K, V]) Parent() bst.Node[K, V] { return nil }
+func (it item[K, V]) Color() string          { return "" }
+func (it item[K, V]) IsNil() bool            { return false }
+
+// Entries returns the keys and data of the whole node
+func (it item[K, V]) Entries() ([]K, []V) { return it.n.key, it.n.data }
+
+// Children returns the first key of each branch, nil for a leaf
+func (it item[K, V]) Children() []bst.Node[K, V] {
+	var children []bst.Node[K, V]
+	for _, c := range it.n.children {
+		if c == nil || len(c.key) == 0 {
+			continue
+		}
+		children = append(children, item[K, V]{c, 0})
+	}
+	return children
+}
+
+func (it item[K, V]) SetKey(key K)             { it.n.key[it.i] = key }
+func (it item[K, V]) SetData(data V)           { it.n.data[it.i] = data }
+func (it item[K, V]) SetLChild(bst.Node[K, V]) {}
+func (it item[K, V]) SetRChild(bst.Node[K, V]) {}
+func (it item[K, V]) SetParent(bst.Node[K, V]) {}
@@ -0,0 +1,67 @@
+package btree
+
+import "github.com/mooncaker816/gostructure/bst"
+
+func (b *bTree[K, V]) Floor(key K) (bst.Node[K, V], bool) {
+	it := &iterator[K, V]{b: b}
+	it.seekAfter(key)
+	return b.lowerFrom(it)
+}
+
+func (b *bTree[K, V]) Ceiling(key K) (bst.Node[K, V], bool) {
+	it := &iterator[K, V]{b: b}
+	it.Seek(key)
+	return it.item()
+}
+
+func (b *bTree[K, V]) Lower(key K) (bst.Node[K, V], bool) {
+	it := &iterator[K, V]{b: b}
+	it.Seek(key)
+	return b.lowerFrom(it)
+}
+
+// lowerFrom returns the key before the position it seeked to
+func (b *bTree[K, V]) lowerFrom(it *iterator[K, V]) (bst.Node[K, V], bool) {
+	if it.Valid() {
+		it.Prev()
+	} else {
+		it.Last()
+	}
+	return it.item()
+}
+
+func (b *bTree[K, V]) Higher(key K) (bst.Node[K, V], bool) {
+	it := &iterator[K, V]{b: b}
+	it.seekAfter(key)
+	return it.item()
+}
+
+func (b *bTree[K, V]) Min() (bst.Node[K, V], bool) {
+	it := &iterator[K, V]{b: b}
+	it.First()
+	return it.item()
+}
+
+func (b *bTree[K, V]) Max() (bst.Node[K, V], bool) {
+	it := &iterator[K, V]{b: b}
+	it.Last()
+	return it.item()
+}
+
+func (b *bTree[K, V]) Range(lo, hi K, loInclusive, hiInclusive bool, fn func(n bst.Node[K, V]) bool) {
+	it := &iterator[K, V]{b: b}
+	var ok bool
+	if loInclusive {
+		ok = it.Seek(lo)
+	} else {
+		ok = it.seekAfter(lo)
+	}
+	for ; ok; ok = it.Next() {
+		if c := b.comp(it.Key(), hi); c > 0 || c == 0 && !hiInclusive {
+			return
+		}
+		if !fn(item[K, V]{it.n, it.i}) {
+			return
+		}
+	}
+}
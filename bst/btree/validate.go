@@ -0,0 +1,106 @@
+package btree
+
+import (
+	"math"
+
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+// Validate checks the key order, the parent pointers, the fill bounds of the
+// order m and the depth of the leaves of the B-tree
+func (b *bTree[K, V]) Validate() error {
+	if b.root == nil || len(b.root.key) == 0 {
+		if b.size != 0 || b.root != nil && len(b.root.children) > 0 {
+			return bst.NewInvariantError(bst.BTree, bst.RuleSize, nil, "empty root, Len %d", b.size)
+		}
+		return nil
+	}
+	if b.root.parent != nil {
+		return bst.NewInvariantError(bst.BTree, bst.RuleParent, b.root.key[0], "root has parent")
+	}
+	v := validator[K, V]{b: b, bottom: int(math.Ceil(float64(b.m)/2)) - 1, leaf: -1}
+	size, err := v.walk(b.root, nil, nil, 0)
+	if err != nil {
+		return err
+	}
+	if size != b.size {
+		return bst.NewInvariantError(bst.BTree, bst.RuleSize, b.root.key[0], "%d keys, Len %d", size, b.size)
+	}
+	return nil
+}
+
+type validator[K, V any] struct {
+	b      *bTree[K, V]
+	bottom int // 非根节点关键码数的下限
+	leaf   int // 叶节点的深度，-1 为尚未确定
+}
+
+// walk 检查子树，其关键码应在开区间 (lo, hi) 内，多重映射中为闭区间，返回关键码总数
+func (v *validator[K, V]) walk(n *node[K, V], lo, hi *K, depth int) (int, error) {
+	b := v.b
+	first := firstKey(n)
+	if len(n.key) > b.m-1 || n.parent != nil && len(n.key) < v.bottom || len(n.key) == 0 {
+		return 0, bst.NewInvariantError(bst.BTree, bst.RuleFill, first, "%d keys in a node of order %d", len(n.key), b.m)
+	}
+	if len(n.data) != len(n.key) {
+		return 0, bst.NewInvariantError(bst.BTree, bst.RuleSize, first, "%d keys with %d data", len(n.key), len(n.data))
+	}
+	for i, key := range n.key {
+		switch {
+		case i == 0 && lo != nil && !b.before(*lo, key):
+			return 0, bst.NewInvariantError(bst.BTree, bst.RuleOrder, key, "not greater than %v", *lo)
+		case i > 0 && !b.before(n.key[i-1], key):
+			return 0, bst.NewInvariantError(bst.BTree, bst.RuleOrder, key, "not greater than %v", n.key[i-1])
+		case i == len(n.key)-1 && hi != nil && !b.before(key, *hi):
+			return 0, bst.NewInvariantError(bst.BTree, bst.RuleOrder, key, "not less than %v", *hi)
+		}
+	}
+	if len(n.children) == 0 {
+		if v.leaf < 0 {
+			v.leaf = depth
+		}
+		if depth != v.leaf {
+			return 0, bst.NewInvariantError(bst.BTree, bst.RuleDepth, first, "leaf at depth %d, want %d", depth, v.leaf)
+		}
+		return len(n.key), nil
+	}
+	if len(n.children) != len(n.key)+1 {
+		return 0, bst.NewInvariantError(bst.BTree, bst.RuleFill, first, "%d branches for %d keys", len(n.children), len(n.key))
+	}
+	size := len(n.key)
+	for i, c := range n.children {
+		if c == nil {
+			return 0, bst.NewInvariantError(bst.BTree, bst.RuleFill, first, "branch %d is nil", i)
+		}
+		if c.parent != n {
+			return 0, bst.NewInvariantError(bst.BTree, bst.RuleParent, firstKey(c), "parent is not the node of %v", first)
+		}
+		l, h := lo, hi
+		if i > 0 {
+			l = &n.key[i-1]
+		}
+		if i < len(n.key) {
+			h = &n.key[i]
+		}
+		s, err := v.walk(c, l, h, depth+1)
+		if err != nil {
+			return 0, err
+		}
+		size += s
+	}
+	return size, nil
+}
+
+// before 检查 x 是否应位于 y 之前，多重映射中允许相等
+func (b *bTree[K, V]) before(x, y K) bool {
+	c := b.comp(x, y)
+	return c < 0 || c == 0 && b.multi
+}
+
+// firstKey 返回节点的首个关键码，空节点返回 nil
+func firstKey[K, V any](n *node[K, V]) interface{} {
+	if len(n.key) == 0 {
+		return nil
+	}
+	return n.key[0]
+}
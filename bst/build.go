@@ -0,0 +1,69 @@
+package bst
+
+import (
+	"errors"
+	"fmt"
+)
+
+// SortedBuilder is implemented by the trees able to load sorted keys in O(n)
+type SortedBuilder[K, V any] interface {
+	// BuildSorted replaces the content of the tree by keys and values, which
+	// have the same length. keys must be strictly ascending, which is checked
+	// first when verify is true, the tree is left unchanged on error.
+	BuildSorted(keys []K, values []V, verify bool) error
+}
+
+// BuildFlag changes how BuildSorted loads the keys
+type BuildFlag uint8
+
+const (
+	// VerifySorted makes BuildSorted reject the keys not strictly ascending,
+	// otherwise such keys make an invalid tree
+	VerifySorted BuildFlag = 1 << iota
+)
+
+// BuildSorted returns a new tree of class c holding keys and values, which
+// are ascending by key, in O(n). values may be nil for zero data, otherwise
+// it has the same length as keys. A BuildFlag in parms controls the loading,
+// the other parms are passed to NewOf.
+func BuildSorted[K, V any](c Class, keys []K, values []V, parms ...interface{}) (BST[K, V], error) {
+	var flags BuildFlag
+	rest := make([]interface{}, 0, len(parms))
+	for _, p := range parms {
+		if f, ok := p.(BuildFlag); ok {
+			flags |= f
+			continue
+		}
+		rest = append(rest, p)
+	}
+	if values == nil {
+		values = make([]V, len(keys))
+	}
+	if len(values) != len(keys) {
+		return nil, errors.New("bst: keys and values differ in length")
+	}
+	t := NewOf[K, V](c, rest...)
+	if b, ok := t.(SortedBuilder[K, V]); ok {
+		if err := b.BuildSorted(keys, values, flags&VerifySorted != 0); err != nil {
+			return nil, err
+		}
+		return t, nil
+	}
+	// 不支持批量构建的类逐个插入
+	for i, key := range keys {
+		if _, err := t.Insert(key, values[i]); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// CheckSorted returns an error if keys are not strictly ascending by comp
+func CheckSorted[K any](keys []K, comp Comparator[K]) error {
+	for i := 1; i < len(keys); i++ {
+		if comp(keys[i-1], keys[i]) >= 0 {
+			return fmt.Errorf("bst: keys are not strictly ascending at index %d", i)
+		}
+	}
+	return nil
+}
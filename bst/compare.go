@@ -1,19 +1,76 @@
 package bst
 
 import (
+	"cmp"
 	"reflect"
 )
 
-// Comparator 比较器
-type Comparator func(a, b interface{}) int
+// Comparator 比较器，a < b 返回负数，a == b 返回 0，a > b 返回正数
+type Comparator[K any] func(a, b K) int
 
-// BasicCompare 比较大小
+// DefaultCompare 返回 K 的默认比较器，K 为内置的有序类型时直接使用 cmp.Compare，
+// 否则退化为基于反射的 BasicCompare
+func DefaultCompare[K any]() Comparator[K] {
+	if comp, ok := orderedComparator[K](); ok {
+		return comp
+	}
+	return func(a, b K) int { return BasicCompare(a, b) }
+}
+
+// orderedComparator 当 K 为内置的有序类型时返回 cmp.Compare
+func orderedComparator[K any]() (Comparator[K], bool) {
+	var k K
+	switch any(k).(type) {
+	case int:
+		return any(Comparator[int](cmp.Compare[int])).(Comparator[K]), true
+	case int8:
+		return any(Comparator[int8](cmp.Compare[int8])).(Comparator[K]), true
+	case int16:
+		return any(Comparator[int16](cmp.Compare[int16])).(Comparator[K]), true
+	case int32:
+		return any(Comparator[int32](cmp.Compare[int32])).(Comparator[K]), true
+	case int64:
+		return any(Comparator[int64](cmp.Compare[int64])).(Comparator[K]), true
+	case uint:
+		return any(Comparator[uint](cmp.Compare[uint])).(Comparator[K]), true
+	case uint8:
+		return any(Comparator[uint8](cmp.Compare[uint8])).(Comparator[K]), true
+	case uint16:
+		return any(Comparator[uint16](cmp.Compare[uint16])).(Comparator[K]), true
+	case uint32:
+		return any(Comparator[uint32](cmp.Compare[uint32])).(Comparator[K]), true
+	case uint64:
+		return any(Comparator[uint64](cmp.Compare[uint64])).(Comparator[K]), true
+	case uintptr:
+		return any(Comparator[uintptr](cmp.Compare[uintptr])).(Comparator[K]), true
+	case float32:
+		return any(Comparator[float32](cmp.Compare[float32])).(Comparator[K]), true
+	case float64:
+		return any(Comparator[float64](cmp.Compare[float64])).(Comparator[K]), true
+	case string:
+		return any(Comparator[string](cmp.Compare[string])).(Comparator[K]), true
+	}
+	return nil, false
+}
+
+// OrderedCompare 比较 cmp.Ordered 类型的大小
+func OrderedCompare[K cmp.Ordered](a, b K) int { return cmp.Compare(a, b) }
+
+// eraseComparator 将 K 的比较器包装为 interface{} 的比较器
+func eraseComparator[K any](comp Comparator[K]) Comparator[any] {
+	if c, ok := any(comp).(Comparator[any]); ok {
+		return c
+	}
+	return func(a, b interface{}) int { return comp(as[K](a), as[K](b)) }
+}
+
+// BasicCompare 比较大小，类型不同或不受支持时以包装了 ErrIncomparable 的错误 panic
 func BasicCompare(a, b interface{}) int {
 
 	va := reflect.ValueOf(a)
 	vb := reflect.ValueOf(b)
 	if va.Kind() != vb.Kind() {
-		panic("can not compare between different types")
+		panic(incomparable("%T and %T", a, b))
 	}
 	switch va.Kind() {
 	case
@@ -62,6 +119,6 @@ func BasicCompare(a, b interface{}) int {
 		}
 		return 1
 	default:
-		panic("type not support for comparing,should customize compare method")
+		panic(incomparable("%T is not supported, provide a Comparator", a))
 	}
 }
@@ -0,0 +1,9 @@
+//go:build bstdebug
+
+package bst
+
+// Debug is set by the bstdebug build tag, with which every tree validates
+// itself after each Insert and Remove, and the splay tree after each Search,
+// panicking on a broken invariant. Validation takes O(n), so the tag is
+// meant for tests only, such as go test -tags bstdebug.
+const Debug = true
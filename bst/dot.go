@@ -0,0 +1,178 @@
+package bst
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// RenderOptions controls FprintDOT and FprintSVG, the zero value draws the
+// keys only, plus the colors of red-black trees
+type RenderOptions struct {
+	Name string // 图名，默认为 bst
+	// Data draws the data of each key beside it
+	Data bool
+	// Heights draws the height of each binary node, and its balance factor,
+	// the height of its left subtree minus the right one, when it has no
+	// color. The height of red-black nodes is their black height.
+	Heights bool
+}
+
+// drawNode 绘制用的节点，二叉节点的 children 为左右孩子，可为 nil，
+// 多路节点的 children 为各分支
+type drawNode struct {
+	labels   []string
+	color    string
+	note     string // 高度与平衡因子
+	multiway bool
+	children []*drawNode
+
+	id   int
+	x, w float64 // 中心横坐标与宽度
+	lvl  int
+}
+
+// drawTree 将以 n 为根的子树转换为绘制用的节点
+func drawTree[K, V any](n Node[K, V], opts *RenderOptions) *drawNode {
+	if IsNil(n) {
+		return nil
+	}
+	label := func(key K, data V) string {
+		if opts.Data {
+			return fmt.Sprintf("%v: %v", key, data)
+		}
+		return fmt.Sprint(key)
+	}
+	if m, ok := n.(Multiway[K, V]); ok {
+		if keys, data := m.Entries(); keys != nil {
+			d := &drawNode{multiway: true}
+			for i, key := range keys {
+				d.labels = append(d.labels, label(key, data[i]))
+			}
+			for _, c := range m.Children() {
+				d.children = append(d.children, drawTree(c, opts))
+			}
+			return d
+		}
+	}
+	d := &drawNode{labels: []string{label(n.Key(), n.Data())}, color: n.Color()}
+	if opts.Heights {
+		d.note = fmt.Sprintf("h=%d", n.Height())
+		if d.color == "" {
+			d.note += fmt.Sprintf(" b=%d", height(n.LChild())-height(n.RChild()))
+		}
+	}
+	l, r := drawTree(n.LChild(), opts), drawTree(n.RChild(), opts)
+	if l != nil || r != nil {
+		d.children = []*drawNode{l, r}
+	}
+	return d
+}
+
+// height 返回节点的高度，空节点为 -1
+func height[K, V any](n Node[K, V]) int {
+	if IsNil(n) {
+		return -1
+	}
+	return n.Height()
+}
+
+// number 先序为各节点编号
+func (d *drawNode) number(next *int) {
+	d.id = *next
+	*next++
+	for _, c := range d.children {
+		if c != nil {
+			c.number(next)
+		}
+	}
+}
+
+// FprintDOT writes the topology of the subtree rooted at n to w in the
+// Graphviz DOT language. Binary nodes are circles, filled by their color if
+// any, and B-tree nodes are records whose fields lie between the branches.
+func FprintDOT[K, V any](w io.Writer, n Node[K, V], opts *RenderOptions) error {
+	if opts == nil {
+		opts = new(RenderOptions)
+	}
+	name := opts.Name
+	if name == "" {
+		name = "bst"
+	}
+	buf := bufio.NewWriter(w)
+	fmt.Fprintf(buf, "digraph %s {\n", quote(labelEscape(name)))
+	buf.WriteString("\tnode [fontname=\"monospace\"];\n")
+	buf.WriteString("\tedge [arrowhead=none];\n")
+	if root := drawTree(n, opts); root != nil {
+		next := 0
+		root.number(&next)
+		writeDOT(buf, root)
+	}
+	buf.WriteString("}\n")
+	return buf.Flush()
+}
+
+func writeDOT(buf *bufio.Writer, d *drawNode) {
+	if d.multiway {
+		fields := make([]string, 0, 2*len(d.labels)+1)
+		for i, l := range d.labels {
+			fields = append(fields, fmt.Sprintf("<c%d>", i), recordEscape(l))
+		}
+		fields = append(fields, fmt.Sprintf("<c%d>", len(d.labels)))
+		fmt.Fprintf(buf, "\tn%d [shape=record, label=%s];\n", d.id, quote(strings.Join(fields, "|")))
+		for i, c := range d.children {
+			fmt.Fprintf(buf, "\tn%d:c%d -> n%d;\n", d.id, i, c.id)
+		}
+	} else {
+		label := labelEscape(d.labels[0])
+		if d.note != "" {
+			label += `\n` + labelEscape(d.note)
+		}
+		attrs := "shape=circle, label=" + quote(label)
+		switch d.color {
+		case "R":
+			attrs += ", style=filled, fillcolor=red, fontcolor=white"
+		case "B":
+			attrs += ", style=filled, fillcolor=black, fontcolor=white"
+		}
+		fmt.Fprintf(buf, "\tn%d [%s];\n", d.id, attrs)
+		// 仅有一个孩子时以不可见的节点占位，保持左右方向
+		for i, c := range d.children {
+			if c == nil {
+				fmt.Fprintf(buf, "\tn%d_%d [style=invis];\n", d.id, i)
+				fmt.Fprintf(buf, "\tn%d -> n%d_%d [style=invis];\n", d.id, d.id, i)
+				continue
+			}
+			fmt.Fprintf(buf, "\tn%d -> n%d;\n", d.id, c.id)
+		}
+	}
+	for _, c := range d.children {
+		if c != nil {
+			writeDOT(buf, c)
+		}
+	}
+}
+
+// quote 返回带引号的 DOT 字符串
+func quote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+// labelEscape 转义标签中的反斜杠与换行
+func labelEscape(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return strings.ReplaceAll(s, "\n", `\n`)
+}
+
+// recordEscape 转义 record 标签中的特殊字符
+func recordEscape(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ReplaceAll(s, "\n", " ") {
+		if strings.ContainsRune(`\{}|<> `, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
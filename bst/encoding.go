@@ -0,0 +1,472 @@
+package bst
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// EncodingVersion is the version of the binary and JSON formats written by
+// this package, data of a later version is rejected
+const EncodingVersion = 1
+
+var (
+	magic = []byte("BST")
+	// ErrCorrupt is returned when decoding malformed data
+	ErrCorrupt = errors.New("bst: corrupt encoding")
+)
+
+// Codec encodes and decodes the keys or the data of a tree. When a tree is
+// encoded to JSON, the output of Marshal is embedded as a JSON value and
+// must be valid JSON.
+type Codec[T any] interface {
+	Marshal(v T) ([]byte, error)
+	Unmarshal(data []byte) (T, error)
+}
+
+// JSONCodec encodes values by encoding/json, it is the default Codec. Keys
+// and data of type interface{} are decoded as encoding/json does, such as
+// float64 for all numbers.
+type JSONCodec[T any] struct{}
+
+func (JSONCodec[T]) Marshal(v T) ([]byte, error) { return json.Marshal(v) }
+
+func (JSONCodec[T]) Unmarshal(data []byte) (T, error) {
+	var v T
+	err := json.Unmarshal(data, &v)
+	return v, err
+}
+
+// Encoding configures how a tree is serialized by its MarshalBinary and
+// MarshalJSON methods, pass it to New or NewOf.
+type Encoding[K, V any] struct {
+	Key   Codec[K] // JSONCodec if nil
+	Value Codec[V] // JSONCodec if nil
+	// Shape keeps the exact shape of the tree, such as AVL heights, RB colors
+	// and the layout of B-tree nodes, instead of only the sorted contents
+	Shape bool
+}
+
+// WithDefaults returns e with the nil codecs replaced by JSONCodec
+func (e Encoding[K, V]) WithDefaults() Encoding[K, V] {
+	if e.Key == nil {
+		e.Key = JSONCodec[K]{}
+	}
+	if e.Value == nil {
+		e.Value = JSONCodec[V]{}
+	}
+	return e
+}
+
+// eraseEncoding 将 Encoding[K, V] 包装为 Encoding[any, any]
+func eraseEncoding[K, V any](e Encoding[K, V]) Encoding[any, any] {
+	if e0, ok := any(e).(Encoding[any, any]); ok {
+		return e0
+	}
+	e = e.WithDefaults()
+	return Encoding[any, any]{Key: erasedCodec[K]{e.Key}, Value: erasedCodec[V]{e.Value}, Shape: e.Shape}
+}
+
+type erasedCodec[T any] struct {
+	c Codec[T]
+}
+
+func (e erasedCodec[T]) Marshal(v any) ([]byte, error) { return e.c.Marshal(as[T](v)) }
+
+func (e erasedCodec[T]) Unmarshal(data []byte) (any, error) { return e.c.Unmarshal(data) }
+
+// AppendHeader appends the versioned header of a tree of class c
+func AppendHeader(buf []byte, c Class, shape bool) []byte {
+	var flags byte
+	if shape {
+		flags |= 1
+	}
+	buf = append(buf, magic...)
+	return append(buf, EncodingVersion, byte(c), flags)
+}
+
+// ReadHeader reads the header of a tree of class c, reporting whether the
+// shape of the tree is kept
+func ReadHeader(data []byte, c Class) (shape bool, rest []byte, err error) {
+	if len(data) < len(magic)+3 || !bytes.Equal(data[:len(magic)], magic) {
+		return false, nil, ErrCorrupt
+	}
+	data = data[len(magic):]
+	if data[0] == 0 || data[0] > EncodingVersion {
+		return false, nil, fmt.Errorf("bst: unsupported encoding version %d", data[0])
+	}
+	if Class(data[1]) != c {
+		return false, nil, fmt.Errorf("bst: decoding class %d into class %d", data[1], c)
+	}
+	return data[2]&1 != 0, data[3:], nil
+}
+
+// AppendUvarint appends x in the varint format of encoding/binary
+func AppendUvarint(buf []byte, x int) []byte {
+	return binary.AppendUvarint(buf, uint64(x))
+}
+
+// ReadUvarint reads an int written by AppendUvarint, which is not greater
+// than limit
+func ReadUvarint(data []byte, limit int) (int, []byte, error) {
+	x, n := binary.Uvarint(data)
+	if n <= 0 || x > uint64(limit) {
+		return 0, nil, ErrCorrupt
+	}
+	return int(x), data[n:], nil
+}
+
+// AppendEntry appends key and data encoded by the codecs of e
+func AppendEntry[K, V any](buf []byte, key K, data V, e Encoding[K, V]) ([]byte, error) {
+	k, err := e.Key.Marshal(key)
+	if err != nil {
+		return nil, err
+	}
+	v, err := e.Value.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	buf = append(AppendUvarint(buf, len(k)), k...)
+	return append(AppendUvarint(buf, len(v)), v...), nil
+}
+
+// ReadEntry reads key and data written by AppendEntry
+func ReadEntry[K, V any](data []byte, e Encoding[K, V]) (key K, value V, rest []byte, err error) {
+	var k, v []byte
+	if k, data, err = readBytes(data); err != nil {
+		return
+	}
+	if v, data, err = readBytes(data); err != nil {
+		return
+	}
+	if key, err = e.Key.Unmarshal(k); err != nil {
+		return
+	}
+	value, err = e.Value.Unmarshal(v)
+	return key, value, data, err
+}
+
+// readBytes 读取带长度前缀的字节串，长度须在读出前缀后与剩余数据比较，截断的数据返回 ErrCorrupt
+func readBytes(data []byte) ([]byte, []byte, error) {
+	n, rest, err := ReadUvarint(data, len(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	if n > len(rest) {
+		return nil, nil, ErrCorrupt
+	}
+	return rest[:n], rest[n:], nil
+}
+
+// ShapeBuilder restores the nodes of a binary tree class from its shape
+type ShapeBuilder[K, V any] struct {
+	// Comp orders the keys, which are checked to be in order
+	Comp Comparator[K]
+	// Multi allows equal keys, for the trees in MultiKeys mode
+	Multi bool
+	// Node returns a new node with the children l and r, which may be nil,
+	// or an error if the node breaks the rules of the class
+	Node func(key K, data V, black bool, l, r Node[K, V]) (Node[K, V], error)
+	// SetRoot replaces the content of the tree by the subtree rooted on root,
+	// or returns an error if root can not be the root of the class
+	SetRoot func(root Node[K, V]) error
+}
+
+// node 检查关键码次序后新建节点
+func (sb ShapeBuilder[K, V]) node(key K, data V, black bool, l, r Node[K, V]) (Node[K, V], error) {
+	if max, ok := Max(l); ok && !sb.before(max.Key(), key) {
+		return nil, ErrCorrupt
+	}
+	if min, ok := Min(r); ok && !sb.before(key, min.Key()) {
+		return nil, ErrCorrupt
+	}
+	return sb.Node(key, data, black, l, r)
+}
+
+func (sb ShapeBuilder[K, V]) before(a, b K) bool {
+	c := sb.Comp(a, b)
+	return c < 0 || c == 0 && sb.Multi
+}
+
+// EncodeBinary returns the binary encoding of t of class c, a binary tree
+// whose shape is kept by the pre-order of its nodes when e.Shape is set
+func EncodeBinary[K, V any](t BST[K, V], c Class, e Encoding[K, V]) ([]byte, error) {
+	e = e.WithDefaults()
+	buf := AppendHeader(nil, c, e.Shape)
+	buf = AppendUvarint(buf, t.Len())
+	if e.Shape {
+		if t.Len() == 0 {
+			return buf, nil
+		}
+		return appendShape(buf, t.Root(), e)
+	}
+	var err error
+	it := t.Iterator()
+	for ok := it.First(); ok && err == nil; ok = it.Next() {
+		buf, err = AppendEntry(buf, it.Key(), it.Data(), e)
+	}
+	return buf, err
+}
+
+// appendShape 先序写入节点，每个节点以一个字节标记左右孩子是否存在及颜色
+func appendShape[K, V any](buf []byte, n Node[K, V], e Encoding[K, V]) ([]byte, error) {
+	var flags byte
+	if HasLChild(n) {
+		flags |= 1
+	}
+	if HasRChild(n) {
+		flags |= 2
+	}
+	if n.Color() == "B" {
+		flags |= 4
+	}
+	buf, err := AppendEntry(append(buf, flags), n.Key(), n.Data(), e)
+	if err == nil && HasLChild(n) {
+		buf, err = appendShape(buf, n.LChild(), e)
+	}
+	if err == nil && HasRChild(n) {
+		buf, err = appendShape(buf, n.RChild(), e)
+	}
+	return buf, err
+}
+
+// DecodeBinary replaces the content of t of class c by data written by
+// EncodeBinary. Sorted contents are loaded by BuildSorted, while a kept shape
+// is restored by sb as it is.
+func DecodeBinary[K, V any](t BST[K, V], c Class, e Encoding[K, V], data []byte, sb ShapeBuilder[K, V]) error {
+	e = e.WithDefaults()
+	shape, data, err := ReadHeader(data, c)
+	if err != nil {
+		return err
+	}
+	n, data, err := ReadUvarint(data, len(data))
+	if err != nil {
+		return err
+	}
+	if shape {
+		if sb.Node == nil {
+			return fmt.Errorf("bst: class %d can not restore a shape", c)
+		}
+		var root Node[K, V]
+		if n > 0 {
+			if root, data, err = readShape(data, e, sb); err != nil {
+				return err
+			}
+		}
+		if len(data) != 0 || Size(root) != n {
+			return ErrCorrupt
+		}
+		return sb.SetRoot(root)
+	}
+	keys, values := make([]K, n), make([]V, n)
+	for i := range keys {
+		if keys[i], values[i], data, err = ReadEntry(data, e); err != nil {
+			return err
+		}
+	}
+	if len(data) != 0 {
+		return ErrCorrupt
+	}
+	return load(t, keys, values)
+}
+
+func readShape[K, V any](data []byte, e Encoding[K, V], sb ShapeBuilder[K, V]) (Node[K, V], []byte, error) {
+	if len(data) == 0 {
+		return nil, nil, ErrCorrupt
+	}
+	flags := data[0]
+	key, value, data, err := ReadEntry(data[1:], e)
+	if err != nil {
+		return nil, nil, err
+	}
+	var l, r Node[K, V]
+	if flags&1 != 0 {
+		if l, data, err = readShape(data, e, sb); err != nil {
+			return nil, nil, err
+		}
+	}
+	if flags&2 != 0 {
+		if r, data, err = readShape(data, e, sb); err != nil {
+			return nil, nil, err
+		}
+	}
+	n, err := sb.node(key, value, flags&4 != 0, l, r)
+	return n, data, err
+}
+
+// load 以有序的关键码替换 t 的内容
+func load[K, V any](t BST[K, V], keys []K, values []V) error {
+	if b, ok := t.(SortedBuilder[K, V]); ok {
+		return b.BuildSorted(keys, values, true)
+	}
+	if t.Len() > 0 {
+		return errors.New("bst: decoding into a non-empty tree")
+	}
+	for i, key := range keys {
+		if _, err := t.Insert(key, values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TreeJSON is the JSON document of a tree, holding either its sorted
+// entries or the shape of its root node
+type TreeJSON struct {
+	Version int             `json:"version"`
+	Class   Class           `json:"class"`
+	Shape   bool            `json:"shape"`
+	Len     int             `json:"len"`
+	Entries []EntryJSON     `json:"entries,omitempty"`
+	Root    json.RawMessage `json:"root,omitempty"`
+}
+
+// EntryJSON is a key and its data encoded by the codecs of an Encoding
+type EntryJSON struct {
+	Key   json.RawMessage `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Check returns an error if doc is not of class c or of a later version
+func (doc *TreeJSON) Check(c Class) error {
+	if doc.Version == 0 || doc.Version > EncodingVersion {
+		return fmt.Errorf("bst: unsupported encoding version %d", doc.Version)
+	}
+	if doc.Class != c {
+		return fmt.Errorf("bst: decoding class %d into class %d", doc.Class, c)
+	}
+	return nil
+}
+
+// MarshalEntry encodes key and data by the codecs of e
+func MarshalEntry[K, V any](key K, data V, e Encoding[K, V]) (EntryJSON, error) {
+	k, err := e.Key.Marshal(key)
+	if err != nil {
+		return EntryJSON{}, err
+	}
+	v, err := e.Value.Marshal(data)
+	return EntryJSON{k, v}, err
+}
+
+// UnmarshalEntry decodes an entry written by MarshalEntry
+func UnmarshalEntry[K, V any](entry EntryJSON, e Encoding[K, V]) (K, V, error) {
+	key, err := e.Key.Unmarshal(entry.Key)
+	if err != nil {
+		var zero V
+		return key, zero, err
+	}
+	value, err := e.Value.Unmarshal(entry.Value)
+	return key, value, err
+}
+
+// nodeJSON 二叉树节点，颜色与高度仅供阅读，解码时以颜色为准
+type nodeJSON struct {
+	EntryJSON
+	Color  string    `json:"color,omitempty"`
+	Height int       `json:"height,omitempty"`
+	Left   *nodeJSON `json:"left,omitempty"`
+	Right  *nodeJSON `json:"right,omitempty"`
+}
+
+// EncodeJSON returns the JSON encoding of t of class c, a binary tree
+// whose shape is kept as nested nodes when e.Shape is set
+func EncodeJSON[K, V any](t BST[K, V], c Class, e Encoding[K, V]) ([]byte, error) {
+	e = e.WithDefaults()
+	doc := TreeJSON{Version: EncodingVersion, Class: c, Shape: e.Shape, Len: t.Len()}
+	var err error
+	if e.Shape {
+		var root *nodeJSON
+		if root, err = shapeJSON(t.Root(), e); err == nil && root != nil {
+			doc.Root, err = json.Marshal(root)
+		}
+	} else {
+		doc.Entries = make([]EntryJSON, 0, t.Len())
+		it := t.Iterator()
+		for ok := it.First(); ok && err == nil; ok = it.Next() {
+			var entry EntryJSON
+			entry, err = MarshalEntry(it.Key(), it.Data(), e)
+			doc.Entries = append(doc.Entries, entry)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+func shapeJSON[K, V any](n Node[K, V], e Encoding[K, V]) (*nodeJSON, error) {
+	if IsNil(n) {
+		return nil, nil
+	}
+	entry, err := MarshalEntry(n.Key(), n.Data(), e)
+	if err != nil {
+		return nil, err
+	}
+	j := &nodeJSON{EntryJSON: entry, Color: n.Color(), Height: n.Height()}
+	if j.Left, err = shapeJSON(n.LChild(), e); err != nil {
+		return nil, err
+	}
+	j.Right, err = shapeJSON(n.RChild(), e)
+	return j, err
+}
+
+// DecodeJSON replaces the content of t of class c by data written by
+// EncodeJSON, see DecodeBinary
+func DecodeJSON[K, V any](t BST[K, V], c Class, e Encoding[K, V], data []byte, sb ShapeBuilder[K, V]) error {
+	e = e.WithDefaults()
+	var doc TreeJSON
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if err := doc.Check(c); err != nil {
+		return err
+	}
+	if doc.Shape {
+		if sb.Node == nil {
+			return fmt.Errorf("bst: class %d can not restore a shape", c)
+		}
+		var j *nodeJSON
+		if len(doc.Root) > 0 {
+			if err := json.Unmarshal(doc.Root, &j); err != nil {
+				return err
+			}
+		}
+		root, err := nodeOf(j, e, sb)
+		if err != nil {
+			return err
+		}
+		if Size(root) != doc.Len {
+			return ErrCorrupt
+		}
+		return sb.SetRoot(root)
+	}
+	keys, values := make([]K, len(doc.Entries)), make([]V, len(doc.Entries))
+	for i, entry := range doc.Entries {
+		var err error
+		if keys[i], values[i], err = UnmarshalEntry(entry, e); err != nil {
+			return err
+		}
+	}
+	return load(t, keys, values)
+}
+
+func nodeOf[K, V any](j *nodeJSON, e Encoding[K, V], sb ShapeBuilder[K, V]) (Node[K, V], error) {
+	if j == nil {
+		return nil, nil
+	}
+	key, value, err := UnmarshalEntry(j.EntryJSON, e)
+	if err != nil {
+		return nil, err
+	}
+	l, err := nodeOf(j.Left, e, sb)
+	if err != nil {
+		return nil, err
+	}
+	r, err := nodeOf(j.Right, e, sb)
+	if err != nil {
+		return nil, err
+	}
+	return sb.node(key, value, j.Color == "B", l, r)
+}
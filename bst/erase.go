@@ -0,0 +1,222 @@
+package bst
+
+// Erase returns t as a BST with interface{} keys and data, which is how a
+// class whose keys are not plain values registers itself by Register.
+// Keys and data passed to the returned BST must be of type K and V.
+func Erase[K, V any](t BST[K, V]) BST[any, any] {
+	if t0, ok := any(t).(BST[any, any]); ok {
+		return t0
+	}
+	return &erasedBST[K, V]{t: t}
+}
+
+// erasedBST 将 BST[K, V] 包装为以 interface{} 为键值的 BST
+type erasedBST[K, V any] struct {
+	t BST[K, V]
+}
+
+func (t *erasedBST[K, V]) Search(key any) (Node[any, any], bool) {
+	n, ok := t.t.Search(as[K](key))
+	return eraseNode(n), ok
+}
+
+func (t *erasedBST[K, V]) Insert(key, data any) (Node[any, any], error) {
+	n, err := t.t.Insert(as[K](key), as[V](data))
+	return eraseNode(n), err
+}
+
+func (t *erasedBST[K, V]) Remove(key any) (Node[any, any], error) {
+	n, err := t.t.Remove(as[K](key))
+	return eraseNode(n), err
+}
+
+func (t *erasedBST[K, V]) Root() Node[any, any] { return eraseNode(t.t.Root()) }
+
+func (t *erasedBST[K, V]) Len() int { return t.t.Len() }
+
+func (t *erasedBST[K, V]) unwrap() interface{} { return t.t }
+
+func (t *erasedBST[K, V]) Print() { t.t.Print() }
+
+func (t *erasedBST[K, V]) Walk(o Order, opts ...Option[any, any]) {
+	typed := make([]Option[K, V], len(opts))
+	for i, opt := range opts {
+		opt := opt
+		typed[i] = func(n Node[K, V]) { opt(eraseNode(n)) }
+	}
+	t.t.Walk(o, typed...)
+}
+
+func (t *erasedBST[K, V]) Traverse(o Order, fn func(n Node[any, any]) bool) bool {
+	return Visit(t.t, o, func(n Node[K, V]) bool { return fn(eraseNode(n)) })
+}
+
+func (t *erasedBST[K, V]) Iterator() Iterator[any, any] {
+	return erasedIterator[K, V]{t.t.Iterator()}
+}
+
+func (t *erasedBST[K, V]) Floor(key any) (Node[any, any], bool) {
+	n, ok := t.t.Floor(as[K](key))
+	return eraseNode(n), ok
+}
+
+func (t *erasedBST[K, V]) Ceiling(key any) (Node[any, any], bool) {
+	n, ok := t.t.Ceiling(as[K](key))
+	return eraseNode(n), ok
+}
+
+func (t *erasedBST[K, V]) Lower(key any) (Node[any, any], bool) {
+	n, ok := t.t.Lower(as[K](key))
+	return eraseNode(n), ok
+}
+
+func (t *erasedBST[K, V]) Higher(key any) (Node[any, any], bool) {
+	n, ok := t.t.Higher(as[K](key))
+	return eraseNode(n), ok
+}
+
+func (t *erasedBST[K, V]) Min() (Node[any, any], bool) {
+	n, ok := t.t.Min()
+	return eraseNode(n), ok
+}
+
+func (t *erasedBST[K, V]) Max() (Node[any, any], bool) {
+	n, ok := t.t.Max()
+	return eraseNode(n), ok
+}
+
+func (t *erasedBST[K, V]) Range(lo, hi any, loInclusive, hiInclusive bool, fn func(n Node[any, any]) bool) {
+	t.t.Range(as[K](lo), as[K](hi), loInclusive, hiInclusive, func(n Node[K, V]) bool {
+		return fn(eraseNode(n))
+	})
+}
+
+func (t *erasedBST[K, V]) BuildSorted(keys, values []any, verify bool) error {
+	b, ok := t.t.(SortedBuilder[K, V])
+	if !ok {
+		for i, key := range keys {
+			if _, err := t.Insert(key, values[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	keys0 := make([]K, len(keys))
+	values0 := make([]V, len(values))
+	for i := range keys {
+		keys0[i], values0[i] = as[K](keys[i]), as[V](values[i])
+	}
+	return b.BuildSorted(keys0, values0, verify)
+}
+
+func (t *erasedBST[K, V]) SelfAdjusting() bool { return selfAdjusting(t.t) }
+
+func (t *erasedBST[K, V]) Validate() error { return Validate(t.t) }
+
+func (t *erasedBST[K, V]) Put(key, data any) (any, bool, error) {
+	return Put(t.t, as[K](key), as[V](data))
+}
+
+func (t *erasedBST[K, V]) Update(key any, fn func(old any, ok bool) (any, bool)) error {
+	return Update(t.t, as[K](key), func(old V, ok bool) (V, bool) {
+		data, keep := fn(old, ok)
+		return as[V](data), keep
+	})
+}
+
+func (t *erasedBST[K, V]) GetOrInsert(key, data any) (Node[any, any], bool, error) {
+	n, found, err := GetOrInsert(t.t, as[K](key), as[V](data))
+	return eraseNode(n), found, err
+}
+
+func (t *erasedBST[K, V]) Count(key any) int { return Count(t.t, as[K](key)) }
+
+func (t *erasedBST[K, V]) All(key any, fn func(n Node[any, any]) bool) {
+	All(t.t, as[K](key), func(n Node[K, V]) bool { return fn(eraseNode(n)) })
+}
+
+func (t *erasedBST[K, V]) RemoveOne(key any) (Node[any, any], error) { return t.Remove(key) }
+
+func (t *erasedBST[K, V]) RemoveAll(key any) (int, error) { return RemoveAll(t.t, as[K](key)) }
+
+type erasedIterator[K, V any] struct {
+	it Iterator[K, V]
+}
+
+func (it erasedIterator[K, V]) Seek(key any) bool { return it.it.Seek(as[K](key)) }
+func (it erasedIterator[K, V]) First() bool       { return it.it.First() }
+func (it erasedIterator[K, V]) Last() bool        { return it.it.Last() }
+func (it erasedIterator[K, V]) Next() bool        { return it.it.Next() }
+func (it erasedIterator[K, V]) Prev() bool        { return it.it.Prev() }
+func (it erasedIterator[K, V]) Valid() bool       { return it.it.Valid() }
+func (it erasedIterator[K, V]) Key() any          { return it.it.Key() }
+func (it erasedIterator[K, V]) Data() any         { return it.it.Data() }
+
+// erasedNode 将 Node[K, V] 包装为 Node[any, any]，其值可比较
+type erasedNode[K, V any] struct {
+	n Node[K, V]
+}
+
+func eraseNode[K, V any](n Node[K, V]) Node[any, any] {
+	if IsNil(n) {
+		return nil
+	}
+	return erasedNode[K, V]{n}
+}
+
+func uneraseNode[K, V any](n Node[any, any]) Node[K, V] {
+	if IsNil(n) {
+		return nil
+	}
+	return n.(erasedNode[K, V]).n
+}
+
+func (n erasedNode[K, V]) LChild() Node[any, any]     { return eraseNode(n.n.LChild()) }
+func (n erasedNode[K, V]) RChild() Node[any, any]     { return eraseNode(n.n.RChild()) }
+func (n erasedNode[K, V]) Parent() Node[any, any]     { return eraseNode(n.n.Parent()) }
+func (n erasedNode[K, V]) SetLChild(c Node[any, any]) { n.n.SetLChild(uneraseNode[K, V](c)) }
+func (n erasedNode[K, V]) SetRChild(c Node[any, any]) { n.n.SetRChild(uneraseNode[K, V](c)) }
+func (n erasedNode[K, V]) SetParent(p Node[any, any]) { n.n.SetParent(uneraseNode[K, V](p)) }
+func (n erasedNode[K, V]) Key() any                   { return n.n.Key() }
+func (n erasedNode[K, V]) Data() any                  { return n.n.Data() }
+func (n erasedNode[K, V]) SetKey(key any)             { n.n.SetKey(as[K](key)) }
+func (n erasedNode[K, V]) SetData(data any)           { n.n.SetData(as[V](data)) }
+func (n erasedNode[K, V]) Height() int                { return n.n.Height() }
+func (n erasedNode[K, V]) Color() string              { return n.n.Color() }
+func (n erasedNode[K, V]) Level() int                 { return levelOf(n.n) }
+func (n erasedNode[K, V]) IsNil() bool                { return false } // eraseNode 不包装 nil 节点
+
+func (n erasedNode[K, V]) Entries() ([]any, []any) {
+	m, ok := n.n.(Multiway[K, V])
+	if !ok {
+		return nil, nil
+	}
+	keys, data := m.Entries()
+	if keys == nil {
+		return nil, nil
+	}
+	ek, ed := make([]any, len(keys)), make([]any, len(data))
+	for i := range keys {
+		ek[i] = keys[i]
+	}
+	for i := range data {
+		ed[i] = data[i]
+	}
+	return ek, ed
+}
+
+func (n erasedNode[K, V]) Children() []Node[any, any] {
+	m, ok := n.n.(Multiway[K, V])
+	if !ok {
+		return nil
+	}
+	children := m.Children()
+	if children == nil {
+		return nil
+	}
+	erased := make([]Node[any, any], len(children))
+	for i, c := range children {
+		erased[i] = eraseNode(c)
+	}
+	return erased
+}
@@ -0,0 +1,41 @@
+package bst
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrDuplicateKey is returned by Insert when the key is already present
+	ErrDuplicateKey = errors.New("bst: duplicate key")
+	// ErrNotFound is returned by Remove when the key is absent
+	ErrNotFound = errors.New("bst: key not found")
+	// ErrIncomparable is returned by the writes of the trees when their keys
+	// can not be compared, such as keys of different types under
+	// BasicCompare. The reads, which return no error, panic with an error
+	// wrapping it instead.
+	ErrIncomparable = errors.New("bst: incomparable keys")
+	// ErrInvalidOption is returned by Make for an invalid TreeOption, one
+	// the class does not support or types of keys and data it can not hold
+	ErrInvalidOption = errors.New("bst: invalid option")
+)
+
+// incomparable 返回包装了 ErrIncomparable 的错误
+func incomparable(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: "+format, append([]interface{}{ErrIncomparable}, args...)...)
+}
+
+// CatchIncomparable recovers a panic with an error wrapping ErrIncomparable
+// into *err, any other panic goes on. The trees defer it in their writes,
+// whose comparisons all happen before the tree is modified.
+func CatchIncomparable(err *error) {
+	p := recover()
+	if p == nil {
+		return
+	}
+	if e, ok := p.(error); ok && errors.Is(e, ErrIncomparable) {
+		*err = e
+		return
+	}
+	panic(p)
+}
@@ -0,0 +1,254 @@
+package bst
+
+import (
+	"encoding"
+	"encoding/json"
+)
+
+// complete 汇集 NewOf 返回的树所实现的可选接口
+type complete[K, V any] interface {
+	BST[K, V]
+	Traverser[K, V]
+	OrderStatistic[K, V]
+	Summarizer[K]
+	SortedBuilder[K, V]
+	Splitter[K, V]
+	Upserter[K, V]
+	Multimap[K, V]
+	SelfAdjusting
+	Validator
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+	json.Marshaler
+	json.Unmarshaler
+}
+
+// withFallbacks 返回实现了全部可选接口的 t，缺少其中一些时包装为 fallbackBST
+func withFallbacks[K, V any](t BST[K, V], c Class, parms []interface{}) BST[K, V] {
+	if _, ok := t.(complete[K, V]); ok {
+		return t
+	}
+	return &fallbackBST[K, V]{BST: t, c: c, parms: parms}
+}
+
+// fallbackBST 为 Factory.Typed 或 RegisterOf 构造的树补齐其类别未实现的可选接口，
+// 同 typedBST 一样退化为逐个访问或移动关键码，但不必装箱关键码与数据
+type fallbackBST[K, V any] struct {
+	BST[K, V]
+	c     Class // 创建树所用的类别与参数
+	parms []interface{}
+}
+
+func (t *fallbackBST[K, V]) unwrap() interface{} { return t.BST }
+
+func (t *fallbackBST[K, V]) Traverse(o Order, fn func(n Node[K, V]) bool) bool {
+	return Visit(t.BST, o, fn)
+}
+
+func (t *fallbackBST[K, V]) Select(k int) (Node[K, V], bool) {
+	if os, ok := t.BST.(OrderStatistic[K, V]); ok {
+		return os.Select(k)
+	}
+	return selectScan(t.BST, k)
+}
+
+func (t *fallbackBST[K, V]) Rank(key K) int {
+	if os, ok := t.BST.(OrderStatistic[K, V]); ok {
+		return os.Rank(key)
+	}
+	return countScan(t.BST, nil, &key, false)
+}
+
+func (t *fallbackBST[K, V]) CountRange(lo, hi K) int {
+	if os, ok := t.BST.(OrderStatistic[K, V]); ok {
+		return os.CountRange(lo, hi)
+	}
+	return countScan(t.BST, &lo, &hi, true)
+}
+
+func (t *fallbackBST[K, V]) Summary() any {
+	if s, ok := t.BST.(Summarizer[K]); ok {
+		return s.Summary()
+	}
+	return nil
+}
+
+func (t *fallbackBST[K, V]) SummaryRange(lo, hi K) any {
+	if s, ok := t.BST.(Summarizer[K]); ok {
+		return s.SummaryRange(lo, hi)
+	}
+	return nil
+}
+
+func (t *fallbackBST[K, V]) BuildSorted(keys []K, values []V, verify bool) error {
+	if b, ok := t.BST.(SortedBuilder[K, V]); ok {
+		return b.BuildSorted(keys, values, verify)
+	}
+	return insertEach(t.BST, keys, values)
+}
+
+func (t *fallbackBST[K, V]) Split(key K) BST[K, V] {
+	if s, ok := t.BST.(Splitter[K, V]); ok {
+		return &fallbackBST[K, V]{BST: s.Split(key), c: t.c, parms: t.parms}
+	}
+	other := &fallbackBST[K, V]{BST: NewOf[K, V](t.c, t.parms...), c: t.c, parms: t.parms}
+	if o, ok := other.BST.(*fallbackBST[K, V]); ok {
+		other.BST = o.BST
+	}
+	splitScan(t.BST, other.BST, key)
+	return other
+}
+
+func (t *fallbackBST[K, V]) Join(other BST[K, V]) error {
+	o, ok := other.(*fallbackBST[K, V])
+	if !ok || o.c != t.c {
+		return ErrJoinClass
+	}
+	if s, ok := t.BST.(Splitter[K, V]); ok {
+		return s.Join(o.BST)
+	}
+	return joinScan(t.BST, o.BST)
+}
+
+func (t *fallbackBST[K, V]) MarshalBinary() ([]byte, error) {
+	if m, ok := t.BST.(encoding.BinaryMarshaler); ok {
+		return m.MarshalBinary()
+	}
+	return nil, unsupported(t.c, "binary encoding")
+}
+
+func (t *fallbackBST[K, V]) UnmarshalBinary(data []byte) error {
+	if u, ok := t.BST.(encoding.BinaryUnmarshaler); ok {
+		return u.UnmarshalBinary(data)
+	}
+	return unsupported(t.c, "binary decoding")
+}
+
+func (t *fallbackBST[K, V]) MarshalJSON() ([]byte, error) {
+	if m, ok := t.BST.(json.Marshaler); ok {
+		return m.MarshalJSON()
+	}
+	return nil, unsupported(t.c, "JSON encoding")
+}
+
+func (t *fallbackBST[K, V]) UnmarshalJSON(data []byte) error {
+	if u, ok := t.BST.(json.Unmarshaler); ok {
+		return u.UnmarshalJSON(data)
+	}
+	return unsupported(t.c, "JSON decoding")
+}
+
+func (t *fallbackBST[K, V]) SelfAdjusting() bool { return selfAdjusting(t.BST) }
+
+func (t *fallbackBST[K, V]) Validate() error { return Validate(t.BST) }
+
+func (t *fallbackBST[K, V]) Put(key K, data V) (V, bool, error) { return Put(t.BST, key, data) }
+
+func (t *fallbackBST[K, V]) Update(key K, fn func(old V, ok bool) (V, bool)) error {
+	return Update(t.BST, key, fn)
+}
+
+func (t *fallbackBST[K, V]) GetOrInsert(key K, data V) (Node[K, V], bool, error) {
+	return GetOrInsert(t.BST, key, data)
+}
+
+func (t *fallbackBST[K, V]) Count(key K) int { return Count(t.BST, key) }
+
+func (t *fallbackBST[K, V]) All(key K, fn func(n Node[K, V]) bool) { All(t.BST, key, fn) }
+
+func (t *fallbackBST[K, V]) RemoveOne(key K) (Node[K, V], error) {
+	if m, ok := t.BST.(Multimap[K, V]); ok {
+		return m.RemoveOne(key)
+	}
+	return t.BST.Remove(key)
+}
+
+func (t *fallbackBST[K, V]) RemoveAll(key K) (int, error) { return RemoveAll(t.BST, key) }
+
+// selectScan 按升序访问节点，返回第 k 个节点，自 0 起计
+func selectScan[K, V any](t BST[K, V], k int) (Node[K, V], bool) {
+	var n Node[K, V]
+	if k >= 0 && k < t.Len() {
+		scanRange(t, nil, nil, false, func(m Node[K, V]) bool {
+			if k == 0 {
+				n = m
+			}
+			k--
+			return k >= 0
+		})
+	}
+	return n, !IsNil(n)
+}
+
+// countScan 统计 [lo, hi) 或 [lo, hi] 中的关键码个数，lo 为 nil 时自最小关键码开始
+func countScan[K, V any](t BST[K, V], lo, hi *K, hiInclusive bool) int {
+	c := 0
+	scanRange(t, lo, hi, hiInclusive, func(Node[K, V]) bool {
+		c++
+		return true
+	})
+	return c
+}
+
+// scanRange 按升序访问 lo 与 hi 之间的节点，lo 或 hi 为 nil 时不设该侧边界
+func scanRange[K, V any](t BST[K, V], lo, hi *K, hiInclusive bool, fn func(Node[K, V]) bool) {
+	if lo == nil {
+		min, ok := t.Min()
+		if !ok {
+			return
+		}
+		key := min.Key()
+		lo = &key
+	}
+	if hi == nil {
+		max, ok := t.Max()
+		if !ok {
+			return
+		}
+		key := max.Key()
+		hi, hiInclusive = &key, true
+	}
+	t.Range(*lo, *hi, true, hiInclusive, fn)
+}
+
+// insertEach 逐个插入关键码，用于不支持批量构建的类
+func insertEach[K, V any](t BST[K, V], keys []K, values []V) error {
+	for i, key := range keys {
+		if _, err := t.Insert(key, values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitScan 将 t 中不小于 key 的关键码逐个移入 other
+func splitScan[K, V any](t, other BST[K, V], key K) {
+	var keys []K
+	var data []V
+	scanRange(t, &key, nil, true, func(n Node[K, V]) bool {
+		keys, data = append(keys, n.Key()), append(data, n.Data())
+		return true
+	})
+	for i, k := range keys {
+		t.Remove(k)
+		other.Insert(k, data[i])
+	}
+}
+
+// joinScan 将 other 的关键码逐个移入 t，两者的关键码范围重叠时返回 ErrJoinOverlap
+func joinScan[K, V any](t, other BST[K, V]) error {
+	min, ok1 := other.Min()
+	max, ok2 := t.Max()
+	if ok1 && ok2 {
+		lo, hi := min.Key(), max.Key()
+		if countScan(t, &lo, &hi, true) > 0 {
+			return ErrJoinOverlap
+		}
+	}
+	for min, ok := other.Min(); ok; min, ok = other.Min() {
+		k, data := min.Key(), min.Data()
+		other.Remove(k)
+		t.Insert(k, data)
+	}
+	return nil
+}
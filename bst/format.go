@@ -8,23 +8,40 @@ import (
 	"strconv"
 )
 
-// Print 以子树节点个数的位数为一个基本单元的长度，打印子树的拓扑结构到标准输出
-func Print(n Node) {
-	PrintWithUnitSize(n, len(strconv.Itoa(Size(n))))
+// Print 以子树节点个数的位数为一个基本单元的长度，打印子树的拓扑结构到标准输出，
+// 红黑树节点后标注颜色，AA 树节点后标注层数
+func Print[K, V any](n Node[K, V]) {
+	PrintWithUnitSize(n, unitSize(n))
 }
 
 // Fprint 以树节点个数的位数为一个基本单元的长度，打印子树的拓扑结构到io.Writer
-func Fprint(n Node, w io.Writer) {
-	FprintWithUnitSize(n, w, len(strconv.Itoa(Size(n))))
+func Fprint[K, V any](n Node[K, V], w io.Writer) {
+	FprintWithUnitSize(n, w, unitSize(n))
+}
+
+// unitSize 基本单元的长度为子树节点个数的位数，按层数平衡时再留出根节点（层数最高）的层数标记
+func unitSize[K, V any](n Node[K, V]) int {
+	size := len(strconv.Itoa(Size(n)))
+	if !IsNil(n) {
+		if lv := levelOf(n); lv > 0 {
+			size += len(levelMark(lv))
+		}
+	}
+	return size
+}
+
+// levelMark 节点层数的标记
+func levelMark(lv int) string {
+	return ":" + strconv.Itoa(lv)
 }
 
 // PrintWithUnitSize 以指定的长度为一个基本单元，打印子树的拓扑结构到标准输出
-func PrintWithUnitSize(n Node, size int) {
+func PrintWithUnitSize[K, V any](n Node[K, V], size int) {
 	FprintWithUnitSize(n, os.Stdout, size)
 }
 
 // FprintWithUnitSize 以指定的长度为一个基本单元，打印子树的拓扑结构到io.Writer，树宽为节点数
-func FprintWithUnitSize(n Node, w io.Writer, size int) {
+func FprintWithUnitSize[K, V any](n Node[K, V], w io.Writer, size int) {
 	buf := bufio.NewWriter(w)
 	if IsNil(n) {
 		buf.WriteString("Empty tree!")
@@ -36,7 +53,7 @@ func FprintWithUnitSize(n Node, w io.Writer, size int) {
 	}
 
 	total := Size(n)
-	q := make([]nodePos, 0, total)
+	q := make([]nodePos[K, V], 0, total)
 	prevlevel := 0
 	line := make([]rune, total*size+1)
 	for i := range line {
@@ -50,7 +67,7 @@ func FprintWithUnitSize(n Node, w io.Writer, size int) {
 	if !IsNil(n.RChild()) {
 		right = mid + Size(n.RChild().LChild()) + 1
 	}
-	q = append(q, nodePos{n, left, mid, right})
+	q = append(q, nodePos[K, V]{n, left, mid, right})
 	for len(q) > 0 {
 		np := q[0]
 		q = q[1:]
@@ -68,11 +85,11 @@ func FprintWithUnitSize(n Node, w io.Writer, size int) {
 
 		if HasLChild(n) {
 			left, mid, right = np.computelchildPos()
-			q = append(q, nodePos{n.LChild(), left, mid, right})
+			q = append(q, nodePos[K, V]{n.LChild(), left, mid, right})
 		}
 		if HasRChild(n) {
 			left, mid, right = np.computerchildPos()
-			q = append(q, nodePos{n.RChild(), left, mid, right})
+			q = append(q, nodePos[K, V]{n.RChild(), left, mid, right})
 		}
 	}
 	buf.WriteString("\n")
@@ -83,7 +100,7 @@ func FprintWithUnitSize(n Node, w io.Writer, size int) {
 	buf.Flush()
 }
 
-func (np nodePos) fillNode(line []rune, size int) {
+func (np nodePos[K, V]) fillNode(line []rune, size int) {
 	if HasLChild(np.node) {
 		i := np.left * size
 		// for ; i < np.left*size; i++ {
@@ -99,7 +116,11 @@ func (np nodePos) fillNode(line []rune, size int) {
 		}
 	}
 	i := np.mid * size
-	for _, r := range fmt.Sprintf("%*v%s", size, np.node.Key(), np.node.Color()) {
+	label := fmt.Sprintf("%*v%s", size, np.node.Key(), np.node.Color())
+	if lv := levelOf(np.node); lv > 0 {
+		label = fmt.Sprintf("%*s", size, fmt.Sprint(np.node.Key())+levelMark(lv))
+	}
+	for _, r := range label {
 		line[i] = r
 		i++
 	}
@@ -114,12 +135,12 @@ func (np nodePos) fillNode(line []rune, size int) {
 	}
 }
 
-type nodePos struct {
-	node             Node
+type nodePos[K, V any] struct {
+	node             Node[K, V]
 	left, mid, right int
 }
 
-func (np nodePos) computelchildPos() (left, mid, right int) {
+func (np nodePos[K, V]) computelchildPos() (left, mid, right int) {
 	if IsNil(np.node) {
 		return
 	}
@@ -141,7 +162,7 @@ func (np nodePos) computelchildPos() (left, mid, right int) {
 	return
 }
 
-func (np nodePos) computerchildPos() (left, mid, right int) {
+func (np nodePos[K, V]) computerchildPos() (left, mid, right int) {
 	if IsNil(np.node) {
 		return
 	}
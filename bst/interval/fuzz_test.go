@@ -0,0 +1,18 @@
+package interval_test
+
+import (
+	"testing"
+
+	"github.com/mooncaker816/gostructure/bst"
+	"github.com/mooncaker816/gostructure/bst/bsttest"
+	"github.com/mooncaker816/gostructure/bst/interval"
+)
+
+// 关键码 k 映射为 [k/4, k/4+k%4]，按 (Lo, Hi) 保持顺序
+func FuzzInterval(f *testing.F) {
+	bsttest.Fuzz(f, bsttest.Target[interval.Interval[int]]{
+		Name: "interval",
+		New:  func() bst.BST[interval.Interval[int], int] { return interval.New[int, int]() },
+		Key:  func(k int) interval.Interval[int] { return interval.Interval[int]{Lo: k / 4, Hi: k/4 + k%4} },
+	})
+}
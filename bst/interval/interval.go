@@ -0,0 +1,348 @@
+package interval
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+
+	"github.com/mooncaker816/gostructure/bst"
+	"github.com/mooncaker816/gostructure/bst/redblack"
+)
+
+func init() {
+	bst.Register("interval", bst.Factory{
+		New: newAny,
+		Typed: []interface{}{
+			newOf[int, int], newOf[int, string], newOf[int, any],
+			newOf[float64, int], newOf[float64, string], newOf[float64, any],
+			newOf[string, int], newOf[string, string], newOf[string, any],
+			newOf[any, any],
+		},
+		TypedOnly: true,
+	})
+}
+
+// newOf 为 bst.NewOf[Interval[K], V] 构造区间树，其他端点类型须以 bst.RegisterOf 注册
+func newOf[K, V any](parms ...interface{}) bst.BST[Interval[K], V] {
+	return New[K, V](parms...)
+}
+
+// ErrInverted is returned when an interval to insert has Lo greater than Hi
+var ErrInverted = errors.New("interval: lo is greater than hi")
+
+// Interval is the closed interval [Lo, Hi]
+type Interval[K any] struct {
+	Lo, Hi K
+}
+
+// Tree is an interval tree built on a red-black tree ordered by (Lo, Hi),
+// every node of which keeps the max Hi of its subtree.
+type Tree[K, V any] struct {
+	bst.BST[Interval[K], V]
+	comp bst.Comparator[K]
+}
+
+// maxHi 子树中区间右端点的最大值
+type maxHi[K any] struct {
+	hi K
+	ok bool
+}
+
+// New returns an empty interval tree, a Comparator[K] or func(a, b K) int in
+// parms orders the endpoints, a bst.Encoding[Interval[K], V] configures its
+// serialization. The concurrency contract is the one of the
+// red-black tree: parallel reads are safe, writes need exclusive access.
+func New[K, V any](parms ...interface{}) *Tree[K, V] {
+	t := new(Tree[K, V])
+	t.comp = bst.DefaultCompare[K]()
+	var enc bst.Encoding[Interval[K], V]
+	for _, p := range parms {
+		switch v := p.(type) {
+		case bst.Comparator[K]:
+			t.comp = v
+		case func(a, b K) int:
+			t.comp = v
+		case bst.Encoding[Interval[K], V]:
+			enc = v
+		}
+	}
+	t.BST = redblack.New[Interval[K], V](bst.Comparator[Interval[K]](t.compare), bst.Augment(maxHi[K]{}, t.aggregate), enc)
+	return t
+}
+
+func (t *Tree[K, V]) compare(a, b Interval[K]) int {
+	if c := t.comp(a.Lo, b.Lo); c != 0 {
+		return c
+	}
+	return t.comp(a.Hi, b.Hi)
+}
+
+func (t *Tree[K, V]) aggregate(left maxHi[K], n bst.Node[Interval[K], V], right maxHi[K]) maxHi[K] {
+	m := maxHi[K]{n.Key().Hi, true}
+	if left.ok && t.comp(left.hi, m.hi) > 0 {
+		m = left
+	}
+	if right.ok && t.comp(right.hi, m.hi) > 0 {
+		m = right
+	}
+	return m
+}
+
+// check 拒绝左端点大于右端点的区间，其会破坏子树右端点最大值的维护
+func (t *Tree[K, V]) check(key Interval[K]) (err error) {
+	defer bst.CatchIncomparable(&err)
+	if t.comp(key.Lo, key.Hi) > 0 {
+		return ErrInverted
+	}
+	return nil
+}
+
+// Insert inserts the interval key with data, failing with ErrInverted if
+// key.Lo is greater than key.Hi
+func (t *Tree[K, V]) Insert(key Interval[K], data V) (bst.Node[Interval[K], V], error) {
+	if err := t.check(key); err != nil {
+		return nil, err
+	}
+	return t.BST.Insert(key, data)
+}
+
+// InsertInterval inserts [lo, hi] with data
+func (t *Tree[K, V]) InsertInterval(lo, hi K, data V) (bst.Node[Interval[K], V], error) {
+	return t.Insert(Interval[K]{lo, hi}, data)
+}
+
+// RemoveInterval removes [lo, hi] and returns the removed entry as Remove
+// does, or bst.ErrNotFound
+func (t *Tree[K, V]) RemoveInterval(lo, hi K) (bst.Node[Interval[K], V], error) {
+	return t.Remove(Interval[K]{lo, hi})
+}
+
+// Stab returns the nodes of all intervals containing x by start order
+func (t *Tree[K, V]) Stab(x K) []bst.Node[Interval[K], V] {
+	return t.Overlapping(x, x)
+}
+
+// Overlapping returns the nodes of all intervals intersecting [lo, hi] by
+// start order
+func (t *Tree[K, V]) Overlapping(lo, hi K) []bst.Node[Interval[K], V] {
+	var found []bst.Node[Interval[K], V]
+	t.overlapping(t.Root(), lo, hi, &found)
+	return found
+}
+
+func (t *Tree[K, V]) overlapping(n bst.Node[Interval[K], V], lo, hi K, found *[]bst.Node[Interval[K], V]) {
+	// 子树中所有区间的右端点都小于 lo，不可能相交
+	if bst.IsNil(n) || t.comp(n.(bst.Augmented).Summary().(maxHi[K]).hi, lo) < 0 {
+		return
+	}
+	t.overlapping(n.LChild(), lo, hi, found)
+	// 当前及右子树中区间的左端点都大于 hi，不可能相交
+	if t.comp(n.Key().Lo, hi) > 0 {
+		return
+	}
+	if t.comp(n.Key().Hi, lo) >= 0 {
+		*found = append(*found, n)
+	}
+	t.overlapping(n.RChild(), lo, hi, found)
+}
+
+// Querier is implemented by Tree, the trees returned by
+// bst.NewOf[Interval[K], V](bst.IntervalTree) and those returned by
+// bst.New(bst.IntervalTree), whose keys are Interval[any]
+type Querier[K, V any] interface {
+	InsertInterval(lo, hi K, data V) (bst.Node[Interval[K], V], error)
+	RemoveInterval(lo, hi K) (bst.Node[Interval[K], V], error)
+	Stab(x K) []bst.Node[Interval[K], V]
+	Overlapping(lo, hi K) []bst.Node[Interval[K], V]
+}
+
+// anyTree 为注册到 bst 的区间树，以 Interval[any] 为键
+type anyTree struct {
+	bst.BST[any, any]
+	t *Tree[any, any]
+}
+
+func newAny(parms ...interface{}) bst.BST[any, any] {
+	t := New[any, any](parms...)
+	return &anyTree{bst.Erase[Interval[any], any](t), t}
+}
+
+func (a *anyTree) InsertInterval(lo, hi, data any) (bst.Node[Interval[any], any], error) {
+	return a.t.InsertInterval(lo, hi, data)
+}
+
+func (a *anyTree) RemoveInterval(lo, hi any) (bst.Node[Interval[any], any], error) {
+	return a.t.RemoveInterval(lo, hi)
+}
+
+func (a *anyTree) Stab(x any) []bst.Node[Interval[any], any] { return a.t.Stab(x) }
+
+func (a *anyTree) Overlapping(lo, hi any) []bst.Node[Interval[any], any] {
+	return a.t.Overlapping(lo, hi)
+}
+
+// MarshalBinary, UnmarshalBinary, MarshalJSON and UnmarshalJSON serialize
+// the underlying red-black tree
+func (t *Tree[K, V]) MarshalBinary() ([]byte, error) {
+	return t.BST.(encoding.BinaryMarshaler).MarshalBinary()
+}
+
+func (t *Tree[K, V]) UnmarshalBinary(data []byte) error {
+	return t.BST.(encoding.BinaryUnmarshaler).UnmarshalBinary(data)
+}
+
+func (t *Tree[K, V]) MarshalJSON() ([]byte, error) {
+	return t.BST.(json.Marshaler).MarshalJSON()
+}
+
+func (t *Tree[K, V]) UnmarshalJSON(data []byte) error {
+	return t.BST.(json.Unmarshaler).UnmarshalJSON(data)
+}
+
+func (a *anyTree) MarshalBinary() ([]byte, error)    { return a.t.MarshalBinary() }
+func (a *anyTree) UnmarshalBinary(data []byte) error { return a.t.UnmarshalBinary(data) }
+func (a *anyTree) MarshalJSON() ([]byte, error)      { return a.t.MarshalJSON() }
+func (a *anyTree) UnmarshalJSON(data []byte) error   { return a.t.UnmarshalJSON(data) }
+
+// Validate checks the underlying red-black tree and the max Hi kept by every
+// node
+func (t *Tree[K, V]) Validate() error {
+	if err := bst.Validate(t.BST); err != nil {
+		return err
+	}
+	_, err := bst.ValidateBinary(bst.IntervalTree, t.Root(), t.compare, func(n bst.Node[Interval[K], V]) error {
+		m := t.aggregate(summary(n.LChild()), n, summary(n.RChild()))
+		if got := summary(n); !got.ok || t.comp(got.hi, m.hi) != 0 {
+			return bst.NewInvariantError(bst.IntervalTree, bst.RuleSummary, n.Key(), "max hi %v, want %v", got.hi, m.hi)
+		}
+		return nil
+	})
+	return err
+}
+
+// summary 返回子树中区间右端点的最大值
+func summary[K, V any](n bst.Node[Interval[K], V]) maxHi[K] {
+	if bst.IsNil(n) {
+		return maxHi[K]{}
+	}
+	return n.(bst.Augmented).Summary().(maxHi[K])
+}
+
+func (a *anyTree) Validate() error { return a.t.Validate() }
+
+// Put, Update and GetOrInsert forward to the red-black tree, see
+// bst.Upserter, and fail with ErrInverted as Insert does
+func (t *Tree[K, V]) Put(key Interval[K], data V) (old V, replaced bool, err error) {
+	if err = t.check(key); err != nil {
+		return old, false, err
+	}
+	return bst.Put(t.BST, key, data)
+}
+
+func (t *Tree[K, V]) Update(key Interval[K], fn func(old V, ok bool) (V, bool)) error {
+	if err := t.check(key); err != nil {
+		return err
+	}
+	return bst.Update(t.BST, key, fn)
+}
+
+func (t *Tree[K, V]) GetOrInsert(key Interval[K], data V) (bst.Node[Interval[K], V], bool, error) {
+	if err := t.check(key); err != nil {
+		return nil, false, err
+	}
+	return bst.GetOrInsert(t.BST, key, data)
+}
+
+func (a *anyTree) Put(key, data any) (any, bool, error) { return bst.Put(a.BST, key, data) }
+
+func (a *anyTree) Update(key any, fn func(old any, ok bool) (any, bool)) error {
+	return bst.Update(a.BST, key, fn)
+}
+
+func (a *anyTree) GetOrInsert(key, data any) (bst.Node[any, any], bool, error) {
+	return bst.GetOrInsert(a.BST, key, data)
+}
+
+// Traverse forwards to the red-black tree, see bst.Traverser
+func (t *Tree[K, V]) Traverse(o bst.Order, fn func(n bst.Node[Interval[K], V]) bool) bool {
+	return bst.Visit(t.BST, o, fn)
+}
+
+func (a *anyTree) Traverse(o bst.Order, fn func(n bst.Node[any, any]) bool) bool {
+	return bst.Visit(a.BST, o, fn)
+}
+
+// Select, Rank and CountRange forward to the red-black tree, see
+// bst.OrderStatistic
+func (t *Tree[K, V]) Select(k int) (bst.Node[Interval[K], V], bool) {
+	return t.BST.(bst.OrderStatistic[Interval[K], V]).Select(k)
+}
+
+func (t *Tree[K, V]) Rank(key Interval[K]) int {
+	return t.BST.(bst.OrderStatistic[Interval[K], V]).Rank(key)
+}
+
+func (t *Tree[K, V]) CountRange(lo, hi Interval[K]) int {
+	return t.BST.(bst.OrderStatistic[Interval[K], V]).CountRange(lo, hi)
+}
+
+// Summary returns the greatest Hi of the intervals, nil if the tree is empty
+func (t *Tree[K, V]) Summary() any {
+	return t.BST.(bst.Summarizer[Interval[K]]).Summary().(maxHi[K]).value()
+}
+
+// SummaryRange returns the greatest Hi of the intervals in [lo, hi] by
+// (Lo, Hi) order, nil if there is none
+func (t *Tree[K, V]) SummaryRange(lo, hi Interval[K]) any {
+	return t.BST.(bst.Summarizer[Interval[K]]).SummaryRange(lo, hi).(maxHi[K]).value()
+}
+
+// value 返回右端点的最大值，空子树为 nil
+func (m maxHi[K]) value() any {
+	if !m.ok {
+		return nil
+	}
+	return m.hi
+}
+
+// BuildSorted loads the intervals by the red-black tree, see
+// bst.SortedBuilder, failing with ErrInverted as Insert does
+func (t *Tree[K, V]) BuildSorted(keys []Interval[K], values []V, verify bool) error {
+	for _, key := range keys {
+		if err := t.check(key); err != nil {
+			return err
+		}
+	}
+	return t.BST.(bst.SortedBuilder[Interval[K], V]).BuildSorted(keys, values, verify)
+}
+
+// Split and Join cut and concatenate the red-black tree, see bst.Splitter,
+// the tree split off is an interval tree too
+func (t *Tree[K, V]) Split(key Interval[K]) bst.BST[Interval[K], V] {
+	return &Tree[K, V]{BST: t.BST.(bst.Splitter[Interval[K], V]).Split(key), comp: t.comp}
+}
+
+func (t *Tree[K, V]) Join(other bst.BST[Interval[K], V]) error {
+	o, ok := other.(*Tree[K, V])
+	if !ok {
+		return bst.ErrJoinClass
+	}
+	return t.BST.(bst.Splitter[Interval[K], V]).Join(o.BST)
+}
+
+// Count, All, RemoveOne and RemoveAll see bst.Multimap, an interval tree
+// keeps no duplicate intervals
+func (t *Tree[K, V]) Count(key Interval[K]) int { return bst.Count(t.BST, key) }
+
+func (t *Tree[K, V]) All(key Interval[K], fn func(n bst.Node[Interval[K], V]) bool) {
+	bst.All(t.BST, key, fn)
+}
+
+func (t *Tree[K, V]) RemoveOne(key Interval[K]) (bst.Node[Interval[K], V], error) {
+	return t.Remove(key)
+}
+
+func (t *Tree[K, V]) RemoveAll(key Interval[K]) (int, error) { return bst.RemoveAll(t.BST, key) }
+
+// SelfAdjusting reports false, reads do not restructure the tree
+func (t *Tree[K, V]) SelfAdjusting() bool { return false }
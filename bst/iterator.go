@@ -0,0 +1,72 @@
+package bst
+
+// Iterator is a bidirectional cursor over the keys of a BST in ascending
+// order. An iterator is invalidated by any Insert or Remove on its tree.
+type Iterator[K, V any] interface {
+	// Seek moves to the smallest key not less than key
+	Seek(key K) bool
+	// First moves to the smallest key
+	First() bool
+	// Last moves to the largest key
+	Last() bool
+	// Next moves to the next larger key
+	Next() bool
+	// Prev moves to the next smaller key
+	Prev() bool
+	// Valid reports whether the iterator is positioned at a key
+	Valid() bool
+	Key() K
+	Data() V
+}
+
+// iterator walks a binary tree by Successor/Predecessor
+type iterator[K, V any] struct {
+	t    BST[K, V]
+	comp Comparator[K]
+	n    Node[K, V]
+}
+
+// NewIterator returns an Iterator over the binary tree t ordered by comp,
+// moving the iterator never changes the shape of t.
+func NewIterator[K, V any](t BST[K, V], comp Comparator[K]) Iterator[K, V] {
+	return &iterator[K, V]{t: t, comp: comp}
+}
+
+func (it *iterator[K, V]) Seek(key K) bool {
+	it.n, _ = Ceiling(it.t.Root(), key, it.comp)
+	return it.Valid()
+}
+
+func (it *iterator[K, V]) First() bool {
+	it.n = nil
+	if root := it.t.Root(); !IsNil(root) {
+		it.n = subTreeMin(root)
+	}
+	return it.Valid()
+}
+
+func (it *iterator[K, V]) Last() bool {
+	it.n = nil
+	if root := it.t.Root(); !IsNil(root) {
+		it.n = subTreeMax(root)
+	}
+	return it.Valid()
+}
+
+func (it *iterator[K, V]) Next() bool {
+	if it.Valid() {
+		it.n = Successor(it.n)
+	}
+	return it.Valid()
+}
+
+func (it *iterator[K, V]) Prev() bool {
+	if it.Valid() {
+		it.n = Predecessor(it.n)
+	}
+	return it.Valid()
+}
+
+func (it *iterator[K, V]) Valid() bool { return !IsNil(it.n) }
+func (it *iterator[K, V]) Key() K      { return it.n.Key() }
+func (it *iterator[K, V]) Data() V     { return it.n.Data() }
@@ -0,0 +1,50 @@
+package llrb
+
+import (
+	"errors"
+
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+// MarshalBinary encodes the tree as configured by the bst.Encoding given to New
+func (t *llrb[K, V]) MarshalBinary() ([]byte, error) {
+	return bst.EncodeBinary[K, V](t, bst.LLRB, t.enc)
+}
+
+// UnmarshalBinary replaces the content of the tree by data from MarshalBinary
+func (t *llrb[K, V]) UnmarshalBinary(data []byte) error {
+	return bst.DecodeBinary[K, V](t, bst.LLRB, t.enc, data, t.shapeBuilder())
+}
+
+func (t *llrb[K, V]) MarshalJSON() ([]byte, error) {
+	return bst.EncodeJSON[K, V](t, bst.LLRB, t.enc)
+}
+
+func (t *llrb[K, V]) UnmarshalJSON(data []byte) error {
+	return bst.DecodeJSON[K, V](t, bst.LLRB, t.enc, data, t.shapeBuilder())
+}
+
+var errColor = errors.New("llrb: decoding an invalid coloring")
+
+// shapeBuilder 按原有形状与颜色重建节点，拒绝右倾或连续的红链接及黑高度不等的节点
+func (t *llrb[K, V]) shapeBuilder() bst.ShapeBuilder[K, V] {
+	return bst.ShapeBuilder[K, V]{
+		Comp:  t.comp,
+		Multi: t.multi,
+		Node: func(key K, data V, black bool, l, r bst.Node[K, V]) (bst.Node[K, V], error) {
+			l0, r0 := nodeOf(l), nodeOf(r)
+			if blackHeight(l0) != blackHeight(r0) || isRed(r0) || !black && isRed(l0) {
+				return nil, errColor
+			}
+			return link(l0, &node[K, V]{key: key, data: data, red: !black}, r0, t.updates), nil
+		},
+		SetRoot: func(root bst.Node[K, V]) error {
+			n := nodeOf(root)
+			if isRed(n) {
+				return errColor
+			}
+			t.root = orphan(n)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,13 @@
+package llrb_test
+
+import (
+	"testing"
+
+	"github.com/mooncaker816/gostructure/bst"
+	"github.com/mooncaker816/gostructure/bst/bsttest"
+	_ "github.com/mooncaker816/gostructure/bst/llrb"
+)
+
+func FuzzLLRB(f *testing.F) {
+	bsttest.Fuzz(f, bsttest.ClassTarget(bst.LLRB))
+}
@@ -0,0 +1,488 @@
+// Package llrb implements Sedgewick's left-leaning red-black tree, a
+// redblack tree whose red links lean left so that it mirrors a 2-3 tree one
+// to one. Insertion and deletion are the recursive descents of the 2-3 tree
+// fixed up on the way back by three local rules, a simpler but slower
+// alternative to the bottom-up cases of package redblack.
+package llrb
+
+import (
+	"math/bits"
+
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+func init() {
+	bst.Register("llrb", bst.Factory{
+		New: New[any, any],
+		Typed: []interface{}{
+			New[int, int], New[int, string], New[int, any],
+			New[string, int], New[string, string], New[string, any],
+		},
+		Multimap: true,
+	})
+}
+
+type llrb[K, V any] struct {
+	root    *node[K, V]
+	comp    bst.Comparator[K]
+	aug     bst.Augmenter[K, V]
+	enc     bst.Encoding[K, V]
+	stats   *bst.Stats
+	multi   bool               // MultiKeys 模式
+	updates []bst.Option[K, V] // 结构变化后自底向上更新节点
+}
+
+// New returns an empty left-leaning redblack tree. It is not safe for
+// concurrent use with writes, wrap it by bst.Synchronized to let readers run
+// in parallel.
+func New[K, V any](parms ...interface{}) bst.BST[K, V] {
+	t := new(llrb[K, V])
+	t.comp = bst.DefaultCompare[K]()
+	for _, p := range parms {
+		switch v := p.(type) {
+		case bst.Comparator[K]:
+			t.comp = v
+		case func(a, b K) int:
+			t.comp = v
+		case bst.Augmenter[K, V]:
+			t.aug = v
+		case bst.Encoding[K, V]:
+			t.enc = v
+		case *bst.Stats:
+			t.stats = v
+		case bst.Mode:
+			t.multi = v == bst.MultiKeys
+		}
+	}
+	t.comp = bst.CountComparisons(t.comp, t.stats)
+	t.updates = []bst.Option[K, V]{updateSize[K, V]}
+	if t.aug != nil {
+		t.updates = append(t.updates, bst.AugmentOption(t.aug))
+	}
+	return t
+}
+
+func (t *llrb[K, V]) Root() bst.Node[K, V] { return t.root }
+
+// Len returns the number of keys in the tree
+func (t *llrb[K, V]) Len() int {
+	if t.root == nil {
+		return 0
+	}
+	return t.root.size
+}
+
+func (t *llrb[K, V]) Print() {
+	bst.PrintWithUnitSize(t.root, 2)
+}
+
+func (t *llrb[K, V]) Search(key K) (bst.Node[K, V], bool) {
+	if n := t.find(key); n != nil {
+		return n, true
+	}
+	return nil, false
+}
+
+// find 查找 key，多重映射中返回最早插入的节点
+func (t *llrb[K, V]) find(key K) *node[K, V] {
+	var hit *node[K, V]
+	for n := t.root; n != nil; {
+		c := t.comp(key, n.key)
+		switch {
+		case c < 0:
+			n = n.lchild
+		case c > 0:
+			n = n.rchild
+		case !t.multi:
+			return n
+		default:
+			hit, n = n, n.lchild
+		}
+	}
+	return hit
+}
+
+// dir 返回目标节点 target 相对于 h 的方向，相等的关键码中 target 最早插入，位于其余节点的左侧
+func (t *llrb[K, V]) dir(h, target *node[K, V]) int {
+	if h == target {
+		return 0
+	}
+	if c := t.comp(target.key, h.key); c != 0 {
+		return c
+	}
+	return -1
+}
+
+// rotateLeft 将右倾的红链接转为左倾，返回新的子树根
+func (t *llrb[K, V]) rotateLeft(h *node[K, V]) *node[K, V] {
+	x := h.rchild
+	x.red, h.red = h.red, true
+	link(h.lchild, h, x.lchild, t.updates)
+	t.stats.Add(1)
+	return link(h, x, x.rchild, t.updates)
+}
+
+// rotateRight 将左倾的红链接转为右倾，返回新的子树根
+func (t *llrb[K, V]) rotateRight(h *node[K, V]) *node[K, V] {
+	x := h.lchild
+	x.red, h.red = h.red, true
+	link(x.rchild, h, h.rchild, t.updates)
+	t.stats.Add(1)
+	return link(x.lchild, x, h, t.updates)
+}
+
+// flip 翻转 h 及其两个孩子的颜色，即分裂或合并 2-3 树中的节点
+func flip[K, V any](h *node[K, V]) {
+	h.red = !h.red
+	h.lchild.red = !h.lchild.red
+	h.rchild.red = !h.rchild.red
+}
+
+// fixUp 插入后自底向上恢复左倾：右倾的红链接左旋，连续的左倾红链接右旋，两个红孩子翻转颜色
+func (t *llrb[K, V]) fixUp(h *node[K, V]) *node[K, V] {
+	if isRed(h.rchild) && !isRed(h.lchild) {
+		h = t.rotateLeft(h)
+	}
+	if isRed(h.lchild) && isRed(h.lchild.lchild) {
+		h = t.rotateRight(h)
+	}
+	if isRed(h.lchild) && isRed(h.rchild) {
+		flip(h)
+	}
+	return h
+}
+
+// balance 删除后恢复左倾，此时两个孩子可能同为红色，右孩子为红色时总是先左旋
+func (t *llrb[K, V]) balance(h *node[K, V]) *node[K, V] {
+	if isRed(h.rchild) {
+		h = t.rotateLeft(h)
+	}
+	return t.fixUp(h)
+}
+
+// moveRedLeft 从 h 或其右孩子借得红链接，使 h 的左孩子或其左孩子为红色
+func (t *llrb[K, V]) moveRedLeft(h *node[K, V]) *node[K, V] {
+	flip(h)
+	if isRed(h.rchild.lchild) {
+		link(h.lchild, h, t.rotateRight(h.rchild), t.updates)
+		h = t.rotateLeft(h)
+		flip(h)
+	}
+	return h
+}
+
+// moveRedRight 从 h 或其左孩子借得红链接，使 h 的右孩子或其右孩子为红色
+func (t *llrb[K, V]) moveRedRight(h *node[K, V]) *node[K, V] {
+	flip(h)
+	if isRed(h.lchild.lchild) {
+		h = t.rotateRight(h)
+		flip(h)
+	}
+	return h
+}
+
+// insertion 一次插入的参数与结果
+type insertion[K, V any] struct {
+	key  K
+	data V
+	last bool        // 多重映射中插入到相等的关键码之后，否则查找最早插入的相等节点
+	hit  *node[K, V] // 已有的相等节点，此时不插入
+	new  *node[K, V] // 插入的节点
+}
+
+// put 插入 in 描述的关键码，已有相等的节点且非 in.last 时只记录该节点
+func (t *llrb[K, V]) put(in *insertion[K, V]) {
+	root := t.insert(t.root, in)
+	if in.new != nil {
+		t.root = orphan(root)
+		t.root.red = false
+	}
+}
+
+// insert 在以 h 为根的子树中插入，返回新的子树根
+func (t *llrb[K, V]) insert(h *node[K, V], in *insertion[K, V]) *node[K, V] {
+	if h == nil {
+		if in.hit != nil {
+			return nil
+		}
+		in.new = &node[K, V]{key: in.key, data: in.data, red: true}
+		update(t.updates, in.new)
+		return in.new
+	}
+	l, r := h.lchild, h.rchild
+	c := t.comp(in.key, h.key)
+	switch {
+	case c == 0 && !t.multi:
+		in.hit = h
+		return h
+	case c < 0 || c == 0 && !in.last:
+		if c == 0 {
+			in.hit = h
+		}
+		l = t.insert(l, in)
+	default:
+		r = t.insert(r, in)
+	}
+	if in.new == nil {
+		return h
+	}
+	link(l, h, r, t.updates)
+	return t.fixUp(h)
+}
+
+func (t *llrb[K, V]) Insert(key K, data V) (_ bst.Node[K, V], err error) {
+	if bst.Debug {
+		defer bst.MustValidate(t)
+	}
+	defer bst.CatchIncomparable(&err)
+	in := insertion[K, V]{key: key, data: data, last: true}
+	t.put(&in)
+	if in.hit != nil {
+		return nil, bst.ErrDuplicateKey
+	}
+	return in.new, nil
+}
+
+func (t *llrb[K, V]) Remove(key K) (_ bst.Node[K, V], err error) {
+	if bst.Debug {
+		defer bst.MustValidate(t)
+	}
+	defer bst.CatchIncomparable(&err)
+	n := t.find(key)
+	if n == nil {
+		return nil, bst.ErrNotFound
+	}
+	t.detach(n)
+	return n, nil
+}
+
+// detach 自顶向下删除节点 n，根的两个孩子均为黑色时先将根染红，以便向下借得红链接
+func (t *llrb[K, V]) detach(n *node[K, V]) {
+	if !isRed(t.root.lchild) && !isRed(t.root.rchild) {
+		t.root.red = true
+	}
+	t.root = orphan(t.delete(t.root, n))
+	if t.root != nil {
+		t.root.red = false
+	}
+	n.lchild, n.rchild, n.parent, n.red = nil, nil, nil, false
+}
+
+// delete 从以 h 为根的子树中删除 target，下降时保证当前节点或其左孩子为红色，返回新的子树根。
+// target 有右孩子时以其后继节点替代它
+func (t *llrb[K, V]) delete(h, target *node[K, V]) *node[K, V] {
+	if t.dir(h, target) < 0 {
+		if !isRed(h.lchild) && !isRed(h.lchild.lchild) {
+			h = t.moveRedLeft(h)
+		}
+		link(t.delete(h.lchild, target), h, h.rchild, t.updates)
+		return t.balance(h)
+	}
+	if isRed(h.lchild) {
+		h = t.rotateRight(h)
+	}
+	if h == target && h.rchild == nil {
+		return nil
+	}
+	if !isRed(h.rchild) && !isRed(h.rchild.lchild) {
+		h = t.moveRedRight(h)
+	}
+	if h != target {
+		link(h.lchild, h, t.delete(h.rchild, target), t.updates)
+		return t.balance(h)
+	}
+	var succ *node[K, V]
+	r := t.deleteMin(h.rchild, &succ)
+	succ.red = h.red
+	return t.balance(link(h.lchild, succ, r, t.updates))
+}
+
+// deleteMin 删除子树中的最小节点并记录于 min，返回新的子树根
+func (t *llrb[K, V]) deleteMin(h *node[K, V], min **node[K, V]) *node[K, V] {
+	if h.lchild == nil {
+		*min = h
+		return nil
+	}
+	if !isRed(h.lchild) && !isRed(h.lchild.lchild) {
+		h = t.moveRedLeft(h)
+	}
+	link(t.deleteMin(h.lchild, min), h, h.rchild, t.updates)
+	return t.balance(h)
+}
+
+// Put inserts key with data or replaces its data after a single search
+func (t *llrb[K, V]) Put(key K, data V) (old V, replaced bool, err error) {
+	if bst.Debug {
+		defer bst.MustValidate(t)
+	}
+	defer bst.CatchIncomparable(&err)
+	in := insertion[K, V]{key: key, data: data}
+	t.put(&in)
+	if in.hit == nil {
+		return old, false, nil
+	}
+	old = in.hit.data
+	t.replace(in.hit, data)
+	return old, true, nil
+}
+
+// replace 替换节点的数据，数据参与增强时更新摘要
+func (t *llrb[K, V]) replace(n *node[K, V], data V) {
+	n.data = data
+	if t.aug != nil {
+		bst.UpdateAbove[K, V](n, t.updates...)
+	}
+}
+
+func (t *llrb[K, V]) Update(key K, fn func(old V, ok bool) (V, bool)) (err error) {
+	if bst.Debug {
+		defer bst.MustValidate(t)
+	}
+	defer bst.CatchIncomparable(&err)
+	n := t.find(key)
+	var old V
+	if n != nil {
+		old = n.data
+	}
+	data, keep := fn(old, n != nil)
+	switch {
+	case n != nil && keep:
+		t.replace(n, data)
+	case n != nil:
+		t.detach(n)
+	case keep:
+		t.put(&insertion[K, V]{key: key, data: data, last: true})
+	}
+	return nil
+}
+
+func (t *llrb[K, V]) GetOrInsert(key K, data V) (_ bst.Node[K, V], found bool, err error) {
+	if bst.Debug {
+		defer bst.MustValidate(t)
+	}
+	defer bst.CatchIncomparable(&err)
+	in := insertion[K, V]{key: key, data: data}
+	t.put(&in)
+	if in.hit != nil {
+		return in.hit, true, nil
+	}
+	return in.new, false, nil
+}
+
+// Count returns the number of entries of key in O(log n)
+func (t *llrb[K, V]) Count(key K) int { return bst.CountRange(t.root, key, key, t.comp) }
+
+func (t *llrb[K, V]) All(key K, fn func(n bst.Node[K, V]) bool) {
+	bst.Range(t.root, key, key, true, true, t.comp, fn)
+}
+
+// RemoveOne removes the oldest entry of key as Remove does
+func (t *llrb[K, V]) RemoveOne(key K) (bst.Node[K, V], error) { return t.Remove(key) }
+
+func (t *llrb[K, V]) RemoveAll(key K) (int, error) { return bst.RemoveEach(key, t.Remove) }
+
+// BuildSorted builds a tree of minimum height from the sorted keys in O(n)
+func (t *llrb[K, V]) BuildSorted(keys []K, values []V, verify bool) error {
+	if verify {
+		check := bst.CheckSorted[K]
+		if t.multi {
+			check = bst.CheckSortedMulti[K]
+		}
+		if err := check(keys, t.comp); err != nil {
+			return err
+		}
+	}
+	t.root = orphan(t.build(keys, values))
+	return nil
+}
+
+// build 构建黑高度为 b = bits.Len(n+1)-1 的子树。两个孩子的黑高度均为 b-1 时各有
+// [2^(b-1)-1, 2^b-2] 个关键码，n 为 2^(b+1)-2 时超出该范围，根带一个红色的左孩子，
+// 对应 2-3 树中的 3-节点
+func (t *llrb[K, V]) build(keys []K, values []V) *node[K, V] {
+	n := len(keys)
+	if n == 0 {
+		return nil
+	}
+	b := bits.Len(uint(n+1)) - 1
+	lo, hi, m := 1<<(b-1)-1, 1<<b-2, n-1
+	if m > 2*hi {
+		mid := 1<<b - 1
+		red := &node[K, V]{key: keys[lo], data: values[lo], red: true}
+		l := link(t.build(keys[:lo], values[:lo]), red, t.build(keys[lo+1:mid], values[lo+1:mid]), t.updates)
+		return link(l, &node[K, V]{key: keys[mid], data: values[mid]}, t.build(keys[mid+1:], values[mid+1:]), t.updates)
+	}
+	mid := min(m-lo, hi)
+	return link(t.build(keys[:mid], values[:mid]), &node[K, V]{key: keys[mid], data: values[mid]}, t.build(keys[mid+1:], values[mid+1:]), t.updates)
+}
+
+func (t *llrb[K, V]) Walk(o bst.Order, opts ...bst.Option[K, V]) {
+	switch o {
+	case bst.PreOrder:
+		bst.TravPre(t.root, opts...)
+	case bst.InOrder:
+		bst.TravIn(t.root, opts...)
+	case bst.PostOrder:
+		bst.TravPost(t.root, opts...)
+	case bst.LevelOrder:
+		bst.TravLevel(t.root, opts...)
+	case bst.ReverseInOrder:
+		bst.TravReverseIn(t.root, opts...)
+	default:
+		panic("unsupported walk order")
+	}
+}
+
+func (t *llrb[K, V]) Traverse(o bst.Order, fn func(n bst.Node[K, V]) bool) bool {
+	return bst.Traverse[K, V](t.root, o, fn)
+}
+
+func (t *llrb[K, V]) Iterator() bst.Iterator[K, V] {
+	return bst.NewIterator[K, V](t, t.comp)
+}
+
+func (t *llrb[K, V]) Floor(key K) (bst.Node[K, V], bool) {
+	return bst.Floor(t.root, key, t.comp)
+}
+
+func (t *llrb[K, V]) Ceiling(key K) (bst.Node[K, V], bool) {
+	return bst.Ceiling(t.root, key, t.comp)
+}
+
+func (t *llrb[K, V]) Lower(key K) (bst.Node[K, V], bool) {
+	return bst.Lower(t.root, key, t.comp)
+}
+
+func (t *llrb[K, V]) Higher(key K) (bst.Node[K, V], bool) {
+	return bst.Higher(t.root, key, t.comp)
+}
+
+func (t *llrb[K, V]) Min() (bst.Node[K, V], bool) { return bst.Min(t.root) }
+
+func (t *llrb[K, V]) Max() (bst.Node[K, V], bool) { return bst.Max(t.root) }
+
+func (t *llrb[K, V]) Range(lo, hi K, loInclusive, hiInclusive bool, fn func(n bst.Node[K, V]) bool) {
+	bst.Range(t.root, lo, hi, loInclusive, hiInclusive, t.comp, fn)
+}
+
+func (t *llrb[K, V]) Select(k int) (bst.Node[K, V], bool) { return bst.Select(t.root, k) }
+
+func (t *llrb[K, V]) Rank(key K) int { return bst.Rank(t.root, key, t.comp) }
+
+func (t *llrb[K, V]) CountRange(lo, hi K) int {
+	return bst.CountRange(t.root, lo, hi, t.comp)
+}
+
+func (t *llrb[K, V]) Summary() any {
+	if t.aug == nil {
+		return nil
+	}
+	return bst.SummaryOf[K, V](t.root, t.aug)
+}
+
+func (t *llrb[K, V]) SummaryRange(lo, hi K) any {
+	if t.aug == nil {
+		return nil
+	}
+	return bst.SummaryRange[K, V](t.root, lo, hi, t.comp, t.aug)
+}
@@ -0,0 +1,34 @@
+package llrb
+
+import (
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+// Validate checks the key order, the parent pointers, the sizes, that red
+// links lean left without two in a row and the black heights of the tree
+func (t *llrb[K, V]) Validate() error {
+	if isRed(t.root) {
+		return bst.NewInvariantError(bst.LLRB, bst.RuleColor, t.root.key, "root is red")
+	}
+	validate := bst.ValidateBinary[K, V]
+	if t.multi {
+		validate = bst.ValidateBinaryMulti[K, V]
+	}
+	_, err := validate(bst.LLRB, t.root, t.comp, func(m bst.Node[K, V]) error {
+		n := m.(*node[K, V])
+		if lh, rh := blackHeight(n.lchild), blackHeight(n.rchild); lh != rh {
+			return bst.NewInvariantError(bst.LLRB, bst.RuleBlackHeight, n.key, "black heights %d and %d", lh, rh)
+		}
+		if isRed(n.rchild) {
+			return bst.NewInvariantError(bst.LLRB, bst.RuleColor, n.key, "right child %v is red", n.rchild.key)
+		}
+		if n.red && isRed(n.lchild) {
+			return bst.NewInvariantError(bst.LLRB, bst.RuleColor, n.key, "red node has a red child")
+		}
+		if size := bst.Size[K, V](n.lchild) + bst.Size[K, V](n.rchild) + 1; n.size != size {
+			return bst.NewInvariantError(bst.LLRB, bst.RuleSize, n.key, "size %d, want %d", n.size, size)
+		}
+		return nil
+	})
+	return err
+}
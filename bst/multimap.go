@@ -0,0 +1,99 @@
+package bst
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Mode chooses how a tree treats duplicate keys, it is passed to New or
+// NewOf among the parms
+type Mode uint8
+
+const (
+	UniqueKeys Mode = iota // 默认，Insert 拒绝重复的关键码
+	MultiKeys              // 多重映射，重复的关键码各占一个节点
+)
+
+// Multimap is implemented by the classes supporting MultiKeys: AVL, RBTree,
+// Splay, BTree, BPlusTree, Treap, SkipList, LLRB and AATree. In MultiKeys
+// mode Insert never returns ErrDuplicateKey and the entries of a key are
+// adjacent in the iteration order, oldest first. Search, Remove, Put, Update
+// and GetOrInsert act on the oldest entry of the key, Ceiling returns the
+// oldest entry and Floor the newest.
+type Multimap[K, V any] interface {
+	// Count returns the number of entries of key
+	Count(key K) int
+	// All calls fn on the entries of key in insertion order, until fn
+	// returns false
+	All(key K, fn func(n Node[K, V]) bool)
+	// RemoveOne removes the oldest entry of key and returns it as Remove
+	// does, or ErrNotFound if there is none
+	RemoveOne(key K) (Node[K, V], error)
+	// RemoveAll removes every entry of key and returns their number, or
+	// ErrNotFound if there is none
+	RemoveAll(key K) (int, error)
+}
+
+// Count returns the number of entries of key in t, at most 1 if t is not a
+// Multimap
+func Count[K, V any](t BST[K, V], key K) int {
+	if m, ok := t.(Multimap[K, V]); ok {
+		return m.Count(key)
+	}
+	if _, ok := t.Search(key); ok {
+		return 1
+	}
+	return 0
+}
+
+// All calls fn on the entries of key in t in insertion order, until fn
+// returns false
+func All[K, V any](t BST[K, V], key K, fn func(n Node[K, V]) bool) {
+	if m, ok := t.(Multimap[K, V]); ok {
+		m.All(key, fn)
+		return
+	}
+	if n, ok := t.Search(key); ok {
+		fn(n)
+	}
+}
+
+// RemoveAll removes every entry of key from t and returns their number
+func RemoveAll[K, V any](t BST[K, V], key K) (int, error) {
+	if m, ok := t.(Multimap[K, V]); ok {
+		return m.RemoveAll(key)
+	}
+	if _, err := t.Remove(key); err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+// RemoveEach removes the entries of key one by one by removeOne, which
+// returns ErrNotFound, possibly wrapped, once none is left, and returns
+// their number
+func RemoveEach[K, V any](key K, removeOne func(key K) (Node[K, V], error)) (int, error) {
+	cnt := 0
+	for {
+		_, err := removeOne(key)
+		switch {
+		case err == nil:
+			cnt++
+			continue
+		case errors.Is(err, ErrNotFound) && cnt > 0:
+			return cnt, nil
+		}
+		return cnt, err
+	}
+}
+
+// CheckSortedMulti returns an error if keys are not ascending by comp, equal
+// keys being allowed as in MultiKeys mode
+func CheckSortedMulti[K any](keys []K, comp Comparator[K]) error {
+	for i := 1; i < len(keys); i++ {
+		if comp(keys[i-1], keys[i]) > 0 {
+			return fmt.Errorf("bst: keys are not ascending at index %d", i)
+		}
+	}
+	return nil
+}
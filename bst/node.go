@@ -3,45 +3,89 @@ package bst
 import "reflect"
 
 // Node identifies a BST Node
-type Node interface {
-	LChild() Node
-	RChild() Node
-	Parent() Node
-	SetLChild(Node)
-	SetRChild(Node)
-	SetParent(Node)
-	Key() interface{}
-	Data() interface{}
-	SetKey(interface{})
-	SetData(interface{})
+type Node[K, V any] interface {
+	LChild() Node[K, V]
+	RChild() Node[K, V]
+	Parent() Node[K, V]
+	SetLChild(Node[K, V])
+	SetRChild(Node[K, V])
+	SetParent(Node[K, V])
+	Key() K
+	Data() V
+	SetKey(K)
+	SetData(V)
 	Height() int
 	Color() string
 }
 
-func IsNil(n Node) bool {
+// Multiway is implemented by the nodes of multiway trees such as the B-tree,
+// which hold several keys in one node. The nodes of binary trees wrapped by
+// NewOf or Erase implement it too, returning nil entries.
+type Multiway[K, V any] interface {
+	Node[K, V]
+	// Entries returns the keys and data of the whole node, which must not be
+	// modified
+	Entries() ([]K, []V)
+	// Children returns the branches of the node, nil for a leaf
+	Children() []Node[K, V]
+}
+
+// Leveled is implemented by the nodes of trees balanced by levels such as the
+// AA tree. The nodes wrapped by NewOf or Erase implement it too, returning 0
+// when the wrapped node has no level.
+type Leveled interface {
+	// Level returns the level of the node, 1 for a leaf
+	Level() int
+}
+
+// NilChecker is implemented by the nodes telling by themselves whether they
+// are nil pointers, as the nodes of the classes of this module do, so that
+// IsNil needs no reflection
+type NilChecker interface {
+	// IsNil reports whether the node is a nil pointer, it is called on nil
+	// receivers
+	IsNil() bool
+}
+
+// IsNil returns whether n is nil or holds a nil pointer. Nodes which do not
+// implement NilChecker are checked by reflection.
+func IsNil[K, V any](n Node[K, V]) bool {
+	return isNil(n)
+}
+
+// isNil 判断接口值 n 是否为 nil 或持有 nil 指针，未实现 NilChecker 时使用反射
+func isNil(n interface{}) bool {
 	if n == nil {
 		return true
 	}
-	return reflect.ValueOf(n).IsNil()
+	if c, ok := n.(NilChecker); ok {
+		return c.IsNil()
+	}
+	v := reflect.ValueOf(n)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return v.IsNil()
+	}
+	return false
 }
 
 // IsRoot returns whether n is root node
-func IsRoot(n Node) bool { return IsNil(n.Parent()) }
+func IsRoot[K, V any](n Node[K, V]) bool { return IsNil(n.Parent()) }
 
 // IsLChild returns whether n is a left child node
-func IsLChild(n Node) bool { return !IsRoot(n) && n == n.Parent().LChild() }
+func IsLChild[K, V any](n Node[K, V]) bool { return !IsRoot(n) && n == n.Parent().LChild() }
 
 // IsRChild returns whether n is a right child node
-func IsRChild(n Node) bool { return !IsRoot(n) && n == n.Parent().RChild() }
+func IsRChild[K, V any](n Node[K, V]) bool { return !IsRoot(n) && n == n.Parent().RChild() }
 
 // HasLChild returns whether n has left child
-func HasLChild(n Node) bool { return !IsNil(n.LChild()) }
+func HasLChild[K, V any](n Node[K, V]) bool { return !IsNil(n.LChild()) }
 
 // HasRChild returns whether n has right child
-func HasRChild(n Node) bool { return !IsNil(n.RChild()) }
+func HasRChild[K, V any](n Node[K, V]) bool { return !IsNil(n.RChild()) }
 
 // IsLeaf returns whether n is leaf node
-func IsLeaf(n Node) bool {
+func IsLeaf[K, V any](n Node[K, V]) bool {
 	if HasLChild(n) || HasRChild(n) {
 		return false
 	}
@@ -49,7 +93,7 @@ func IsLeaf(n Node) bool {
 }
 
 // Sibling returns sibling of n
-func Sibling(n Node) Node {
+func Sibling[K, V any](n Node[K, V]) Node[K, V] {
 	if IsRoot(n) {
 		return nil
 	}
@@ -60,22 +104,34 @@ func Sibling(n Node) Node {
 }
 
 // AttachRChild connects rc as right child of n
-func AttachRChild(n, rc Node) {
+func AttachRChild[K, V any](n, rc Node[K, V]) {
 	n.SetRChild(rc)
-	rc.SetParent(n)
+	if !IsNil(rc) {
+		rc.SetParent(n)
+	}
 }
 
 // AttachLChild connects lc as left child of n
-func AttachLChild(n, lc Node) {
+func AttachLChild[K, V any](n, lc Node[K, V]) {
 	n.SetLChild(lc)
-	lc.SetParent(n)
+	if !IsNil(lc) {
+		lc.SetParent(n)
+	}
+}
+
+// Sizer is implemented by the nodes maintaining the size of their subtrees
+type Sizer interface {
+	Size() int
 }
 
 // Size returns the total node counts of the subtree rooted on n
-func Size(n Node) int {
+func Size[K, V any](n Node[K, V]) int {
 	if IsNil(n) {
 		return 0
 	}
+	if s, ok := n.(Sizer); ok {
+		return s.Size()
+	}
 	count := 1
 	if HasLChild(n) {
 		count += Size(n.LChild())
@@ -87,7 +143,7 @@ func Size(n Node) int {
 }
 
 // Level returns the level where the node lays on
-func Level(n Node) int {
+func Level[K, V any](n Node[K, V]) int {
 	l := 0
 	for !IsNil(n) && !IsRoot(n) {
 		l++
@@ -97,7 +153,7 @@ func Level(n Node) int {
 }
 
 // Successor returns the successor of n
-func Successor(n Node) Node {
+func Successor[K, V any](n Node[K, V]) Node[K, V] {
 	if HasRChild(n) {
 		return subTreeMin(n.RChild())
 	}
@@ -108,7 +164,7 @@ func Successor(n Node) Node {
 }
 
 // Predecessor returns the predecessor of n
-func Predecessor(n Node) Node {
+func Predecessor[K, V any](n Node[K, V]) Node[K, V] {
 	if HasLChild(n) {
 		return subTreeMax(n.LChild())
 	}
@@ -118,14 +174,14 @@ func Predecessor(n Node) Node {
 	return n.Parent()
 }
 
-func subTreeMin(n Node) Node {
+func subTreeMin[K, V any](n Node[K, V]) Node[K, V] {
 	for HasLChild(n) {
 		n = n.LChild()
 	}
 	return n
 }
 
-func subTreeMax(n Node) Node {
+func subTreeMax[K, V any](n Node[K, V]) Node[K, V] {
 	for HasRChild(n) {
 		n = n.RChild()
 	}
@@ -133,65 +189,32 @@ func subTreeMax(n Node) Node {
 }
 
 // TravPre walks the subtree rooted on n by pre-order
-func TravPre(n Node, opts ...Option) {
-	for _, opt := range opts {
-		opt(n)
-	}
-	if HasLChild(n) {
-		TravPre(n.LChild(), opts...)
-	}
-	if HasRChild(n) {
-		TravPre(n.RChild(), opts...)
-	}
+func TravPre[K, V any](n Node[K, V], opts ...Option[K, V]) {
+	Traverse(n, PreOrder, options(opts))
 }
 
 // TravIn walks the subtree rooted on n by in-order
-func TravIn(n Node, opts ...Option) {
-	if HasLChild(n) {
-		TravIn(n.LChild(), opts...)
-	}
-	for _, opt := range opts {
-		opt(n)
-	}
-	if HasRChild(n) {
-		TravIn(n.RChild(), opts...)
-	}
+func TravIn[K, V any](n Node[K, V], opts ...Option[K, V]) {
+	Traverse(n, InOrder, options(opts))
+}
+
+// TravReverseIn walks the subtree rooted on n by reverse in-order
+func TravReverseIn[K, V any](n Node[K, V], opts ...Option[K, V]) {
+	Traverse(n, ReverseInOrder, options(opts))
 }
 
 // TravPost walks the subtree rooted on n by post-order
-func TravPost(n Node, opts ...Option) {
-	if HasLChild(n) {
-		TravIn(n.LChild(), opts...)
-	}
-	if HasRChild(n) {
-		TravIn(n.RChild(), opts...)
-	}
-	for _, opt := range opts {
-		opt(n)
-	}
+func TravPost[K, V any](n Node[K, V], opts ...Option[K, V]) {
+	Traverse(n, PostOrder, options(opts))
 }
 
 // TravLevel walks the subtree rooted on n by level-order
-func TravLevel(n Node, opts ...Option) {
-	queue := make([]Node, 0, Size(n))
-	queue = append(queue, n)
-	for len(queue) > 0 {
-		visitNode := queue[0]
-		queue = queue[1:]
-		if HasLChild(visitNode) {
-			queue = append(queue, visitNode.LChild())
-		}
-		if HasRChild(visitNode) {
-			queue = append(queue, visitNode.RChild())
-		}
-		for _, opt := range opts {
-			opt(visitNode)
-		}
-	}
+func TravLevel[K, V any](n Node[K, V], opts ...Option[K, V]) {
+	Traverse(n, LevelOrder, options(opts))
 }
 
 // RotateAt use connect 3+4 strategy to reconstruct v,p,g which are all existing
-func RotateAt(v Node, opts ...Option) (a, b, c Node) {
+func RotateAt[K, V any](v Node[K, V], opts ...Option[K, V]) (a, b, c Node[K, V]) {
 	p := v.Parent()
 	g := p.Parent()
 	if IsLChild(v) {
@@ -222,10 +245,11 @@ func RotateAt(v Node, opts ...Option) (a, b, c Node) {
 }
 
 // connect34 connect bst.Nodes as below
+//
 //	 	   b
 //		a	  c
 //	  T1 T2 T3 T4
-func connect34(a, b, c, t1, t2, t3, t4 Node, opts ...Option) {
+func connect34[K, V any](a, b, c, t1, t2, t3, t4 Node[K, V], opts ...Option[K, V]) {
 	AttachLChild(a, t1)
 	AttachRChild(a, t2)
 	for _, o := range opts {
@@ -245,8 +269,24 @@ func connect34(a, b, c, t1, t2, t3, t4 Node, opts ...Option) {
 	}
 }
 
-// RemoveAt removes "node n" and returns the exact removed node's parent as hot and the replacement node as r
-func RemoveAt(n, root Node) (hot, r Node) {
+// UpdateAbove applies opts on n and all its ancestors from bottom to top
+func UpdateAbove[K, V any](n Node[K, V], opts ...Option[K, V]) {
+	if len(opts) == 0 {
+		return
+	}
+	for ; !IsNil(n); n = n.Parent() {
+		for _, o := range opts {
+			o(n)
+		}
+	}
+}
+
+// RemoveAt removes "node n" and returns the exact removed node's parent as hot and the replacement node as r,
+// opts are applied on hot and all its ancestors after the removal.
+// When n has two children its successor's entry is moved into n, which keeps the in-order sequence of the other
+// entries, so the equal keys of a multimap stay in insertion order whichever of them n is.
+func RemoveAt[K, V any](n, root Node[K, V], opts ...Option[K, V]) (hot, r Node[K, V]) {
+	defer func() { UpdateAbove(hot, opts...) }()
 	// n has both left and right subtree
 	if HasLChild(n) && HasRChild(n) {
 		succ := Successor(n)
@@ -313,7 +353,7 @@ func RemoveAt(n, root Node) (hot, r Node) {
 	return hot, r
 }
 
-func swapKeyData(n1, n2 Node) {
+func swapKeyData[K, V any](n1, n2 Node[K, V]) {
 	tmpKey, tmpData := n1.Key(), n1.Data()
 	n1.SetKey(n2.Key())
 	n1.SetData(n2.Data())
@@ -321,7 +361,7 @@ func swapKeyData(n1, n2 Node) {
 	n2.SetData(tmpData)
 }
 
-func release(n Node) {
+func release[K, V any](n Node[K, V]) {
 	n.SetParent(nil)
 	n.SetLChild(nil)
 	n.SetRChild(nil)
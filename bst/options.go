@@ -0,0 +1,131 @@
+package bst
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// TreeOption configures a tree created by Make, an invalid option makes Make
+// fail instead of being ignored as the parms of New are
+type TreeOption func(cfg *config) error
+
+// config 收集 TreeOption 给出的设置
+type config struct {
+	comp  interface{} // WithComparator 给出的 Comparator[K]
+	order int
+	alpha float64
+	multi bool
+	stats *Stats
+}
+
+// invalidOption 返回包装了 ErrInvalidOption 的错误
+func invalidOption(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: "+format, append([]interface{}{ErrInvalidOption}, args...)...)
+}
+
+// WithComparator orders the keys by comp instead of the default comparator
+// of K, Make fails if the keys of the tree are not of type K
+func WithComparator[K any](comp Comparator[K]) TreeOption {
+	return func(cfg *config) error {
+		if comp == nil {
+			return invalidOption("nil comparator")
+		}
+		cfg.comp = comp
+		return nil
+	}
+}
+
+// WithOrder sets the order m of a B-tree, at least 3
+func WithOrder(m int) TreeOption {
+	return func(cfg *config) error {
+		if m < 3 {
+			return invalidOption("order %d is less than 3", m)
+		}
+		cfg.order = m
+		return nil
+	}
+}
+
+// WithAlpha sets the balance parameter α of the scapegoat tree, in (0.5, 1),
+// or of the weight-balanced tree, in (2/11, 1-√2/2]
+func WithAlpha(alpha float64) TreeOption {
+	return func(cfg *config) error {
+		if !(alpha > 0 && alpha < 1) {
+			return invalidOption("alpha %v is not in (0, 1)", alpha)
+		}
+		cfg.alpha = alpha
+		return nil
+	}
+}
+
+// WithMultimap makes the tree keep duplicate keys as in MultiKeys mode
+func WithMultimap() TreeOption {
+	return func(cfg *config) error {
+		cfg.multi = true
+		return nil
+	}
+}
+
+// WithStats makes the tree count its work into s
+func WithStats(s *Stats) TreeOption {
+	return func(cfg *config) error {
+		if s == nil {
+			return invalidOption("nil stats")
+		}
+		cfg.stats = s
+		return nil
+	}
+}
+
+// Make returns a new BST of class c with typed keys and data as NewOf does,
+// configured by opts. It fails with an error wrapping ErrInvalidOption if c
+// is unavailable or can not hold K and V, an option is invalid or c does
+// not support it.
+func Make[K, V any](c Class, opts ...TreeOption) (BST[K, V], error) {
+	f, ok := c.factory()
+	if !ok {
+		return nil, invalidOption("%v is unavailable", c)
+	}
+	if !holds[K, V](c) {
+		return nil, invalidOption("%v has no constructor for %v", c, reflect.TypeFor[BST[K, V]]())
+	}
+	var cfg config
+	for _, opt := range opts {
+		if err := opt(&cfg); err != nil {
+			return nil, err
+		}
+	}
+	var parms []interface{}
+	if cfg.comp != nil {
+		comp, ok := cfg.comp.(Comparator[K])
+		if !ok {
+			return nil, invalidOption("%T does not compare keys of %v", cfg.comp, reflect.TypeFor[K]())
+		}
+		parms = append(parms, comp)
+	}
+	if cfg.order != 0 {
+		if !f.Order {
+			return nil, invalidOption("%v does not support WithOrder", c)
+		}
+		parms = append(parms, cfg.order)
+	}
+	if cfg.alpha != 0 {
+		if f.Alpha == nil {
+			return nil, invalidOption("%v does not support WithAlpha", c)
+		}
+		if err := f.Alpha(cfg.alpha); err != nil {
+			return nil, invalidOption("%v: %v", c, err)
+		}
+		parms = append(parms, cfg.alpha)
+	}
+	if cfg.multi {
+		if !f.Multimap {
+			return nil, invalidOption("%v does not support WithMultimap", c)
+		}
+		parms = append(parms, MultiKeys)
+	}
+	if cfg.stats != nil {
+		parms = append(parms, cfg.stats)
+	}
+	return NewOf[K, V](c, parms...), nil
+}
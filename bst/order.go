@@ -0,0 +1,60 @@
+package bst
+
+// OrderStatistic is implemented by the trees whose nodes maintain the size of
+// their subtrees, answering rank queries in O(log n).
+type OrderStatistic[K, V any] interface {
+	// Select returns the node with the k-th smallest key, counting from 0
+	Select(k int) (Node[K, V], bool)
+	// Rank returns the number of keys less than key
+	Rank(key K) int
+	// CountRange returns the number of keys in [lo, hi]
+	CountRange(lo, hi K) int
+}
+
+// Select returns the node with the k-th smallest key in the subtree rooted
+// on n, counting from 0
+func Select[K, V any](n Node[K, V], k int) (Node[K, V], bool) {
+	if k < 0 {
+		return nil, false
+	}
+	for !IsNil(n) {
+		l := Size(n.LChild())
+		switch {
+		case k < l:
+			n = n.LChild()
+		case k == l:
+			return n, true
+		default:
+			k -= l + 1
+			n = n.RChild()
+		}
+	}
+	return nil, false
+}
+
+// Rank returns the number of keys less than key in the subtree rooted on n
+func Rank[K, V any](n Node[K, V], key K, comp Comparator[K]) int {
+	return rank(n, key, comp, false)
+}
+
+// CountRange returns the number of keys in [lo, hi] in the subtree rooted on n
+func CountRange[K, V any](n Node[K, V], lo, hi K, comp Comparator[K]) int {
+	if comp(lo, hi) > 0 {
+		return 0
+	}
+	return rank(n, hi, comp, true) - rank(n, lo, comp, false)
+}
+
+// rank 统计小于 key 的关键码个数，inclusive 时包含等于 key 的关键码
+func rank[K, V any](n Node[K, V], key K, comp Comparator[K], inclusive bool) int {
+	r := 0
+	for !IsNil(n) {
+		if c := comp(n.Key(), key); c < 0 || c == 0 && inclusive {
+			r += Size(n.LChild()) + 1
+			n = n.RChild()
+		} else {
+			n = n.LChild()
+		}
+	}
+	return r
+}
@@ -0,0 +1,373 @@
+// Package pager stores fixed-size pages in a file for the disk-resident
+// trees. Page 0 is the header, recording the page size, the number of pages,
+// the head of the free list and some metadata of the user; freed pages are
+// chained into the free list and allocated again before the file grows.
+// Pages are read through a cache of limited size which evicts the least
+// recently used page, writing it back first if it is dirty.
+//
+// A Pager is not safe for concurrent use. Nothing is journaled: the pages
+// reach the file on eviction or Sync, and a crash in between may leave the
+// file inconsistent.
+package pager
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+)
+
+// PageID numbers the pages of a file from 0, the header page, which is never
+// allocated, so 0 also stands for no page
+type PageID uint32
+
+const (
+	// DefaultPageSize is the page size of a new file when Options gives none
+	DefaultPageSize = 4096
+	// MinPageSize is the least page size, leaving room for the header
+	MinPageSize = 128
+	// DefaultCachePages is the number of cached pages when Options gives none
+	DefaultCachePages = 256
+
+	headerSize = 18 // magic、页大小、页数、空闲链表头及元数据长度
+)
+
+var magic = []byte("PGR1")
+
+var (
+	// ErrCorrupt is returned when the header of the file is malformed
+	ErrCorrupt = errors.New("pager: corrupt file")
+	// ErrPageSize is returned when a file is opened with another page size
+	// than it was created with, or the page size is too small
+	ErrPageSize = errors.New("pager: invalid page size")
+	// ErrPageRange is returned for a page beyond the file or the header page
+	ErrPageRange = errors.New("pager: page out of range")
+	// ErrTooLarge is returned when the data written does not fit in a page
+	ErrTooLarge = errors.New("pager: data larger than the page")
+	// ErrClosed is returned by the methods of a closed Pager
+	ErrClosed = errors.New("pager: closed")
+	// ErrFreed is returned when freeing a page already in the free list
+	ErrFreed = errors.New("pager: page already free")
+)
+
+// Options configures Open, the zero value selects the defaults
+type Options struct {
+	// PageSize of a new file, an existing file keeps its own and is
+	// rejected by ErrPageSize if PageSize is set otherwise
+	PageSize int
+	// CachePages is the number of pages kept in memory
+	CachePages int
+}
+
+// Pager reads and writes the pages of a file through an LRU cache
+type Pager struct {
+	f        *os.File
+	size     int             // 页大小
+	count    PageID          // 文件中的页数，含头页
+	free     PageID          // 空闲链表头，0 为空
+	freed    map[PageID]bool // 空闲链表中的页，首次 Free 时遍历链表得到
+	meta     []byte          // 使用者的元数据，保存在头页中
+	dirty    bool            // 头页有未写回的修改
+	capacity int
+	lru      *list.List // 最近使用的页在前
+	cache    map[PageID]*list.Element
+}
+
+// page 缓存中的页，data 在写入时整体替换，故 Read 返回的切片不随之改变
+type page struct {
+	id    PageID
+	data  []byte
+	dirty bool
+}
+
+// Open opens the file of path, creating an empty one with only the header
+// page if it does not exist
+func Open(path string, opts Options) (*Pager, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	p := &Pager{f: f, capacity: opts.CachePages, lru: list.New(), cache: make(map[PageID]*list.Element)}
+	if p.capacity <= 0 {
+		p.capacity = DefaultCachePages
+	}
+	if err := p.init(opts.PageSize); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// init 读取已有文件的头页，或为空文件写入头页
+func (p *Pager) init(size int) error {
+	info, err := p.f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() == 0 {
+		if size == 0 {
+			size = DefaultPageSize
+		}
+		if size < MinPageSize || size > 1<<30 {
+			return ErrPageSize
+		}
+		p.size, p.count, p.dirty = size, 1, true
+		return p.writeHeader()
+	}
+	buf := make([]byte, headerSize)
+	if _, err := p.f.ReadAt(buf, 0); err != nil {
+		if err == io.EOF {
+			return ErrCorrupt
+		}
+		return err
+	}
+	if !bytes.Equal(buf[:4], magic) {
+		return ErrCorrupt
+	}
+	p.size = int(binary.LittleEndian.Uint32(buf[4:]))
+	p.count = PageID(binary.LittleEndian.Uint32(buf[8:]))
+	p.free = PageID(binary.LittleEndian.Uint32(buf[12:]))
+	n := int(binary.LittleEndian.Uint16(buf[16:]))
+	if p.size < MinPageSize || p.size > 1<<30 || p.count == 0 || p.free >= p.count || headerSize+n > p.size {
+		return ErrCorrupt
+	}
+	if size != 0 && size != p.size {
+		return ErrPageSize
+	}
+	p.meta = make([]byte, n)
+	if _, err := p.f.ReadAt(p.meta, headerSize); err != nil {
+		return ErrCorrupt
+	}
+	return nil
+}
+
+// writeHeader 写回有修改的头页
+func (p *Pager) writeHeader() error {
+	if !p.dirty {
+		return nil
+	}
+	buf := make([]byte, p.size)
+	copy(buf, magic)
+	binary.LittleEndian.PutUint32(buf[4:], uint32(p.size))
+	binary.LittleEndian.PutUint32(buf[8:], uint32(p.count))
+	binary.LittleEndian.PutUint32(buf[12:], uint32(p.free))
+	binary.LittleEndian.PutUint16(buf[16:], uint16(len(p.meta)))
+	copy(buf[headerSize:], p.meta)
+	if _, err := p.f.WriteAt(buf, 0); err != nil {
+		return err
+	}
+	p.dirty = false
+	return nil
+}
+
+// PageSize returns the size of every page in bytes
+func (p *Pager) PageSize() int { return p.size }
+
+// Len returns the number of pages in the file, counting the header page and
+// the free pages
+func (p *Pager) Len() int { return int(p.count) }
+
+// Meta returns the metadata stored in the header page, which must not be
+// modified
+func (p *Pager) Meta() []byte { return p.meta }
+
+// SetMeta replaces the metadata stored in the header page, which holds at
+// most PageSize()-18 bytes
+func (p *Pager) SetMeta(meta []byte) error {
+	if p.f == nil {
+		return ErrClosed
+	}
+	if headerSize+len(meta) > p.size || len(meta) > math.MaxUint16 {
+		return ErrTooLarge
+	}
+	if !bytes.Equal(p.meta, meta) {
+		p.meta, p.dirty = append([]byte(nil), meta...), true
+	}
+	return nil
+}
+
+// Alloc returns a page from the free list, or a new page at the end of the
+// file, whose content is undefined until written
+func (p *Pager) Alloc() (PageID, error) {
+	if p.f == nil {
+		return 0, ErrClosed
+	}
+	p.dirty = true
+	if p.free == 0 {
+		p.count++
+		return p.count - 1, nil
+	}
+	id := p.free
+	data, err := p.Read(id)
+	if err != nil {
+		return 0, err
+	}
+	next := PageID(binary.LittleEndian.Uint32(data))
+	if next >= p.count {
+		return 0, ErrCorrupt
+	}
+	p.free = next
+	if p.freed != nil {
+		delete(p.freed, id)
+	}
+	return id, nil
+}
+
+// Free puts page id into the free list. Freeing a page already free returns
+// ErrFreed, since it would chain the page twice and let Alloc return it twice.
+func (p *Pager) Free(id PageID) error {
+	if p.f == nil {
+		return ErrClosed
+	}
+	if id == 0 || id >= p.count {
+		return ErrPageRange
+	}
+	if p.freed == nil {
+		freed, err := p.freeList()
+		if err != nil {
+			return err
+		}
+		p.freed = freed
+	}
+	if p.freed[id] {
+		return ErrFreed
+	}
+	// 空闲页的前 4 个字节记录链表中的下一页
+	buf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(buf, uint32(p.free))
+	if err := p.Write(id, buf); err != nil {
+		return err
+	}
+	p.free, p.dirty = id, true
+	p.freed[id] = true
+	return nil
+}
+
+// freeList 遍历空闲链表，返回其中的页，链表成环或越界时返回 ErrCorrupt
+func (p *Pager) freeList() (map[PageID]bool, error) {
+	freed := make(map[PageID]bool)
+	for id := p.free; id != 0; {
+		if freed[id] {
+			return nil, ErrCorrupt
+		}
+		freed[id] = true
+		data, err := p.Read(id)
+		if err != nil {
+			return nil, err
+		}
+		if id = PageID(binary.LittleEndian.Uint32(data)); id >= p.count {
+			return nil, ErrCorrupt
+		}
+	}
+	return freed, nil
+}
+
+// Read returns the content of page id, PageSize() bytes which must not be
+// modified and do not change by later writes
+func (p *Pager) Read(id PageID) ([]byte, error) {
+	if p.f == nil {
+		return nil, ErrClosed
+	}
+	if id == 0 || id >= p.count {
+		return nil, ErrPageRange
+	}
+	if e, ok := p.cache[id]; ok {
+		p.lru.MoveToFront(e)
+		return e.Value.(*page).data, nil
+	}
+	data := make([]byte, p.size)
+	// 已分配但尚未写回的页超出文件末尾，读为全零
+	if _, err := p.f.ReadAt(data, int64(id)*int64(p.size)); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if err := p.put(&page{id: id, data: data}); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Write replaces the content of page id by data padded with zeros, the page
+// reaches the file when it is evicted from the cache or on Sync
+func (p *Pager) Write(id PageID, data []byte) error {
+	if p.f == nil {
+		return ErrClosed
+	}
+	if id == 0 || id >= p.count {
+		return ErrPageRange
+	}
+	if len(data) > p.size {
+		return ErrTooLarge
+	}
+	buf := make([]byte, p.size)
+	copy(buf, data)
+	if e, ok := p.cache[id]; ok {
+		p.lru.MoveToFront(e)
+		pg := e.Value.(*page)
+		if !bytes.Equal(pg.data, buf) {
+			pg.data, pg.dirty = buf, true
+		}
+		return nil
+	}
+	return p.put(&page{id: id, data: buf, dirty: true})
+}
+
+// put 将页放入缓存，超出容量时淘汰最久未使用的页，脏页先写回文件
+func (p *Pager) put(pg *page) error {
+	p.cache[pg.id] = p.lru.PushFront(pg)
+	for p.lru.Len() > p.capacity {
+		e := p.lru.Back()
+		old := e.Value.(*page)
+		if err := p.flush(old); err != nil {
+			return err
+		}
+		p.lru.Remove(e)
+		delete(p.cache, old.id)
+	}
+	return nil
+}
+
+// flush 将脏页写回文件
+func (p *Pager) flush(pg *page) error {
+	if !pg.dirty {
+		return nil
+	}
+	if _, err := p.f.WriteAt(pg.data, int64(pg.id)*int64(p.size)); err != nil {
+		return err
+	}
+	pg.dirty = false
+	return nil
+}
+
+// Sync writes the dirty pages and the header back and commits the file to
+// stable storage
+func (p *Pager) Sync() error {
+	if p.f == nil {
+		return ErrClosed
+	}
+	for e := p.lru.Back(); e != nil; e = e.Prev() {
+		if err := p.flush(e.Value.(*page)); err != nil {
+			return err
+		}
+	}
+	if err := p.writeHeader(); err != nil {
+		return err
+	}
+	return p.f.Sync()
+}
+
+// Close syncs and closes the file, the Pager can not be used any more
+func (p *Pager) Close() error {
+	if p.f == nil {
+		return ErrClosed
+	}
+	err := p.Sync()
+	if cerr := p.f.Close(); err == nil {
+		err = cerr
+	}
+	p.f, p.cache, p.freed = nil, nil, nil
+	p.lru.Init()
+	return err
+}
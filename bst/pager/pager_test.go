@@ -0,0 +1,94 @@
+package pager
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPager(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pages")
+	p, err := Open(path, Options{PageSize: 128, CachePages: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 缓存只容纳两页，写入第三页时淘汰最久未使用的脏页并写回文件
+	var ids []PageID
+	for i := 0; i < 3; i++ {
+		id, err := p.Alloc()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Write(id, []byte{byte('a' + i)}); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	if st, err := os.Stat(path); err != nil || st.Size() != 2*128 {
+		t.Errorf("file holds %v bytes before Sync, want the header and the evicted page", st.Size())
+	}
+	if data, err := p.Read(ids[0]); err != nil || data[0] != 'a' || len(data) != 128 {
+		t.Errorf("evicted page reads %q %v", data[:1], err)
+	}
+	if err := p.Write(ids[0], make([]byte, 129)); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("writing past the page got %v", err)
+	}
+	// 释放的页在文件增长之前被再次分配
+	if err := p.Free(ids[1]); err != nil {
+		t.Fatal(err)
+	}
+	if id, err := p.Alloc(); err != nil || id != ids[1] || p.Len() != 4 {
+		t.Errorf("alloc got page %d of %d, want the freed page %d", id, p.Len(), ids[1])
+	}
+	if err := p.Free(ids[2]); err != nil {
+		t.Fatal(err)
+	}
+	// 再次释放链表头或链表中更早的页都会使 Alloc 两次返回同一页
+	if err := p.Free(ids[2]); !errors.Is(err, ErrFreed) {
+		t.Errorf("freeing the head of the free list twice got %v", err)
+	}
+	if err := p.Free(ids[1]); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Free(ids[2]); !errors.Is(err, ErrFreed) {
+		t.Errorf("freeing a page inside the free list twice got %v", err)
+	}
+	if id, err := p.Alloc(); err != nil || id != ids[1] {
+		t.Errorf("alloc got %d %v, want the freed page %d", id, err, ids[1])
+	}
+	if err := p.SetMeta([]byte("meta")); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Read(ids[0]); !errors.Is(err, ErrClosed) {
+		t.Errorf("read after Close got %v", err)
+	}
+
+	// 重新打开后页面、元数据与空闲链表仍在
+	if _, err := Open(path, Options{PageSize: 256}); !errors.Is(err, ErrPageSize) {
+		t.Errorf("opening with another page size got %v", err)
+	}
+	p, err = Open(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if data, err := p.Read(ids[0]); err != nil || data[0] != 'a' || string(p.Meta()) != "meta" || p.PageSize() != 128 {
+		t.Errorf("reopened page reads %q %v, meta %q", data[:1], err, p.Meta())
+	}
+	if err := p.Free(ids[2]); !errors.Is(err, ErrFreed) {
+		t.Errorf("freeing a page freed before reopening got %v", err)
+	}
+	if id, err := p.Alloc(); err != nil || id != ids[2] {
+		t.Errorf("alloc after reopening got %d %v, want the freed page %d", id, err, ids[2])
+	}
+	if id, err := p.Alloc(); err != nil || id == ids[2] {
+		t.Errorf("alloc got %d %v after the free list is used up", id, err)
+	}
+	if err := p.Free(ids[2]); err != nil {
+		t.Errorf("freeing an allocated page got %v", err)
+	}
+}
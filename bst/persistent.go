@@ -0,0 +1,132 @@
+package bst
+
+// Snapshot is a read-only version of a persistent tree, it never changes
+// once created and is safe to read from many goroutines.
+type Snapshot[K, V any] interface {
+	Search(key K) (V, bool)
+	Len() int
+	Print()
+	Iterator() Iterator[K, V]
+}
+
+// Persistent is a version of a persistent tree. Insert and Remove never
+// modify the version they are called on, they copy the search path and
+// return a new version sharing all the unchanged subtrees with it, so every
+// version keeps answering queries as of the time it was created. On
+// ErrDuplicateKey or ErrNotFound they return the version itself.
+type Persistent[K, V any] interface {
+	Snapshot[K, V]
+	Insert(key K, data V) (Persistent[K, V], error)
+	Remove(key K) (Persistent[K, V], error)
+	// Snapshot returns the current version as a read-only view in O(1)
+	Snapshot() Snapshot[K, V]
+}
+
+// Link is a node of a persistent tree, it has no parent pointer so that a
+// node can be shared by the subtrees of many versions.
+type Link[K, V any] interface {
+	Left() Link[K, V]
+	Right() Link[K, V]
+	Key() K
+	Data() V
+}
+
+// SearchLink returns the node with key in the subtree rooted on n
+func SearchLink[K, V any](n Link[K, V], key K, comp Comparator[K]) (Link[K, V], bool) {
+	for !isNil(n) {
+		switch c := comp(key, n.Key()); {
+		case c == 0:
+			return n, true
+		case c < 0:
+			n = n.Left()
+		default:
+			n = n.Right()
+		}
+	}
+	return nil, false
+}
+
+// pathIterator 以根到当前节点的路径代替父指针
+type pathIterator[K, V any] struct {
+	root Link[K, V]
+	comp Comparator[K]
+	path []Link[K, V]
+}
+
+// NewLinkIterator returns an Iterator over the persistent subtree rooted on
+// root ordered by comp, it keeps the path to the current node on a stack.
+func NewLinkIterator[K, V any](root Link[K, V], comp Comparator[K]) Iterator[K, V] {
+	return &pathIterator[K, V]{root: root, comp: comp}
+}
+
+func (it *pathIterator[K, V]) Seek(key K) bool {
+	it.path = it.path[:0]
+	found := 0 // 最后一个不小于 key 的节点在路径中的长度
+	for n := it.root; !isNil(n); {
+		it.path = append(it.path, n)
+		if it.comp(key, n.Key()) <= 0 {
+			found = len(it.path)
+			n = n.Left()
+		} else {
+			n = n.Right()
+		}
+	}
+	it.path = it.path[:found]
+	return it.Valid()
+}
+
+func (it *pathIterator[K, V]) First() bool {
+	it.path = it.path[:0]
+	it.descend(it.root, true)
+	return it.Valid()
+}
+
+func (it *pathIterator[K, V]) Last() bool {
+	it.path = it.path[:0]
+	it.descend(it.root, false)
+	return it.Valid()
+}
+
+func (it *pathIterator[K, V]) Next() bool { return it.step(true) }
+
+func (it *pathIterator[K, V]) Prev() bool { return it.step(false) }
+
+// step 移动到后继（forward）或前驱
+func (it *pathIterator[K, V]) step(forward bool) bool {
+	if !it.Valid() {
+		return false
+	}
+	n := it.path[len(it.path)-1]
+	if c := child(n, forward); !isNil(c) {
+		it.descend(c, forward)
+		return true
+	}
+	// 回溯到第一个自 forward 反侧进入的祖先
+	for it.path = it.path[:len(it.path)-1]; it.Valid(); it.path = it.path[:len(it.path)-1] {
+		p := it.path[len(it.path)-1]
+		if child(p, forward) != n {
+			return true
+		}
+		n = p
+	}
+	return false
+}
+
+// descend 自 n 沿左侧（leftmost）或右侧一直向下
+func (it *pathIterator[K, V]) descend(n Link[K, V], leftmost bool) {
+	for ; !isNil(n); n = child(n, !leftmost) {
+		it.path = append(it.path, n)
+	}
+}
+
+func (it *pathIterator[K, V]) Valid() bool { return len(it.path) > 0 }
+func (it *pathIterator[K, V]) Key() K      { return it.path[len(it.path)-1].Key() }
+func (it *pathIterator[K, V]) Data() V     { return it.path[len(it.path)-1].Data() }
+
+// child 返回 n 的右孩子（right）或左孩子
+func child[K, V any](n Link[K, V], right bool) Link[K, V] {
+	if right {
+		return n.Right()
+	}
+	return n.Left()
+}
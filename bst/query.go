@@ -0,0 +1,81 @@
+package bst
+
+// Floor returns the node with the largest key not greater than key in the
+// subtree rooted on n
+func Floor[K, V any](n Node[K, V], key K, comp Comparator[K]) (Node[K, V], bool) {
+	return nearest(n, key, comp, true, true)
+}
+
+// Ceiling returns the node with the smallest key not less than key in the
+// subtree rooted on n
+func Ceiling[K, V any](n Node[K, V], key K, comp Comparator[K]) (Node[K, V], bool) {
+	return nearest(n, key, comp, false, true)
+}
+
+// Lower returns the node with the largest key less than key in the subtree
+// rooted on n
+func Lower[K, V any](n Node[K, V], key K, comp Comparator[K]) (Node[K, V], bool) {
+	return nearest(n, key, comp, true, false)
+}
+
+// Higher returns the node with the smallest key greater than key in the
+// subtree rooted on n
+func Higher[K, V any](n Node[K, V], key K, comp Comparator[K]) (Node[K, V], bool) {
+	return nearest(n, key, comp, false, false)
+}
+
+// nearest 自 n 向下查找，记录沿途最后一个满足条件的节点。
+// 命中 key 后继续向下，使多重映射中 Ceiling 返回最早插入的节点，Floor 返回最后插入的节点
+func nearest[K, V any](n Node[K, V], key K, comp Comparator[K], below, inclusive bool) (Node[K, V], bool) {
+	var hit Node[K, V]
+	for !IsNil(n) {
+		c := comp(n.Key(), key)
+		if below && c < 0 || !below && c > 0 || c == 0 && inclusive {
+			hit = n
+		}
+		if c < 0 || c == 0 && below == inclusive {
+			n = n.RChild()
+		} else {
+			n = n.LChild()
+		}
+	}
+	if IsNil(hit) {
+		return nil, false
+	}
+	return hit, true
+}
+
+// Min returns the node with the smallest key in the subtree rooted on n
+func Min[K, V any](n Node[K, V]) (Node[K, V], bool) {
+	if IsNil(n) {
+		return nil, false
+	}
+	return subTreeMin(n), true
+}
+
+// Max returns the node with the largest key in the subtree rooted on n
+func Max[K, V any](n Node[K, V]) (Node[K, V], bool) {
+	if IsNil(n) {
+		return nil, false
+	}
+	return subTreeMax(n), true
+}
+
+// Range calls fn on the nodes with keys between lo and hi in the subtree
+// rooted on n by ascending order, until fn returns false
+func Range[K, V any](n Node[K, V], lo, hi K, loInclusive, hiInclusive bool, comp Comparator[K], fn func(n Node[K, V]) bool) {
+	var cur Node[K, V]
+	if loInclusive {
+		cur, _ = Ceiling(n, lo, comp)
+	} else {
+		cur, _ = Higher(n, lo, comp)
+	}
+	for ; !IsNil(cur); cur = Successor(cur) {
+		if c := comp(cur.Key(), hi); c > 0 || c == 0 && !hiInclusive {
+			return
+		}
+		if !fn(cur) {
+			return
+		}
+	}
+}
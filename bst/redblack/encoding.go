@@ -0,0 +1,61 @@
+package redblack
+
+import (
+	"errors"
+
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+// MarshalBinary encodes the tree as configured by the bst.Encoding given to New
+func (rb *rbTree[K, V]) MarshalBinary() ([]byte, error) {
+	return bst.EncodeBinary[K, V](rb, bst.RBTree, rb.enc)
+}
+
+// UnmarshalBinary replaces the content of the tree by data from MarshalBinary
+func (rb *rbTree[K, V]) UnmarshalBinary(data []byte) error {
+	return bst.DecodeBinary[K, V](rb, bst.RBTree, rb.enc, data, rb.shapeBuilder())
+}
+
+func (rb *rbTree[K, V]) MarshalJSON() ([]byte, error) {
+	return bst.EncodeJSON[K, V](rb, bst.RBTree, rb.enc)
+}
+
+func (rb *rbTree[K, V]) UnmarshalJSON(data []byte) error {
+	return bst.DecodeJSON[K, V](rb, bst.RBTree, rb.enc, data, rb.shapeBuilder())
+}
+
+var errColor = errors.New("redblack: decoding an invalid coloring")
+
+// shapeBuilder 按原有形状与颜色重建节点，拒绝双红及黑高度不等的节点
+func (rb *rbTree[K, V]) shapeBuilder() bst.ShapeBuilder[K, V] {
+	return bst.ShapeBuilder[K, V]{
+		Comp:  rb.comp,
+		Multi: rb.multi,
+		Node: func(key K, data V, black bool, l, r bst.Node[K, V]) (bst.Node[K, V], error) {
+			n := newNode(key, data)
+			if black {
+				n.setBlack()
+			}
+			n = rb.link(nodeOf(l), n, nodeOf(r))
+			if n.lchild.h() != n.rchild.h() || n.isRed() && (n.lchild.isRed() || n.rchild.isRed()) {
+				return nil, errColor
+			}
+			return n, nil
+		},
+		SetRoot: func(root bst.Node[K, V]) error {
+			n := nodeOf(root)
+			if n.isRed() {
+				return errColor
+			}
+			rb.root = orphan(n)
+			return nil
+		},
+	}
+}
+
+func nodeOf[K, V any](n bst.Node[K, V]) *node[K, V] {
+	if bst.IsNil(n) {
+		return nil
+	}
+	return n.(*node[K, V])
+}
@@ -4,43 +4,46 @@ import (
 	"github.com/mooncaker816/gostructure/bst"
 )
 
-type node struct {
-	lchild *node
-	rchild *node
-	parent *node
-	key    interface{}
-	data   interface{}
+type node[K, V any] struct {
+	lchild *node[K, V]
+	rchild *node[K, V]
+	parent *node[K, V]
+	key    K
+	data   V
+	aug    any // 子树摘要
 	height int // exact black height -1
 	attr   uint8
+	size   int // 子树规模
 }
 
-func newNode(key, data interface{}) *node {
-	return &node{key: key, data: data, height: -1} //默认红色，初始高度为实际黑高度-1
+func newNode[K, V any](key K, data V) *node[K, V] {
+	return &node[K, V]{key: key, data: data, height: -1, size: 1} //默认红色，初始高度为实际黑高度-1
 }
 
-func (n *node) Key() interface{}  { return n.key }
-func (n *node) Data() interface{} { return n.data }
-func (n *node) Height() int       { return n.height + 1 }
-func (n *node) LChild() bst.Node  { return n.lchild }
-func (n *node) RChild() bst.Node  { return n.rchild }
-func (n *node) Parent() bst.Node  { return n.parent }
+func (n *node[K, V]) Key() K                 { return n.key }
+func (n *node[K, V]) Data() V                { return n.data }
+func (n *node[K, V]) Height() int            { return n.height + 1 }
+func (n *node[K, V]) Size() int              { return n.size }
+func (n *node[K, V]) LChild() bst.Node[K, V] { return n.lchild }
+func (n *node[K, V]) RChild() bst.Node[K, V] { return n.rchild }
+func (n *node[K, V]) Parent() bst.Node[K, V] { return n.parent }
 
-func (n *node) Color() string {
+func (n *node[K, V]) Color() string {
 	if n.isBlack() {
 		return "B"
 	}
 	return "R"
 }
 
-func (n *node) SetKey(key interface{})   { n.key = key }
-func (n *node) SetData(data interface{}) { n.data = data }
-func (n *node) SetLChild(lc bst.Node) {
-	if lc == nil {
-		n.lchild = nil
-		return
-	}
-	lc0, ok := lc.(*node)
-	if !ok {
+func (n *node[K, V]) IsNil() bool { return n == nil }
+
+func (n *node[K, V]) SetKey(key K)     { n.key = key }
+func (n *node[K, V]) SetData(data V)   { n.data = data }
+func (n *node[K, V]) Summary() any     { return n.aug }
+func (n *node[K, V]) SetSummary(s any) { n.aug = s }
+func (n *node[K, V]) SetLChild(lc bst.Node[K, V]) {
+	lc0, ok := lc.(*node[K, V])
+	if !ok && !bst.IsNil(lc) {
 		panic("inconsistent node type")
 	}
 	if n != nil {
@@ -48,13 +51,9 @@ func (n *node) SetLChild(lc bst.Node) {
 	}
 }
 
-func (n *node) SetRChild(rc bst.Node) {
-	if rc == nil {
-		n.rchild = nil
-		return
-	}
-	rc0, ok := rc.(*node)
-	if !ok {
+func (n *node[K, V]) SetRChild(rc bst.Node[K, V]) {
+	rc0, ok := rc.(*node[K, V])
+	if !ok && !bst.IsNil(rc) {
 		panic("inconsistent node type")
 	}
 	if n != nil {
@@ -62,13 +61,9 @@ func (n *node) SetRChild(rc bst.Node) {
 	}
 }
 
-func (n *node) SetParent(p bst.Node) {
-	if p == nil {
-		n.parent = nil
-		return
-	}
-	p0, ok := p.(*node)
-	if !ok {
+func (n *node[K, V]) SetParent(p bst.Node[K, V]) {
+	p0, ok := p.(*node[K, V])
+	if !ok && !bst.IsNil(p) {
 		panic("inconsistent node type")
 	}
 	if n != nil {
@@ -77,19 +72,30 @@ func (n *node) SetParent(p bst.Node) {
 }
 
 // customized
-func (n *node) updateHeight() {
+func (n *node[K, V]) updateHeight() {
 	n.height = n.maxHeightOfChildren()
 	if n.isBlack() {
 		n.height++
 	}
 }
 
-func (n *node) maxHeightOfChildren() int {
+func updateSize[K, V any](n bst.Node[K, V]) {
+	n0 := n.(*node[K, V])
+	n0.size = 1
+	if n0.lchild != nil {
+		n0.size += n0.lchild.size
+	}
+	if n0.rchild != nil {
+		n0.size += n0.rchild.size
+	}
+}
+
+func (n *node[K, V]) maxHeightOfChildren() int {
 	lH, rH := -1, -1
-	if bst.HasLChild(n) {
+	if n.lchild != nil {
 		lH = n.lchild.height
 	}
-	if bst.HasRChild(n) {
+	if n.rchild != nil {
 		rH = n.rchild.height
 	}
 	return max(lH, rH)
@@ -107,22 +113,22 @@ const (
 	Black
 )
 
-func (n *node) isBlack() bool {
+func (n *node[K, V]) isBlack() bool {
 	return n == nil || n.attr&1 == 1 // 外部节点视为黑
 }
 
-func (n *node) isRed() bool {
+func (n *node[K, V]) isRed() bool {
 	return !n.isBlack()
 }
 
-func (n *node) setRed() {
+func (n *node[K, V]) setRed() {
 	n.attr &= 0xfe
 }
 
-func (n *node) setBlack() {
+func (n *node[K, V]) setBlack() {
 	n.attr |= 1
 }
 
-func (n *node) flipColor() {
+func (n *node[K, V]) flipColor() {
 	n.attr ^= 1
 }
@@ -0,0 +1,233 @@
+package redblack
+
+import (
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+// persistent red-black tree, 每个版本创建后不再改变
+type persistent[K, V any] struct {
+	root *pnode[K, V]
+	comp bst.Comparator[K]
+	size int
+}
+
+// pnode 无父指针，可被多个版本共享
+type pnode[K, V any] struct {
+	left  *pnode[K, V]
+	right *pnode[K, V]
+	key   K
+	data  V
+	red   bool
+}
+
+func (n *pnode[K, V]) Left() bst.Link[K, V]  { return n.left }
+func (n *pnode[K, V]) Right() bst.Link[K, V] { return n.right }
+func (n *pnode[K, V]) Key() K                { return n.key }
+func (n *pnode[K, V]) Data() V               { return n.data }
+func (n *pnode[K, V]) IsNil() bool           { return n == nil }
+
+func (n *pnode[K, V]) isRed() bool   { return n != nil && n.red }
+func (n *pnode[K, V]) isBlack() bool { return n != nil && !n.red } // 外部节点不计入
+
+// NewPersistent returns an empty persistent red-black tree with the default
+// comparator of K, a Comparator[K] or func(a, b K) int in parms replaces it.
+func NewPersistent[K, V any](parms ...interface{}) bst.Persistent[K, V] {
+	t := &persistent[K, V]{comp: bst.DefaultCompare[K]()}
+	for _, p := range parms {
+		switch v := p.(type) {
+		case bst.Comparator[K]:
+			t.comp = v
+		case func(a, b K) int:
+			t.comp = v
+		}
+	}
+	return t
+}
+
+func (t *persistent[K, V]) Len() int { return t.size }
+
+func (t *persistent[K, V]) Snapshot() bst.Snapshot[K, V] { return t }
+
+func (t *persistent[K, V]) Search(key K) (V, bool) {
+	if n, ok := bst.SearchLink[K, V](t.root, key, t.comp); ok {
+		return n.Data(), true
+	}
+	var zero V
+	return zero, false
+}
+
+func (t *persistent[K, V]) Iterator() bst.Iterator[K, V] {
+	return bst.NewLinkIterator[K, V](t.root, t.comp)
+}
+
+// Print 将当前版本复制为普通红黑树后打印
+func (t *persistent[K, V]) Print() {
+	root, _ := thaw(t.root, nil)
+	bst.PrintWithUnitSize(root, 2)
+}
+
+// thaw 同时返回子树的黑高度 -1
+func thaw[K, V any](p *pnode[K, V], parent *node[K, V]) (bst.Node[K, V], int) {
+	if p == nil {
+		return nil, -1
+	}
+	n := &node[K, V]{parent: parent, key: p.key, data: p.data}
+	l, height := thaw(p.left, n)
+	r, _ := thaw(p.right, n)
+	n.SetLChild(l)
+	n.SetRChild(r)
+	n.height = height
+	if !p.red {
+		n.setBlack()
+		n.height++
+	}
+	updateSize[K, V](n)
+	return n, n.height
+}
+
+func (t *persistent[K, V]) Insert(key K, data V) (p bst.Persistent[K, V], err error) {
+	p = t
+	defer bst.CatchIncomparable(&err)
+	root, err := t.insert(t.root, key, data)
+	if err != nil {
+		return t, err
+	}
+	return &persistent[K, V]{root: blacken(root), comp: t.comp, size: t.size + 1}, nil
+}
+
+// Remove returns the version itself and ErrNotFound when key is absent
+func (t *persistent[K, V]) Remove(key K) (p bst.Persistent[K, V], err error) {
+	p = t
+	defer bst.CatchIncomparable(&err)
+	if _, ok := bst.SearchLink[K, V](t.root, key, t.comp); !ok {
+		return t, bst.ErrNotFound
+	}
+	return &persistent[K, V]{root: blacken(t.remove(t.root, key)), comp: t.comp, size: t.size - 1}, nil
+}
+
+func (t *persistent[K, V]) insert(n *pnode[K, V], key K, data V) (*pnode[K, V], error) {
+	if n == nil {
+		return &pnode[K, V]{key: key, data: data, red: true}, nil
+	}
+	c := t.comp(key, n.key)
+	if c == 0 {
+		return nil, bst.ErrDuplicateKey
+	}
+	l, r := n.left, n.right
+	var err error
+	if c < 0 {
+		l, err = t.insert(l, key, data)
+	} else {
+		r, err = t.insert(r, key, data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if n.red { // 双红缺陷留给黑色的父节点修正
+		return paint(l, n.key, n.data, r, true), nil
+	}
+	return balance(l, n.key, n.data, r), nil
+}
+
+// remove 删除树中必然存在的 key，以黑节点为根的子树删除后黑高度减一
+func (t *persistent[K, V]) remove(n *pnode[K, V], key K) *pnode[K, V] {
+	switch c := t.comp(key, n.key); {
+	case c < 0:
+		if n.left.isBlack() {
+			return balLeft(t.remove(n.left, key), n.key, n.data, n.right)
+		}
+		return paint(t.remove(n.left, key), n.key, n.data, n.right, true)
+	case c > 0:
+		if n.right.isBlack() {
+			return balRight(n.left, n.key, n.data, t.remove(n.right, key))
+		}
+		return paint(n.left, n.key, n.data, t.remove(n.right, key), true)
+	}
+	return fuse(n.left, n.right)
+}
+
+func paint[K, V any](l *pnode[K, V], key K, data V, r *pnode[K, V], red bool) *pnode[K, V] {
+	return &pnode[K, V]{left: l, right: r, key: key, data: data, red: red}
+}
+
+func blacken[K, V any](n *pnode[K, V]) *pnode[K, V] {
+	if n.isRed() {
+		return paint(n.left, n.key, n.data, n.right, false)
+	}
+	return n
+}
+
+// redden 将黑高度减一，n 必为黑节点
+func redden[K, V any](n *pnode[K, V]) *pnode[K, V] {
+	return paint(n.left, n.key, n.data, n.right, true)
+}
+
+// balance 以黑节点连接 l、r，并修正其中任一侧的双红缺陷
+func balance[K, V any](l *pnode[K, V], key K, data V, r *pnode[K, V]) *pnode[K, V] {
+	switch {
+	case l.isRed() && r.isRed():
+		return paint(blacken(l), key, data, blacken(r), true)
+	case l.isRed() && l.left.isRed():
+		return paint(blacken(l.left), l.key, l.data, paint(l.right, key, data, r, false), true)
+	case l.isRed() && l.right.isRed():
+		lr := l.right
+		return paint(paint(l.left, l.key, l.data, lr.left, false), lr.key, lr.data, paint(lr.right, key, data, r, false), true)
+	case r.isRed() && r.right.isRed():
+		return paint(paint(l, key, data, r.left, false), r.key, r.data, blacken(r.right), true)
+	case r.isRed() && r.left.isRed():
+		rl := r.left
+		return paint(paint(l, key, data, rl.left, false), rl.key, rl.data, paint(rl.right, r.key, r.data, r.right, false), true)
+	}
+	return paint(l, key, data, r, false)
+}
+
+// balLeft 左子树 l 的黑高度比 r 少一
+func balLeft[K, V any](l *pnode[K, V], key K, data V, r *pnode[K, V]) *pnode[K, V] {
+	switch {
+	case l.isRed():
+		return paint(blacken(l), key, data, r, true)
+	case r.isBlack():
+		return balance(l, key, data, redden(r))
+	}
+	// r 为红色，其左孩子为黑色
+	rl := r.left
+	return paint(paint(l, key, data, rl.left, false), rl.key, rl.data, balance(rl.right, r.key, r.data, redden(r.right)), true)
+}
+
+// balRight 右子树 r 的黑高度比 l 少一
+func balRight[K, V any](l *pnode[K, V], key K, data V, r *pnode[K, V]) *pnode[K, V] {
+	switch {
+	case r.isRed():
+		return paint(l, key, data, blacken(r), true)
+	case l.isBlack():
+		return balance(redden(l), key, data, r)
+	}
+	// l 为红色，其右孩子为黑色
+	lr := l.right
+	return paint(balance(redden(l.left), l.key, l.data, lr.left), lr.key, lr.data, paint(lr.right, key, data, r, false), true)
+}
+
+// fuse 合并被删除节点的左右子树，二者黑高度相同
+func fuse[K, V any](l, r *pnode[K, V]) *pnode[K, V] {
+	switch {
+	case l == nil:
+		return r
+	case r == nil:
+		return l
+	case l.red && r.red:
+		m := fuse(l.right, r.left)
+		if m.isRed() {
+			return paint(paint(l.left, l.key, l.data, m.left, true), m.key, m.data, paint(m.right, r.key, r.data, r.right, true), true)
+		}
+		return paint(l.left, l.key, l.data, paint(m, r.key, r.data, r.right, true), true)
+	case !l.red && !r.red:
+		m := fuse(l.right, r.left)
+		if m.isRed() {
+			return paint(paint(l.left, l.key, l.data, m.left, false), m.key, m.data, paint(m.right, r.key, r.data, r.right, false), true)
+		}
+		return balLeft(l.left, l.key, l.data, paint(m, r.key, r.data, r.right, false))
+	case r.red:
+		return paint(fuse(l, r.left), r.key, r.data, r.right, true)
+	}
+	return paint(l.left, l.key, l.data, fuse(l.right, r), true)
+}
@@ -1,41 +1,77 @@
 package redblack
 
 import (
-	"errors"
+	"math/bits"
 
 	"github.com/mooncaker816/gostructure/bst"
 )
 
 func init() {
-	bst.RegisterBST(bst.RBTree, New)
+	bst.Register("redblack", bst.Factory{
+		New: New[any, any],
+		Typed: []interface{}{
+			New[int, int], New[int, string], New[int, any],
+			New[string, int], New[string, string], New[string, any],
+		},
+		Multimap: true,
+	})
 }
 
-type rbTree struct {
-	root *node
-	comp bst.Comparator
+type rbTree[K, V any] struct {
+	root    *node[K, V]
+	comp    bst.Comparator[K]
+	aug     bst.Augmenter[K, V]
+	enc     bst.Encoding[K, V]
+	stats   *bst.Stats
+	multi   bool               // MultiKeys 模式
+	updates []bst.Option[K, V] // 结构变化后自底向上更新节点
 }
 
-// New returns an empty redblack tree
-func New(parms ...interface{}) bst.BST {
-	t := new(rbTree)
-	t.comp = bst.BasicCompare
+// New returns an empty redblack tree. It is not safe for concurrent use
+// with writes, wrap it by bst.Synchronized to let readers run in parallel.
+func New[K, V any](parms ...interface{}) bst.BST[K, V] {
+	t := new(rbTree[K, V])
+	t.comp = bst.DefaultCompare[K]()
 	for _, p := range parms {
 		switch v := p.(type) {
-		case bst.Comparator:
+		case bst.Comparator[K]:
 			t.comp = v
+		case func(a, b K) int:
+			t.comp = v
+		case bst.Augmenter[K, V]:
+			t.aug = v
+		case bst.Encoding[K, V]:
+			t.enc = v
+		case *bst.Stats:
+			t.stats = v
+		case bst.Mode:
+			t.multi = v == bst.MultiKeys
 		}
 	}
+	t.comp = bst.CountComparisons(t.comp, t.stats)
+	t.updates = []bst.Option[K, V]{updateSize[K, V]}
+	if t.aug != nil {
+		t.updates = append(t.updates, bst.AugmentOption(t.aug))
+	}
 	return t
 }
 
-func (rb *rbTree) Root() bst.Node { return rb.root }
+func (rb *rbTree[K, V]) Root() bst.Node[K, V] { return rb.root }
+
+// Len returns the number of keys in the tree
+func (rb *rbTree[K, V]) Len() int {
+	if rb.root == nil {
+		return 0
+	}
+	return rb.root.size
+}
 
-func rbOK(n *node) bool {
+func rbOK[K, V any](n *node[K, V]) bool {
 	lh, rh := -1, -1
-	if bst.HasLChild(n) {
+	if n.lchild != nil {
 		lh = n.lchild.height
 	}
-	if bst.HasRChild(n) {
+	if n.rchild != nil {
 		rh = n.rchild.height
 	}
 	if lh != rh {
@@ -47,58 +83,105 @@ func rbOK(n *node) bool {
 	return n.height == lh+1
 }
 
-func (rb *rbTree) Search(key interface{}) (bst.Node, bool) {
-	n, result := rb.searchIn(rb.root, key)
+func (rb *rbTree[K, V]) Search(key K) (bst.Node[K, V], bool) {
+	if rb.root == nil {
+		return nil, false
+	}
+	n, result := rb.find(key)
 	if result == 0 {
 		return n, true
 	}
 	return n, false
 }
 
-func (rb *rbTree) searchIn(n *node, key interface{}) (*node, int) {
-	switch rb.comp(key, n.key) {
-	case 0:
+// find 在非空树中查找 key，多重映射中返回最早插入的节点
+func (rb *rbTree[K, V]) find(key K) (*node[K, V], int) {
+	if rb.multi {
+		return rb.searchMulti(rb.root, key, false)
+	}
+	return rb.searchIn(rb.root, key)
+}
+
+// searchMulti 在多重映射中查找 key 最早插入的节点，未找到或 last 时返回新节点的插入位置，
+// last 时新节点位于所有相等的关键码之后
+func (rb *rbTree[K, V]) searchMulti(n *node[K, V], key K, last bool) (*node[K, V], int) {
+	var hit *node[K, V]
+	for {
+		c := rb.comp(key, n.key)
+		next, result := n.rchild, 1
+		if c < 0 || c == 0 && !last {
+			next, result = n.lchild, -1
+		}
+		if c == 0 && !last {
+			hit = n
+		}
+		if next == nil {
+			if hit != nil {
+				return hit, 0
+			}
+			return n, result
+		}
+		n = next
+	}
+}
+
+func (rb *rbTree[K, V]) searchIn(n *node[K, V], key K) (*node[K, V], int) {
+	switch c := rb.comp(key, n.key); {
+	case c == 0:
 		return n, 0
-	case -1:
-		if bst.HasLChild(n) {
+	case c < 0:
+		if n.lchild != nil {
 			return rb.searchIn(n.lchild, key)
 		}
 		return n, -1
-	case 1:
-		if bst.HasRChild(n) {
+	default:
+		if n.rchild != nil {
 			return rb.searchIn(n.rchild, key)
 		}
 		return n, 1
-	default:
-		panic("compare result illegal")
 	}
 }
 
-func (rb *rbTree) Insert(key, data interface{}) (bst.Node, error) {
+func (rb *rbTree[K, V]) Insert(key K, data V) (_ bst.Node[K, V], err error) {
+	if bst.Debug {
+		defer bst.MustValidate(rb)
+	}
+	defer bst.CatchIncomparable(&err)
 	if rb.root == nil {
-		rb.root = newNode(key, data)
-		rb.root.setBlack()
-		return rb.root, nil
+		return rb.attach(nil, 0, key, data), nil
+	}
+	if rb.multi {
+		n, result := rb.searchMulti(rb.root, key, true)
+		return rb.attach(n, result, key, data), nil
 	}
 	n, result := rb.searchIn(rb.root, key)
-	switch result {
-	case 0:
-		return nil, errors.New("insert node with duplicate key")
-	case -1:
-		new := newNode(key, data)
+	if result == 0 {
+		return nil, bst.ErrDuplicateKey
+	}
+	return rb.attach(n, result, key, data), nil
+}
+
+// attach 将新节点插入为 searchIn 返回的节点 n 的孩子，result 为查找的结果，n 为 nil 时作为根
+func (rb *rbTree[K, V]) attach(n *node[K, V], result int, key K, data V) *node[K, V] {
+	new := newNode(key, data)
+	switch {
+	case n == nil:
+		rb.root = new
+		new.setBlack()
+		new.updateHeight()
+		bst.UpdateAbove[K, V](new, rb.updates...)
+		return new
+	case result < 0:
 		bst.AttachLChild(n, new)
-		rb.solveDoubleRed(new)
-		return new, nil
-	case 1:
-		new := newNode(key, data)
+	default:
 		bst.AttachRChild(n, new)
-		rb.solveDoubleRed(new)
-		return new, nil
 	}
-	return nil, nil
+	bst.UpdateAbove[K, V](new, rb.updates...)
+	rb.solveDoubleRed(new)
+	return new
 }
 
-func (rb *rbTree) solveDoubleRed(n *node) {
+func (rb *rbTree[K, V]) solveDoubleRed(n *node[K, V]) {
 	if bst.IsRoot(n) {
 		n.setBlack()
 		n.height++
@@ -110,15 +193,16 @@ func (rb *rbTree) solveDoubleRed(n *node) {
 	}
 	g := p.parent
 	u := bst.Sibling(p)
-	u0 := u.(*node)
+	u0 := u.(*node[K, V])
 	if u0.isBlack() { // RR-1
+		rb.stats.Rotate(bst.Zigzag[K, V](n))
 		x := g.parent
 		if bst.IsLChild(g) {
-			x.lchild = rr1(n)
+			x.lchild = rr1(n, rb.updates...)
 		} else if bst.IsRChild(g) {
-			x.rchild = rr1(n)
+			x.rchild = rr1(n, rb.updates...)
 		} else {
-			rb.root = rr1(n)
+			rb.root = rr1(n, rb.updates...)
 		}
 	} else { // RR-2
 		p.setBlack()
@@ -133,50 +217,139 @@ func (rb *rbTree) solveDoubleRed(n *node) {
 }
 
 // roate + change color + update height for RR-1
-func rr1(n *node) *node {
-	a, b, c := bst.RotateAt(n)
-	a.(*node).setRed()
-	c.(*node).setRed()
-	b.(*node).setBlack()
-	a.(*node).updateHeight()
-	b.(*node).updateHeight()
-	c.(*node).updateHeight()
-	return b.(*node)
-}
-
-func (rb *rbTree) Remove(key interface{}) (bst.Node, error) {
-	n, result := rb.searchIn(rb.root, key)
+func rr1[K, V any](n *node[K, V], opts ...bst.Option[K, V]) *node[K, V] {
+	a, b, c := bst.RotateAt[K, V](n, opts...)
+	a.(*node[K, V]).setRed()
+	c.(*node[K, V]).setRed()
+	b.(*node[K, V]).setBlack()
+	a.(*node[K, V]).updateHeight()
+	c.(*node[K, V]).updateHeight()
+	b.(*node[K, V]).updateHeight()
+	return b.(*node[K, V])
+}
+
+func (rb *rbTree[K, V]) Remove(key K) (_ bst.Node[K, V], err error) {
+	if bst.Debug {
+		defer bst.MustValidate(rb)
+	}
+	defer bst.CatchIncomparable(&err)
+	if rb.root == nil {
+		return nil, bst.ErrNotFound
+	}
+	n, result := rb.find(key)
 	if result != 0 {
-		return nil, nil
+		return nil, bst.ErrNotFound
 	}
-	hot, r := bst.RemoveAt(n, rb.root)
+	// n 有两个孩子时改为删除其后继节点，并将后继的关键码与数据移入 n，故先复制
+	removed := newNode(n.key, n.data)
+	rb.detach(n)
+	return removed, nil
+}
+
+// detach 删除节点 n
+func (rb *rbTree[K, V]) detach(n *node[K, V]) {
+	if n == rb.root && (n.lchild == nil || n.rchild == nil) {
+		rb.root = n.lchild
+		if rb.root == nil {
+			rb.root = n.rchild
+		}
+	}
+	hot, r := bst.RemoveAt[K, V](n, rb.root, rb.updates...)
 	// fmt.Println(hot, r)
-	hot0 := hot.(*node)
+	hot0 := hot.(*node[K, V])
 	if rb.root == nil {
-		return nil, nil
+		return
 	}
 	if hot0 == nil {
 		rb.root.setBlack()
 		rb.root.updateHeight()
-		return hot0, nil
+		return
 	}
 	if rbOK(hot0) {
-		return hot0, nil
+		return
 	}
-	r0, ok := r.(*node)
+	r0, ok := r.(*node[K, V])
 	// if ok {
 	if ok && r0.isRed() {
 		r0.setBlack()
 		r0.height++
-		return hot0, nil
+		return
 	}
 	// }
 	rb.solveDoubleBlack(r0, hot0)
-	return hot0, nil
 }
 
-func (rb *rbTree) solveDoubleBlack(r, hot *node) {
-	var p, s *node
+// Put inserts key with data or replaces its data after a single search
+func (rb *rbTree[K, V]) Put(key K, data V) (old V, replaced bool, err error) {
+	if bst.Debug {
+		defer bst.MustValidate(rb)
+	}
+	defer bst.CatchIncomparable(&err)
+	if rb.root == nil {
+		rb.attach(nil, 0, key, data)
+		return old, false, nil
+	}
+	n, result := rb.find(key)
+	if result != 0 {
+		rb.attach(n, result, key, data)
+		return old, false, nil
+	}
+	old = n.data
+	rb.replace(n, data)
+	return old, true, nil
+}
+
+// replace 替换节点的数据，数据参与增强时更新摘要
+func (rb *rbTree[K, V]) replace(n *node[K, V], data V) {
+	n.data = data
+	if rb.aug != nil {
+		bst.UpdateAbove[K, V](n, rb.updates...)
+	}
+}
+
+func (rb *rbTree[K, V]) Update(key K, fn func(old V, ok bool) (V, bool)) (err error) {
+	if bst.Debug {
+		defer bst.MustValidate(rb)
+	}
+	defer bst.CatchIncomparable(&err)
+	var n *node[K, V]
+	result := 1
+	if rb.root != nil {
+		n, result = rb.find(key)
+	}
+	var old V
+	if result == 0 {
+		old = n.data
+	}
+	data, keep := fn(old, result == 0)
+	switch {
+	case result == 0 && keep:
+		rb.replace(n, data)
+	case result == 0:
+		rb.detach(n)
+	case keep:
+		rb.attach(n, result, key, data)
+	}
+	return nil
+}
+
+func (rb *rbTree[K, V]) GetOrInsert(key K, data V) (_ bst.Node[K, V], found bool, err error) {
+	if bst.Debug {
+		defer bst.MustValidate(rb)
+	}
+	defer bst.CatchIncomparable(&err)
+	if rb.root == nil {
+		return rb.attach(nil, 0, key, data), false, nil
+	}
+	n, result := rb.find(key)
+	if result == 0 {
+		return n, true, nil
+	}
+	return rb.attach(n, result, key, data), false, nil
+}
+
+func (rb *rbTree[K, V]) solveDoubleBlack(r, hot *node[K, V]) {
+	var p, s *node[K, V]
 	if r != nil {
 		p = r.parent
 	} else {
@@ -192,7 +365,7 @@ func (rb *rbTree) solveDoubleBlack(r, hot *node) {
 		s = p.lchild
 	}
 	if s.isBlack() {
-		var t *node
+		var t *node[K, V]
 		if s.rchild.isRed() {
 			t = s.rchild
 		}
@@ -201,13 +374,14 @@ func (rb *rbTree) solveDoubleBlack(r, hot *node) {
 		}
 		if t != nil { // BB-1 s至少有一个红孩子
 			oldattr := p.attr
+			rb.stats.Rotate(bst.Zigzag[K, V](t))
 			x := p.parent
 			if bst.IsLChild(p) {
-				x.lchild = bb1(t, oldattr)
+				x.lchild = bb1(t, oldattr, rb.updates...)
 			} else if bst.IsRChild(p) {
-				x.rchild = bb1(t, oldattr)
+				x.rchild = bb1(t, oldattr, rb.updates...)
 			} else {
-				rb.root = bb1(t, oldattr)
+				rb.root = bb1(t, oldattr, rb.updates...)
 			}
 		} else {
 			s.setRed()
@@ -223,59 +397,110 @@ func (rb *rbTree) solveDoubleBlack(r, hot *node) {
 		s.setBlack()
 		p.setRed()
 		hot = p
-		var t *node
+		var t *node[K, V]
 		if bst.IsLChild(s) {
 			t = s.lchild
 		} else {
 			t = s.rchild
 		}
+		rb.stats.Rotate(false)
 		x := p.parent
 		if bst.IsLChild(p) {
-			x.lchild = bb3(t)
+			x.lchild = bb3(t, rb.updates...)
 		} else if bst.IsRChild(p) {
-			x.rchild = bb3(t)
+			x.rchild = bb3(t, rb.updates...)
 		} else {
-			rb.root = bb3(t)
+			rb.root = bb3(t, rb.updates...)
 		}
 		rb.solveDoubleBlack(r, hot)
 	}
 }
 
-func bb1(n *node, oldattr uint8) *node {
-	a, b, c := bst.RotateAt(n)
-	a0, b0, c0 := a.(*node), b.(*node), c.(*node)
+func bb1[K, V any](n *node[K, V], oldattr uint8, opts ...bst.Option[K, V]) *node[K, V] {
+	a, b, c := bst.RotateAt[K, V](n, opts...)
+	a0, b0, c0 := a.(*node[K, V]), b.(*node[K, V]), c.(*node[K, V])
 	a0.updateHeight()
-	b0.updateHeight()
 	c0.updateHeight()
 
-	b0.attr = b0.attr&0xfe | oldattr&1
-	b0.updateHeight()
-
-	if bst.HasLChild(b0) {
+	if b0.lchild != nil {
 		b0.lchild.setBlack()
 		b0.lchild.updateHeight()
 	}
-	if bst.HasRChild(b0) {
+	if b0.rchild != nil {
 		b0.rchild.setBlack()
 		b0.rchild.updateHeight()
 	}
+
+	b0.attr = b0.attr&0xfe | oldattr&1
+	b0.updateHeight()
 	return b0
 }
 
-func bb3(n *node) *node {
-	a, b, c := bst.RotateAt(n)
-	a0, b0, c0 := a.(*node), b.(*node), c.(*node)
+func bb3[K, V any](n *node[K, V], opts ...bst.Option[K, V]) *node[K, V] {
+	a, b, c := bst.RotateAt[K, V](n, opts...)
+	a0, b0, c0 := a.(*node[K, V]), b.(*node[K, V]), c.(*node[K, V])
 	a0.updateHeight()
-	b0.updateHeight()
 	c0.updateHeight()
+	b0.updateHeight()
 	return b0
 }
 
-func (rb *rbTree) Print() {
+// Count returns the number of entries of key in O(log n)
+func (rb *rbTree[K, V]) Count(key K) int { return bst.CountRange(rb.root, key, key, rb.comp) }
+
+func (rb *rbTree[K, V]) All(key K, fn func(n bst.Node[K, V]) bool) {
+	bst.Range(rb.root, key, key, true, true, rb.comp, fn)
+}
+
+// RemoveOne removes the oldest entry of key as Remove does
+func (rb *rbTree[K, V]) RemoveOne(key K) (bst.Node[K, V], error) { return rb.Remove(key) }
+
+func (rb *rbTree[K, V]) RemoveAll(key K) (int, error) { return bst.RemoveEach(key, rb.Remove) }
+
+// BuildSorted builds a perfectly balanced tree from the sorted keys in O(n),
+// only the nodes on the last level are red when it is not full
+func (rb *rbTree[K, V]) BuildSorted(keys []K, values []V, verify bool) error {
+	if verify {
+		check := bst.CheckSorted[K]
+		if rb.multi {
+			check = bst.CheckSortedMulti[K]
+		}
+		if err := check(keys, rb.comp); err != nil {
+			return err
+		}
+	}
+	red := bits.Len(uint(len(keys))) - 1 // 最底层的深度
+	if len(keys)&(len(keys)+1) == 0 {
+		red = -1 // 满二叉树全为黑色
+	}
+	rb.root = rb.build(keys, values, nil, 0, red)
+	return nil
+}
+
+func (rb *rbTree[K, V]) build(keys []K, values []V, parent *node[K, V], depth, red int) *node[K, V] {
+	if len(keys) == 0 {
+		return nil
+	}
+	mid := len(keys) / 2
+	n := newNode(keys[mid], values[mid])
+	n.parent = parent
+	n.lchild = rb.build(keys[:mid], values[:mid], n, depth+1, red)
+	n.rchild = rb.build(keys[mid+1:], values[mid+1:], n, depth+1, red)
+	if depth != red {
+		n.setBlack()
+	}
+	n.updateHeight()
+	for _, opt := range rb.updates {
+		opt(n)
+	}
+	return n
+}
+
+func (rb *rbTree[K, V]) Print() {
 	bst.PrintWithUnitSize(rb.root, 2)
 }
 
-func (rb *rbTree) Walk(o bst.Order, opts ...bst.Option) {
+func (rb *rbTree[K, V]) Walk(o bst.Order, opts ...bst.Option[K, V]) {
 	switch o {
 	case bst.PreOrder:
 		bst.TravPre(rb.root, opts...)
@@ -285,7 +510,63 @@ func (rb *rbTree) Walk(o bst.Order, opts ...bst.Option) {
 		bst.TravPost(rb.root, opts...)
 	case bst.LevelOrder:
 		bst.TravLevel(rb.root, opts...)
+	case bst.ReverseInOrder:
+		bst.TravReverseIn(rb.root, opts...)
 	default:
 		panic("unsupported walk order")
 	}
 }
+
+func (rb *rbTree[K, V]) Traverse(o bst.Order, fn func(n bst.Node[K, V]) bool) bool {
+	return bst.Traverse[K, V](rb.root, o, fn)
+}
+
+func (rb *rbTree[K, V]) Iterator() bst.Iterator[K, V] {
+	return bst.NewIterator[K, V](rb, rb.comp)
+}
+
+func (rb *rbTree[K, V]) Floor(key K) (bst.Node[K, V], bool) {
+	return bst.Floor(rb.root, key, rb.comp)
+}
+
+func (rb *rbTree[K, V]) Ceiling(key K) (bst.Node[K, V], bool) {
+	return bst.Ceiling(rb.root, key, rb.comp)
+}
+
+func (rb *rbTree[K, V]) Lower(key K) (bst.Node[K, V], bool) {
+	return bst.Lower(rb.root, key, rb.comp)
+}
+
+func (rb *rbTree[K, V]) Higher(key K) (bst.Node[K, V], bool) {
+	return bst.Higher(rb.root, key, rb.comp)
+}
+
+func (rb *rbTree[K, V]) Min() (bst.Node[K, V], bool) { return bst.Min(rb.root) }
+
+func (rb *rbTree[K, V]) Max() (bst.Node[K, V], bool) { return bst.Max(rb.root) }
+
+func (rb *rbTree[K, V]) Range(lo, hi K, loInclusive, hiInclusive bool, fn func(n bst.Node[K, V]) bool) {
+	bst.Range(rb.root, lo, hi, loInclusive, hiInclusive, rb.comp, fn)
+}
+
+func (rb *rbTree[K, V]) Select(k int) (bst.Node[K, V], bool) { return bst.Select(rb.root, k) }
+
+func (rb *rbTree[K, V]) Rank(key K) int { return bst.Rank(rb.root, key, rb.comp) }
+
+func (rb *rbTree[K, V]) CountRange(lo, hi K) int {
+	return bst.CountRange(rb.root, lo, hi, rb.comp)
+}
+
+func (rb *rbTree[K, V]) Summary() any {
+	if rb.aug == nil {
+		return nil
+	}
+	return bst.SummaryOf[K, V](rb.root, rb.aug)
+}
+
+func (rb *rbTree[K, V]) SummaryRange(lo, hi K) any {
+	if rb.aug == nil {
+		return nil
+	}
+	return bst.SummaryRange[K, V](rb.root, lo, hi, rb.comp, rb.aug)
+}
@@ -0,0 +1,143 @@
+package redblack
+
+import (
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+// Split moves the keys not less than key into a new redblack tree in O(log n)
+func (rb *rbTree[K, V]) Split(key K) bst.BST[K, V] {
+	l, r := rb.split(rb.root, key)
+	other := *rb
+	rb.root, other.root = rb.blacken(orphan(l)), rb.blacken(orphan(r))
+	return &other
+}
+
+// Join moves the keys of other, a redblack tree with greater keys, into the
+// tree in O(log n)
+func (rb *rbTree[K, V]) Join(other bst.BST[K, V]) error {
+	o, ok := other.(*rbTree[K, V])
+	if !ok {
+		return bst.ErrJoinClass
+	}
+	if err := bst.CheckJoin[K, V](rb, o, rb.comp); err != nil {
+		return err
+	}
+	if o.root == nil {
+		return nil
+	}
+	if rb.root == nil {
+		rb.root, o.root = o.root, nil
+		return nil
+	}
+	// 以左树的最大节点连接两树
+	last, _ := rb.Max()
+	mid := newNode(last.Key(), last.Data())
+	rb.detach(last.(*node[K, V]))
+	rb.root, o.root = orphan(rb.join(rb.root, mid, o.root)), nil
+	return nil
+}
+
+func orphan[K, V any](n *node[K, V]) *node[K, V] {
+	if n != nil {
+		n.parent = nil
+	}
+	return n
+}
+
+// h 返回黑高度 -1，外部节点为 -1
+func (n *node[K, V]) h() int {
+	if n == nil {
+		return -1
+	}
+	return n.height
+}
+
+// split 将子树切分为小于 key 和不小于 key 的两棵子树，沿途的节点作为连接点重新加入
+func (rb *rbTree[K, V]) split(n *node[K, V], key K) (*node[K, V], *node[K, V]) {
+	if n == nil {
+		return nil, nil
+	}
+	l, r := orphan(n.lchild), orphan(n.rchild)
+	if rb.comp(key, n.key) <= 0 {
+		ll, lr := rb.split(l, key)
+		return ll, rb.join(lr, n, r)
+	}
+	rl, rr := rb.split(r, key)
+	return rb.join(l, n, rl), rr
+}
+
+// join 以 k 连接 l 和 r，l 中的关键码均小于 k，r 中的均大于 k。
+// 两树的根先染黑，再沿黑高度较高一侧下降到黑高度相同的黑节点处以红色的 k 连接，
+// 向上修正双红缺陷，返回以黑节点为根的树
+func (rb *rbTree[K, V]) join(l, k, r *node[K, V]) *node[K, V] {
+	l, r = rb.blacken(l), rb.blacken(r)
+	switch {
+	case l.h() > r.h():
+		return rb.blacken(rb.joinRight(l, k, r))
+	case r.h() > l.h():
+		return rb.blacken(rb.joinLeft(l, k, r))
+	}
+	k.setBlack()
+	return rb.link(l, k, r)
+}
+
+func (rb *rbTree[K, V]) joinRight(l, k, r *node[K, V]) *node[K, V] {
+	if l.isBlack() && l.h() == r.h() {
+		k.setRed()
+		return rb.link(l, k, r)
+	}
+	ll, lr := l.lchild, l.rchild
+	t := rb.link(ll, l, rb.joinRight(lr, k, r))
+	if t.isBlack() && t.rchild.isRed() && t.rchild.rchild.isRed() {
+		rb.stats.Rotate(false)
+		t.rchild.rchild.setBlack()
+		t.rchild.rchild.updateHeight()
+		x := t.rchild
+		xl, xr := x.lchild, x.rchild
+		return rb.link(rb.link(ll, t, xl), x, xr)
+	}
+	return t
+}
+
+func (rb *rbTree[K, V]) joinLeft(l, k, r *node[K, V]) *node[K, V] {
+	if r.isBlack() && r.h() == l.h() {
+		k.setRed()
+		return rb.link(l, k, r)
+	}
+	rl, rr := r.lchild, r.rchild
+	t := rb.link(rb.joinLeft(l, k, rl), r, rr)
+	if t.isBlack() && t.lchild.isRed() && t.lchild.lchild.isRed() {
+		rb.stats.Rotate(false)
+		t.lchild.lchild.setBlack()
+		t.lchild.lchild.updateHeight()
+		x := t.lchild
+		xl, xr := x.lchild, x.rchild
+		return rb.link(xl, x, rb.link(xr, t, rr))
+	}
+	return t
+}
+
+// blacken 将根节点染黑
+func (rb *rbTree[K, V]) blacken(n *node[K, V]) *node[K, V] {
+	if n.isRed() {
+		n.setBlack()
+		n.updateHeight()
+	}
+	return n
+}
+
+// link 以 l、r 为 n 的左右子树，并更新 n 的高度、规模与摘要
+func (rb *rbTree[K, V]) link(l, n, r *node[K, V]) *node[K, V] {
+	n.lchild, n.rchild = l, r
+	if l != nil {
+		l.parent = n
+	}
+	if r != nil {
+		r.parent = n
+	}
+	n.updateHeight()
+	for _, opt := range rb.updates {
+		opt(n)
+	}
+	return n
+}
@@ -0,0 +1,38 @@
+package redblack
+
+import (
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+// Validate checks the key order, the parent pointers, the sizes, colors and
+// black heights of the redblack tree
+func (rb *rbTree[K, V]) Validate() error {
+	if rb.root.isRed() {
+		return bst.NewInvariantError(bst.RBTree, bst.RuleColor, rb.root.key, "root is red")
+	}
+	validate := bst.ValidateBinary[K, V]
+	if rb.multi {
+		validate = bst.ValidateBinaryMulti[K, V]
+	}
+	_, err := validate(bst.RBTree, rb.root, rb.comp, func(m bst.Node[K, V]) error {
+		n := m.(*node[K, V])
+		if lh, rh := n.lchild.h(), n.rchild.h(); lh != rh {
+			return bst.NewInvariantError(bst.RBTree, bst.RuleBlackHeight, n.key, "black heights %d and %d", lh+1, rh+1)
+		}
+		if !rbOK(n) {
+			want := n.lchild.h() + 1
+			if n.isBlack() {
+				want++
+			}
+			return bst.NewInvariantError(bst.RBTree, bst.RuleHeight, n.key, "black height %d, want %d", n.height+1, want)
+		}
+		if n.isRed() && (n.lchild.isRed() || n.rchild.isRed()) {
+			return bst.NewInvariantError(bst.RBTree, bst.RuleColor, n.key, "red node has a red child")
+		}
+		if size := bst.Size[K, V](n.lchild) + bst.Size[K, V](n.rchild) + 1; n.size != size {
+			return bst.NewInvariantError(bst.RBTree, bst.RuleSize, n.key, "size %d, want %d", n.size, size)
+		}
+		return nil
+	})
+	return err
+}
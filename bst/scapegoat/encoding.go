@@ -0,0 +1,46 @@
+package scapegoat
+
+import (
+	"errors"
+
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+// MarshalBinary encodes the tree as configured by the bst.Encoding given to New
+func (t *scapegoat[K, V]) MarshalBinary() ([]byte, error) {
+	return bst.EncodeBinary[K, V](t, bst.Scapegoat, t.enc)
+}
+
+// UnmarshalBinary replaces the content of the tree by data from
+// MarshalBinary, a kept shape must be within the height allowed by α
+func (t *scapegoat[K, V]) UnmarshalBinary(data []byte) error {
+	return bst.DecodeBinary[K, V](t, bst.Scapegoat, t.enc, data, t.shapeBuilder())
+}
+
+func (t *scapegoat[K, V]) MarshalJSON() ([]byte, error) {
+	return bst.EncodeJSON[K, V](t, bst.Scapegoat, t.enc)
+}
+
+func (t *scapegoat[K, V]) UnmarshalJSON(data []byte) error {
+	return bst.DecodeJSON[K, V](t, bst.Scapegoat, t.enc, data, t.shapeBuilder())
+}
+
+var errDepth = errors.New("scapegoat: decoding a shape higher than alpha allows")
+
+// shapeBuilder 按原有形状重建节点，拒绝高度超过 ⌊log_{1/α} n⌋ 的形状
+func (t *scapegoat[K, V]) shapeBuilder() bst.ShapeBuilder[K, V] {
+	return bst.ShapeBuilder[K, V]{
+		Comp: t.comp,
+		Node: func(key K, data V, _ bool, l, r bst.Node[K, V]) (bst.Node[K, V], error) {
+			return link(nodeOf(l), &node[K, V]{key: key, data: data}, nodeOf(r)), nil
+		},
+		SetRoot: func(root bst.Node[K, V]) error {
+			n, size := nodeOf(root), bst.Size(root)
+			if height(n) > t.maxDepth(size) {
+				return errDepth
+			}
+			t.root, t.size, t.maxSize = orphan(n), size, size
+			return nil
+		},
+	}
+}
@@ -0,0 +1,13 @@
+package scapegoat_test
+
+import (
+	"testing"
+
+	"github.com/mooncaker816/gostructure/bst"
+	"github.com/mooncaker816/gostructure/bst/bsttest"
+	_ "github.com/mooncaker816/gostructure/bst/scapegoat"
+)
+
+func FuzzScapegoat(f *testing.F) {
+	bsttest.Fuzz(f, bsttest.ClassTarget(bst.Scapegoat))
+}
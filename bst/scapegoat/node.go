@@ -0,0 +1,83 @@
+package scapegoat
+
+import (
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+// node 不记录任何平衡信息，子树规模在需要时计数
+type node[K, V any] struct {
+	lchild *node[K, V]
+	rchild *node[K, V]
+	parent *node[K, V]
+	key    K
+	data   V
+}
+
+func (n *node[K, V]) Key() K                 { return n.key }
+func (n *node[K, V]) Data() V                { return n.data }
+func (n *node[K, V]) Height() int            { return 0 }
+func (n *node[K, V]) LChild() bst.Node[K, V] { return n.lchild }
+func (n *node[K, V]) RChild() bst.Node[K, V] { return n.rchild }
+func (n *node[K, V]) Parent() bst.Node[K, V] { return n.parent }
+func (n *node[K, V]) Color() string          { return "" }
+func (n *node[K, V]) IsNil() bool            { return n == nil }
+
+func (n *node[K, V]) SetKey(key K)   { n.key = key }
+func (n *node[K, V]) SetData(data V) { n.data = data }
+func (n *node[K, V]) SetLChild(lc bst.Node[K, V]) {
+	lc0, ok := lc.(*node[K, V])
+	if !ok && !bst.IsNil(lc) {
+		panic("inconsistent node type")
+	}
+	n.lchild = lc0
+}
+
+func (n *node[K, V]) SetRChild(rc bst.Node[K, V]) {
+	rc0, ok := rc.(*node[K, V])
+	if !ok && !bst.IsNil(rc) {
+		panic("inconsistent node type")
+	}
+	n.rchild = rc0
+}
+
+func (n *node[K, V]) SetParent(p bst.Node[K, V]) {
+	p0, ok := p.(*node[K, V])
+	if !ok && !bst.IsNil(p) {
+		panic("inconsistent node type")
+	}
+	n.parent = p0
+}
+
+// link 以 l、r 为 n 的左右子树
+func link[K, V any](l, n, r *node[K, V]) *node[K, V] {
+	n.lchild, n.rchild = l, r
+	if l != nil {
+		l.parent = n
+	}
+	if r != nil {
+		r.parent = n
+	}
+	return n
+}
+
+// height 返回子树的高度，空树为 -1
+func height[K, V any](n *node[K, V]) int {
+	if n == nil {
+		return -1
+	}
+	return 1 + max(height(n.lchild), height(n.rchild))
+}
+
+func orphan[K, V any](n *node[K, V]) *node[K, V] {
+	if n != nil {
+		n.parent = nil
+	}
+	return n
+}
+
+func nodeOf[K, V any](n bst.Node[K, V]) *node[K, V] {
+	if bst.IsNil(n) {
+		return nil
+	}
+	return n.(*node[K, V])
+}
@@ -0,0 +1,393 @@
+// Package scapegoat implements the scapegoat tree of Galperin and Rivest, a
+// binary search tree whose nodes keep no balance information at all. An
+// insertion deeper than log_{1/α} n climbs to the first ancestor outweighed
+// by one of its children, the scapegoat, and rebuilds its subtree perfectly
+// balanced; a removal rebuilds the whole tree once it shrinks below α times
+// its size since the last full rebuild. Updates take amortized O(log n) time
+// and searches visit at most log_{1/α} n + 1 nodes, which suits nodes that
+// can not spare a field.
+package scapegoat
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+func init() {
+	bst.Register("scapegoat", bst.Factory{
+		New: New[any, any],
+		Typed: []interface{}{
+			New[int, int], New[int, string], New[int, any],
+			New[string, int], New[string, string], New[string, any],
+		},
+		Alpha: checkAlpha,
+	})
+}
+
+const defaultAlpha = 0.7
+
+type scapegoat[K, V any] struct {
+	root    *node[K, V]
+	comp    bst.Comparator[K]
+	enc     bst.Encoding[K, V]
+	stats   *bst.Stats
+	alpha   float64 // 孩子的规模不超过父节点的 α 倍，α 在 (0.5, 1) 内
+	logInv  float64 // ln(1/α)
+	size    int
+	maxSize int // 上次重建整棵树以来的最大规模
+}
+
+// checkAlpha 检查 bst.WithAlpha 给出的 α 是否在 (0.5, 1) 内
+func checkAlpha(alpha float64) error {
+	if !(alpha > 0.5 && alpha < 1) {
+		return fmt.Errorf("alpha %v is not in (0.5, 1)", alpha)
+	}
+	return nil
+}
+
+// New returns an empty scapegoat tree. A float64 in parms stands for α in
+// (0.5, 1), 0.7 by default, a lower α keeps the tree lower at the cost of
+// more rebuilds. An α out of range is replaced by the default here, while
+// bst.Make rejects it with bst.WithAlpha. Subtrees are only rebuilt by
+// Insert and Remove, so bst.Synchronized lets searches run in parallel.
+func New[K, V any](parms ...interface{}) bst.BST[K, V] {
+	t := new(scapegoat[K, V])
+	t.comp = bst.DefaultCompare[K]()
+	for _, p := range parms {
+		switch v := p.(type) {
+		case bst.Comparator[K]:
+			t.comp = v
+		case func(a, b K) int:
+			t.comp = v
+		case bst.Encoding[K, V]:
+			t.enc = v
+		case *bst.Stats:
+			t.stats = v
+		case float64:
+			t.alpha = v
+		}
+	}
+	t.comp = bst.CountComparisons(t.comp, t.stats)
+	if checkAlpha(t.alpha) != nil {
+		t.alpha = defaultAlpha
+	}
+	t.logInv = math.Log(1 / t.alpha)
+	return t
+}
+
+// maxDepth 返回规模为 n 的树中节点允许的最大深度 ⌊log_{1/α} n⌋
+func (t *scapegoat[K, V]) maxDepth(n int) int {
+	if n <= 1 {
+		return 0
+	}
+	return int(math.Log(float64(n)) / t.logInv)
+}
+
+func (t *scapegoat[K, V]) Root() bst.Node[K, V] { return t.root }
+
+// Len returns the number of keys in the tree
+func (t *scapegoat[K, V]) Len() int { return t.size }
+
+func (t *scapegoat[K, V]) Print() {
+	bst.PrintWithUnitSize(t.root, 2)
+}
+
+func (t *scapegoat[K, V]) Search(key K) (bst.Node[K, V], bool) {
+	if t.root == nil {
+		return nil, false
+	}
+	n, result := t.searchIn(key)
+	return n, result == 0
+}
+
+// searchIn 在非空树中查找 key，未找到时返回最后访问的节点及新节点应在其哪一侧
+func (t *scapegoat[K, V]) searchIn(key K) (*node[K, V], int) {
+	n := t.root
+	for {
+		c := t.comp(key, n.key)
+		next := n.rchild
+		switch {
+		case c == 0:
+			return n, 0
+		case c < 0:
+			next = n.lchild
+		}
+		if next == nil {
+			return n, c
+		}
+		n = next
+	}
+}
+
+func (t *scapegoat[K, V]) Insert(key K, data V) (_ bst.Node[K, V], err error) {
+	if bst.Debug {
+		defer bst.MustValidate(t)
+	}
+	defer bst.CatchIncomparable(&err)
+	if t.root == nil {
+		return t.attach(nil, 0, key, data), nil
+	}
+	n, result := t.searchIn(key)
+	if result == 0 {
+		return nil, bst.ErrDuplicateKey
+	}
+	return t.attach(n, result, key, data), nil
+}
+
+// attach 将新节点插入为 searchIn 返回的节点 n 的孩子，result 为查找的结果，n 为 nil 时作为根；
+// 新节点的深度超过 ⌊log_{1/α} n⌋ 时重建替罪羊节点的子树
+func (t *scapegoat[K, V]) attach(n *node[K, V], result int, key K, data V) *node[K, V] {
+	new := &node[K, V]{key: key, data: data, parent: n}
+	t.size++
+	t.maxSize = max(t.maxSize, t.size)
+	switch {
+	case n == nil:
+		t.root = new
+		return new
+	case result < 0:
+		n.lchild = new
+	default:
+		n.rchild = new
+	}
+	depth := 0
+	for x := new; x.parent != nil; x = x.parent {
+		depth++
+	}
+	if depth > t.maxDepth(t.size) {
+		t.rebuildAbove(new)
+	}
+	return new
+}
+
+// rebuildAbove 自过深的节点 n 向上逐层计数子树规模，在第一个孩子的规模超过其 α 倍的祖先处重建
+func (t *scapegoat[K, V]) rebuildAbove(n *node[K, V]) {
+	size := 1 // 以 n 为根的子树规模
+	for ; n.parent != nil; n = n.parent {
+		p, sibling := n.parent, n.parent.lchild
+		if sibling == n {
+			sibling = p.rchild
+		}
+		psize := size + 1 + bst.Size[K, V](sibling)
+		if float64(size) > t.alpha*float64(psize) {
+			t.rebuild(p, psize)
+			return
+		}
+		size = psize
+	}
+}
+
+// rebuild 将以 x 为根、规模为 size 的子树重新链接为完全平衡的子树，不分配新节点
+func (t *scapegoat[K, V]) rebuild(x *node[K, V], size int) {
+	p := x.parent
+	nodes := appendInOrder(make([]*node[K, V], 0, size), x)
+	t.transplant(x, balance(nodes), p)
+	t.stats.Rebuild()
+}
+
+// appendInOrder 按中序将子树的节点追加到 nodes
+func appendInOrder[K, V any](nodes []*node[K, V], n *node[K, V]) []*node[K, V] {
+	for n != nil {
+		nodes = appendInOrder(nodes, n.lchild)
+		nodes = append(nodes, n)
+		n = n.rchild
+	}
+	return nodes
+}
+
+// balance 以居中的节点为根，将有序的节点链接为完全平衡的子树
+func balance[K, V any](nodes []*node[K, V]) *node[K, V] {
+	if len(nodes) == 0 {
+		return nil
+	}
+	mid := len(nodes) / 2
+	return link(balance(nodes[:mid]), nodes[mid], balance(nodes[mid+1:]))
+}
+
+// transplant 以 c 替代 p 的孩子 n，p 为 nil 时 c 成为根
+func (t *scapegoat[K, V]) transplant(n, c, p *node[K, V]) {
+	switch {
+	case p == nil:
+		t.root = c
+	case p.lchild == n:
+		p.lchild = c
+	default:
+		p.rchild = c
+	}
+	if c != nil {
+		c.parent = p
+	}
+}
+
+func (t *scapegoat[K, V]) Remove(key K) (_ bst.Node[K, V], err error) {
+	if bst.Debug {
+		defer bst.MustValidate(t)
+	}
+	defer bst.CatchIncomparable(&err)
+	if t.root == nil {
+		return nil, bst.ErrNotFound
+	}
+	n, result := t.searchIn(key)
+	if result != 0 {
+		return nil, bst.ErrNotFound
+	}
+	t.detach(n)
+	return n, nil
+}
+
+// detach 删除节点 n，有两个孩子时以其后继节点替代它；
+// 规模低于上次重建以来最大规模的 α 倍时重建整棵树
+func (t *scapegoat[K, V]) detach(n *node[K, V]) {
+	c := n.lchild
+	switch {
+	case n.rchild == nil:
+	case c == nil:
+		c = n.rchild
+	default:
+		succ := n.rchild
+		for succ.lchild != nil {
+			succ = succ.lchild
+		}
+		r := succ.rchild
+		if succ != n.rchild {
+			t.transplant(succ, succ.rchild, succ.parent)
+			r = n.rchild
+		}
+		c = link(n.lchild, succ, r)
+	}
+	t.transplant(n, c, n.parent)
+	n.lchild, n.rchild, n.parent = nil, nil, nil
+	t.size--
+	if float64(t.size) < t.alpha*float64(t.maxSize) {
+		if t.root != nil {
+			t.rebuild(t.root, t.size)
+		}
+		t.maxSize = t.size
+	}
+}
+
+// Put inserts key with data or replaces its data after a single search
+func (t *scapegoat[K, V]) Put(key K, data V) (old V, replaced bool, err error) {
+	if bst.Debug {
+		defer bst.MustValidate(t)
+	}
+	defer bst.CatchIncomparable(&err)
+	if t.root == nil {
+		t.attach(nil, 0, key, data)
+		return old, false, nil
+	}
+	n, result := t.searchIn(key)
+	if result != 0 {
+		t.attach(n, result, key, data)
+		return old, false, nil
+	}
+	old, n.data = n.data, data
+	return old, true, nil
+}
+
+func (t *scapegoat[K, V]) Update(key K, fn func(old V, ok bool) (V, bool)) (err error) {
+	if bst.Debug {
+		defer bst.MustValidate(t)
+	}
+	defer bst.CatchIncomparable(&err)
+	var n *node[K, V]
+	result := 1
+	if t.root != nil {
+		n, result = t.searchIn(key)
+	}
+	var old V
+	if result == 0 {
+		old = n.data
+	}
+	data, keep := fn(old, result == 0)
+	switch {
+	case result == 0 && keep:
+		n.data = data
+	case result == 0:
+		t.detach(n)
+	case keep:
+		t.attach(n, result, key, data)
+	}
+	return nil
+}
+
+func (t *scapegoat[K, V]) GetOrInsert(key K, data V) (_ bst.Node[K, V], found bool, err error) {
+	if bst.Debug {
+		defer bst.MustValidate(t)
+	}
+	defer bst.CatchIncomparable(&err)
+	if t.root == nil {
+		return t.attach(nil, 0, key, data), false, nil
+	}
+	n, result := t.searchIn(key)
+	if result == 0 {
+		return n, true, nil
+	}
+	return t.attach(n, result, key, data), false, nil
+}
+
+// BuildSorted builds a perfectly balanced tree from the sorted keys in O(n)
+func (t *scapegoat[K, V]) BuildSorted(keys []K, values []V, verify bool) error {
+	if verify {
+		if err := bst.CheckSorted(keys, t.comp); err != nil {
+			return err
+		}
+	}
+	nodes := make([]*node[K, V], len(keys))
+	for i := range nodes {
+		nodes[i] = &node[K, V]{key: keys[i], data: values[i]}
+	}
+	t.root = orphan(balance(nodes))
+	t.size, t.maxSize = len(keys), len(keys)
+	return nil
+}
+
+func (t *scapegoat[K, V]) Walk(o bst.Order, opts ...bst.Option[K, V]) {
+	switch o {
+	case bst.PreOrder:
+		bst.TravPre(t.root, opts...)
+	case bst.InOrder:
+		bst.TravIn(t.root, opts...)
+	case bst.PostOrder:
+		bst.TravPost(t.root, opts...)
+	case bst.LevelOrder:
+		bst.TravLevel(t.root, opts...)
+	case bst.ReverseInOrder:
+		bst.TravReverseIn(t.root, opts...)
+	default:
+		panic("unsupported walk order")
+	}
+}
+
+func (t *scapegoat[K, V]) Traverse(o bst.Order, fn func(n bst.Node[K, V]) bool) bool {
+	return bst.Traverse[K, V](t.root, o, fn)
+}
+
+func (t *scapegoat[K, V]) Iterator() bst.Iterator[K, V] {
+	return bst.NewIterator[K, V](t, t.comp)
+}
+
+func (t *scapegoat[K, V]) Floor(key K) (bst.Node[K, V], bool) {
+	return bst.Floor(t.root, key, t.comp)
+}
+
+func (t *scapegoat[K, V]) Ceiling(key K) (bst.Node[K, V], bool) {
+	return bst.Ceiling(t.root, key, t.comp)
+}
+
+func (t *scapegoat[K, V]) Lower(key K) (bst.Node[K, V], bool) {
+	return bst.Lower(t.root, key, t.comp)
+}
+
+func (t *scapegoat[K, V]) Higher(key K) (bst.Node[K, V], bool) {
+	return bst.Higher(t.root, key, t.comp)
+}
+
+func (t *scapegoat[K, V]) Min() (bst.Node[K, V], bool) { return bst.Min(t.root) }
+
+func (t *scapegoat[K, V]) Max() (bst.Node[K, V], bool) { return bst.Max(t.root) }
+
+func (t *scapegoat[K, V]) Range(lo, hi K, loInclusive, hiInclusive bool, fn func(n bst.Node[K, V]) bool) {
+	bst.Range(t.root, lo, hi, loInclusive, hiInclusive, t.comp, fn)
+}
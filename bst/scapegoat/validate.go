@@ -0,0 +1,25 @@
+package scapegoat
+
+import (
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+// Validate checks the key order, the parent pointers, the length and that no
+// node is deeper than log_{1/α} of the maximum size since the last full
+// rebuild, which is at most 1/α times the length
+func (t *scapegoat[K, V]) Validate() error {
+	count, err := bst.ValidateBinary[K, V](bst.Scapegoat, t.root, t.comp, nil)
+	if err != nil {
+		return err
+	}
+	if count != t.size {
+		return bst.NewInvariantError(bst.Scapegoat, bst.RuleSize, nil, "%d nodes, Len %d", count, t.size)
+	}
+	if float64(t.size) < t.alpha*float64(t.maxSize) || t.size > t.maxSize {
+		return bst.NewInvariantError(bst.Scapegoat, bst.RuleSize, nil, "Len %d, maximum size %d", t.size, t.maxSize)
+	}
+	if h := height(t.root); h > t.maxDepth(t.maxSize) {
+		return bst.NewInvariantError(bst.Scapegoat, bst.RuleDepth, t.root.key, "height %d, at most %d", h, t.maxDepth(t.maxSize))
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package skiplist
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+var errLayout = errors.New("skiplist: decoding an invalid level layout")
+
+// MarshalBinary encodes the list as configured by the bst.Encoding given to
+// New, a kept shape records the number of levels of every node
+func (s *skipList[K, V]) MarshalBinary() ([]byte, error) {
+	e := s.enc.WithDefaults()
+	if !e.Shape {
+		return bst.EncodeBinary[K, V](s, bst.SkipList, e)
+	}
+	buf := bst.AppendHeader(nil, bst.SkipList, true)
+	buf = bst.AppendUvarint(buf, s.size)
+	var err error
+	for n := s.head.next[0]; n != nil && err == nil; n = n.next[0] {
+		buf = bst.AppendUvarint(buf, len(n.next))
+		buf, err = bst.AppendEntry(buf, n.key, n.data, e)
+	}
+	return buf, err
+}
+
+// UnmarshalBinary replaces the content of the list by data from
+// MarshalBinary
+func (s *skipList[K, V]) UnmarshalBinary(data []byte) error {
+	e := s.enc.WithDefaults()
+	shape, rest, err := bst.ReadHeader(data, bst.SkipList)
+	if err != nil {
+		return err
+	}
+	if !shape {
+		return bst.DecodeBinary[K, V](s, bst.SkipList, e, data, bst.ShapeBuilder[K, V]{})
+	}
+	size, rest, err := bst.ReadUvarint(rest, len(rest))
+	if err != nil {
+		return err
+	}
+	keys, values, levels := make([]K, size), make([]V, size), make([]int, size)
+	for i := range keys {
+		if levels[i], rest, err = bst.ReadUvarint(rest, maxLevel); err != nil {
+			return err
+		}
+		if keys[i], values[i], rest, err = bst.ReadEntry(rest, e); err != nil {
+			return err
+		}
+	}
+	if len(rest) != 0 {
+		return bst.ErrCorrupt
+	}
+	return s.restore(keys, values, levels)
+}
+
+// levelJSON 保留形状时的节点及其层数
+type levelJSON struct {
+	bst.EntryJSON
+	Levels int `json:"levels"`
+}
+
+func (s *skipList[K, V]) MarshalJSON() ([]byte, error) {
+	e := s.enc.WithDefaults()
+	if !e.Shape {
+		return bst.EncodeJSON[K, V](s, bst.SkipList, e)
+	}
+	doc := bst.TreeJSON{Version: bst.EncodingVersion, Class: bst.SkipList, Shape: true, Len: s.size}
+	nodes := make([]levelJSON, 0, s.size)
+	for n := s.head.next[0]; n != nil; n = n.next[0] {
+		entry, err := bst.MarshalEntry(n.key, n.data, e)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, levelJSON{entry, len(n.next)})
+	}
+	var err error
+	if doc.Root, err = json.Marshal(nodes); err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+func (s *skipList[K, V]) UnmarshalJSON(data []byte) error {
+	e := s.enc.WithDefaults()
+	var doc bst.TreeJSON
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if err := doc.Check(bst.SkipList); err != nil {
+		return err
+	}
+	if !doc.Shape {
+		return bst.DecodeJSON[K, V](s, bst.SkipList, e, data, bst.ShapeBuilder[K, V]{})
+	}
+	var nodes []levelJSON
+	if err := json.Unmarshal(doc.Root, &nodes); err != nil {
+		return err
+	}
+	if len(nodes) != doc.Len {
+		return bst.ErrCorrupt
+	}
+	keys, values, levels := make([]K, len(nodes)), make([]V, len(nodes)), make([]int, len(nodes))
+	for i, j := range nodes {
+		var err error
+		if keys[i], values[i], err = bst.UnmarshalEntry(j.EntryJSON, e); err != nil {
+			return err
+		}
+		levels[i] = j.Levels
+	}
+	return s.restore(keys, values, levels)
+}
+
+// restore 检查关键码的次序及各节点的层数后重建跳表
+func (s *skipList[K, V]) restore(keys []K, values []V, levels []int) error {
+	for _, h := range levels {
+		if h < 1 || h > maxLevel {
+			return errLayout
+		}
+	}
+	if err := s.checkSorted(keys); err != nil {
+		return err
+	}
+	s.build(keys, values, levels)
+	return nil
+}
@@ -0,0 +1,13 @@
+package skiplist_test
+
+import (
+	"testing"
+
+	"github.com/mooncaker816/gostructure/bst"
+	"github.com/mooncaker816/gostructure/bst/bsttest"
+	_ "github.com/mooncaker816/gostructure/bst/skiplist"
+)
+
+func FuzzSkipList(f *testing.F) {
+	bsttest.Fuzz(f, bsttest.ClassTarget(bst.SkipList))
+}
@@ -0,0 +1,44 @@
+package skiplist
+
+import (
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+// node is linked on the levels [0, len(next)). It implements bst.Node without
+// children, the neighbours are reached through the list itself.
+type node[K, V any] struct {
+	key  K
+	data V
+	next []*node[K, V] // 各层的后继
+	span []int         // 各层到后继跨过的节点数，后继为空时为到表尾的节点数
+	prev *node[K, V]   // 第 0 层的前驱，首个节点为 nil
+}
+
+func (n *node[K, V]) Key() K                 { return n.key }
+func (n *node[K, V]) Data() V                { return n.data }
+func (n *node[K, V]) LChild() bst.Node[K, V] { return nil }
+func (n *node[K, V]) RChild() bst.Node[K, V] { return nil }
+func (n *node[K, V]) Parent() bst.Node[K, V] { return nil }
+func (n *node[K, V]) Color() string          { return "" }
+func (n *node[K, V]) IsNil() bool            { return n == nil }
+
+// Height returns the top level the node is linked on, 0 for the bottom list
+func (n *node[K, V]) Height() int { return len(n.next) - 1 }
+
+func (n *node[K, V]) SetKey(key K)             { n.key = key }
+func (n *node[K, V]) SetData(data V)           { n.data = data }
+func (n *node[K, V]) SetLChild(bst.Node[K, V]) {}
+func (n *node[K, V]) SetRChild(bst.Node[K, V]) {}
+func (n *node[K, V]) SetParent(bst.Node[K, V]) {}
+
+func newNode[K, V any](key K, data V, level int) *node[K, V] {
+	return &node[K, V]{key: key, data: data, next: make([]*node[K, V], level), span: make([]int, level)}
+}
+
+// item 将节点转换为 bst.Node，nil 节点转换为 nil 接口
+func item[K, V any](n *node[K, V]) (bst.Node[K, V], bool) {
+	if n == nil {
+		return nil, false
+	}
+	return n, true
+}
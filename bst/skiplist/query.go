@@ -0,0 +1,114 @@
+package skiplist
+
+import "github.com/mooncaker816/gostructure/bst"
+
+// iterator 沿第 0 层的前后链接移动
+type iterator[K, V any] struct {
+	s *skipList[K, V]
+	n *node[K, V]
+}
+
+func (s *skipList[K, V]) Iterator() bst.Iterator[K, V] {
+	return &iterator[K, V]{s: s}
+}
+
+func (it *iterator[K, V]) Seek(key K) bool {
+	x, _ := it.s.seek(key, false, nil)
+	it.n = x.next[0]
+	return it.Valid()
+}
+
+func (it *iterator[K, V]) First() bool {
+	it.n = it.s.head.next[0]
+	return it.Valid()
+}
+
+func (it *iterator[K, V]) Last() bool {
+	it.n = it.s.last()
+	return it.Valid()
+}
+
+func (it *iterator[K, V]) Next() bool {
+	if it.Valid() {
+		it.n = it.n.next[0]
+	}
+	return it.Valid()
+}
+
+func (it *iterator[K, V]) Prev() bool {
+	if it.Valid() {
+		it.n = it.n.prev
+	}
+	return it.Valid()
+}
+
+func (it *iterator[K, V]) Valid() bool { return it.n != nil }
+func (it *iterator[K, V]) Key() K      { return it.n.key }
+func (it *iterator[K, V]) Data() V     { return it.n.data }
+
+func (s *skipList[K, V]) Floor(key K) (bst.Node[K, V], bool) {
+	x, _ := s.seek(key, true, nil)
+	return item(s.orNil(x))
+}
+
+func (s *skipList[K, V]) Ceiling(key K) (bst.Node[K, V], bool) {
+	x, _ := s.seek(key, false, nil)
+	return item(x.next[0])
+}
+
+func (s *skipList[K, V]) Lower(key K) (bst.Node[K, V], bool) {
+	x, _ := s.seek(key, false, nil)
+	return item(s.orNil(x))
+}
+
+func (s *skipList[K, V]) Higher(key K) (bst.Node[K, V], bool) {
+	x, _ := s.seek(key, true, nil)
+	return item(x.next[0])
+}
+
+func (s *skipList[K, V]) Min() (bst.Node[K, V], bool) { return item(s.head.next[0]) }
+
+func (s *skipList[K, V]) Max() (bst.Node[K, V], bool) { return item(s.last()) }
+
+// Range calls fn on the keys between lo and hi in order, it finds lo in
+// O(log n) and then follows the bottom level
+func (s *skipList[K, V]) Range(lo, hi K, loInclusive, hiInclusive bool, fn func(n bst.Node[K, V]) bool) {
+	x, _ := s.seek(lo, !loInclusive, nil)
+	for n := x.next[0]; n != nil; n = n.next[0] {
+		if c := s.comp(n.key, hi); c > 0 || c == 0 && !hiInclusive {
+			return
+		}
+		if !fn(n) {
+			return
+		}
+	}
+}
+
+// Select returns the k-th smallest key, counting from 0, by the spans of the
+// links in O(log n)
+func (s *skipList[K, V]) Select(k int) (bst.Node[K, V], bool) {
+	if k < 0 || k >= s.size {
+		return nil, false
+	}
+	x, rank := s.head, 0
+	for i := s.level - 1; i >= 0; i-- {
+		for x.next[i] != nil && rank+x.span[i] <= k+1 {
+			rank += x.span[i]
+			x = x.next[i]
+		}
+	}
+	return x, true
+}
+
+// Rank returns the number of keys less than key
+func (s *skipList[K, V]) Rank(key K) int {
+	_, rank := s.seek(key, false, nil)
+	return rank
+}
+
+// CountRange returns the number of keys in [lo, hi] in O(log n)
+func (s *skipList[K, V]) CountRange(lo, hi K) int {
+	_, below := s.seek(lo, false, nil)
+	_, upto := s.seek(hi, true, nil)
+	return max(upto-below, 0)
+}
@@ -0,0 +1,453 @@
+// Package skiplist implements the skip list, a sorted linked list with
+// express lanes: every node is linked on a random number of levels, each
+// level skipping about half of the nodes of the level below. Searches,
+// insertions and removals take O(log n) expected time without rebalancing,
+// and the changes stay local to the neighbours of a node, which makes the
+// skip list a probabilistic alternative to the balanced trees of package bst.
+//
+// The links also record how many nodes they skip, so the skip list answers
+// the rank queries of bst.OrderStatistic and splits or joins in O(log n).
+package skiplist
+
+import (
+	"fmt"
+	"io"
+	"math/bits"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+func init() {
+	bst.Register("skiplist", bst.Factory{
+		New: New[any, any],
+		Typed: []interface{}{
+			New[int, int], New[int, string], New[int, any],
+			New[string, int], New[string, string], New[string, any],
+		},
+		Multimap: true,
+	})
+}
+
+const maxLevel = 32 // 层数的上限，足以容纳 2^32 个节点
+
+type skipList[K, V any] struct {
+	head  *node[K, V] // 哨兵，拥有全部 maxLevel 层
+	level int         // 非空的层数
+	size  int
+	comp  bst.Comparator[K]
+	enc   bst.Encoding[K, V]
+	stats *bst.Stats
+	rnd   *rand.Rand // 层数的来源
+	multi bool       // MultiKeys 模式
+}
+
+// New returns a new empty skip list with the default comparator of K. The
+// levels of the nodes are drawn from a rand.Source in parms, which makes the
+// layout reproducible, or from a randomly seeded source. An Augmenter is not
+// supported and ignored. Concurrent reads are safe as long as no write runs,
+// use bst.Synchronized to share the list with writers.
+func New[K, V any](parms ...interface{}) bst.BST[K, V] {
+	s := &skipList[K, V]{head: newHead[K, V]()}
+	s.comp = bst.DefaultCompare[K]()
+	for _, p := range parms {
+		switch v := p.(type) {
+		case bst.Comparator[K]:
+			s.comp = v
+		case func(a, b K) int:
+			s.comp = v
+		case bst.Encoding[K, V]:
+			s.enc = v
+		case *bst.Stats:
+			s.stats = v
+		case bst.Mode:
+			s.multi = v == bst.MultiKeys
+		case rand.Source:
+			s.rnd = rand.New(v)
+		}
+	}
+	if s.rnd == nil {
+		s.rnd = rand.New(rand.NewSource(rand.Int63()))
+	}
+	s.comp = bst.CountComparisons(s.comp, s.stats)
+	return s
+}
+
+func newHead[K, V any]() *node[K, V] {
+	var key K
+	var data V
+	return newNode(key, data, maxLevel)
+}
+
+// randomLevel 抽取新节点的层数，高于 i 层的概率为 2^-i
+func (s *skipList[K, V]) randomLevel() int {
+	return 1 + bits.TrailingZeros64(s.rnd.Uint64()|1<<(maxLevel-1))
+}
+
+// Len returns the number of keys in the list
+func (s *skipList[K, V]) Len() int { return s.size }
+
+// Levels returns the number of non-empty levels, see bst.Layered
+func (s *skipList[K, V]) Levels() int { return s.level }
+
+// Root returns the first node, the skip list has no tree shape and its
+// nodes have no children
+func (s *skipList[K, V]) Root() bst.Node[K, V] {
+	n, _ := item(s.head.next[0])
+	return n
+}
+
+// path 记录自顶层下行时各层最后一个位于查找位置之前的节点及其秩，头节点的秩为 0
+type path[K, V any] struct {
+	prevs [maxLevel]*node[K, V]
+	ranks [maxLevel]int
+}
+
+// seek 自顶层下行至第 0 层最后一个位于 key 之前的节点，返回该节点及其秩，p 不为 nil 时记录下行的路径。
+// after 为 false 时"之前"指小于 key，为 true 时指不大于 key
+func (s *skipList[K, V]) seek(key K, after bool, p *path[K, V]) (*node[K, V], int) {
+	x, rank := s.head, 0
+	for i := s.level - 1; i >= 0; i-- {
+		for y := x.next[i]; y != nil; y = x.next[i] {
+			if c := s.comp(y.key, key); c > 0 || c == 0 && !after {
+				break
+			}
+			rank += x.span[i]
+			x = y
+		}
+		if p != nil {
+			p.prevs[i], p.ranks[i] = x, rank
+		}
+	}
+	return x, rank
+}
+
+// find 返回 key 最早插入的节点，未找到时返回 nil，p 不为 nil 时记录其插入位置
+func (s *skipList[K, V]) find(key K, p *path[K, V]) *node[K, V] {
+	x, _ := s.seek(key, false, p)
+	if n := x.next[0]; n != nil && s.comp(n.key, key) == 0 {
+		return n
+	}
+	return nil
+}
+
+// orNil 将头节点转换为 nil
+func (s *skipList[K, V]) orNil(x *node[K, V]) *node[K, V] {
+	if x == s.head {
+		return nil
+	}
+	return x
+}
+
+// last 返回最后一个节点，空表返回 nil
+func (s *skipList[K, V]) last() *node[K, V] {
+	x := s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for x.next[i] != nil {
+			x = x.next[i]
+		}
+	}
+	return s.orNil(x)
+}
+
+// Search returns the node of key, the oldest one in a multimap
+func (s *skipList[K, V]) Search(key K) (bst.Node[K, V], bool) {
+	return item(s.find(key, nil))
+}
+
+func (s *skipList[K, V]) Insert(key K, data V) (_ bst.Node[K, V], err error) {
+	if bst.Debug {
+		defer bst.MustValidate(s)
+	}
+	defer bst.CatchIncomparable(&err)
+	var p path[K, V]
+	if s.multi {
+		s.seek(key, true, &p)
+	} else if s.find(key, &p) != nil {
+		return nil, bst.ErrDuplicateKey
+	}
+	return s.attach(&p, key, data), nil
+}
+
+// attach 在 p 记录的位置插入新节点，并更新其前驱在各层的跨度
+func (s *skipList[K, V]) attach(p *path[K, V], key K, data V) *node[K, V] {
+	n := newNode(key, data, s.randomLevel())
+	for i := s.level; i < len(n.next); i++ {
+		p.prevs[i], p.ranks[i] = s.head, 0
+		s.head.span[i] = s.size
+	}
+	s.level = max(s.level, len(n.next))
+	rank := p.ranks[0] // n 之前的节点数
+	for i := range n.next {
+		x := p.prevs[i]
+		n.next[i], x.next[i] = x.next[i], n
+		n.span[i] = x.span[i] - (rank - p.ranks[i])
+		x.span[i] = rank - p.ranks[i] + 1
+	}
+	for i := len(n.next); i < s.level; i++ {
+		p.prevs[i].span[i]++
+	}
+	n.prev = s.orNil(p.prevs[0])
+	if n.next[0] != nil {
+		n.next[0].prev = n
+	}
+	s.size++
+	return n
+}
+
+func (s *skipList[K, V]) Remove(key K) (_ bst.Node[K, V], err error) {
+	if bst.Debug {
+		defer bst.MustValidate(s)
+	}
+	defer bst.CatchIncomparable(&err)
+	var p path[K, V]
+	n := s.find(key, &p)
+	if n == nil {
+		return nil, bst.ErrNotFound
+	}
+	s.detach(&p, n)
+	return n, nil
+}
+
+// detach 将 p 记录的位置上的节点 n 自各层摘除，并去掉空出的顶层。
+// n 为首个不小于其关键码的节点，故 n 所在的各层中其前驱均为 p 中记录的节点
+func (s *skipList[K, V]) detach(p *path[K, V], n *node[K, V]) {
+	for i := 0; i < s.level; i++ {
+		x := p.prevs[i]
+		if x.next[i] == n {
+			x.next[i], x.span[i] = n.next[i], x.span[i]+n.span[i]-1
+		} else {
+			x.span[i]--
+		}
+	}
+	if n.next[0] != nil {
+		n.next[0].prev = n.prev
+	}
+	s.trim()
+	s.size--
+	clear(n.next)
+	n.prev = nil
+}
+
+// trim 去掉空的顶层
+func (s *skipList[K, V]) trim() {
+	for s.level > 0 && s.head.next[s.level-1] == nil {
+		s.level--
+	}
+}
+
+// Put inserts key with data or replaces its data after a single search
+func (s *skipList[K, V]) Put(key K, data V) (old V, replaced bool, err error) {
+	if bst.Debug {
+		defer bst.MustValidate(s)
+	}
+	defer bst.CatchIncomparable(&err)
+	var p path[K, V]
+	if n := s.find(key, &p); n != nil {
+		old, n.data = n.data, data
+		return old, true, nil
+	}
+	s.attach(&p, key, data)
+	return old, false, nil
+}
+
+func (s *skipList[K, V]) Update(key K, fn func(old V, ok bool) (V, bool)) (err error) {
+	if bst.Debug {
+		defer bst.MustValidate(s)
+	}
+	defer bst.CatchIncomparable(&err)
+	var p path[K, V]
+	n := s.find(key, &p)
+	var old V
+	if n != nil {
+		old = n.data
+	}
+	data, keep := fn(old, n != nil)
+	switch {
+	case n != nil && keep:
+		n.data = data
+	case n != nil:
+		s.detach(&p, n)
+	case keep:
+		s.attach(&p, key, data)
+	}
+	return nil
+}
+
+func (s *skipList[K, V]) GetOrInsert(key K, data V) (_ bst.Node[K, V], found bool, err error) {
+	if bst.Debug {
+		defer bst.MustValidate(s)
+	}
+	defer bst.CatchIncomparable(&err)
+	var p path[K, V]
+	if n := s.find(key, &p); n != nil {
+		return n, true, nil
+	}
+	return s.attach(&p, key, data), false, nil
+}
+
+// Count returns the number of entries of key in O(log n)
+func (s *skipList[K, V]) Count(key K) int { return s.CountRange(key, key) }
+
+func (s *skipList[K, V]) All(key K, fn func(n bst.Node[K, V]) bool) {
+	s.Range(key, key, true, true, fn)
+}
+
+// RemoveOne removes the oldest entry of key as Remove does
+func (s *skipList[K, V]) RemoveOne(key K) (bst.Node[K, V], error) { return s.Remove(key) }
+
+func (s *skipList[K, V]) RemoveAll(key K) (int, error) { return bst.RemoveEach(key, s.Remove) }
+
+// BuildSorted builds the list from the sorted keys in O(n), the node i
+// (counting from 1) is linked on one level more than the trailing zeros of
+// i, which spaces the nodes of every level evenly
+func (s *skipList[K, V]) BuildSorted(keys []K, values []V, verify bool) error {
+	if verify {
+		if err := s.checkSorted(keys); err != nil {
+			return err
+		}
+	}
+	s.build(keys, values, nil)
+	return nil
+}
+
+func (s *skipList[K, V]) checkSorted(keys []K) error {
+	if s.multi {
+		return bst.CheckSortedMulti(keys, s.comp)
+	}
+	return bst.CheckSorted(keys, s.comp)
+}
+
+// build 以有序的关键码重建跳表，levels 给出各节点的层数，为 nil 时按 BuildSorted 的规则确定
+func (s *skipList[K, V]) build(keys []K, values []V, levels []int) {
+	s.head, s.level, s.size = newHead[K, V](), 0, len(keys)
+	var p path[K, V] // 各层当前的末尾节点及其秩
+	for i := range p.prevs {
+		p.prevs[i] = s.head
+	}
+	var prev *node[K, V]
+	for i, key := range keys {
+		h := min(1+bits.TrailingZeros(uint(i+1)), maxLevel)
+		if levels != nil {
+			h = levels[i]
+		}
+		n := newNode(key, values[i], h)
+		for j := range n.next {
+			x := p.prevs[j]
+			x.next[j], x.span[j] = n, i+1-p.ranks[j]
+			p.prevs[j], p.ranks[j] = n, i+1
+		}
+		n.prev, prev = prev, n
+		s.level = max(s.level, h)
+	}
+	for j, x := range p.prevs {
+		x.span[j] = len(keys) - p.ranks[j]
+	}
+}
+
+// Walk walks the nodes by o, see Traverse
+func (s *skipList[K, V]) Walk(o bst.Order, opts ...bst.Option[K, V]) {
+	s.Traverse(o, func(n bst.Node[K, V]) bool {
+		for _, opt := range opts {
+			opt(n)
+		}
+		return true
+	})
+}
+
+// Traverse walks the nodes by o until fn returns false. The levels make a
+// tree rooted at the head, where every node is the parent of the lower nodes
+// it skips over: PreOrder is the key order as InOrder, PostOrder visits a
+// node after those it skips over, and LevelOrder visits the nodes by their
+// top levels from the highest, each level in key order.
+func (s *skipList[K, V]) Traverse(o bst.Order, fn func(n bst.Node[K, V]) bool) bool {
+	switch o {
+	case bst.PreOrder, bst.InOrder:
+		for n := s.head.next[0]; n != nil; n = n.next[0] {
+			if !fn(n) {
+				return false
+			}
+		}
+	case bst.ReverseInOrder:
+		for n := s.last(); n != nil; n = n.prev {
+			if !fn(n) {
+				return false
+			}
+		}
+	case bst.PostOrder:
+		// 栈中为尚未访问的节点，层数自底向顶递减；遇到不低于栈顶的节点时栈顶跨过的节点均已访问
+		var stack []*node[K, V]
+		for n := s.head.next[0]; ; n = n.next[0] {
+			for len(stack) > 0 && (n == nil || len(stack[len(stack)-1].next) <= len(n.next)) {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				if !fn(top) {
+					return false
+				}
+			}
+			if n == nil {
+				break
+			}
+			stack = append(stack, n)
+		}
+	case bst.LevelOrder:
+		for i := s.level - 1; i >= 0; i-- {
+			for n := s.head.next[i]; n != nil; n = n.next[i] {
+				if len(n.next) == i+1 && !fn(n) {
+					return false
+				}
+			}
+		}
+	default:
+		panic("unsupported walk order")
+	}
+	return true
+}
+
+// Print prints the levels from the top, one per line, with every node in
+// the same column on all the levels it is linked on
+func (s *skipList[K, V]) Print() {
+	s.Fprint(os.Stdout)
+}
+
+// Fprint writes the levels to w as Print does, such as
+//
+//	L1 head -> 3 ------> 17 -> nil
+//	L0 head -> 3 -> 9 -> 17 -> nil
+func (s *skipList[K, V]) Fprint(w io.Writer) error {
+	var keys []string
+	for n := s.head.next[0]; n != nil; n = n.next[0] {
+		keys = append(keys, fmt.Sprint(n.key))
+	}
+	width := len(strconv.Itoa(max(s.level-1, 0)))
+	for i := max(s.level, 1) - 1; i >= 0; i-- {
+		var b strings.Builder
+		fmt.Fprintf(&b, "L%-*d head", width, i)
+		linked := true // 上一列是否链接在该层
+		j := 0
+		for n := s.head.next[0]; n != nil; n, j = n.next[0], j+1 {
+			gap := "-"
+			if linked {
+				gap = " "
+			}
+			if linked = len(n.next) > i; linked {
+				b.WriteString(gap + "-> " + keys[j])
+			} else {
+				b.WriteString(gap + strings.Repeat("-", 3+utf8.RuneCountInString(keys[j])))
+			}
+		}
+		if linked {
+			b.WriteString(" -> nil\n")
+		} else {
+			b.WriteString("--> nil\n")
+		}
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
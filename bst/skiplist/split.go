@@ -0,0 +1,69 @@
+package skiplist
+
+import (
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+// Split moves the keys not less than key into a new skip list in O(log n),
+// cutting every level after the last node less than key
+func (s *skipList[K, V]) Split(key K) bst.BST[K, V] {
+	var p path[K, V]
+	_, j := s.seek(key, false, &p)
+	other := *s
+	other.head, other.size = newHead[K, V](), s.size-j
+	for i := 0; i < s.level; i++ {
+		x := p.prevs[i]
+		other.head.next[i], other.head.span[i] = x.next[i], x.span[i]-(j-p.ranks[i])
+		x.next[i], x.span[i] = nil, j-p.ranks[i]
+	}
+	if first := other.head.next[0]; first != nil {
+		first.prev = nil
+	}
+	s.size = j
+	s.trim()
+	other.trim()
+	return &other
+}
+
+// Join moves the keys of other, a skip list with greater keys, into the list
+// in O(log n) expected time, linking the last node of every level to the
+// first node of other on that level
+func (s *skipList[K, V]) Join(other bst.BST[K, V]) error {
+	o, ok := other.(*skipList[K, V])
+	if !ok {
+		return bst.ErrJoinClass
+	}
+	if err := bst.CheckJoin[K, V](s, o, s.comp); err != nil {
+		return err
+	}
+	if o.size == 0 {
+		return nil
+	}
+	level := max(s.level, o.level)
+	x, rank := s.head, 0
+	var p path[K, V]
+	for i := level - 1; i >= 0; i-- {
+		if i >= s.level {
+			s.head.span[i] = s.size
+		}
+		for x.next[i] != nil {
+			rank += x.span[i]
+			x = x.next[i]
+		}
+		p.prevs[i], p.ranks[i] = x, rank
+	}
+	// 末尾节点的空链接跨度为其后的节点数，接上 other 后加上 other 中跨过的节点数
+	for i := 0; i < level; i++ {
+		x := p.prevs[i]
+		if i < o.level {
+			x.next[i] = o.head.next[i]
+			x.span[i] += o.head.span[i]
+		} else {
+			x.span[i] += o.size
+		}
+	}
+	o.head.next[0].prev = s.orNil(p.prevs[0])
+	s.level, s.size = level, s.size+o.size
+	o.head, o.level, o.size = newHead[K, V](), 0, 0
+	return nil
+}
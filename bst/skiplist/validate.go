@@ -0,0 +1,69 @@
+package skiplist
+
+import (
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+// Validate checks the key order, that every level links the nodes of the
+// level below which are that high, the spans of the links, the back links of
+// the bottom level and the length of the skip list
+func (s *skipList[K, V]) Validate() error {
+	if s.level < 0 || s.level > maxLevel || s.level > 0 && s.head.next[s.level-1] == nil {
+		return bst.NewInvariantError(bst.SkipList, bst.RuleLevel, nil, "level %d is not the top level", s.level)
+	}
+	for i := s.level; i < maxLevel; i++ {
+		if s.head.next[i] != nil {
+			return bst.NewInvariantError(bst.SkipList, bst.RuleLevel, s.head.next[i].key, "linked on level %d above the top level %d", i, s.level-1)
+		}
+	}
+	var p path[K, V] // 各层最后访问的节点及其秩
+	for i := range p.prevs {
+		p.prevs[i] = s.head
+	}
+	var prev *node[K, V]
+	rank := 0
+	for n := s.head.next[0]; n != nil; n = n.next[0] {
+		if rank++; rank > s.size {
+			return bst.NewInvariantError(bst.SkipList, bst.RuleSize, n.key, "more than Len %d nodes", s.size)
+		}
+		if len(n.next) == 0 || len(n.next) > s.level || len(n.span) != len(n.next) {
+			return bst.NewInvariantError(bst.SkipList, bst.RuleLevel, n.key, "linked on %d levels of %d", len(n.next), s.level)
+		}
+		if prev != nil && !s.before(prev.key, n.key) {
+			return bst.NewInvariantError(bst.SkipList, bst.RuleOrder, n.key, "not greater than %v", prev.key)
+		}
+		if n.prev != prev {
+			return bst.NewInvariantError(bst.SkipList, bst.RuleLevel, n.key, "back link is not the previous node")
+		}
+		for i := range n.next {
+			x := p.prevs[i]
+			if x.next[i] != n {
+				return bst.NewInvariantError(bst.SkipList, bst.RuleLevel, n.key, "skipped by level %d", i)
+			}
+			if x.span[i] != rank-p.ranks[i] {
+				return bst.NewInvariantError(bst.SkipList, bst.RuleSize, n.key, "span %d on level %d, want %d", x.span[i], i, rank-p.ranks[i])
+			}
+			p.prevs[i], p.ranks[i] = n, rank
+		}
+		prev = n
+	}
+	if rank != s.size {
+		return bst.NewInvariantError(bst.SkipList, bst.RuleSize, nil, "%d nodes, Len %d", rank, s.size)
+	}
+	for i := 0; i < s.level; i++ {
+		x := p.prevs[i]
+		if x.next[i] != nil {
+			return bst.NewInvariantError(bst.SkipList, bst.RuleLevel, x.next[i].key, "missing from level 0")
+		}
+		if x.span[i] != s.size-p.ranks[i] {
+			return bst.NewInvariantError(bst.SkipList, bst.RuleSize, x.key, "span %d to the end of level %d, want %d", x.span[i], i, s.size-p.ranks[i])
+		}
+	}
+	return nil
+}
+
+// before 检查 x 是否应位于 y 之前，多重映射中允许相等
+func (s *skipList[K, V]) before(x, y K) bool {
+	c := s.comp(x, y)
+	return c < 0 || c == 0 && s.multi
+}
@@ -0,0 +1,46 @@
+package splay
+
+import (
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+// MarshalBinary encodes the tree as configured by the bst.Encoding given to New
+func (s *splayTree[K, V]) MarshalBinary() ([]byte, error) {
+	return bst.EncodeBinary[K, V](s, bst.Splay, s.enc)
+}
+
+// UnmarshalBinary replaces the content of the tree by data from MarshalBinary
+func (s *splayTree[K, V]) UnmarshalBinary(data []byte) error {
+	return bst.DecodeBinary[K, V](s, bst.Splay, s.enc, data, s.shapeBuilder())
+}
+
+func (s *splayTree[K, V]) MarshalJSON() ([]byte, error) {
+	return bst.EncodeJSON[K, V](s, bst.Splay, s.enc)
+}
+
+func (s *splayTree[K, V]) UnmarshalJSON(data []byte) error {
+	return bst.DecodeJSON[K, V](s, bst.Splay, s.enc, data, s.shapeBuilder())
+}
+
+// shapeBuilder 按原有形状重建节点，伸展树的任意形状均合法
+func (s *splayTree[K, V]) shapeBuilder() bst.ShapeBuilder[K, V] {
+	return bst.ShapeBuilder[K, V]{
+		Comp:  s.comp,
+		Multi: s.multi,
+		Node: func(key K, data V, _ bool, l, r bst.Node[K, V]) (bst.Node[K, V], error) {
+			n := newNode(key, data)
+			bst.AttachLChild[K, V](n, l)
+			bst.AttachRChild[K, V](n, r)
+			update(s.updates, n)
+			return n, nil
+		},
+		SetRoot: func(root bst.Node[K, V]) error {
+			s.root = nil
+			if !bst.IsNil(root) {
+				s.root = root.(*node[K, V])
+				s.root.parent = nil
+			}
+			return nil
+		},
+	}
+}
@@ -1,63 +1,129 @@
 package splay
 
 import (
-	"errors"
-
 	"github.com/mooncaker816/gostructure/bst"
 )
 
 func init() {
-	bst.RegisterBST(bst.Splay, New)
+	bst.Register("splay", bst.Factory{
+		New: New[any, any],
+		Typed: []interface{}{
+			New[int, int], New[int, string], New[int, any],
+			New[string, int], New[string, string], New[string, any],
+		},
+		Multimap: true,
+	})
 }
 
-type splayTree struct {
-	root *node
-	comp bst.Comparator
+type splayTree[K, V any] struct {
+	root    *node[K, V]
+	comp    bst.Comparator[K]
+	aug     bst.Augmenter[K, V]
+	enc     bst.Encoding[K, V]
+	stats   *bst.Stats
+	multi   bool               // MultiKeys 模式
+	updates []bst.Option[K, V] // 结构变化后自底向上更新节点
 }
 
-// New returns a new empty splay tree with basic comparator
-func New(parms ...interface{}) bst.BST {
-	t := new(splayTree)
-	t.comp = bst.BasicCompare
+// New returns a new empty splay tree with basic comparator.
+// Search splays the tree, so even concurrent reads of a splay tree race,
+// bst.Synchronized serializes every access to it.
+func New[K, V any](parms ...interface{}) bst.BST[K, V] {
+	t := new(splayTree[K, V])
+	t.comp = bst.DefaultCompare[K]()
 	for _, p := range parms {
 		switch v := p.(type) {
-		case bst.Comparator:
+		case bst.Comparator[K]:
+			t.comp = v
+		case func(a, b K) int:
 			t.comp = v
+		case bst.Augmenter[K, V]:
+			t.aug = v
+		case bst.Encoding[K, V]:
+			t.enc = v
+		case *bst.Stats:
+			t.stats = v
+		case bst.Mode:
+			t.multi = v == bst.MultiKeys
 		}
 	}
+	t.comp = bst.CountComparisons(t.comp, t.stats)
+	t.updates = []bst.Option[K, V]{updateSize[K, V]}
+	if t.aug != nil {
+		t.updates = append(t.updates, bst.AugmentOption(t.aug))
+	}
 	return t
 }
 
-func (s *splayTree) Search(key interface{}) (bst.Node, bool) {
-	n, result := s.searchIn(s.root, key)
+func (s *splayTree[K, V]) Search(key K) (bst.Node[K, V], bool) {
+	if bst.Debug {
+		defer bst.MustValidate(s)
+	}
+	if s.root == nil {
+		return nil, false
+	}
+	n, result := s.find(key)
 	if result == 0 {
 		return n, true
 	}
 	return n, false
 }
 
-func (s *splayTree) searchIn(n *node, key interface{}) (*node, int) {
-	switch s.comp(key, n.key) {
-	case 0:
-		s.root = splay(n)
+// find 在非空树中查找 key 并将命中或最后访问的节点伸展至根，多重映射中命中最早插入的节点
+func (s *splayTree[K, V]) find(key K) (*node[K, V], int) {
+	if !s.multi {
+		return s.searchIn(s.root, key)
+	}
+	n, result := s.searchMulti(s.root, key, false)
+	s.root = splay(n, s.stats, s.updates...)
+	return n, result
+}
+
+// searchMulti 在多重映射中查找 key 最早插入的节点，未找到或 last 时返回新节点的插入位置，
+// last 时新节点位于所有相等的关键码之后
+func (s *splayTree[K, V]) searchMulti(n *node[K, V], key K, last bool) (*node[K, V], int) {
+	var hit *node[K, V]
+	for {
+		c := s.comp(key, n.key)
+		next, result := n.rchild, 1
+		if c < 0 || c == 0 && !last {
+			next, result = n.lchild, -1
+		}
+		if c == 0 && !last {
+			hit = n
+		}
+		if next == nil {
+			if hit != nil {
+				return hit, 0
+			}
+			return n, result
+		}
+		n = next
+	}
+}
+
+func (s *splayTree[K, V]) searchIn(n *node[K, V], key K) (*node[K, V], int) {
+	switch c := s.comp(key, n.key); {
+	case c == 0:
+		s.root = splay(n, s.stats, s.updates...)
 		return s.root, 0
-	case -1:
+	case c < 0:
 		if n.lchild != nil {
 			return s.searchIn(n.lchild, key)
 		}
-		s.root = splay(n)
+		s.root = splay(n, s.stats, s.updates...)
 		return s.root, -1
-	case 1:
+	default:
 		if n.rchild != nil {
 			return s.searchIn(n.rchild, key)
 		}
-		s.root = splay(n)
+		s.root = splay(n, s.stats, s.updates...)
 		return s.root, 1
 	}
-	return nil, 0
 }
 
-func splay(n *node) *node {
+// splay 将 n 伸展至根，旋转次数计入 stats
+func splay[K, V any](n *node[K, V], stats *bst.Stats, opts ...bst.Option[K, V]) *node[K, V] {
 	if n == nil {
 		return nil
 	}
@@ -90,6 +156,8 @@ func splay(n *node) *node {
 				bst.AttachLChild(n, p)
 			}
 		}
+		update(opts, g, p, n)
+		stats.Add(2)
 		if gp != nil {
 			if gp.lchild == g {
 				bst.AttachLChild(gp, n)
@@ -101,58 +169,97 @@ func splay(n *node) *node {
 		}
 	}
 	if n.parent != nil {
+		p := n.parent
 		if bst.IsLChild(n) {
-			bst.AttachLChild(n.parent, n.rchild)
-			bst.AttachRChild(n, n.parent)
+			bst.AttachLChild(p, n.rchild)
+			bst.AttachRChild(n, p)
 		} else {
-			bst.AttachRChild(n.parent, n.lchild)
-			bst.AttachLChild(n, n.parent)
+			bst.AttachRChild(p, n.lchild)
+			bst.AttachLChild(n, p)
 		}
+		update(opts, p, n)
+		stats.Add(1)
 	}
 	n.parent = nil
 	return n
 }
 
-func (s *splayTree) Insert(key, data interface{}) (bst.Node, error) {
+// update applies opts on ns in order
+func update[K, V any](opts []bst.Option[K, V], ns ...*node[K, V]) {
+	for _, n := range ns {
+		for _, o := range opts {
+			o(n)
+		}
+	}
+}
+
+func (s *splayTree[K, V]) Insert(key K, data V) (_ bst.Node[K, V], err error) {
+	if bst.Debug {
+		defer bst.MustValidate(s)
+	}
+	defer bst.CatchIncomparable(&err)
 	if s.root == nil {
-		s.root = newNode(key, data)
-		return s.root, nil
+		return s.attach(nil, 0, key, data), nil
+	}
+	if s.multi {
+		n, result := s.searchMulti(s.root, key, true)
+		s.root = splay(n, s.stats, s.updates...)
+		return s.attach(n, result, key, data), nil
 	}
 	n, result := s.searchIn(s.root, key)
-	switch result {
-	case 0:
-		return nil, errors.New("insert node with duplicate key")
-	case -1:
-		new := newNode(key, data)
+	if result == 0 {
+		return nil, bst.ErrDuplicateKey
+	}
+	return s.attach(n, result, key, data), nil
+}
+
+// attach 以新节点为根，将 searchIn 伸展至根的节点 n 作为其孩子，result 为查找的结果
+func (s *splayTree[K, V]) attach(n *node[K, V], result int, key K, data V) *node[K, V] {
+	new := newNode(key, data)
+	switch {
+	case n == nil:
+		update(s.updates, new)
+	case result < 0:
 		bst.AttachRChild(new, n)
 		bst.AttachLChild(new, n.lchild)
 		n.lchild = nil
-		s.root = new
-		return new, nil
-	case 1:
-		new := newNode(key, data)
+		update(s.updates, n, new)
+	default:
 		bst.AttachLChild(new, n)
 		bst.AttachRChild(new, n.rchild)
 		n.rchild = nil
-		s.root = new
-		return new, nil
+		update(s.updates, n, new)
 	}
-	return nil, nil
+	s.root = new
+	return new
 }
 
-func (s *splayTree) Remove(key interface{}) (bst.Node, error) {
-	n, result := s.searchIn(s.root, key)
+func (s *splayTree[K, V]) Remove(key K) (_ bst.Node[K, V], err error) {
+	if bst.Debug {
+		defer bst.MustValidate(s)
+	}
+	defer bst.CatchIncomparable(&err)
+	if s.root == nil {
+		return nil, bst.ErrNotFound
+	}
+	n, result := s.find(key)
 	if result != 0 {
-		return nil, nil
+		return nil, bst.ErrNotFound
 	}
+	s.detach(n)
+	return n, nil
+}
+
+// detach 删除 searchIn 伸展至根的节点 n
+func (s *splayTree[K, V]) detach(n *node[K, V]) {
 	// 此时待删节点位于 root
-	if !bst.HasLChild(s.root) {
+	if s.root.lchild == nil {
 		s.root = s.root.rchild
 		if s.root != nil {
 			s.root.parent = nil
 		}
 		n.rchild = nil
-	} else if !bst.HasRChild(s.root) {
+	} else if s.root.rchild == nil {
 		s.root = s.root.lchild
 		s.root.parent = nil
 		n.lchild = nil
@@ -164,24 +271,151 @@ func (s *splayTree) Remove(key interface{}) (bst.Node, error) {
 		s.root = s.root.rchild
 		s.root.parent = nil
 		n.rchild = nil
-		// 在新树中再次查找原来的值，必然不存在，但会把最小值提升至顶端，且没有左子树
-		// 该最小值一定比之前切除的左子树大，以此值为 root 重新连接原左子树即可
-		s.searchIn(s.root, key)
+		// 把右子树的最小值伸展至顶端，它没有左子树，且不小于之前切除的左子树，
+		// 以此值为 root 重新连接原左子树即可。多重映射中右子树可能含有与 n 相等的关键码，
+		// 故不能按 n 的关键码查找
+		min := s.root
+		for min.lchild != nil {
+			min = min.lchild
+		}
+		s.root = splay(min, s.stats, s.updates...)
 		s.root.lchild = lc
 		lc.parent = s.root
+		update(s.updates, s.root)
 	}
-	return n, nil
 }
 
-func (s *splayTree) Root() bst.Node {
+// Put inserts key with data or replaces its data after a single search
+func (s *splayTree[K, V]) Put(key K, data V) (old V, replaced bool, err error) {
+	if bst.Debug {
+		defer bst.MustValidate(s)
+	}
+	defer bst.CatchIncomparable(&err)
+	if s.root == nil {
+		s.attach(nil, 0, key, data)
+		return old, false, nil
+	}
+	n, result := s.find(key)
+	if result != 0 {
+		s.attach(n, result, key, data)
+		return old, false, nil
+	}
+	old = n.data
+	s.replace(n, data)
+	return old, true, nil
+}
+
+// replace 替换根节点 n 的数据，数据参与增强时更新摘要
+func (s *splayTree[K, V]) replace(n *node[K, V], data V) {
+	n.data = data
+	if s.aug != nil {
+		update(s.updates, n)
+	}
+}
+
+func (s *splayTree[K, V]) Update(key K, fn func(old V, ok bool) (V, bool)) (err error) {
+	if bst.Debug {
+		defer bst.MustValidate(s)
+	}
+	defer bst.CatchIncomparable(&err)
+	var n *node[K, V]
+	result := 1
+	if s.root != nil {
+		n, result = s.find(key)
+	}
+	var old V
+	if result == 0 {
+		old = n.data
+	}
+	data, keep := fn(old, result == 0)
+	switch {
+	case result == 0 && keep:
+		s.replace(n, data)
+	case result == 0:
+		s.detach(n)
+	case keep:
+		s.attach(n, result, key, data)
+	}
+	return nil
+}
+
+func (s *splayTree[K, V]) GetOrInsert(key K, data V) (_ bst.Node[K, V], found bool, err error) {
+	if bst.Debug {
+		defer bst.MustValidate(s)
+	}
+	defer bst.CatchIncomparable(&err)
+	if s.root == nil {
+		return s.attach(nil, 0, key, data), false, nil
+	}
+	n, result := s.find(key)
+	if result == 0 {
+		return n, true, nil
+	}
+	return s.attach(n, result, key, data), false, nil
+}
+
+// Count returns the number of entries of key in O(log n), without
+// splaying
+func (s *splayTree[K, V]) Count(key K) int { return bst.CountRange(s.root, key, key, s.comp) }
+
+// All visits the entries of key without splaying
+func (s *splayTree[K, V]) All(key K, fn func(n bst.Node[K, V]) bool) {
+	bst.Range(s.root, key, key, true, true, s.comp, fn)
+}
+
+// RemoveOne removes the oldest entry of key as Remove does
+func (s *splayTree[K, V]) RemoveOne(key K) (bst.Node[K, V], error) { return s.Remove(key) }
+
+func (s *splayTree[K, V]) RemoveAll(key K) (int, error) { return bst.RemoveEach(key, s.Remove) }
+
+// BuildSorted builds a tree of minimum height from the sorted keys in O(n)
+func (s *splayTree[K, V]) BuildSorted(keys []K, values []V, verify bool) error {
+	if verify {
+		check := bst.CheckSorted[K]
+		if s.multi {
+			check = bst.CheckSortedMulti[K]
+		}
+		if err := check(keys, s.comp); err != nil {
+			return err
+		}
+	}
+	s.root = s.build(keys, values, nil)
+	return nil
+}
+
+func (s *splayTree[K, V]) build(keys []K, values []V, parent *node[K, V]) *node[K, V] {
+	if len(keys) == 0 {
+		return nil
+	}
+	mid := len(keys) / 2
+	n := newNode(keys[mid], values[mid])
+	n.parent = parent
+	n.lchild = s.build(keys[:mid], values[:mid], n)
+	n.rchild = s.build(keys[mid+1:], values[mid+1:], n)
+	update(s.updates, n)
+	return n
+}
+
+// SelfAdjusting reports that reads restructure the tree
+func (s *splayTree[K, V]) SelfAdjusting() bool { return true }
+
+// Len returns the number of keys in the tree
+func (s *splayTree[K, V]) Len() int {
+	if s.root == nil {
+		return 0
+	}
+	return s.root.size
+}
+
+func (s *splayTree[K, V]) Root() bst.Node[K, V] {
 	return s.root
 }
 
-func (s *splayTree) Print() {
+func (s *splayTree[K, V]) Print() {
 	bst.PrintWithUnitSize(s.root, 2)
 }
 
-func (s *splayTree) Walk(o bst.Order, opts ...bst.Option) {
+func (s *splayTree[K, V]) Walk(o bst.Order, opts ...bst.Option[K, V]) {
 	switch o {
 	case bst.PreOrder:
 		bst.TravPre(s.root, opts...)
@@ -191,7 +425,57 @@ func (s *splayTree) Walk(o bst.Order, opts ...bst.Option) {
 		bst.TravPost(s.root, opts...)
 	case bst.LevelOrder:
 		bst.TravLevel(s.root, opts...)
+	case bst.ReverseInOrder:
+		bst.TravReverseIn(s.root, opts...)
 	default:
 		panic("unsupported walk order")
 	}
 }
+
+// Traverse walks the tree by order o until fn returns false, it never splays
+func (s *splayTree[K, V]) Traverse(o bst.Order, fn func(n bst.Node[K, V]) bool) bool {
+	return bst.Traverse[K, V](s.root, o, fn)
+}
+
+// Iterator returns an iterator over the tree, which never splays
+func (s *splayTree[K, V]) Iterator() bst.Iterator[K, V] {
+	return bst.NewIterator[K, V](s, s.comp)
+}
+
+func (s *splayTree[K, V]) Floor(key K) (bst.Node[K, V], bool) {
+	return bst.Floor(s.root, key, s.comp)
+}
+
+func (s *splayTree[K, V]) Ceiling(key K) (bst.Node[K, V], bool) {
+	return bst.Ceiling(s.root, key, s.comp)
+}
+
+func (s *splayTree[K, V]) Lower(key K) (bst.Node[K, V], bool) {
+	return bst.Lower(s.root, key, s.comp)
+}
+
+func (s *splayTree[K, V]) Higher(key K) (bst.Node[K, V], bool) {
+	return bst.Higher(s.root, key, s.comp)
+}
+
+func (s *splayTree[K, V]) Min() (bst.Node[K, V], bool) { return bst.Min(s.root) }
+
+func (s *splayTree[K, V]) Max() (bst.Node[K, V], bool) { return bst.Max(s.root) }
+
+func (s *splayTree[K, V]) Range(lo, hi K, loInclusive, hiInclusive bool, fn func(n bst.Node[K, V]) bool) {
+	bst.Range(s.root, lo, hi, loInclusive, hiInclusive, s.comp, fn)
+}
+
+func (s *splayTree[K, V]) Summary() any {
+	if s.aug == nil {
+		return nil
+	}
+	return bst.SummaryOf[K, V](s.root, s.aug)
+}
+
+func (s *splayTree[K, V]) SummaryRange(lo, hi K) any {
+	if s.aug == nil {
+		return nil
+	}
+	return bst.SummaryRange[K, V](s.root, lo, hi, s.comp, s.aug)
+}
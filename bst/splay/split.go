@@ -0,0 +1,57 @@
+package splay
+
+import (
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+// Split splays the nearest key to the root and cuts the tree there, moving
+// the keys not less than key into a new splay tree
+func (s *splayTree[K, V]) Split(key K) bst.BST[K, V] {
+	other := *s
+	other.root = nil
+	if s.root == nil {
+		return &other
+	}
+	s.find(key)
+	if s.comp(key, s.root.key) <= 0 {
+		other.root, s.root = s.root, s.root.lchild
+		other.root.lchild = nil
+		update(s.updates, other.root)
+	} else {
+		other.root = s.root.rchild
+		s.root.rchild = nil
+		update(s.updates, s.root)
+	}
+	if s.root != nil {
+		s.root.parent = nil
+	}
+	if other.root != nil {
+		other.root.parent = nil
+	}
+	return &other
+}
+
+// Join splays the largest key to the root and attaches other, a splay tree
+// with greater keys, as its right subtree
+func (s *splayTree[K, V]) Join(other bst.BST[K, V]) error {
+	o, ok := other.(*splayTree[K, V])
+	if !ok {
+		return bst.ErrJoinClass
+	}
+	if err := bst.CheckJoin[K, V](s, o, s.comp); err != nil {
+		return err
+	}
+	if s.root == nil {
+		s.root, o.root = o.root, nil
+		return nil
+	}
+	last := s.root
+	for last.rchild != nil {
+		last = last.rchild
+	}
+	s.root = splay(last, s.stats, s.updates...)
+	bst.AttachRChild(s.root, o.root)
+	update(s.updates, s.root)
+	o.root = nil
+	return nil
+}
@@ -0,0 +1,22 @@
+package splay
+
+import (
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+// Validate checks the key order, the parent pointers and the sizes of the
+// splay tree, which has no balance invariant
+func (s *splayTree[K, V]) Validate() error {
+	validate := bst.ValidateBinary[K, V]
+	if s.multi {
+		validate = bst.ValidateBinaryMulti[K, V]
+	}
+	_, err := validate(bst.Splay, s.root, s.comp, func(m bst.Node[K, V]) error {
+		n := m.(*node[K, V])
+		if size := bst.Size[K, V](n.lchild) + bst.Size[K, V](n.rchild) + 1; n.size != size {
+			return bst.NewInvariantError(bst.Splay, bst.RuleSize, n.key, "size %d, want %d", n.size, size)
+		}
+		return nil
+	})
+	return err
+}
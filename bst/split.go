@@ -0,0 +1,32 @@
+package bst
+
+import "errors"
+
+// Splitter is implemented by the trees able to be cut at a key and to be
+// concatenated with another tree of the same class
+type Splitter[K, V any] interface {
+	// Split moves the keys not less than key into a new tree of the same
+	// class and returns it, the tree keeps the keys less than key
+	Split(key K) BST[K, V]
+	// Join moves every key of other into the tree, other must be of the same
+	// class and all its keys greater than the keys of the tree
+	Join(other BST[K, V]) error
+}
+
+var (
+	// ErrJoinClass is returned by Join when the trees are of different classes
+	ErrJoinClass = errors.New("bst: join with a tree of another class")
+	// ErrJoinOverlap is returned by Join when the key ranges of the trees overlap
+	ErrJoinOverlap = errors.New("bst: joined trees overlap")
+)
+
+// CheckJoin returns an error if the largest key of t is not less than the
+// smallest key of other
+func CheckJoin[K, V any](t, other BST[K, V], comp Comparator[K]) error {
+	max, ok1 := t.Max()
+	min, ok2 := other.Min()
+	if ok1 && ok2 && comp(max.Key(), min.Key()) >= 0 {
+		return ErrJoinOverlap
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package bst
+
+import "sync/atomic"
+
+// Stats counts the work done by a tree, such as for benchmarks. Passing a
+// *Stats in the parms of New or NewOf makes the tree count into it. The
+// counters are updated atomically, since the reads of a tree guarded by
+// Synchronized run in parallel; use Load to read them while the tree is in
+// use.
+type Stats struct {
+	Comparisons int64 // 关键码的比较次数
+	Rotations   int64 // 单旋次数，双旋计为两次；B-树为节点向兄弟借关键码的次数
+	Splits      int64 // B-树节点的分裂次数
+	Merges      int64 // B-树节点的合并次数
+	Rebuilds    int64 // 替罪羊树重建子树的次数
+}
+
+// Reset zeroes the counters
+func (s *Stats) Reset() {
+	for _, c := range s.counters() {
+		atomic.StoreInt64(c, 0)
+	}
+}
+
+// Load returns a copy of the counters read atomically
+func (s *Stats) Load() Stats {
+	var cp Stats
+	dst := cp.counters()
+	for i, c := range s.counters() {
+		*dst[i] = atomic.LoadInt64(c)
+	}
+	return cp
+}
+
+// counters 返回各计数器的地址
+func (s *Stats) counters() []*int64 {
+	return []*int64{&s.Comparisons, &s.Rotations, &s.Splits, &s.Merges, &s.Rebuilds}
+}
+
+// Rotate counts a rotation, double for a zig-zag, s may be nil
+func (s *Stats) Rotate(double bool) {
+	if double {
+		s.Add(2)
+	} else {
+		s.Add(1)
+	}
+}
+
+// Add adds n rotations, s may be nil
+func (s *Stats) Add(rotations int) {
+	if s != nil {
+		atomic.AddInt64(&s.Rotations, int64(rotations))
+	}
+}
+
+// Split counts a split of a B-tree node, s may be nil
+func (s *Stats) Split() {
+	if s != nil {
+		atomic.AddInt64(&s.Splits, 1)
+	}
+}
+
+// Merge counts a merge of B-tree nodes, s may be nil
+func (s *Stats) Merge() {
+	if s != nil {
+		atomic.AddInt64(&s.Merges, 1)
+	}
+}
+
+// Rebuild counts a rebuilt subtree, s may be nil
+func (s *Stats) Rebuild() {
+	if s != nil {
+		atomic.AddInt64(&s.Rebuilds, 1)
+	}
+}
+
+// CountComparisons returns comp counting its calls into s, or comp itself
+// if s is nil
+func CountComparisons[K any](comp Comparator[K], s *Stats) Comparator[K] {
+	if s == nil {
+		return comp
+	}
+	return func(a, b K) int {
+		atomic.AddInt64(&s.Comparisons, 1)
+		return comp(a, b)
+	}
+}
+
+// Zigzag reports whether v and its parent are children on different sides,
+// in which case RotateAt(v) makes a double rotation
+func Zigzag[K, V any](v Node[K, V]) bool {
+	return IsLChild(v) != IsLChild(v.Parent())
+}
@@ -0,0 +1,172 @@
+package bst
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"unicode/utf8"
+)
+
+// SVG 布局参数，以像素为单位，按等宽字体估算文字宽度
+const (
+	svgFont   = 13 // 字号
+	svgChar   = 8  // 字宽
+	svgPad    = 6  // 文字两侧留白
+	svgGap    = 12 // 同层节点的最小间距
+	svgCellH  = 26 // 多路节点的高度
+	svgMargin = 16
+)
+
+// svgLayout 以中序确定二叉节点的横坐标，多路节点位于其分支的中间
+type svgLayout struct {
+	cursor float64 // 下一个节点的左边界
+	depth  int     // 最大层数
+	size   float64 // 二叉节点的最大直径
+}
+
+func textWidth(s string) float64 {
+	return float64(utf8.RuneCountInString(s)*svgChar + 2*svgPad)
+}
+
+// measure 计算节点的宽度
+func (d *drawNode) measure() {
+	if d.multiway {
+		for _, l := range d.labels {
+			d.w += textWidth(l)
+		}
+	} else {
+		d.w = max(2*svgCellH, textWidth(d.labels[0]), textWidth(d.note))
+	}
+	for _, c := range d.children {
+		if c != nil {
+			c.measure()
+		}
+	}
+}
+
+func (l *svgLayout) place(d *drawNode, lvl int) {
+	d.lvl = lvl
+	l.depth = max(l.depth, lvl)
+	if !d.multiway {
+		l.size = max(l.size, d.w)
+	}
+	switch {
+	case !d.multiway && len(d.children) > 0:
+		if c := d.children[0]; c != nil {
+			l.place(c, lvl+1)
+		}
+		d.x = l.cursor + d.w/2
+		l.cursor += d.w + svgGap
+		if c := d.children[1]; c != nil {
+			l.place(c, lvl+1)
+		}
+	case len(d.children) > 0:
+		for _, c := range d.children {
+			l.place(c, lvl+1)
+		}
+		d.x = (d.children[0].x + d.children[len(d.children)-1].x) / 2
+		l.cursor = max(l.cursor, d.x+d.w/2+svgGap)
+	default:
+		d.x = l.cursor + d.w/2
+		l.cursor += d.w + svgGap
+	}
+}
+
+// FprintSVG draws the topology of the subtree rooted at n to w as an SVG
+// image, like FprintDOT but without depending on Graphviz
+func FprintSVG[K, V any](w io.Writer, n Node[K, V], opts *RenderOptions) error {
+	if opts == nil {
+		opts = new(RenderOptions)
+	}
+	root := drawTree(n, opts)
+	l := &svgLayout{cursor: svgMargin}
+	if root != nil {
+		root.measure()
+		l.place(root, 0)
+	}
+	// 每层的高度容纳最大的二叉节点及其注释
+	nodeH := max(float64(svgCellH), l.size)
+	levelH := nodeH + 2*svgCellH
+	if opts.Heights {
+		levelH += svgFont
+	}
+	width := max(l.cursor-svgGap, 0) + svgMargin
+	height := 2*svgMargin + nodeH
+	if root != nil {
+		height += float64(l.depth) * levelH
+	}
+	y := func(d *drawNode) float64 { return svgMargin + nodeH/2 + float64(d.lvl)*levelH }
+
+	buf := bufio.NewWriter(w)
+	fmt.Fprintf(buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%g" height="%g" viewBox="0 0 %g %g"`+
+		` font-family="monospace" font-size="%d" text-anchor="middle">`+"\n", width, height, width, height, svgFont)
+	if opts.Name != "" {
+		fmt.Fprintf(buf, "<title>%s</title>\n", html.EscapeString(opts.Name))
+	}
+	if root != nil {
+		buf.WriteString(`<g stroke="#444" fill="none">` + "\n")
+		root.walk(func(d *drawNode) {
+			for i, c := range d.children {
+				if c == nil {
+					continue
+				}
+				x0, y0 := d.x, y(d)
+				if d.multiway {
+					x0, y0 = d.x-d.w/2, y0+svgCellH/2
+					for _, l := range d.labels[:min(i, len(d.labels))] {
+						x0 += textWidth(l)
+					}
+				}
+				fmt.Fprintf(buf, `<line x1="%g" y1="%g" x2="%g" y2="%g"/>`+"\n", x0, y0, c.x, y(c))
+			}
+		})
+		buf.WriteString("</g>\n")
+		root.walk(func(d *drawNode) { d.writeSVG(buf, y(d)) })
+	}
+	buf.WriteString("</svg>\n")
+	return buf.Flush()
+}
+
+// walk 先序访问各节点
+func (d *drawNode) walk(visit func(d *drawNode)) {
+	visit(d)
+	for _, c := range d.children {
+		if c != nil {
+			c.walk(visit)
+		}
+	}
+}
+
+func (d *drawNode) writeSVG(buf *bufio.Writer, y float64) {
+	if d.multiway {
+		x := d.x - d.w/2
+		for _, l := range d.labels {
+			cw := textWidth(l)
+			fmt.Fprintf(buf, `<rect x="%g" y="%g" width="%g" height="%d" fill="#eef3fb" stroke="#444"/>`+"\n",
+				x, y-svgCellH/2, cw, svgCellH)
+			fmt.Fprintf(buf, `<text x="%g" y="%g" dominant-baseline="central">%s</text>`+"\n",
+				x+cw/2, y, html.EscapeString(l))
+			x += cw
+		}
+		return
+	}
+	fill, text := "#fff", "#000"
+	switch d.color {
+	case "R":
+		fill, text = "#d32f2f", "#fff"
+	case "B":
+		fill, text = "#222", "#fff"
+	}
+	r := d.w / 2
+	if d.note != "" {
+		r = max(svgCellH, textWidth(d.labels[0])/2)
+	}
+	fmt.Fprintf(buf, `<circle cx="%g" cy="%g" r="%g" fill="%s" stroke="#444"/>`+"\n", d.x, y, r, fill)
+	fmt.Fprintf(buf, `<text x="%g" y="%g" fill="%s" dominant-baseline="central">%s</text>`+"\n",
+		d.x, y, text, html.EscapeString(d.labels[0]))
+	if d.note != "" {
+		fmt.Fprintf(buf, `<text x="%g" y="%g" font-size="%d" fill="#555">%s</text>`+"\n",
+			d.x, y+r+svgFont, svgFont-3, html.EscapeString(d.note))
+	}
+}
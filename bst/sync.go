@@ -0,0 +1,412 @@
+package bst
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// SelfAdjusting is implemented by the trees restructuring themselves on
+// reads, such as the splay tree. Reads of such a tree must not run
+// concurrently even without writes.
+type SelfAdjusting interface {
+	SelfAdjusting() bool
+}
+
+func selfAdjusting(t interface{}) bool {
+	s, ok := t.(SelfAdjusting)
+	return ok && s.SelfAdjusting()
+}
+
+// Synchronized returns a BST safe for concurrent use wrapping t, which must
+// not be used directly afterwards.
+//
+// Reads of AVL, red-black, B-tree and interval trees share a read lock and
+// run in parallel, while writes are exclusive. A SelfAdjusting tree such as
+// the splay tree takes the exclusive lock on every access.
+//
+// Nodes returned by the wrapper lock the tree on each method call, but a node
+// may be detached or reused by a later write, so read what is needed from it
+// right away. Iterators lock on each step and are still invalidated by
+// writes. The callbacks of Range and Walk run with the lock held: they get the
+// nodes of t itself and must not call into the wrapper.
+func Synchronized[K, V any](t BST[K, V]) *SyncBST[K, V] {
+	if s, ok := t.(*SyncBST[K, V]); ok {
+		return s
+	}
+	return &SyncBST[K, V]{t: t, exclusive: selfAdjusting(t)}
+}
+
+// SyncBST is a BST guarded by a reader/writer lock
+type SyncBST[K, V any] struct {
+	mu        sync.RWMutex
+	t         BST[K, V]
+	exclusive bool // 读操作同样需要独占锁
+}
+
+// rlock 获取读锁，返回对应的解锁函数
+func (t *SyncBST[K, V]) rlock() func() {
+	if t.exclusive {
+		t.mu.Lock()
+		return t.mu.Unlock
+	}
+	t.mu.RLock()
+	return t.mu.RUnlock
+}
+
+func (t *SyncBST[K, V]) lock() func() {
+	t.mu.Lock()
+	return t.mu.Unlock
+}
+
+// View calls fn with the wrapped tree under the read lock, fn must not
+// modify the tree
+func (t *SyncBST[K, V]) View(fn func(t BST[K, V])) {
+	defer t.rlock()()
+	fn(t.t)
+}
+
+// Do calls fn with the wrapped tree under the exclusive lock, such as to
+// use the optional interfaces of the tree or to apply several writes at once
+func (t *SyncBST[K, V]) Do(fn func(t BST[K, V])) {
+	defer t.lock()()
+	fn(t.t)
+}
+
+func (t *SyncBST[K, V]) SelfAdjusting() bool { return t.exclusive }
+
+func (t *SyncBST[K, V]) Validate() error {
+	defer t.rlock()()
+	return Validate(t.t)
+}
+
+func (t *SyncBST[K, V]) Search(key K) (Node[K, V], bool) {
+	defer t.rlock()()
+	return t.node(t.t.Search(key))
+}
+
+func (t *SyncBST[K, V]) Insert(key K, data V) (Node[K, V], error) {
+	defer t.lock()()
+	n, err := t.t.Insert(key, data)
+	return t.wrap(n), err
+}
+
+func (t *SyncBST[K, V]) Remove(key K) (Node[K, V], error) {
+	defer t.lock()()
+	n, err := t.t.Remove(key)
+	return t.wrap(n), err
+}
+
+func (t *SyncBST[K, V]) Put(key K, data V) (V, bool, error) {
+	defer t.lock()()
+	return Put(t.t, key, data)
+}
+
+// Update calls fn under the exclusive lock, fn must not use the tree
+func (t *SyncBST[K, V]) Update(key K, fn func(old V, ok bool) (V, bool)) error {
+	defer t.lock()()
+	return Update(t.t, key, fn)
+}
+
+func (t *SyncBST[K, V]) GetOrInsert(key K, data V) (Node[K, V], bool, error) {
+	defer t.lock()()
+	n, found, err := GetOrInsert(t.t, key, data)
+	return t.wrap(n), found, err
+}
+
+func (t *SyncBST[K, V]) Count(key K) int {
+	defer t.rlock()()
+	return Count(t.t, key)
+}
+
+// All calls fn under the read lock, fn must not use the tree
+func (t *SyncBST[K, V]) All(key K, fn func(n Node[K, V]) bool) {
+	defer t.rlock()()
+	All(t.t, key, fn)
+}
+
+func (t *SyncBST[K, V]) RemoveOne(key K) (Node[K, V], error) { return t.Remove(key) }
+
+func (t *SyncBST[K, V]) RemoveAll(key K) (int, error) {
+	defer t.lock()()
+	return RemoveAll(t.t, key)
+}
+
+func (t *SyncBST[K, V]) Root() Node[K, V] {
+	defer t.rlock()()
+	return t.wrap(t.t.Root())
+}
+
+func (t *SyncBST[K, V]) Len() int {
+	defer t.rlock()()
+	return t.t.Len()
+}
+
+func (t *SyncBST[K, V]) Print() {
+	defer t.rlock()()
+	t.t.Print()
+}
+
+// Walk takes the exclusive lock since opts may modify the nodes
+func (t *SyncBST[K, V]) Walk(o Order, opts ...Option[K, V]) {
+	defer t.lock()()
+	t.t.Walk(o, opts...)
+}
+
+// Traverse takes the exclusive lock as Walk does, fn must not use the tree
+func (t *SyncBST[K, V]) Traverse(o Order, fn func(n Node[K, V]) bool) bool {
+	defer t.lock()()
+	return Visit(t.t, o, fn)
+}
+
+func (t *SyncBST[K, V]) Iterator() Iterator[K, V] {
+	defer t.rlock()()
+	return &syncIterator[K, V]{t: t, it: t.t.Iterator()}
+}
+
+func (t *SyncBST[K, V]) Floor(key K) (Node[K, V], bool) {
+	defer t.rlock()()
+	return t.node(t.t.Floor(key))
+}
+
+func (t *SyncBST[K, V]) Ceiling(key K) (Node[K, V], bool) {
+	defer t.rlock()()
+	return t.node(t.t.Ceiling(key))
+}
+
+func (t *SyncBST[K, V]) Lower(key K) (Node[K, V], bool) {
+	defer t.rlock()()
+	return t.node(t.t.Lower(key))
+}
+
+func (t *SyncBST[K, V]) Higher(key K) (Node[K, V], bool) {
+	defer t.rlock()()
+	return t.node(t.t.Higher(key))
+}
+
+func (t *SyncBST[K, V]) Min() (Node[K, V], bool) {
+	defer t.rlock()()
+	return t.node(t.t.Min())
+}
+
+func (t *SyncBST[K, V]) Max() (Node[K, V], bool) {
+	defer t.rlock()()
+	return t.node(t.t.Max())
+}
+
+func (t *SyncBST[K, V]) Range(lo, hi K, loInclusive, hiInclusive bool, fn func(n Node[K, V]) bool) {
+	defer t.rlock()()
+	t.t.Range(lo, hi, loInclusive, hiInclusive, fn)
+}
+
+func (t *SyncBST[K, V]) node(n Node[K, V], ok bool) (Node[K, V], bool) {
+	return t.wrap(n), ok
+}
+
+func (t *SyncBST[K, V]) wrap(n Node[K, V]) Node[K, V] {
+	if IsNil(n) {
+		return nil
+	}
+	return syncNode[K, V]{n: n, t: t}
+}
+
+// syncNode 每次访问节点时加锁，其值可比较
+type syncNode[K, V any] struct {
+	n Node[K, V]
+	t *SyncBST[K, V]
+}
+
+func (n syncNode[K, V]) unwrap(m Node[K, V]) Node[K, V] {
+	if IsNil(m) {
+		return nil
+	}
+	return m.(syncNode[K, V]).n
+}
+
+func (n syncNode[K, V]) LChild() Node[K, V] {
+	defer n.t.rlock()()
+	return n.t.wrap(n.n.LChild())
+}
+
+func (n syncNode[K, V]) RChild() Node[K, V] {
+	defer n.t.rlock()()
+	return n.t.wrap(n.n.RChild())
+}
+
+func (n syncNode[K, V]) Parent() Node[K, V] {
+	defer n.t.rlock()()
+	return n.t.wrap(n.n.Parent())
+}
+
+func (n syncNode[K, V]) SetLChild(c Node[K, V]) {
+	defer n.t.lock()()
+	n.n.SetLChild(n.unwrap(c))
+}
+
+func (n syncNode[K, V]) SetRChild(c Node[K, V]) {
+	defer n.t.lock()()
+	n.n.SetRChild(n.unwrap(c))
+}
+
+func (n syncNode[K, V]) SetParent(p Node[K, V]) {
+	defer n.t.lock()()
+	n.n.SetParent(n.unwrap(p))
+}
+
+func (n syncNode[K, V]) Key() K {
+	defer n.t.rlock()()
+	return n.n.Key()
+}
+
+func (n syncNode[K, V]) Data() V {
+	defer n.t.rlock()()
+	return n.n.Data()
+}
+
+func (n syncNode[K, V]) SetKey(key K) {
+	defer n.t.lock()()
+	n.n.SetKey(key)
+}
+
+func (n syncNode[K, V]) SetData(data V) {
+	defer n.t.lock()()
+	n.n.SetData(data)
+}
+
+func (n syncNode[K, V]) Height() int {
+	defer n.t.rlock()()
+	return n.n.Height()
+}
+
+func (n syncNode[K, V]) Color() string {
+	defer n.t.rlock()()
+	return n.n.Color()
+}
+
+func (n syncNode[K, V]) Level() int {
+	defer n.t.rlock()()
+	return levelOf(n.n)
+}
+
+// IsNil 不必加锁，wrap 不包装 nil 节点
+func (n syncNode[K, V]) IsNil() bool { return false }
+
+func (n syncNode[K, V]) Entries() ([]K, []V) {
+	defer n.t.rlock()()
+	m, ok := n.n.(Multiway[K, V])
+	if !ok {
+		return nil, nil
+	}
+	keys, data := m.Entries()
+	if keys == nil {
+		return nil, nil
+	}
+	return append([]K(nil), keys...), append([]V(nil), data...)
+}
+
+func (n syncNode[K, V]) Children() []Node[K, V] {
+	defer n.t.rlock()()
+	m, ok := n.n.(Multiway[K, V])
+	if !ok {
+		return nil
+	}
+	children := m.Children()
+	if children == nil {
+		return nil
+	}
+	wrapped := make([]Node[K, V], len(children))
+	for i, c := range children {
+		wrapped[i] = n.t.wrap(c)
+	}
+	return wrapped
+}
+
+type syncIterator[K, V any] struct {
+	t  *SyncBST[K, V]
+	it Iterator[K, V]
+}
+
+func (it *syncIterator[K, V]) Seek(key K) bool {
+	defer it.t.rlock()()
+	return it.it.Seek(key)
+}
+
+func (it *syncIterator[K, V]) First() bool {
+	defer it.t.rlock()()
+	return it.it.First()
+}
+
+func (it *syncIterator[K, V]) Last() bool {
+	defer it.t.rlock()()
+	return it.it.Last()
+}
+
+func (it *syncIterator[K, V]) Next() bool {
+	defer it.t.rlock()()
+	return it.it.Next()
+}
+
+func (it *syncIterator[K, V]) Prev() bool {
+	defer it.t.rlock()()
+	return it.it.Prev()
+}
+
+func (it *syncIterator[K, V]) Valid() bool {
+	defer it.t.rlock()()
+	return it.it.Valid()
+}
+
+func (it *syncIterator[K, V]) Key() K {
+	defer it.t.rlock()()
+	return it.it.Key()
+}
+
+func (it *syncIterator[K, V]) Data() V {
+	defer it.t.rlock()()
+	return it.it.Data()
+}
+
+// Versioned publishes the versions of a persistent tree to concurrent
+// readers: writes are serialized, and Snapshot returns the latest version
+// without locking, which stays unchanged however long it is read.
+type Versioned[K, V any] struct {
+	mu  sync.Mutex // 串行化写操作
+	cur atomic.Pointer[Persistent[K, V]]
+}
+
+// NewVersioned returns a Versioned whose current version is p
+func NewVersioned[K, V any](p Persistent[K, V]) *Versioned[K, V] {
+	v := new(Versioned[K, V])
+	v.cur.Store(&p)
+	return v
+}
+
+// Snapshot returns the current version
+func (v *Versioned[K, V]) Snapshot() Snapshot[K, V] {
+	return *v.cur.Load()
+}
+
+func (v *Versioned[K, V]) Insert(key K, data V) error {
+	return v.Update(func(p Persistent[K, V]) (Persistent[K, V], error) {
+		return p.Insert(key, data)
+	})
+}
+
+func (v *Versioned[K, V]) Remove(key K) error {
+	return v.Update(func(p Persistent[K, V]) (Persistent[K, V], error) {
+		return p.Remove(key)
+	})
+}
+
+// Update publishes the version returned by fn from the current one, readers
+// see either all or none of the writes made by fn. Nothing is published when
+// fn returns an error.
+func (v *Versioned[K, V]) Update(fn func(p Persistent[K, V]) (Persistent[K, V], error)) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	p, err := fn(*v.cur.Load())
+	if err != nil {
+		return err
+	}
+	v.cur.Store(&p)
+	return nil
+}
@@ -0,0 +1,46 @@
+package treap
+
+import (
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+// MarshalBinary encodes the tree as configured by the bst.Encoding given to
+// New, the priorities are not encoded
+func (t *treap[K, V]) MarshalBinary() ([]byte, error) {
+	return bst.EncodeBinary[K, V](t, bst.Treap, t.enc)
+}
+
+// UnmarshalBinary replaces the content of the tree by data from MarshalBinary
+func (t *treap[K, V]) UnmarshalBinary(data []byte) error {
+	return bst.DecodeBinary[K, V](t, bst.Treap, t.enc, data, t.shapeBuilder())
+}
+
+func (t *treap[K, V]) MarshalJSON() ([]byte, error) {
+	return bst.EncodeJSON[K, V](t, bst.Treap, t.enc)
+}
+
+func (t *treap[K, V]) UnmarshalJSON(data []byte) error {
+	return bst.DecodeJSON[K, V](t, bst.Treap, t.enc, data, t.shapeBuilder())
+}
+
+// shapeBuilder 按原有形状重建节点，新抽取的优先级不低于孩子的，以满足堆序
+func (t *treap[K, V]) shapeBuilder() bst.ShapeBuilder[K, V] {
+	return bst.ShapeBuilder[K, V]{
+		Comp:  t.comp,
+		Multi: t.multi,
+		Node: func(key K, data V, _ bool, l, r bst.Node[K, V]) (bst.Node[K, V], error) {
+			l0, r0 := nodeOf(l), nodeOf(r)
+			n := t.newNode(key, data)
+			for _, c := range [2]*node[K, V]{l0, r0} {
+				if c != nil {
+					n.pri = max(n.pri, c.pri)
+				}
+			}
+			return link(l0, n, r0, t.updates), nil
+		},
+		SetRoot: func(root bst.Node[K, V]) error {
+			t.root = orphan(nodeOf(root))
+			return nil
+		},
+	}
+}
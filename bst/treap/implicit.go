@@ -0,0 +1,310 @@
+package treap
+
+import (
+	"math/rand"
+
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+// Implicit is an implicit treap, a sequence of values ordered by their
+// positions instead of by keys. Access, insertion and removal at any
+// position, Split and Concat take O(log n) expected time. Its nodes implement
+// bst.Node with the position of the value as the key, which Key computes in
+// O(log n) from the sizes of the subtrees.
+type Implicit[V any] struct {
+	root *inode[V]
+	rnd  *rand.Rand // 优先级的来源
+}
+
+// NewImplicit returns an empty sequence, a rand.Source in parms draws the
+// priorities as for New
+func NewImplicit[V any](parms ...interface{}) *Implicit[V] {
+	s := new(Implicit[V])
+	for _, p := range parms {
+		if src, ok := p.(rand.Source); ok {
+			s.rnd = rand.New(src)
+		}
+	}
+	if s.rnd == nil {
+		s.rnd = rand.New(rand.NewSource(rand.Int63()))
+	}
+	return s
+}
+
+// Len returns the number of values in the sequence
+func (s *Implicit[V]) Len() int { return s.root.sz() }
+
+// Root returns the root node, whose key is its position
+func (s *Implicit[V]) Root() bst.Node[int, V] { return s.root }
+
+func (s *Implicit[V]) Print() {
+	bst.PrintWithUnitSize[int, V](s.root, 2)
+}
+
+// At returns the value at position i, it panics if i is out of range
+func (s *Implicit[V]) At(i int) V { return s.node(i).data }
+
+// Set replaces the value at position i, it panics if i is out of range
+func (s *Implicit[V]) Set(i int, data V) { s.node(i).data = data }
+
+// node 按子树规模下行至位置 i 的节点
+func (s *Implicit[V]) node(i int) *inode[V] {
+	if i < 0 || i >= s.Len() {
+		panic("treap: index out of range")
+	}
+	n := s.root
+	for {
+		switch l := n.lchild.sz(); {
+		case i < l:
+			n = n.lchild
+		case i == l:
+			return n
+		default:
+			i -= l + 1
+			n = n.rchild
+		}
+	}
+}
+
+// Insert inserts data at position i, shifting the values from i on, it
+// panics if i is not in [0, Len]
+func (s *Implicit[V]) Insert(i int, data V) {
+	if i < 0 || i > s.Len() {
+		panic("treap: index out of range")
+	}
+	n := &inode[V]{data: data, pri: s.rnd.Uint64(), size: 1}
+	l, r := isplit(s.root, i)
+	s.root = iorphan(imerge(imerge(l, n), r))
+}
+
+// Append appends the values at the end of the sequence
+func (s *Implicit[V]) Append(values ...V) {
+	s.root = iorphan(imerge(s.root, s.build(values)))
+}
+
+// Remove removes the value at position i and returns it, it panics if i is
+// out of range
+func (s *Implicit[V]) Remove(i int) V {
+	if i < 0 || i >= s.Len() {
+		panic("treap: index out of range")
+	}
+	l, r := isplit(s.root, i)
+	m, r := isplit(r, 1)
+	s.root = iorphan(imerge(l, r))
+	return m.data
+}
+
+// Split moves the values from position i on into a new sequence, it panics
+// if i is not in [0, Len]
+func (s *Implicit[V]) Split(i int) *Implicit[V] {
+	if i < 0 || i > s.Len() {
+		panic("treap: index out of range")
+	}
+	l, r := isplit(s.root, i)
+	s.root = iorphan(l)
+	return &Implicit[V]{root: iorphan(r), rnd: s.rnd}
+}
+
+// Concat appends the values of other to the sequence and empties other
+func (s *Implicit[V]) Concat(other *Implicit[V]) {
+	if other == s {
+		return
+	}
+	s.root, other.root = iorphan(imerge(s.root, other.root)), nil
+}
+
+// Values returns the values in order
+func (s *Implicit[V]) Values() []V {
+	values := make([]V, 0, s.Len())
+	bst.Traverse[int, V](s.root, bst.InOrder, func(n bst.Node[int, V]) bool {
+		values = append(values, n.Data())
+		return true
+	})
+	return values
+}
+
+// Traverse walks the nodes by order o until fn returns false, see
+// bst.Traverser
+func (s *Implicit[V]) Traverse(o bst.Order, fn func(n bst.Node[int, V]) bool) bool {
+	return bst.Traverse[int, V](s.root, o, fn)
+}
+
+// Validate checks the parent pointers, the sizes and the heap order of the
+// priorities, the positions being in order by construction
+func (s *Implicit[V]) Validate() error {
+	if s.root == nil {
+		return nil
+	}
+	if s.root.parent != nil {
+		return bst.NewInvariantError(bst.Treap, bst.RuleParent, 0, "root has parent")
+	}
+	_, err := s.root.validate(0)
+	return err
+}
+
+// validate 检查以 n 为根、首个值位于 base 的子树，返回其规模
+func (n *inode[V]) validate(base int) (int, error) {
+	l, r := 0, 0
+	var err error
+	if n.lchild != nil {
+		if l, err = n.lchild.validate(base); err != nil {
+			return 0, err
+		}
+	}
+	pos := base + l
+	if n.rchild != nil {
+		if r, err = n.rchild.validate(pos + 1); err != nil {
+			return 0, err
+		}
+	}
+	for _, c := range [2]*inode[V]{n.lchild, n.rchild} {
+		if c == nil {
+			continue
+		}
+		if c.parent != n {
+			return 0, bst.NewInvariantError(bst.Treap, bst.RuleParent, pos, "child is not linked to its parent")
+		}
+		if c.pri > n.pri {
+			return 0, bst.NewInvariantError(bst.Treap, bst.RuleHeap, pos, "priority %d below a child", n.pri)
+		}
+	}
+	if n.size != l+r+1 {
+		return 0, bst.NewInvariantError(bst.Treap, bst.RuleSize, pos, "size %d, want %d", n.size, l+r+1)
+	}
+	return n.size, nil
+}
+
+// build 以 O(n) 将 values 构建为笛卡尔树。自左向右加入节点，栈中保存当前最右侧通路，
+// 新节点弹出优先级低于它的节点作为左子树，再接为栈顶的右孩子；弹出的节点此后不再变化，故可随即更新
+func (s *Implicit[V]) build(values []V) *inode[V] {
+	var stack []*inode[V]
+	for _, v := range values {
+		n := &inode[V]{data: v, pri: s.rnd.Uint64(), size: 1}
+		var last *inode[V]
+		for len(stack) > 0 && stack[len(stack)-1].pri < n.pri {
+			last = stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			last.update()
+		}
+		n.lchild = last
+		if last != nil {
+			last.parent = n
+		}
+		if len(stack) > 0 {
+			top := stack[len(stack)-1]
+			top.rchild, n.parent = n, top
+		}
+		stack = append(stack, n)
+	}
+	for i := len(stack) - 1; i >= 0; i-- {
+		stack[i].update()
+	}
+	if len(stack) == 0 {
+		return nil
+	}
+	return iorphan(stack[0])
+}
+
+// isplit 将子树切分为前 k 个值和其余的值
+func isplit[V any](n *inode[V], k int) (*inode[V], *inode[V]) {
+	if n == nil {
+		return nil, nil
+	}
+	l := n.lchild.sz()
+	if k <= l {
+		ll, lr := isplit(iorphan(n.lchild), k)
+		return ll, ilink(lr, n, n.rchild)
+	}
+	rl, rr := isplit(iorphan(n.rchild), k-l-1)
+	return ilink(n.lchild, n, rl), rr
+}
+
+// imerge 合并子树 l 和 r，l 中的值均位于 r 之前
+func imerge[V any](l, r *inode[V]) *inode[V] {
+	switch {
+	case l == nil:
+		return r
+	case r == nil:
+		return l
+	case l.pri >= r.pri:
+		return ilink(l.lchild, l, imerge(iorphan(l.rchild), r))
+	}
+	return ilink(imerge(l, iorphan(r.lchild)), r, r.rchild)
+}
+
+// inode 隐式树堆的节点，关键码为其在序列中的位置
+type inode[V any] struct {
+	lchild *inode[V]
+	rchild *inode[V]
+	parent *inode[V]
+	data   V
+	pri    uint64
+	size   int
+}
+
+// Key returns the position of n in the sequence
+func (n *inode[V]) Key() int {
+	k := n.lchild.sz()
+	for ; n.parent != nil; n = n.parent {
+		if n.parent.rchild == n {
+			k += n.parent.lchild.sz() + 1
+		}
+	}
+	return k
+}
+
+func (n *inode[V]) Data() V                  { return n.data }
+func (n *inode[V]) Height() int              { return 0 }
+func (n *inode[V]) Size() int                { return n.size }
+func (n *inode[V]) LChild() bst.Node[int, V] { return n.lchild }
+func (n *inode[V]) RChild() bst.Node[int, V] { return n.rchild }
+func (n *inode[V]) Parent() bst.Node[int, V] { return n.parent }
+func (n *inode[V]) Color() string            { return "" }
+func (n *inode[V]) IsNil() bool              { return n == nil }
+
+// SetKey does nothing, the position follows from the shape
+func (n *inode[V]) SetKey(int)                    {}
+func (n *inode[V]) SetData(data V)                { n.data = data }
+func (n *inode[V]) SetLChild(lc bst.Node[int, V]) { n.lchild = inodeOf(lc) }
+func (n *inode[V]) SetRChild(rc bst.Node[int, V]) { n.rchild = inodeOf(rc) }
+func (n *inode[V]) SetParent(p bst.Node[int, V])  { n.parent = inodeOf(p) }
+
+func inodeOf[V any](n bst.Node[int, V]) *inode[V] {
+	if bst.IsNil(n) {
+		return nil
+	}
+	n0, ok := n.(*inode[V])
+	if !ok {
+		panic("inconsistent node type")
+	}
+	return n0
+}
+
+func (n *inode[V]) sz() int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+func (n *inode[V]) update() { n.size = n.lchild.sz() + n.rchild.sz() + 1 }
+
+// ilink 以 l、r 为 n 的左右子树，并更新 n 的规模
+func ilink[V any](l, n, r *inode[V]) *inode[V] {
+	n.lchild, n.rchild = l, r
+	if l != nil {
+		l.parent = n
+	}
+	if r != nil {
+		r.parent = n
+	}
+	n.update()
+	return n
+}
+
+func iorphan[V any](n *inode[V]) *inode[V] {
+	if n != nil {
+		n.parent = nil
+	}
+	return n
+}
@@ -0,0 +1,101 @@
+package treap
+
+import (
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+type node[K, V any] struct {
+	lchild *node[K, V]
+	rchild *node[K, V]
+	parent *node[K, V]
+	key    K
+	data   V
+	pri    uint64 // 优先级，父节点不低于孩子
+	aug    any    // 子树摘要
+	size   int    // 子树规模
+}
+
+func (n *node[K, V]) Key() K                 { return n.key }
+func (n *node[K, V]) Data() V                { return n.data }
+func (n *node[K, V]) Height() int            { return 0 }
+func (n *node[K, V]) Size() int              { return n.size }
+func (n *node[K, V]) LChild() bst.Node[K, V] { return n.lchild }
+func (n *node[K, V]) RChild() bst.Node[K, V] { return n.rchild }
+func (n *node[K, V]) Parent() bst.Node[K, V] { return n.parent }
+func (n *node[K, V]) Color() string          { return "" }
+func (n *node[K, V]) IsNil() bool            { return n == nil }
+
+func (n *node[K, V]) SetKey(key K)     { n.key = key }
+func (n *node[K, V]) SetData(data V)   { n.data = data }
+func (n *node[K, V]) Summary() any     { return n.aug }
+func (n *node[K, V]) SetSummary(s any) { n.aug = s }
+func (n *node[K, V]) SetLChild(lc bst.Node[K, V]) {
+	lc0, ok := lc.(*node[K, V])
+	if !ok && !bst.IsNil(lc) {
+		panic("inconsistent node type")
+	}
+	n.lchild = lc0
+}
+
+func (n *node[K, V]) SetRChild(rc bst.Node[K, V]) {
+	rc0, ok := rc.(*node[K, V])
+	if !ok && !bst.IsNil(rc) {
+		panic("inconsistent node type")
+	}
+	n.rchild = rc0
+}
+
+func (n *node[K, V]) SetParent(p bst.Node[K, V]) {
+	p0, ok := p.(*node[K, V])
+	if !ok && !bst.IsNil(p) {
+		panic("inconsistent node type")
+	}
+	n.parent = p0
+}
+
+func updateSize[K, V any](n bst.Node[K, V]) {
+	n0 := n.(*node[K, V])
+	n0.size = 1
+	if n0.lchild != nil {
+		n0.size += n0.lchild.size
+	}
+	if n0.rchild != nil {
+		n0.size += n0.rchild.size
+	}
+}
+
+// update applies opts on ns in order
+func update[K, V any](opts []bst.Option[K, V], ns ...*node[K, V]) {
+	for _, n := range ns {
+		for _, o := range opts {
+			o(n)
+		}
+	}
+}
+
+// link 以 l、r 为 n 的左右子树，并更新 n 的规模与摘要
+func link[K, V any](l, n, r *node[K, V], opts []bst.Option[K, V]) *node[K, V] {
+	n.lchild, n.rchild = l, r
+	if l != nil {
+		l.parent = n
+	}
+	if r != nil {
+		r.parent = n
+	}
+	update(opts, n)
+	return n
+}
+
+func orphan[K, V any](n *node[K, V]) *node[K, V] {
+	if n != nil {
+		n.parent = nil
+	}
+	return n
+}
+
+func nodeOf[K, V any](n bst.Node[K, V]) *node[K, V] {
+	if bst.IsNil(n) {
+		return nil
+	}
+	return n.(*node[K, V])
+}
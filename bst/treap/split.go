@@ -0,0 +1,54 @@
+package treap
+
+import (
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+// Split moves the keys not less than key into a new treap in O(log n)
+// expected time
+func (t *treap[K, V]) Split(key K) bst.BST[K, V] {
+	l, r := t.split(t.root, key)
+	other := *t
+	t.root, other.root = orphan(l), orphan(r)
+	return &other
+}
+
+// Join moves the keys of other, a treap with greater keys, into the tree in
+// O(log n) expected time
+func (t *treap[K, V]) Join(other bst.BST[K, V]) error {
+	o, ok := other.(*treap[K, V])
+	if !ok {
+		return bst.ErrJoinClass
+	}
+	if err := bst.CheckJoin[K, V](t, o, t.comp); err != nil {
+		return err
+	}
+	t.root, o.root = orphan(t.merge(t.root, o.root)), nil
+	return nil
+}
+
+// split 将子树切分为小于 key 和不小于 key 的两棵子树，沿查找路径切开，堆序不变
+func (t *treap[K, V]) split(n *node[K, V], key K) (*node[K, V], *node[K, V]) {
+	if n == nil {
+		return nil, nil
+	}
+	if t.comp(key, n.key) <= 0 {
+		ll, lr := t.split(orphan(n.lchild), key)
+		return ll, link(lr, n, n.rchild, t.updates)
+	}
+	rl, rr := t.split(orphan(n.rchild), key)
+	return link(n.lchild, n, rl, t.updates), rr
+}
+
+// merge 合并子树 l 和 r，l 中的关键码均不大于 r 中的，优先级较高的根保留为合并后的根
+func (t *treap[K, V]) merge(l, r *node[K, V]) *node[K, V] {
+	switch {
+	case l == nil:
+		return r
+	case r == nil:
+		return l
+	case l.pri >= r.pri:
+		return link(l.lchild, l, t.merge(orphan(l.rchild), r), t.updates)
+	}
+	return link(t.merge(l, orphan(r.lchild)), r, r.rchild, t.updates)
+}
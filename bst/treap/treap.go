@@ -0,0 +1,460 @@
+// Package treap implements the treap, a binary search tree whose nodes are
+// also a max-heap by random priorities, and the implicit treap, a sequence
+// ordered by position. Both expect O(log n) depth without any balance
+// information, which makes the treap a simple baseline among the classes of
+// package bst.
+package treap
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+func init() {
+	bst.Register("treap", bst.Factory{
+		New: New[any, any],
+		Typed: []interface{}{
+			New[int, int], New[int, string], New[int, any],
+			New[string, int], New[string, string], New[string, any],
+		},
+		Multimap: true,
+	})
+}
+
+type treap[K, V any] struct {
+	root    *node[K, V]
+	comp    bst.Comparator[K]
+	aug     bst.Augmenter[K, V]
+	enc     bst.Encoding[K, V]
+	stats   *bst.Stats
+	rnd     *rand.Rand         // 优先级的来源
+	multi   bool               // MultiKeys 模式
+	updates []bst.Option[K, V] // 结构变化后自底向上更新节点
+}
+
+// New returns a new empty treap with the default comparator of K. The
+// priorities are drawn from a rand.Source in parms, such as a *rand.Rand,
+// which makes the shape of the treap reproducible, or from a randomly seeded
+// source. Concurrent reads are safe as long as no write runs, use
+// bst.Synchronized to share the tree with writers.
+func New[K, V any](parms ...interface{}) bst.BST[K, V] {
+	t := new(treap[K, V])
+	t.comp = bst.DefaultCompare[K]()
+	for _, p := range parms {
+		switch v := p.(type) {
+		case bst.Comparator[K]:
+			t.comp = v
+		case func(a, b K) int:
+			t.comp = v
+		case bst.Augmenter[K, V]:
+			t.aug = v
+		case bst.Encoding[K, V]:
+			t.enc = v
+		case *bst.Stats:
+			t.stats = v
+		case bst.Mode:
+			t.multi = v == bst.MultiKeys
+		case rand.Source:
+			t.rnd = rand.New(v)
+		}
+	}
+	if t.rnd == nil {
+		t.rnd = rand.New(rand.NewSource(rand.Int63()))
+	}
+	t.comp = bst.CountComparisons(t.comp, t.stats)
+	t.updates = []bst.Option[K, V]{updateSize[K, V]}
+	if t.aug != nil {
+		t.updates = append(t.updates, bst.AugmentOption(t.aug))
+	}
+	return t
+}
+
+// newNode 新建节点并抽取其优先级
+func (t *treap[K, V]) newNode(key K, data V) *node[K, V] {
+	return &node[K, V]{key: key, data: data, pri: t.rnd.Uint64(), size: 1}
+}
+
+// Len returns the number of keys in the tree
+func (t *treap[K, V]) Len() int {
+	if t.root == nil {
+		return 0
+	}
+	return t.root.size
+}
+
+func (t *treap[K, V]) Root() bst.Node[K, V] {
+	return t.root
+}
+
+func (t *treap[K, V]) Print() {
+	bst.PrintWithUnitSize(t.root, 2)
+}
+
+func (t *treap[K, V]) Search(key K) (bst.Node[K, V], bool) {
+	if t.root == nil {
+		return nil, false
+	}
+	n, result := t.find(key)
+	if result == 0 {
+		return n, true
+	}
+	return n, false
+}
+
+// find 在非空树中查找 key，多重映射中返回最早插入的节点
+func (t *treap[K, V]) find(key K) (*node[K, V], int) {
+	if t.multi {
+		return t.searchMulti(t.root, key, false)
+	}
+	return t.searchIn(t.root, key)
+}
+
+// searchMulti 在多重映射中查找 key 最早插入的节点，未找到或 last 时返回新节点的插入位置，
+// last 时新节点位于所有相等的关键码之后
+func (t *treap[K, V]) searchMulti(n *node[K, V], key K, last bool) (*node[K, V], int) {
+	var hit *node[K, V]
+	for {
+		c := t.comp(key, n.key)
+		next, result := n.rchild, 1
+		if c < 0 || c == 0 && !last {
+			next, result = n.lchild, -1
+		}
+		if c == 0 && !last {
+			hit = n
+		}
+		if next == nil {
+			if hit != nil {
+				return hit, 0
+			}
+			return n, result
+		}
+		n = next
+	}
+}
+
+// searchIn 查找 key，未找到时返回最后访问的节点及新节点应在其哪一侧
+func (t *treap[K, V]) searchIn(n *node[K, V], key K) (*node[K, V], int) {
+	for {
+		c := t.comp(key, n.key)
+		next := n.rchild
+		switch {
+		case c == 0:
+			return n, 0
+		case c < 0:
+			next = n.lchild
+		}
+		if next == nil {
+			return n, c
+		}
+		n = next
+	}
+}
+
+func (t *treap[K, V]) Insert(key K, data V) (_ bst.Node[K, V], err error) {
+	if bst.Debug {
+		defer bst.MustValidate(t)
+	}
+	defer bst.CatchIncomparable(&err)
+	if t.root == nil {
+		return t.attach(nil, 0, key, data), nil
+	}
+	if t.multi {
+		n, result := t.searchMulti(t.root, key, true)
+		return t.attach(n, result, key, data), nil
+	}
+	n, result := t.searchIn(t.root, key)
+	if result == 0 {
+		return nil, bst.ErrDuplicateKey
+	}
+	return t.attach(n, result, key, data), nil
+}
+
+// attach 将新节点插入为 searchIn 返回的节点 n 的孩子，result 为查找的结果，n 为 nil 时作为根；
+// 新节点随后上旋，直至其父节点的优先级不低于它
+func (t *treap[K, V]) attach(n *node[K, V], result int, key K, data V) *node[K, V] {
+	new := t.newNode(key, data)
+	switch {
+	case n == nil:
+		t.root = new
+		update(t.updates, new)
+		return new
+	case result < 0:
+		bst.AttachLChild[K, V](n, new)
+	default:
+		bst.AttachRChild[K, V](n, new)
+	}
+	for new.parent != nil && new.parent.pri < new.pri {
+		t.rotateUp(new)
+	}
+	bst.UpdateAbove[K, V](new, t.updates...)
+	return new
+}
+
+// rotateUp 将 n 与其父节点 p 单旋交换位置，p 成为 n 的孩子
+func (t *treap[K, V]) rotateUp(n *node[K, V]) {
+	p := n.parent
+	g := p.parent
+	if p.lchild == n {
+		bst.AttachLChild[K, V](p, n.rchild)
+		bst.AttachRChild[K, V](n, p)
+	} else {
+		bst.AttachRChild[K, V](p, n.lchild)
+		bst.AttachLChild[K, V](n, p)
+	}
+	switch {
+	case g == nil:
+		t.root = n
+		n.parent = nil
+	case g.lchild == p:
+		bst.AttachLChild[K, V](g, n)
+	default:
+		bst.AttachRChild[K, V](g, n)
+	}
+	update(t.updates, p, n)
+	t.stats.Add(1)
+}
+
+func (t *treap[K, V]) Remove(key K) (_ bst.Node[K, V], err error) {
+	if bst.Debug {
+		defer bst.MustValidate(t)
+	}
+	defer bst.CatchIncomparable(&err)
+	if t.root == nil {
+		return nil, bst.ErrNotFound
+	}
+	n, result := t.find(key)
+	if result != 0 {
+		return nil, bst.ErrNotFound
+	}
+	t.detach(n)
+	return n, nil
+}
+
+// detach 将 n 向下旋转至至多有一个孩子，再以该孩子替代 n。
+// 每次上旋优先级较高的孩子，以保持堆序
+func (t *treap[K, V]) detach(n *node[K, V]) {
+	for n.lchild != nil && n.rchild != nil {
+		c := n.lchild
+		if n.rchild.pri > c.pri {
+			c = n.rchild
+		}
+		t.rotateUp(c)
+	}
+	c := n.lchild
+	if c == nil {
+		c = n.rchild
+	}
+	p := n.parent
+	switch {
+	case p == nil:
+		t.root = orphan(c)
+	case p.lchild == n:
+		bst.AttachLChild[K, V](p, c)
+	default:
+		bst.AttachRChild[K, V](p, c)
+	}
+	if p != nil {
+		bst.UpdateAbove[K, V](p, t.updates...)
+	}
+	n.lchild, n.rchild, n.parent = nil, nil, nil
+}
+
+// Put inserts key with data or replaces its data after a single search
+func (t *treap[K, V]) Put(key K, data V) (old V, replaced bool, err error) {
+	if bst.Debug {
+		defer bst.MustValidate(t)
+	}
+	defer bst.CatchIncomparable(&err)
+	if t.root == nil {
+		t.attach(nil, 0, key, data)
+		return old, false, nil
+	}
+	n, result := t.find(key)
+	if result != 0 {
+		t.attach(n, result, key, data)
+		return old, false, nil
+	}
+	old = n.data
+	t.replace(n, data)
+	return old, true, nil
+}
+
+// replace 替换节点的数据，数据参与增强时更新摘要
+func (t *treap[K, V]) replace(n *node[K, V], data V) {
+	n.data = data
+	if t.aug != nil {
+		bst.UpdateAbove[K, V](n, t.updates...)
+	}
+}
+
+func (t *treap[K, V]) Update(key K, fn func(old V, ok bool) (V, bool)) (err error) {
+	if bst.Debug {
+		defer bst.MustValidate(t)
+	}
+	defer bst.CatchIncomparable(&err)
+	var n *node[K, V]
+	result := 1
+	if t.root != nil {
+		n, result = t.find(key)
+	}
+	var old V
+	if result == 0 {
+		old = n.data
+	}
+	data, keep := fn(old, result == 0)
+	switch {
+	case result == 0 && keep:
+		t.replace(n, data)
+	case result == 0:
+		t.detach(n)
+	case keep:
+		t.attach(n, result, key, data)
+	}
+	return nil
+}
+
+func (t *treap[K, V]) GetOrInsert(key K, data V) (_ bst.Node[K, V], found bool, err error) {
+	if bst.Debug {
+		defer bst.MustValidate(t)
+	}
+	defer bst.CatchIncomparable(&err)
+	if t.root == nil {
+		return t.attach(nil, 0, key, data), false, nil
+	}
+	n, result := t.find(key)
+	if result == 0 {
+		return n, true, nil
+	}
+	return t.attach(n, result, key, data), false, nil
+}
+
+// Count returns the number of entries of key in O(log n)
+func (t *treap[K, V]) Count(key K) int { return bst.CountRange(t.root, key, key, t.comp) }
+
+func (t *treap[K, V]) All(key K, fn func(n bst.Node[K, V]) bool) {
+	bst.Range(t.root, key, key, true, true, t.comp, fn)
+}
+
+// RemoveOne removes the oldest entry of key as Remove does
+func (t *treap[K, V]) RemoveOne(key K) (bst.Node[K, V], error) { return t.Remove(key) }
+
+func (t *treap[K, V]) RemoveAll(key K) (int, error) { return bst.RemoveEach(key, t.Remove) }
+
+// BuildSorted builds a treap of minimum height from the sorted keys in O(n)
+func (t *treap[K, V]) BuildSorted(keys []K, values []V, verify bool) error {
+	if verify {
+		check := bst.CheckSorted[K]
+		if t.multi {
+			check = bst.CheckSortedMulti[K]
+		}
+		if err := check(keys, t.comp); err != nil {
+			return err
+		}
+	}
+	t.root = t.build(keys, values, nil)
+	t.prioritize(len(keys))
+	return nil
+}
+
+// build 以中位数为根递归构建子树
+func (t *treap[K, V]) build(keys []K, values []V, parent *node[K, V]) *node[K, V] {
+	if len(keys) == 0 {
+		return nil
+	}
+	mid := len(keys) / 2
+	n := &node[K, V]{key: keys[mid], data: values[mid], parent: parent}
+	n.lchild = t.build(keys[:mid], values[:mid], n)
+	n.rchild = t.build(keys[mid+1:], values[mid+1:], n)
+	update(t.updates, n)
+	return n
+}
+
+// prioritize 按层次序为 n 个节点赋予降序的随机优先级，父节点均先于孩子，故满足堆序。
+// 指数分布间隔的前缀和归一化后即为有序的均匀分布样本，无需排序
+func (t *treap[K, V]) prioritize(n int) {
+	sums := make([]float64, n+1)
+	sum := 0.0
+	for i := range sums {
+		sum += t.rnd.ExpFloat64()
+		sums[i] = sum
+	}
+	i := 0
+	bst.Traverse[K, V](t.root, bst.LevelOrder, func(m bst.Node[K, V]) bool {
+		m.(*node[K, V]).pri = uint64((1 - sums[i]/sum) * math.MaxUint64)
+		i++
+		return true
+	})
+}
+
+func (t *treap[K, V]) Walk(o bst.Order, opts ...bst.Option[K, V]) {
+	switch o {
+	case bst.PreOrder:
+		bst.TravPre(t.root, opts...)
+	case bst.InOrder:
+		bst.TravIn(t.root, opts...)
+	case bst.PostOrder:
+		bst.TravPost(t.root, opts...)
+	case bst.LevelOrder:
+		bst.TravLevel(t.root, opts...)
+	case bst.ReverseInOrder:
+		bst.TravReverseIn(t.root, opts...)
+	default:
+		panic("unsupported walk order")
+	}
+}
+
+func (t *treap[K, V]) Traverse(o bst.Order, fn func(n bst.Node[K, V]) bool) bool {
+	return bst.Traverse[K, V](t.root, o, fn)
+}
+
+func (t *treap[K, V]) Iterator() bst.Iterator[K, V] {
+	return bst.NewIterator[K, V](t, t.comp)
+}
+
+func (t *treap[K, V]) Floor(key K) (bst.Node[K, V], bool) {
+	return bst.Floor(t.root, key, t.comp)
+}
+
+func (t *treap[K, V]) Ceiling(key K) (bst.Node[K, V], bool) {
+	return bst.Ceiling(t.root, key, t.comp)
+}
+
+func (t *treap[K, V]) Lower(key K) (bst.Node[K, V], bool) {
+	return bst.Lower(t.root, key, t.comp)
+}
+
+func (t *treap[K, V]) Higher(key K) (bst.Node[K, V], bool) {
+	return bst.Higher(t.root, key, t.comp)
+}
+
+func (t *treap[K, V]) Min() (bst.Node[K, V], bool) { return bst.Min(t.root) }
+
+func (t *treap[K, V]) Max() (bst.Node[K, V], bool) { return bst.Max(t.root) }
+
+func (t *treap[K, V]) Range(lo, hi K, loInclusive, hiInclusive bool, fn func(n bst.Node[K, V]) bool) {
+	bst.Range(t.root, lo, hi, loInclusive, hiInclusive, t.comp, fn)
+}
+
+func (t *treap[K, V]) Select(k int) (bst.Node[K, V], bool) { return bst.Select(t.root, k) }
+
+func (t *treap[K, V]) Rank(key K) int { return bst.Rank(t.root, key, t.comp) }
+
+func (t *treap[K, V]) CountRange(lo, hi K) int {
+	return bst.CountRange(t.root, lo, hi, t.comp)
+}
+
+func (t *treap[K, V]) Summary() any {
+	if t.aug == nil {
+		return nil
+	}
+	return bst.SummaryOf[K, V](t.root, t.aug)
+}
+
+func (t *treap[K, V]) SummaryRange(lo, hi K) any {
+	if t.aug == nil {
+		return nil
+	}
+	return bst.SummaryRange[K, V](t.root, lo, hi, t.comp, t.aug)
+}
@@ -0,0 +1,27 @@
+package treap
+
+import (
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+// Validate checks the key order, the parent pointers, the sizes and the heap
+// order of the priorities of the treap
+func (t *treap[K, V]) Validate() error {
+	validate := bst.ValidateBinary[K, V]
+	if t.multi {
+		validate = bst.ValidateBinaryMulti[K, V]
+	}
+	_, err := validate(bst.Treap, t.root, t.comp, func(m bst.Node[K, V]) error {
+		n := m.(*node[K, V])
+		if size := bst.Size[K, V](n.lchild) + bst.Size[K, V](n.rchild) + 1; n.size != size {
+			return bst.NewInvariantError(bst.Treap, bst.RuleSize, n.key, "size %d, want %d", n.size, size)
+		}
+		for _, c := range [2]*node[K, V]{n.lchild, n.rchild} {
+			if c != nil && c.pri > n.pri {
+				return bst.NewInvariantError(bst.Treap, bst.RuleHeap, c.key, "priority %d above its parent %v", c.pri, n.key)
+			}
+		}
+		return nil
+	})
+	return err
+}
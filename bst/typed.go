@@ -0,0 +1,335 @@
+package bst
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+)
+
+// typedBST 将注册表中以 interface{} 为键值的 BST 包装为 BST[K, V]
+type typedBST[K, V any] struct {
+	t     BST[any, any]
+	c     Class // 创建 t 所用的类别与参数
+	parms []interface{}
+}
+
+func (t *typedBST[K, V]) Search(key K) (Node[K, V], bool) {
+	n, ok := t.t.Search(key)
+	return wrapNode[K, V](n), ok
+}
+
+func (t *typedBST[K, V]) Insert(key K, data V) (Node[K, V], error) {
+	n, err := t.t.Insert(key, data)
+	return wrapNode[K, V](n), err
+}
+
+func (t *typedBST[K, V]) Remove(key K) (Node[K, V], error) {
+	n, err := t.t.Remove(key)
+	return wrapNode[K, V](n), err
+}
+
+func (t *typedBST[K, V]) Root() Node[K, V] { return wrapNode[K, V](t.t.Root()) }
+
+func (t *typedBST[K, V]) Len() int { return t.t.Len() }
+
+func (t *typedBST[K, V]) unwrap() interface{} { return t.t }
+
+func (t *typedBST[K, V]) Print() { t.t.Print() }
+
+func (t *typedBST[K, V]) Walk(o Order, opts ...Option[K, V]) {
+	t.t.Walk(o, eraseOptions(opts)...)
+}
+
+func (t *typedBST[K, V]) Traverse(o Order, fn func(n Node[K, V]) bool) bool {
+	return Visit(t.t, o, func(n Node[any, any]) bool { return fn(wrapNode[K, V](n)) })
+}
+
+func (t *typedBST[K, V]) Iterator() Iterator[K, V] {
+	return typedIterator[K, V]{t.t.Iterator()}
+}
+
+func (t *typedBST[K, V]) Floor(key K) (Node[K, V], bool) {
+	n, ok := t.t.Floor(key)
+	return wrapNode[K, V](n), ok
+}
+
+func (t *typedBST[K, V]) Ceiling(key K) (Node[K, V], bool) {
+	n, ok := t.t.Ceiling(key)
+	return wrapNode[K, V](n), ok
+}
+
+func (t *typedBST[K, V]) Lower(key K) (Node[K, V], bool) {
+	n, ok := t.t.Lower(key)
+	return wrapNode[K, V](n), ok
+}
+
+func (t *typedBST[K, V]) Higher(key K) (Node[K, V], bool) {
+	n, ok := t.t.Higher(key)
+	return wrapNode[K, V](n), ok
+}
+
+func (t *typedBST[K, V]) Min() (Node[K, V], bool) {
+	n, ok := t.t.Min()
+	return wrapNode[K, V](n), ok
+}
+
+func (t *typedBST[K, V]) Max() (Node[K, V], bool) {
+	n, ok := t.t.Max()
+	return wrapNode[K, V](n), ok
+}
+
+func (t *typedBST[K, V]) Range(lo, hi K, loInclusive, hiInclusive bool, fn func(n Node[K, V]) bool) {
+	t.t.Range(lo, hi, loInclusive, hiInclusive, func(n Node[any, any]) bool {
+		return fn(wrapNode[K, V](n))
+	})
+}
+
+// Select, Rank and CountRange fall back to scanning the tree when its class
+// does not implement OrderStatistic
+func (t *typedBST[K, V]) Select(k int) (Node[K, V], bool) {
+	if os, ok := t.t.(OrderStatistic[any, any]); ok {
+		n, ok := os.Select(k)
+		return wrapNode[K, V](n), ok
+	}
+	n, ok := selectScan(t.t, k)
+	return wrapNode[K, V](n), ok
+}
+
+func (t *typedBST[K, V]) Rank(key K) int {
+	if os, ok := t.t.(OrderStatistic[any, any]); ok {
+		return os.Rank(key)
+	}
+	hi := any(key)
+	return countScan(t.t, nil, &hi, false)
+}
+
+func (t *typedBST[K, V]) CountRange(lo, hi K) int {
+	if os, ok := t.t.(OrderStatistic[any, any]); ok {
+		return os.CountRange(lo, hi)
+	}
+	lo0, hi0 := any(lo), any(hi)
+	return countScan(t.t, &lo0, &hi0, true)
+}
+
+// Summary and SummaryRange return nil when the tree maintains no Augmenter
+func (t *typedBST[K, V]) Summary() any {
+	if s, ok := t.t.(Summarizer[any]); ok {
+		return s.Summary()
+	}
+	return nil
+}
+
+func (t *typedBST[K, V]) SummaryRange(lo, hi K) any {
+	if s, ok := t.t.(Summarizer[any]); ok {
+		return s.SummaryRange(lo, hi)
+	}
+	return nil
+}
+
+// BuildSorted falls back to inserting the keys one by one when the class
+// does not implement SortedBuilder
+func (t *typedBST[K, V]) BuildSorted(keys []K, values []V, verify bool) error {
+	b, ok := t.t.(SortedBuilder[any, any])
+	if !ok {
+		return insertEach(t, keys, values)
+	}
+	keys0 := make([]any, len(keys))
+	values0 := make([]any, len(values))
+	for i := range keys {
+		keys0[i], values0[i] = keys[i], values[i]
+	}
+	return b.BuildSorted(keys0, values0, verify)
+}
+
+// Split and Join fall back to moving the keys one by one when the class
+// does not implement Splitter
+func (t *typedBST[K, V]) Split(key K) BST[K, V] {
+	if s, ok := t.t.(Splitter[any, any]); ok {
+		return &typedBST[K, V]{t: s.Split(key), c: t.c, parms: t.parms}
+	}
+	other := &typedBST[K, V]{t: New(t.c, t.parms...), c: t.c, parms: t.parms}
+	splitScan(t.t, other.t, any(key))
+	return other
+}
+
+func (t *typedBST[K, V]) Join(other BST[K, V]) error {
+	o, ok := other.(*typedBST[K, V])
+	if !ok || o.c != t.c {
+		return ErrJoinClass
+	}
+	if s, ok := t.t.(Splitter[any, any]); ok {
+		return s.Join(o.t)
+	}
+	return joinScan(t.t, o.t)
+}
+
+// MarshalBinary, UnmarshalBinary, MarshalJSON and UnmarshalJSON fail when
+// the class does not implement them
+func (t *typedBST[K, V]) MarshalBinary() ([]byte, error) {
+	if m, ok := t.t.(encoding.BinaryMarshaler); ok {
+		return m.MarshalBinary()
+	}
+	return nil, unsupported(t.c, "binary encoding")
+}
+
+func (t *typedBST[K, V]) UnmarshalBinary(data []byte) error {
+	if u, ok := t.t.(encoding.BinaryUnmarshaler); ok {
+		return u.UnmarshalBinary(data)
+	}
+	return unsupported(t.c, "binary decoding")
+}
+
+func (t *typedBST[K, V]) MarshalJSON() ([]byte, error) {
+	if m, ok := t.t.(json.Marshaler); ok {
+		return m.MarshalJSON()
+	}
+	return nil, unsupported(t.c, "JSON encoding")
+}
+
+func (t *typedBST[K, V]) UnmarshalJSON(data []byte) error {
+	if u, ok := t.t.(json.Unmarshaler); ok {
+		return u.UnmarshalJSON(data)
+	}
+	return unsupported(t.c, "JSON decoding")
+}
+
+// unsupported 返回类别 c 不支持 what 的错误
+func unsupported(c Class, what string) error {
+	return fmt.Errorf("bst: %v does not support %s", c, what)
+}
+
+func (t *typedBST[K, V]) SelfAdjusting() bool { return selfAdjusting(t.t) }
+
+func (t *typedBST[K, V]) Validate() error { return Validate(t.t) }
+
+func (t *typedBST[K, V]) Put(key K, data V) (V, bool, error) {
+	old, replaced, err := Put(t.t, any(key), any(data))
+	return as[V](old), replaced, err
+}
+
+func (t *typedBST[K, V]) Update(key K, fn func(old V, ok bool) (V, bool)) error {
+	return Update(t.t, any(key), func(old any, ok bool) (any, bool) {
+		return fn(as[V](old), ok)
+	})
+}
+
+func (t *typedBST[K, V]) GetOrInsert(key K, data V) (Node[K, V], bool, error) {
+	n, found, err := GetOrInsert(t.t, any(key), any(data))
+	return wrapNode[K, V](n), found, err
+}
+
+func (t *typedBST[K, V]) Count(key K) int { return Count(t.t, any(key)) }
+
+func (t *typedBST[K, V]) All(key K, fn func(n Node[K, V]) bool) {
+	All(t.t, any(key), func(n Node[any, any]) bool { return fn(wrapNode[K, V](n)) })
+}
+
+func (t *typedBST[K, V]) RemoveOne(key K) (Node[K, V], error) { return t.Remove(key) }
+
+func (t *typedBST[K, V]) RemoveAll(key K) (int, error) { return RemoveAll(t.t, any(key)) }
+
+type typedIterator[K, V any] struct {
+	it Iterator[any, any]
+}
+
+func (it typedIterator[K, V]) Seek(key K) bool { return it.it.Seek(key) }
+func (it typedIterator[K, V]) First() bool     { return it.it.First() }
+func (it typedIterator[K, V]) Last() bool      { return it.it.Last() }
+func (it typedIterator[K, V]) Next() bool      { return it.it.Next() }
+func (it typedIterator[K, V]) Prev() bool      { return it.it.Prev() }
+func (it typedIterator[K, V]) Valid() bool     { return it.it.Valid() }
+func (it typedIterator[K, V]) Key() K          { return as[K](it.it.Key()) }
+func (it typedIterator[K, V]) Data() V         { return as[V](it.it.Data()) }
+
+// typedNode 将 Node[any, any] 包装为 Node[K, V]，其值可比较，
+// 同一节点的两次包装结果相等
+type typedNode[K, V any] struct {
+	n Node[any, any]
+}
+
+func wrapNode[K, V any](n Node[any, any]) Node[K, V] {
+	if IsNil(n) {
+		return nil
+	}
+	return typedNode[K, V]{n}
+}
+
+func unwrapNode[K, V any](n Node[K, V]) Node[any, any] {
+	if IsNil(n) {
+		return nil
+	}
+	return n.(typedNode[K, V]).n
+}
+
+func (n typedNode[K, V]) LChild() Node[K, V]     { return wrapNode[K, V](n.n.LChild()) }
+func (n typedNode[K, V]) RChild() Node[K, V]     { return wrapNode[K, V](n.n.RChild()) }
+func (n typedNode[K, V]) Parent() Node[K, V]     { return wrapNode[K, V](n.n.Parent()) }
+func (n typedNode[K, V]) SetLChild(c Node[K, V]) { n.n.SetLChild(unwrapNode(c)) }
+func (n typedNode[K, V]) SetRChild(c Node[K, V]) { n.n.SetRChild(unwrapNode(c)) }
+func (n typedNode[K, V]) SetParent(p Node[K, V]) { n.n.SetParent(unwrapNode(p)) }
+func (n typedNode[K, V]) Key() K                 { return as[K](n.n.Key()) }
+func (n typedNode[K, V]) Data() V                { return as[V](n.n.Data()) }
+func (n typedNode[K, V]) SetKey(key K)           { n.n.SetKey(key) }
+func (n typedNode[K, V]) SetData(data V)         { n.n.SetData(data) }
+func (n typedNode[K, V]) Height() int            { return n.n.Height() }
+func (n typedNode[K, V]) Color() string          { return n.n.Color() }
+func (n typedNode[K, V]) Level() int             { return levelOf(n.n) }
+func (n typedNode[K, V]) IsNil() bool            { return false } // wrapNode 不包装 nil 节点
+
+func eraseOptions[K, V any](opts []Option[K, V]) []Option[any, any] {
+	erased := make([]Option[any, any], len(opts))
+	for i, opt := range opts {
+		opt := opt
+		erased[i] = func(n Node[any, any]) { opt(wrapNode[K, V](n)) }
+	}
+	return erased
+}
+
+// levelOf 返回节点 n 的层数，n 不按层数平衡时返回 0
+func levelOf(n interface{}) int {
+	if l, ok := n.(Leveled); ok {
+		return l.Level()
+	}
+	return 0
+}
+
+// as 将 v 断言为 T，v 为 nil 时返回 T 的零值
+func as[T any](v interface{}) T {
+	t, _ := v.(T)
+	return t
+}
+
+func (n typedNode[K, V]) Entries() ([]K, []V) {
+	m, ok := n.n.(Multiway[any, any])
+	if !ok {
+		return nil, nil
+	}
+	keys, data := m.Entries()
+	if keys == nil {
+		return nil, nil
+	}
+	tk, td := make([]K, len(keys)), make([]V, len(data))
+	for i := range keys {
+		tk[i] = as[K](keys[i])
+	}
+	for i := range data {
+		td[i] = as[V](data[i])
+	}
+	return tk, td
+}
+
+func (n typedNode[K, V]) Children() []Node[K, V] {
+	m, ok := n.n.(Multiway[any, any])
+	if !ok {
+		return nil
+	}
+	children := m.Children()
+	if children == nil {
+		return nil
+	}
+	typed := make([]Node[K, V], len(children))
+	for i, c := range children {
+		typed[i] = wrapNode[K, V](c)
+	}
+	return typed
+}
@@ -0,0 +1,67 @@
+package bst
+
+// Upserter is implemented by the trees able to insert, replace or remove a
+// key after a single search for it
+type Upserter[K, V any] interface {
+	// Put inserts key with data, or replaces the data of key if present and
+	// returns the replaced data
+	Put(key K, data V) (old V, replaced bool, err error)
+	// Update calls fn with the data of key, ok reports whether key is
+	// present. The data returned by fn is stored under key if keep is set,
+	// otherwise key is removed, or left absent.
+	Update(key K, fn func(old V, ok bool) (data V, keep bool)) error
+	// GetOrInsert returns the node of key, inserting key with data first if
+	// it is absent, found reports whether key was present
+	GetOrInsert(key K, data V) (n Node[K, V], found bool, err error)
+}
+
+// Put inserts or replaces key in t, searching twice if t is not an Upserter
+func Put[K, V any](t BST[K, V], key K, data V) (old V, replaced bool, err error) {
+	if u, ok := t.(Upserter[K, V]); ok {
+		return u.Put(key, data)
+	}
+	if n, ok := t.Search(key); ok {
+		old = n.Data()
+		n.SetData(data)
+		return old, true, nil
+	}
+	_, err = t.Insert(key, data)
+	return old, false, err
+}
+
+// Update updates key in t by fn as Upserter.Update does, searching twice if
+// t is not an Upserter
+func Update[K, V any](t BST[K, V], key K, fn func(old V, ok bool) (data V, keep bool)) error {
+	if u, ok := t.(Upserter[K, V]); ok {
+		return u.Update(key, fn)
+	}
+	var old V
+	n, ok := t.Search(key)
+	if ok {
+		old = n.Data()
+	}
+	data, keep := fn(old, ok)
+	var err error
+	switch {
+	case ok && keep:
+		n.SetData(data)
+	case ok:
+		_, err = t.Remove(key)
+	case keep:
+		_, err = t.Insert(key, data)
+	}
+	return err
+}
+
+// GetOrInsert returns the node of key in t, inserting key with data first if
+// it is absent, searching twice if t is not an Upserter
+func GetOrInsert[K, V any](t BST[K, V], key K, data V) (Node[K, V], bool, error) {
+	if u, ok := t.(Upserter[K, V]); ok {
+		return u.GetOrInsert(key, data)
+	}
+	if n, ok := t.Search(key); ok {
+		return n, true, nil
+	}
+	n, err := t.Insert(key, data)
+	return n, false, err
+}
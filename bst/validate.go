@@ -0,0 +1,141 @@
+package bst
+
+import (
+	"fmt"
+)
+
+// Validator is implemented by the trees able to check their own invariants
+type Validator interface {
+	// Validate returns an *InvariantError for the first broken invariant
+	// found, or nil if the tree is sound
+	Validate() error
+}
+
+// Rule names an invariant checked by Validate
+type Rule string
+
+const (
+	RuleOrder       Rule = "order"        // 关键码按比较器严格递增，多重映射中不减
+	RuleParent      Rule = "parent"       // 孩子的父节点指针指向其父节点，根节点没有父节点
+	RuleSize        Rule = "size"         // 子树规模及 Len 正确
+	RuleHeight      Rule = "height"       // 节点记录的高度正确
+	RuleBalance     Rule = "balance"      // AVL 节点左右子树的高度差不超过 1；重量平衡树节点左右子树的权重比在 [α, 1-α] 内
+	RuleColor       Rule = "color"        // 根节点为黑，红节点的孩子均为黑
+	RuleBlackHeight Rule = "black-height" // 各外部节点的黑深度相同
+	RuleFill        Rule = "fill"         // B-树及 B+ 树节点的关键码及分支数在阶数允许的范围内
+	RuleDepth       Rule = "depth"        // B-树及 B+ 树的叶节点深度相同；替罪羊树的高度不超过 α 所允许的
+	RuleSummary     Rule = "summary"      // 节点的摘要与子树一致
+	RuleHeap        Rule = "heap"         // 树堆中节点的优先级不低于其孩子
+	RuleLevel       Rule = "level"        // 跳表各层依次链接下层中同样高的节点，前向链接与后向链接一致；AA 树的层数满足约束；B+ 树的叶节点按次序双向链接
+)
+
+// InvariantError reports an invariant broken at a node of a tree
+type InvariantError struct {
+	Class Class
+	Rule  Rule
+	Key   interface{} // 违反规则的节点的关键码，B-树为节点的首个关键码
+	Msg   string
+}
+
+// NewInvariantError returns an *InvariantError for the node of key in a tree
+// of class c, formatting the message by fmt.Sprintf
+func NewInvariantError(c Class, r Rule, key interface{}, format string, args ...interface{}) *InvariantError {
+	return &InvariantError{Class: c, Rule: r, Key: key, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (e *InvariantError) Error() string {
+	return fmt.Sprintf("bst: class %d breaks the %s rule at key %v: %s", e.Class, e.Rule, e.Key, e.Msg)
+}
+
+// Validate checks the invariants of t, which must implement Validator as
+// all the tree classes of this module do
+func Validate[K, V any](t BST[K, V]) error {
+	if v, ok := t.(Validator); ok {
+		return v.Validate()
+	}
+	return fmt.Errorf("bst: %T can not be validated", t)
+}
+
+// ValidateBinary checks the key order and the parent pointers of the binary
+// tree rooted at root of class c, and calls check on each node in post-order,
+// so check may assume the subtrees of the node are sound. It returns the
+// number of nodes.
+func ValidateBinary[K, V any](c Class, root Node[K, V], comp Comparator[K], check func(n Node[K, V]) error) (int, error) {
+	if IsNil(root) {
+		return 0, nil
+	}
+	if !IsNil(root.Parent()) {
+		return 0, NewInvariantError(c, RuleParent, root.Key(), "root has parent %v", root.Parent().Key())
+	}
+	v := binaryValidator[K, V]{c: c, comp: comp, check: check}
+	return v.walk(root, nil, nil)
+}
+
+// ValidateBinaryMulti is ValidateBinary for the trees in MultiKeys mode,
+// whose equal keys may lie on both sides of a node
+func ValidateBinaryMulti[K, V any](c Class, root Node[K, V], comp Comparator[K], check func(n Node[K, V]) error) (int, error) {
+	if IsNil(root) {
+		return 0, nil
+	}
+	if !IsNil(root.Parent()) {
+		return 0, NewInvariantError(c, RuleParent, root.Key(), "root has parent %v", root.Parent().Key())
+	}
+	v := binaryValidator[K, V]{c: c, comp: comp, check: check, multi: true}
+	return v.walk(root, nil, nil)
+}
+
+type binaryValidator[K, V any] struct {
+	c     Class
+	comp  Comparator[K]
+	check func(n Node[K, V]) error
+	multi bool // 允许相等的关键码
+}
+
+// before 检查 a 是否应位于 b 之前
+func (v binaryValidator[K, V]) before(a, b K) bool {
+	c := v.comp(a, b)
+	return c < 0 || c == 0 && v.multi
+}
+
+// walk 检查子树，其关键码应在开区间 (lo, hi) 内，nil 表示无界，多重映射中为闭区间
+func (v binaryValidator[K, V]) walk(n Node[K, V], lo, hi *K) (int, error) {
+	key := n.Key()
+	if lo != nil && !v.before(*lo, key) {
+		return 0, NewInvariantError(v.c, RuleOrder, key, "not greater than %v", *lo)
+	}
+	if hi != nil && !v.before(key, *hi) {
+		return 0, NewInvariantError(v.c, RuleOrder, key, "not less than %v", *hi)
+	}
+	size := 1
+	for i, c := range [2]Node[K, V]{n.LChild(), n.RChild()} {
+		if IsNil(c) {
+			continue
+		}
+		if p := c.Parent(); IsNil(p) || p != n {
+			return 0, NewInvariantError(v.c, RuleParent, c.Key(), "parent is not %v", key)
+		}
+		l, h := lo, &key
+		if i == 1 {
+			l, h = &key, hi
+		}
+		s, err := v.walk(c, l, h)
+		if err != nil {
+			return 0, err
+		}
+		size += s
+	}
+	if v.check != nil {
+		if err := v.check(n); err != nil {
+			return 0, err
+		}
+	}
+	return size, nil
+}
+
+// MustValidate panics if v breaks an invariant, the trees call it after
+// every mutation when Debug is set
+func MustValidate(v Validator) {
+	if err := v.Validate(); err != nil {
+		panic(err)
+	}
+}
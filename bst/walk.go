@@ -0,0 +1,175 @@
+package bst
+
+// Traverser is implemented by the trees able to walk their nodes under a
+// visitor which stops the walk by returning false
+type Traverser[K, V any] interface {
+	// Traverse calls fn on the nodes by order o until fn returns false, and
+	// reports whether the walk went to the end
+	Traverse(o Order, fn func(n Node[K, V]) bool) bool
+}
+
+// Visit walks t by order o, calling fn on the nodes until fn returns false,
+// and reports whether the walk went to the end. A tree not implementing
+// Traverser is walked from its root by Traverse.
+func Visit[K, V any](t BST[K, V], o Order, fn func(n Node[K, V]) bool) bool {
+	if tr, ok := t.(Traverser[K, V]); ok {
+		return tr.Traverse(o, fn)
+	}
+	return Traverse(t.Root(), o, fn)
+}
+
+// Layered is implemented by the structures made of levels of lists instead
+// of a hierarchy of nodes, such as the skip list, whose root has no children
+type Layered interface {
+	// Levels returns the number of levels in use, 0 when empty
+	Levels() int
+}
+
+// wrapper 由包装其他树的 typedBST、fallbackBST 与 erasedBST 实现
+type wrapper interface {
+	unwrap() interface{}
+}
+
+// Levels returns the number of levels of t if it is Layered, looking through
+// the wrappers of NewOf and Erase
+func Levels[K, V any](t BST[K, V]) (int, bool) {
+	var x interface{} = t
+	for {
+		if l, ok := x.(Layered); ok {
+			return l.Levels(), true
+		}
+		w, ok := x.(wrapper)
+		if !ok {
+			return 0, false
+		}
+		x = w.unwrap()
+	}
+}
+
+// VisitErr walks t by order o, calling fn on the nodes until fn returns an
+// error, which is returned
+func VisitErr[K, V any](t BST[K, V], o Order, fn func(n Node[K, V]) error) error {
+	var err error
+	Visit(t, o, func(n Node[K, V]) bool {
+		err = fn(n)
+		return err == nil
+	})
+	return err
+}
+
+// Traverse walks the binary subtree rooted on n by order o, calling fn on the
+// nodes until fn returns false, and reports whether the walk went to the end.
+// The walk keeps its own stack or queue instead of recursing, so degenerate
+// trees such as a splay tree after sequential accesses are safe to walk.
+func Traverse[K, V any](n Node[K, V], o Order, fn func(n Node[K, V]) bool) bool {
+	if IsNil(n) {
+		return true
+	}
+	switch o {
+	case PreOrder:
+		return travPre(n, fn)
+	case InOrder:
+		return travIn(n, fn, false)
+	case ReverseInOrder:
+		return travIn(n, fn, true)
+	case PostOrder:
+		return travPost(n, fn)
+	case LevelOrder:
+		return travLevel(n, fn)
+	}
+	panic("unsupported walk order")
+}
+
+// travPre 先序遍历，右孩子先于左孩子入栈
+func travPre[K, V any](n Node[K, V], fn func(n Node[K, V]) bool) bool {
+	stack := []Node[K, V]{n}
+	for len(stack) > 0 {
+		x := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if !fn(x) {
+			return false
+		}
+		if HasRChild(x) {
+			stack = append(stack, x.RChild())
+		}
+		if HasLChild(x) {
+			stack = append(stack, x.LChild())
+		}
+	}
+	return true
+}
+
+// travIn 中序遍历，reverse 时左右互换，自大到小访问
+func travIn[K, V any](n Node[K, V], fn func(n Node[K, V]) bool, reverse bool) bool {
+	first, second := Node[K, V].LChild, Node[K, V].RChild
+	if reverse {
+		first, second = second, first
+	}
+	var stack []Node[K, V]
+	for x := n; !IsNil(x) || len(stack) > 0; {
+		// 沿 first 一侧的通路下行至尽头
+		for ; !IsNil(x); x = first(x) {
+			stack = append(stack, x)
+		}
+		x = stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if !fn(x) {
+			return false
+		}
+		x = second(x)
+	}
+	return true
+}
+
+// travPost 后序遍历，last 为最近访问的节点，据此判断栈顶的右子树是否已访问
+func travPost[K, V any](n Node[K, V], fn func(n Node[K, V]) bool) bool {
+	var stack []Node[K, V]
+	var last Node[K, V]
+	for x := n; !IsNil(x) || len(stack) > 0; {
+		if !IsNil(x) {
+			stack = append(stack, x)
+			x = x.LChild()
+			continue
+		}
+		top := stack[len(stack)-1]
+		if r := top.RChild(); !IsNil(r) && r != last {
+			x = r
+			continue
+		}
+		if !fn(top) {
+			return false
+		}
+		last = top
+		stack = stack[:len(stack)-1]
+	}
+	return true
+}
+
+// travLevel 层次遍历
+func travLevel[K, V any](n Node[K, V], fn func(n Node[K, V]) bool) bool {
+	queue := []Node[K, V]{n}
+	for len(queue) > 0 {
+		x := queue[0]
+		queue = queue[1:]
+		if !fn(x) {
+			return false
+		}
+		if HasLChild(x) {
+			queue = append(queue, x.LChild())
+		}
+		if HasRChild(x) {
+			queue = append(queue, x.RChild())
+		}
+	}
+	return true
+}
+
+// options 将 opts 合为一个从不终止遍历的访问者
+func options[K, V any](opts []Option[K, V]) func(n Node[K, V]) bool {
+	return func(n Node[K, V]) bool {
+		for _, opt := range opts {
+			opt(n)
+		}
+		return true
+	}
+}
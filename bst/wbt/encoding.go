@@ -0,0 +1,46 @@
+package wbt
+
+import (
+	"errors"
+
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+// MarshalBinary encodes the tree as configured by the bst.Encoding given to New
+func (t *wbTree[K, V]) MarshalBinary() ([]byte, error) {
+	return bst.EncodeBinary[K, V](t, bst.WeightBalanced, t.enc)
+}
+
+// UnmarshalBinary replaces the content of the tree by data from
+// MarshalBinary, a kept shape must be balanced by the α of the tree
+func (t *wbTree[K, V]) UnmarshalBinary(data []byte) error {
+	return bst.DecodeBinary[K, V](t, bst.WeightBalanced, t.enc, data, t.shapeBuilder())
+}
+
+func (t *wbTree[K, V]) MarshalJSON() ([]byte, error) {
+	return bst.EncodeJSON[K, V](t, bst.WeightBalanced, t.enc)
+}
+
+func (t *wbTree[K, V]) UnmarshalJSON(data []byte) error {
+	return bst.DecodeJSON[K, V](t, bst.WeightBalanced, t.enc, data, t.shapeBuilder())
+}
+
+var errBalance = errors.New("wbt: decoding a shape out of weight balance")
+
+// shapeBuilder 按原有形状重建节点，拒绝孩子的权重低于 α 倍的节点
+func (t *wbTree[K, V]) shapeBuilder() bst.ShapeBuilder[K, V] {
+	return bst.ShapeBuilder[K, V]{
+		Comp: t.comp,
+		Node: func(key K, data V, _ bool, l, r bst.Node[K, V]) (bst.Node[K, V], error) {
+			n := link(nodeOf(l), &node[K, V]{key: key, data: data}, nodeOf(r), t.updates)
+			if t.checkBalance(n) != nil {
+				return nil, errBalance
+			}
+			return n, nil
+		},
+		SetRoot: func(root bst.Node[K, V]) error {
+			t.root = orphan(nodeOf(root))
+			return nil
+		},
+	}
+}
@@ -0,0 +1,26 @@
+package wbt
+
+import (
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+// Validate checks the key order, the parent pointers, the sizes and that both
+// children of every node weigh at least α of it
+func (t *wbTree[K, V]) Validate() error {
+	_, err := bst.ValidateBinary[K, V](bst.WeightBalanced, t.root, t.comp, func(m bst.Node[K, V]) error {
+		n := m.(*node[K, V])
+		if size := bst.Size[K, V](n.lchild) + bst.Size[K, V](n.rchild) + 1; n.size != size {
+			return bst.NewInvariantError(bst.WeightBalanced, bst.RuleSize, n.key, "size %d, want %d", n.size, size)
+		}
+		return t.checkBalance(n)
+	})
+	return err
+}
+
+// checkBalance 检查 n 的两个孩子的权重均不低于 n 的 α 倍
+func (t *wbTree[K, V]) checkBalance(n *node[K, V]) error {
+	if t.light(n.lchild, n) || t.light(n.rchild, n) {
+		return bst.NewInvariantError(bst.WeightBalanced, bst.RuleBalance, n.key, "children weigh %d and %d of %d", weight(n.lchild), weight(n.rchild), weight(n))
+	}
+	return nil
+}
@@ -0,0 +1,442 @@
+// Package wbt implements the weight-balanced tree BB[α] of Nievergelt and
+// Reingold, a binary search tree keeping the size of every subtree such that
+// either side of a node holds at least α of its weight, the size plus one.
+// A node outweighed after an update is fixed by a single or double rotation
+// as shown by Blum and Mehlhorn, so the height stays within log_{1/(1-α)} n
+// and the sizes double as order statistics.
+package wbt
+
+import (
+	"fmt"
+
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+func init() {
+	bst.Register("wbt", bst.Factory{
+		New: New[any, any],
+		Typed: []interface{}{
+			New[int, int], New[int, string], New[int, any],
+			New[string, int], New[string, string], New[string, any],
+		},
+		Alpha: checkAlpha,
+	})
+}
+
+const (
+	defaultAlpha = 0.25
+	minAlpha     = 2.0 / 11       // α 须大于 2/11，单旋或双旋才足以恢复平衡
+	maxAlpha     = 0.292893218813 // 1-√2/2，α 更大时不一定存在满足条件的树
+)
+
+type wbTree[K, V any] struct {
+	root    *node[K, V]
+	comp    bst.Comparator[K]
+	aug     bst.Augmenter[K, V]
+	enc     bst.Encoding[K, V]
+	stats   *bst.Stats
+	alpha   float64            // 孩子的权重不低于父节点的 α 倍
+	delta   float64            // 1/(2-α)，重的孩子中内侧孙子的权重比超过它时须双旋
+	updates []bst.Option[K, V] // 结构变化后自底向上更新节点
+}
+
+// checkAlpha 检查 bst.WithAlpha 给出的 α 是否在 (2/11, 1-√2/2] 内
+func checkAlpha(alpha float64) error {
+	if !(alpha > minAlpha && alpha <= maxAlpha) {
+		return fmt.Errorf("alpha %v is not in (2/11, 1-√2/2]", alpha)
+	}
+	return nil
+}
+
+// New returns an empty weight-balanced tree. A float64 in parms stands for
+// α in (2/11, 1-√2/2], 0.25 by default, a greater α keeps the tree lower at
+// the cost of more rotations. An α out of range is replaced by the default
+// here, while bst.Make rejects it with bst.WithAlpha. Reads leave the
+// subtree weights as they are, so under bst.Synchronized they run in
+// parallel while the rotating writes are exclusive.
+func New[K, V any](parms ...interface{}) bst.BST[K, V] {
+	t := new(wbTree[K, V])
+	t.comp = bst.DefaultCompare[K]()
+	for _, p := range parms {
+		switch v := p.(type) {
+		case bst.Comparator[K]:
+			t.comp = v
+		case func(a, b K) int:
+			t.comp = v
+		case bst.Augmenter[K, V]:
+			t.aug = v
+		case bst.Encoding[K, V]:
+			t.enc = v
+		case *bst.Stats:
+			t.stats = v
+		case float64:
+			t.alpha = v
+		}
+	}
+	t.comp = bst.CountComparisons(t.comp, t.stats)
+	if checkAlpha(t.alpha) != nil {
+		t.alpha = defaultAlpha
+	}
+	t.delta = 1 / (2 - t.alpha)
+	t.updates = []bst.Option[K, V]{updateSize[K, V]}
+	if t.aug != nil {
+		t.updates = append(t.updates, bst.AugmentOption(t.aug))
+	}
+	return t
+}
+
+// Len returns the number of keys in the tree
+func (t *wbTree[K, V]) Len() int {
+	if t.root == nil {
+		return 0
+	}
+	return t.root.size
+}
+
+func (t *wbTree[K, V]) Root() bst.Node[K, V] {
+	return t.root
+}
+
+func (t *wbTree[K, V]) Print() {
+	bst.PrintWithUnitSize(t.root, 2)
+}
+
+func (t *wbTree[K, V]) Search(key K) (bst.Node[K, V], bool) {
+	if t.root == nil {
+		return nil, false
+	}
+	n, result := t.searchIn(key)
+	return n, result == 0
+}
+
+// searchIn 在非空树中查找 key，未找到时返回最后访问的节点及新节点应在其哪一侧
+func (t *wbTree[K, V]) searchIn(key K) (*node[K, V], int) {
+	n := t.root
+	for {
+		c := t.comp(key, n.key)
+		next := n.rchild
+		switch {
+		case c == 0:
+			return n, 0
+		case c < 0:
+			next = n.lchild
+		}
+		if next == nil {
+			return n, c
+		}
+		n = next
+	}
+}
+
+// light 判断 n 的孩子 c 是否过轻，即其权重低于 n 的 α 倍
+func (t *wbTree[K, V]) light(c, n *node[K, V]) bool {
+	return float64(weight(c)) < t.alpha*float64(weight(n))
+}
+
+// rebalance 在 n 的一侧过轻时将另一侧的孩子上旋，该孩子中内侧孙子的权重比超过 δ 时
+// 先将内侧孙子上旋为双旋，返回新的子树根
+func (t *wbTree[K, V]) rebalance(n *node[K, V]) *node[K, V] {
+	switch {
+	case t.light(n.lchild, n):
+		c := n.rchild
+		if float64(weight(c.lchild)) > t.delta*float64(weight(c)) {
+			c = t.rotateUp(c.lchild)
+		}
+		return t.rotateUp(c)
+	case t.light(n.rchild, n):
+		c := n.lchild
+		if float64(weight(c.rchild)) > t.delta*float64(weight(c)) {
+			c = t.rotateUp(c.rchild)
+		}
+		return t.rotateUp(c)
+	}
+	return n
+}
+
+// fixAbove 自 n 向上更新各节点的规模与摘要，并恢复其平衡
+func (t *wbTree[K, V]) fixAbove(n *node[K, V]) {
+	for ; n != nil; n = n.parent {
+		update(t.updates, n)
+		n = t.rebalance(n)
+	}
+}
+
+// rotateUp 将 n 与其父节点 p 单旋交换位置，p 成为 n 的孩子，返回 n
+func (t *wbTree[K, V]) rotateUp(n *node[K, V]) *node[K, V] {
+	p := n.parent
+	g := p.parent
+	if p.lchild == n {
+		bst.AttachLChild[K, V](p, n.rchild)
+		bst.AttachRChild[K, V](n, p)
+	} else {
+		bst.AttachRChild[K, V](p, n.lchild)
+		bst.AttachLChild[K, V](n, p)
+	}
+	switch {
+	case g == nil:
+		t.root = n
+		n.parent = nil
+	case g.lchild == p:
+		bst.AttachLChild[K, V](g, n)
+	default:
+		bst.AttachRChild[K, V](g, n)
+	}
+	update(t.updates, p, n)
+	t.stats.Add(1)
+	return n
+}
+
+func (t *wbTree[K, V]) Insert(key K, data V) (_ bst.Node[K, V], err error) {
+	if bst.Debug {
+		defer bst.MustValidate(t)
+	}
+	defer bst.CatchIncomparable(&err)
+	if t.root == nil {
+		return t.attach(nil, 0, key, data), nil
+	}
+	n, result := t.searchIn(key)
+	if result == 0 {
+		return nil, bst.ErrDuplicateKey
+	}
+	return t.attach(n, result, key, data), nil
+}
+
+// attach 将新节点插入为 searchIn 返回的节点 n 的孩子，result 为查找的结果，n 为 nil 时作为根，
+// 随后自下而上恢复各祖先的平衡
+func (t *wbTree[K, V]) attach(n *node[K, V], result int, key K, data V) *node[K, V] {
+	new := &node[K, V]{key: key, data: data, parent: n}
+	update(t.updates, new)
+	switch {
+	case n == nil:
+		t.root = new
+		return new
+	case result < 0:
+		n.lchild = new
+	default:
+		n.rchild = new
+	}
+	t.fixAbove(n)
+	return new
+}
+
+func (t *wbTree[K, V]) Remove(key K) (_ bst.Node[K, V], err error) {
+	if bst.Debug {
+		defer bst.MustValidate(t)
+	}
+	defer bst.CatchIncomparable(&err)
+	if t.root == nil {
+		return nil, bst.ErrNotFound
+	}
+	n, result := t.searchIn(key)
+	if result != 0 {
+		return nil, bst.ErrNotFound
+	}
+	t.detach(n)
+	return n, nil
+}
+
+// detach 删除节点 n，有两个孩子时以其后继节点替代它，再自最低的变化处向上恢复平衡
+func (t *wbTree[K, V]) detach(n *node[K, V]) {
+	c, hot := n.lchild, n.parent
+	switch {
+	case n.rchild == nil:
+	case c == nil:
+		c = n.rchild
+	default:
+		succ := n.rchild
+		for succ.lchild != nil {
+			succ = succ.lchild
+		}
+		r := succ.rchild
+		hot = succ
+		if succ != n.rchild {
+			hot = succ.parent
+			t.transplant(succ, succ.rchild)
+			r = n.rchild
+		}
+		c = succ
+		link(n.lchild, succ, r, nil)
+	}
+	t.transplant(n, c)
+	n.lchild, n.rchild, n.parent = nil, nil, nil
+	t.fixAbove(hot)
+}
+
+// transplant 以 c 替代 n 在其父节点中的位置
+func (t *wbTree[K, V]) transplant(n, c *node[K, V]) {
+	p := n.parent
+	switch {
+	case p == nil:
+		t.root = c
+	case p.lchild == n:
+		p.lchild = c
+	default:
+		p.rchild = c
+	}
+	if c != nil {
+		c.parent = p
+	}
+}
+
+// Put inserts key with data or replaces its data after a single search
+func (t *wbTree[K, V]) Put(key K, data V) (old V, replaced bool, err error) {
+	if bst.Debug {
+		defer bst.MustValidate(t)
+	}
+	defer bst.CatchIncomparable(&err)
+	if t.root == nil {
+		t.attach(nil, 0, key, data)
+		return old, false, nil
+	}
+	n, result := t.searchIn(key)
+	if result != 0 {
+		t.attach(n, result, key, data)
+		return old, false, nil
+	}
+	old = n.data
+	t.replace(n, data)
+	return old, true, nil
+}
+
+// replace 替换节点的数据，数据参与增强时更新摘要
+func (t *wbTree[K, V]) replace(n *node[K, V], data V) {
+	n.data = data
+	if t.aug != nil {
+		bst.UpdateAbove[K, V](n, t.updates...)
+	}
+}
+
+func (t *wbTree[K, V]) Update(key K, fn func(old V, ok bool) (V, bool)) (err error) {
+	if bst.Debug {
+		defer bst.MustValidate(t)
+	}
+	defer bst.CatchIncomparable(&err)
+	var n *node[K, V]
+	result := 1
+	if t.root != nil {
+		n, result = t.searchIn(key)
+	}
+	var old V
+	if result == 0 {
+		old = n.data
+	}
+	data, keep := fn(old, result == 0)
+	switch {
+	case result == 0 && keep:
+		t.replace(n, data)
+	case result == 0:
+		t.detach(n)
+	case keep:
+		t.attach(n, result, key, data)
+	}
+	return nil
+}
+
+func (t *wbTree[K, V]) GetOrInsert(key K, data V) (_ bst.Node[K, V], found bool, err error) {
+	if bst.Debug {
+		defer bst.MustValidate(t)
+	}
+	defer bst.CatchIncomparable(&err)
+	if t.root == nil {
+		return t.attach(nil, 0, key, data), false, nil
+	}
+	n, result := t.searchIn(key)
+	if result == 0 {
+		return n, true, nil
+	}
+	return t.attach(n, result, key, data), false, nil
+}
+
+// BuildSorted builds a tree of minimum height from the sorted keys in O(n)
+func (t *wbTree[K, V]) BuildSorted(keys []K, values []V, verify bool) error {
+	if verify {
+		if err := bst.CheckSorted(keys, t.comp); err != nil {
+			return err
+		}
+	}
+	t.root = orphan(t.build(keys, values))
+	return nil
+}
+
+// build 以中位数为根递归构建子树，两侧的规模至多相差 1
+func (t *wbTree[K, V]) build(keys []K, values []V) *node[K, V] {
+	if len(keys) == 0 {
+		return nil
+	}
+	mid := len(keys) / 2
+	n := &node[K, V]{key: keys[mid], data: values[mid]}
+	return link(t.build(keys[:mid], values[:mid]), n, t.build(keys[mid+1:], values[mid+1:]), t.updates)
+}
+
+func (t *wbTree[K, V]) Walk(o bst.Order, opts ...bst.Option[K, V]) {
+	switch o {
+	case bst.PreOrder:
+		bst.TravPre(t.root, opts...)
+	case bst.InOrder:
+		bst.TravIn(t.root, opts...)
+	case bst.PostOrder:
+		bst.TravPost(t.root, opts...)
+	case bst.LevelOrder:
+		bst.TravLevel(t.root, opts...)
+	case bst.ReverseInOrder:
+		bst.TravReverseIn(t.root, opts...)
+	default:
+		panic("unsupported walk order")
+	}
+}
+
+func (t *wbTree[K, V]) Traverse(o bst.Order, fn func(n bst.Node[K, V]) bool) bool {
+	return bst.Traverse[K, V](t.root, o, fn)
+}
+
+func (t *wbTree[K, V]) Iterator() bst.Iterator[K, V] {
+	return bst.NewIterator[K, V](t, t.comp)
+}
+
+func (t *wbTree[K, V]) Floor(key K) (bst.Node[K, V], bool) {
+	return bst.Floor(t.root, key, t.comp)
+}
+
+func (t *wbTree[K, V]) Ceiling(key K) (bst.Node[K, V], bool) {
+	return bst.Ceiling(t.root, key, t.comp)
+}
+
+func (t *wbTree[K, V]) Lower(key K) (bst.Node[K, V], bool) {
+	return bst.Lower(t.root, key, t.comp)
+}
+
+func (t *wbTree[K, V]) Higher(key K) (bst.Node[K, V], bool) {
+	return bst.Higher(t.root, key, t.comp)
+}
+
+func (t *wbTree[K, V]) Min() (bst.Node[K, V], bool) { return bst.Min(t.root) }
+
+func (t *wbTree[K, V]) Max() (bst.Node[K, V], bool) { return bst.Max(t.root) }
+
+func (t *wbTree[K, V]) Range(lo, hi K, loInclusive, hiInclusive bool, fn func(n bst.Node[K, V]) bool) {
+	bst.Range(t.root, lo, hi, loInclusive, hiInclusive, t.comp, fn)
+}
+
+// Select returns the k-th smallest key, counting from 0, by the sizes that
+// keep the tree balanced
+func (t *wbTree[K, V]) Select(k int) (bst.Node[K, V], bool) { return bst.Select(t.root, k) }
+
+func (t *wbTree[K, V]) Rank(key K) int { return bst.Rank(t.root, key, t.comp) }
+
+func (t *wbTree[K, V]) CountRange(lo, hi K) int {
+	return bst.CountRange(t.root, lo, hi, t.comp)
+}
+
+func (t *wbTree[K, V]) Summary() any {
+	if t.aug == nil {
+		return nil
+	}
+	return bst.SummaryOf[K, V](t.root, t.aug)
+}
+
+func (t *wbTree[K, V]) SummaryRange(lo, hi K) any {
+	if t.aug == nil {
+		return nil
+	}
+	return bst.SummaryRange[K, V](t.root, lo, hi, t.comp, t.aug)
+}
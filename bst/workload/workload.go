@@ -0,0 +1,228 @@
+// Package workload generates the operation mixes used to compare the tree
+// classes of package bst, and measures a class running them.
+package workload
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"time"
+
+	"github.com/mooncaker816/gostructure/bst"
+)
+
+// Kind is the kind of an operation
+type Kind uint8
+
+const (
+	Search Kind = iota
+	Insert
+	Remove
+	Scan // 遍历 [Key, Key+Span) 内的关键码
+)
+
+// Op is an operation of a workload
+type Op struct {
+	Kind Kind
+	Key  int
+}
+
+// Access is the distribution of the keys accessed
+type Access uint8
+
+const (
+	Uniform    Access = iota // 均匀分布
+	Zipf                     // 少数热点关键码占多数访问
+	Sequential               // 依次递增
+)
+
+// Workload describes a mix of operations on int keys in [0, Keys)
+type Workload struct {
+	Name string
+	Keys int // 关键码空间，开始前预先插入其中的一半
+	// 各类操作的占比，和不必为 1
+	Search, Insert, Remove, Scan float64
+
+	Access Access
+	Span   int // 每次 Scan 遍历的关键码范围
+}
+
+// Standard returns the predefined workloads on keys in [0, keys): read-heavy,
+// write-heavy, skewed Zipf reads, sequential inserts and range scans
+func Standard(keys int) []Workload {
+	return []Workload{
+		{Name: "read", Keys: keys, Search: 0.9, Insert: 0.05, Remove: 0.05},
+		{Name: "write", Keys: keys, Search: 0.2, Insert: 0.4, Remove: 0.4},
+		{Name: "zipf", Keys: keys, Search: 0.9, Insert: 0.05, Remove: 0.05, Access: Zipf},
+		{Name: "seq", Keys: keys, Search: 0.2, Insert: 0.6, Remove: 0.2, Access: Sequential},
+		{Name: "range", Keys: keys, Search: 0.5, Insert: 0.1, Remove: 0.1, Scan: 0.3, Span: 100},
+	}
+}
+
+// Lookup returns the workload of name in ws
+func Lookup(ws []Workload, name string) (Workload, bool) {
+	for _, w := range ws {
+		if w.Name == name {
+			return w, true
+		}
+	}
+	return Workload{}, false
+}
+
+// Generate returns the keys to preload and n ops of w drawn from r
+func (w Workload) Generate(r *rand.Rand, n int) (preload []int, ops []Op) {
+	keys := max(w.Keys, 2)
+	perm := r.Perm(keys)
+	preload = perm[:keys/2]
+	var next func() int
+	switch w.Access {
+	case Zipf:
+		// 热点分散到整个关键码空间
+		z := rand.NewZipf(r, 1.2, 1, uint64(keys-1))
+		next = func() int { return perm[z.Uint64()] }
+	case Sequential:
+		i := 0
+		next = func() int {
+			i++
+			return i % keys
+		}
+	default:
+		next = func() int { return r.Intn(keys) }
+	}
+	total := w.Search + w.Insert + w.Remove + w.Scan
+	if total <= 0 {
+		total, w.Search = 1, 1
+	}
+	ops = make([]Op, n)
+	for i := range ops {
+		x := r.Float64() * total
+		kind := Search
+		switch {
+		case x < w.Search:
+		case x < w.Search+w.Insert:
+			kind = Insert
+		case x < w.Search+w.Insert+w.Remove:
+			kind = Remove
+		default:
+			kind = Scan
+		}
+		ops[i] = Op{kind, next()}
+	}
+	return preload, ops
+}
+
+// Apply runs ops on t, returning the number of keys visited, which keeps
+// the work from being optimized away
+func (w Workload) Apply(t bst.BST[int, int], ops []Op) int {
+	visited := 0
+	for _, op := range ops {
+		switch op.Kind {
+		case Search:
+			if _, ok := t.Search(op.Key); ok {
+				visited++
+			}
+		case Insert:
+			t.Insert(op.Key, op.Key)
+		case Remove:
+			t.Remove(op.Key)
+		case Scan:
+			t.Range(op.Key, op.Key+w.Span, true, false, func(bst.Node[int, int]) bool {
+				visited++
+				return true
+			})
+		}
+	}
+	return visited
+}
+
+// Result is the measurement of a class running a workload
+type Result struct {
+	Class    string
+	Workload string
+	Ops      int
+	Elapsed  time.Duration
+	Allocs   uint64 // 堆分配次数
+	Bytes    uint64 // 堆分配字节数
+	Stats    bst.Stats
+	Height   int // 结束时的树高，B-树为节点的层数减一，跳表等 bst.Layered 结构为层数
+	Len      int
+}
+
+// OpsPerSec returns the throughput of r
+func (r Result) OpsPerSec() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Ops) / r.Elapsed.Seconds()
+}
+
+// Run measures a new tree of class c running n ops of w generated from
+// seed, parms are passed to bst.NewOf. The preloading is not measured.
+func Run(name string, c bst.Class, w Workload, n int, seed int64, parms ...interface{}) Result {
+	preload, ops := w.Generate(rand.New(rand.NewSource(seed)), n)
+	stats := new(bst.Stats)
+	t := bst.NewOf[int, int](c, append(parms, stats)...)
+	for _, k := range preload {
+		t.Insert(k, k)
+	}
+	stats.Reset()
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	w.Apply(t, ops)
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+	height, layered := bst.Levels(t)
+	if !layered {
+		height = Height(t.Root())
+	}
+	return Result{
+		Class:    name,
+		Workload: w.Name,
+		Ops:      n,
+		Elapsed:  elapsed,
+		Allocs:   after.Mallocs - before.Mallocs,
+		Bytes:    after.TotalAlloc - before.TotalAlloc,
+		Stats:    stats.Load(),
+		Height:   height,
+		Len:      t.Len(),
+	}
+}
+
+// Height returns the height of the subtree rooted at n, -1 if it is empty.
+// Multiway nodes count as one level.
+func Height[K, V any](n bst.Node[K, V]) int {
+	if bst.IsNil(n) {
+		return -1
+	}
+	h := -1
+	if m, ok := n.(bst.Multiway[K, V]); ok {
+		if keys, _ := m.Entries(); keys != nil {
+			for _, c := range m.Children() {
+				h = max(h, Height(c))
+			}
+			return h + 1
+		}
+	}
+	return max(Height(n.LChild()), Height(n.RChild())) + 1
+}
+
+// Header returns the column names of Result.Row
+func Header() []string {
+	return []string{"class", "workload", "ops", "ops/sec", "allocs/op", "bytes/op",
+		"cmp/op", "rotations", "splits", "merges", "rebuilds", "height", "len"}
+}
+
+// Row returns the columns of r
+func (r Result) Row() []string {
+	per := func(v uint64) string { return fmt.Sprintf("%.2f", float64(v)/float64(max(r.Ops, 1))) }
+	return []string{
+		r.Class, r.Workload, fmt.Sprint(r.Ops),
+		fmt.Sprintf("%.0f", r.OpsPerSec()),
+		per(r.Allocs), per(r.Bytes),
+		per(uint64(r.Stats.Comparisons)),
+		fmt.Sprint(r.Stats.Rotations), fmt.Sprint(r.Stats.Splits), fmt.Sprint(r.Stats.Merges),
+		fmt.Sprint(r.Stats.Rebuilds), fmt.Sprint(r.Height), fmt.Sprint(r.Len),
+	}
+}
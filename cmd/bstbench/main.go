@@ -0,0 +1,120 @@
+// Command bstbench compares the tree classes of package bst on workloads of
+// searches, inserts, removes and range scans, reporting the throughput,
+// allocations, comparisons, rotations and final height of each class, the
+// skip list reporting its number of levels as height.
+//
+// Usage:
+//
+//	bstbench [-class avl,rb,splay,btree,treap,skiplist,llrb,aa,scapegoat,wbt,bplustree] [-workload read,write,zipf,seq,range]
+//	         [-n ops] [-keys n] [-order m] [-seed s] [-csv]
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mooncaker816/gostructure/bst"
+	_ "github.com/mooncaker816/gostructure/bst/aa"
+	_ "github.com/mooncaker816/gostructure/bst/avl"
+	_ "github.com/mooncaker816/gostructure/bst/bplustree"
+	_ "github.com/mooncaker816/gostructure/bst/btree"
+	_ "github.com/mooncaker816/gostructure/bst/llrb"
+	_ "github.com/mooncaker816/gostructure/bst/redblack"
+	_ "github.com/mooncaker816/gostructure/bst/scapegoat"
+	_ "github.com/mooncaker816/gostructure/bst/skiplist"
+	_ "github.com/mooncaker816/gostructure/bst/splay"
+	_ "github.com/mooncaker816/gostructure/bst/treap"
+	_ "github.com/mooncaker816/gostructure/bst/wbt"
+	"github.com/mooncaker816/gostructure/bst/workload"
+)
+
+// aliases 命令行中类名的简写，其余的类名由 bst.Lookup 查找
+var aliases = map[string]string{"rb": "redblack"}
+
+func main() {
+	var (
+		classList    = flag.String("class", "avl,rb,splay,btree,treap,skiplist,llrb,aa,scapegoat,wbt,bplustree", "comma separated classes to run")
+		workloadList = flag.String("workload", "read,write,zipf,seq,range", "comma separated workloads to run")
+		n            = flag.Int("n", 100000, "number of operations of each run")
+		keys         = flag.Int("keys", 10000, "size of the key space, half of which is preloaded")
+		order        = flag.Int("order", 0, "order of the B-tree and B+ tree, 0 for the default")
+		seed         = flag.Int64("seed", 1, "seed of the generated operations")
+		asCSV        = flag.Bool("csv", false, "write CSV instead of a table")
+	)
+	flag.Parse()
+
+	var results []workload.Result
+	std := workload.Standard(*keys)
+	for _, wn := range split(*workloadList) {
+		w, ok := workload.Lookup(std, wn)
+		if !ok {
+			fatalf("unknown workload %q", wn)
+		}
+		for _, cn := range split(*classList) {
+			c, ok := lookup(cn)
+			if !ok {
+				fatalf("unknown class %q", cn)
+			}
+			var parms []interface{}
+			if (c == bst.BTree || c == bst.BPlusTree) && *order > 0 {
+				parms = append(parms, *order)
+			}
+			results = append(results, workload.Run(cn, c, w, *n, *seed, parms...))
+		}
+	}
+	var err error
+	if *asCSV {
+		err = writeCSV(os.Stdout, results)
+	} else {
+		err = writeTable(os.Stdout, results)
+	}
+	if err != nil {
+		fatalf("%v", err)
+	}
+}
+
+func lookup(name string) (bst.Class, bool) {
+	if full, ok := aliases[name]; ok {
+		name = full
+	}
+	return bst.Lookup(name)
+}
+
+func split(list string) []string {
+	var names []string
+	for _, s := range strings.Split(list, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			names = append(names, s)
+		}
+	}
+	return names
+}
+
+func writeTable(w io.Writer, results []workload.Result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, strings.Join(workload.Header(), "\t")+"\t")
+	for _, r := range results {
+		fmt.Fprintln(tw, strings.Join(r.Row(), "\t")+"\t")
+	}
+	return tw.Flush()
+}
+
+func writeCSV(w io.Writer, results []workload.Result) error {
+	cw := csv.NewWriter(w)
+	cw.Write(workload.Header())
+	for _, r := range results {
+		cw.Write(r.Row())
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "bstbench: "+format+"\n", args...)
+	os.Exit(2)
+}